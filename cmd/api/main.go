@@ -62,7 +62,12 @@ func main() {
 	// Setup Outbox Publisher
 	startTime = time.Now()
 	outboxRepo := repository.NewOutboxRepository(dbInstance.GetDB())
-	outboxPublisher := service.NewOutboxPublisher(outboxRepo, queueInstance)
+	outboxListener := db.NewListener(env.Cfg.Database, data.OUTBOX_NOTIFY_CHANNEL)
+	outboxPublisher := service.NewOutboxPublisher(outboxRepo, queueInstance, env.Cfg.Outbox).
+		WithWakeup(outboxListener.Notifications())
+
+	// Wake the publisher on NOTIFY instead of waiting for the next poll
+	go outboxListener.Run(ctx)
 
 	// Start outbox publisher worker
 	go outboxPublisher.Start(ctx)
@@ -71,18 +76,63 @@ func main() {
 	go outboxPublisher.StartCleanupJob(ctx)
 	logger.Info(data.LogOutboxPublisherStarted, map[string]any{"service": data.OutboxService, "duration": utils.Ms(time.Since(startTime))})
 
+	// Start transaction event consumer to keep wallet balances in sync
+	inboxProcessor := service.NewInboxProcessor(
+		repository.NewTxManager(dbInstance.GetDB()),
+		repository.NewInboxRepository(dbInstance.GetDB()),
+	)
+	balanceSyncServ := service.NewBalanceSyncService(inboxProcessor, repository.NewWalletRepository(dbInstance.GetDB()))
+	transactionConsumer := queue.NewConsumer(queueInstance, queue.Subscription{
+		Queue: data.TRANSACTION_EVENTS_QUEUE,
+		RoutingKeys: []string{
+			data.TRANSACTION_EVENT_CREATED,
+			data.TRANSACTION_EVENT_UPDATED,
+			data.TRANSACTION_EVENT_DELETED,
+		},
+		DeadLetterQueue: data.TRANSACTION_EVENTS_DEAD_LETTER_QUEUE,
+	}, balanceSyncServ.HandleTransactionEvent)
+	go transactionConsumer.Run(ctx)
+	go inboxProcessor.StartCleanupJob(ctx)
+
+	// Purge expired idempotency keys of the HTTP and gRPC mutation endpoints
+	idempotencyServ := service.NewIdempotencyService(repository.NewIdempotencyRepository(dbInstance.GetDB()))
+	go idempotencyServ.StartCleanupJob(ctx)
+
+	// Load exchange rates used to convert wallet summaries to the user's base currency
+	exchangeRates, err := service.NewExchangeRateProvider(env.Cfg.ExchangeRate)
+	if err != nil {
+		logger.Fatal(data.LogExchangeRateSetupFailed, map[string]any{"service": data.MainService, "error": err.Error()})
+	}
+
+	// Load the keys bearer tokens of the HTTP API are verified with
+	tokenVerifier, err := service.NewJWTVerifier(ctx, env.Cfg.Auth)
+	if err != nil {
+		logger.Fatal(data.LogAuthSetupFailed, map[string]any{"service": data.AuthService, "error": err.Error()})
+	}
+
+	// Permissions required by every HTTP route and gRPC method, checked against the caller's roles
+	accessPolicy := service.DefaultAccessPolicy()
+
 	// Set up the gRPC client
 	startTime = time.Now()
 	grpcManager := client.GetManager()
-	err := grpcManager.SetupGRPCClient()
+	err = grpcManager.SetupGRPCClient()
 	if err != nil {
 		logger.Fatal(data.LogGRPCClientSetupFailed, map[string]any{"service": data.GRPCClientService, "error": err.Error()})
 	}
 	logger.Info(data.LogGRPCClientSetupSuccess, map[string]any{"service": data.GRPCClientService, "duration": utils.Ms(time.Since(startTime))})
 
+	// Start wallet creation saga worker to finish compensations left by failed creations
+	sagaWorker := service.NewWalletSagaWorker(
+		repository.NewWalletSagaRepository(dbInstance.GetDB()),
+		repository.NewWalletRepository(dbInstance.GetDB()),
+		client.NewTransactionClient(grpcManager.GetTransactionClient()),
+	)
+	go sagaWorker.Start(ctx)
+
 	// Set up the HTTP server
 	startTime = time.Now()
-	httpServer := router.SetupHTTPServer(dbInstance, queueInstance)
+	httpServer := router.SetupHTTPServer(dbInstance, queueInstance, exchangeRates, tokenVerifier, accessPolicy)
 	if httpServer != nil {
 		go func() {
 			if err := httpServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
@@ -94,7 +144,7 @@ func main() {
 
 	// Set up the gRPC server
 	startTime = time.Now()
-	grpcServer, lis, err := grpcserver.SetupGRPCServer(dbInstance, queueInstance)
+	grpcServer, lis, err := grpcserver.SetupGRPCServer(dbInstance, queueInstance, exchangeRates, accessPolicy)
 	if err != nil {
 		logger.Fatal(data.LogGRPCServerSetupFailed, map[string]any{"service": data.GRPCServerService, "error": err.Error()})
 	}
@@ -127,9 +177,9 @@ func main() {
 		}
 	}
 
-	// Cancel context to stop outbox publisher
+	// Cancel context to stop outbox publisher and transaction consumer
 	cancel()
-	time.Sleep(2 * time.Second) // Give some time for outbox publisher to stop
+	time.Sleep(2 * time.Second) // Give some time for outbox publisher and consumer to stop
 
 	// Shutdown gRPC server
 	if grpcServer != nil {
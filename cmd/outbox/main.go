@@ -0,0 +1,101 @@
+// Command outbox runs one-off outbox maintenance against the wallet database:
+//
+//	outbox backfill [-user-id ID] [-from RFC3339] [-to RFC3339]
+//	outbox replay -from-id N -to-id M
+//
+// Events are only written to the outbox; the running API instances publish them.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"refina-wallet/config/db"
+	"refina-wallet/config/env"
+	logger "refina-wallet/config/log"
+	"refina-wallet/internal/repository"
+	"refina-wallet/internal/service"
+	"refina-wallet/internal/types/dto"
+	"refina-wallet/internal/utils/data"
+)
+
+func init() {
+	var err error
+	var missing []string
+	if missing, err = env.LoadByViper(); err != nil {
+		log.Printf("Failed to read JSON config file: %v", err)
+		if missing, err = env.LoadNative(); err != nil {
+			log.Fatalf("Failed to load environment variables: %v", err)
+		}
+	}
+
+	logger.SetupLogger()
+
+	for _, envVar := range missing {
+		logger.Warn(data.LogEnvVarMissing, map[string]any{"service": data.EnvService, "key": envVar})
+	}
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	dbInstance := db.GetInstance(env.Cfg.Database)
+	defer dbInstance.Close()
+
+	gormDB := dbInstance.GetDB()
+	replayServ := service.NewOutboxReplayService(
+		repository.NewTxManager(gormDB),
+		repository.NewWalletRepository(gormDB),
+		repository.NewOutboxRepository(gormDB),
+	)
+
+	var (
+		enqueued int64
+		err      error
+	)
+
+	switch os.Args[1] {
+	case "backfill":
+		var req dto.OutboxBackfillRequest
+		fs := flag.NewFlagSet("backfill", flag.ExitOnError)
+		fs.StringVar(&req.UserID, "user-id", "", "only wallets of this user")
+		fs.StringVar(&req.From, "from", "", "only wallets created at or after this RFC3339 time")
+		fs.StringVar(&req.To, "to", "", "only wallets created before this RFC3339 time")
+		fs.Parse(os.Args[2:])
+
+		enqueued, err = replayServ.BackfillWalletSnapshots(ctx, req)
+	case "replay":
+		var fromID, toID uint
+		fs := flag.NewFlagSet("replay", flag.ExitOnError)
+		fs.UintVar(&fromID, "from-id", 0, "first outbox id to replay (inclusive)")
+		fs.UintVar(&toID, "to-id", 0, "last outbox id to replay (inclusive)")
+		fs.Parse(os.Args[2:])
+
+		enqueued, err = replayServ.ReplayPublished(ctx, dto.OutboxReplayRequest{FromID: fromID, ToID: toID})
+	default:
+		usage()
+	}
+
+	if err != nil {
+		logger.Error(data.LogOutboxReplayFailed, map[string]any{"service": data.OutboxAdminService, "command": os.Args[1], "enqueued": enqueued, "error": err.Error()})
+		os.Exit(1)
+	}
+
+	logger.Info(data.LogOutboxReplayCompleted, map[string]any{"service": data.OutboxAdminService, "command": os.Args[1], "enqueued": enqueued})
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: outbox backfill [-user-id ID] [-from RFC3339] [-to RFC3339]")
+	fmt.Fprintln(os.Stderr, "       outbox replay -from-id N -to-id M")
+	os.Exit(2)
+}
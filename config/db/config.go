@@ -43,8 +43,9 @@ func DefaultPoolConfig() ConnectionPoolConfig {
 	}
 }
 
-func NewDatabaseClient(cfg env.Database, poolCfg ConnectionPoolConfig) (DatabaseClient, error) {
-	dsn := fmt.Sprintf(
+// DSN builds the Postgres connection string shared by GORM and the dedicated LISTEN connection.
+func DSN(cfg env.Database) string {
+	return fmt.Sprintf(
 		"host=%s user=%s password=%s dbname=%s port=%s sslmode=disable TimeZone=UTC",
 		cfg.DBHost,
 		cfg.DBUser,
@@ -52,8 +53,10 @@ func NewDatabaseClient(cfg env.Database, poolCfg ConnectionPoolConfig) (Database
 		cfg.DBName,
 		cfg.DBPort,
 	)
+}
 
-	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
+func NewDatabaseClient(cfg env.Database, poolCfg ConnectionPoolConfig) (DatabaseClient, error) {
+	db, err := gorm.Open(postgres.Open(DSN(cfg)), &gorm.Config{})
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to database: %w", err)
 	}
@@ -0,0 +1,93 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"refina-wallet/config/env"
+	"refina-wallet/config/log"
+	"refina-wallet/internal/utils/data"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Listener holds a dedicated connection that LISTENs on a Postgres channel and turns every
+// NOTIFY into a coalesced signal. It reconnects with backoff and signals once after every
+// (re)connect, because notifications sent while disconnected are lost.
+type Listener struct {
+	dsn     string
+	channel string
+	notify  chan struct{}
+}
+
+func NewListener(cfg env.Database, channel string) *Listener {
+	return &Listener{
+		dsn:     DSN(cfg),
+		channel: channel,
+		notify:  make(chan struct{}, 1),
+	}
+}
+
+// Notifications is signalled at least once after any number of NOTIFYs since the last receive.
+func (l *Listener) Notifications() <-chan struct{} {
+	return l.notify
+}
+
+// Run blocks until ctx is done.
+func (l *Listener) Run(ctx context.Context) {
+	backoff := data.OUTBOX_LISTEN_MIN_BACKOFF
+
+	for ctx.Err() == nil {
+		err := l.listen(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+
+		log.Warn(data.LogOutboxListenerFailed, map[string]any{
+			"service": data.DatabaseService,
+			"channel": l.channel,
+			"backoff": backoff.String(),
+			"error":   err.Error(),
+		})
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, data.OUTBOX_LISTEN_MAX_BACKOFF)
+	}
+}
+
+func (l *Listener) listen(ctx context.Context) error {
+	conn, err := pgx.Connect(ctx, l.dsn)
+	if err != nil {
+		return fmt.Errorf("connect: %w", err)
+	}
+	defer conn.Close(context.Background())
+
+	if _, err := conn.Exec(ctx, "LISTEN "+pgx.Identifier{l.channel}.Sanitize()); err != nil {
+		return fmt.Errorf("listen %s: %w", l.channel, err)
+	}
+
+	log.Info(data.LogOutboxListenerStarted, map[string]any{
+		"service": data.DatabaseService,
+		"channel": l.channel,
+	})
+	l.signal()
+
+	for {
+		if _, err := conn.WaitForNotification(ctx); err != nil {
+			return fmt.Errorf("wait for notification: %w", err)
+		}
+		l.signal()
+	}
+}
+
+func (l *Listener) signal() {
+	select {
+	case l.notify <- struct{}{}:
+	default:
+	}
+}
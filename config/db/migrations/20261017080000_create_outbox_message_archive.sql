@@ -0,0 +1,26 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS outbox_messages_archive (
+    id BIGINT PRIMARY KEY,
+    aggregate_id UUID NOT NULL,
+    event_type VARCHAR(255) NOT NULL,
+    payload JSONB NOT NULL,
+    retries INTEGER DEFAULT 0,
+    published_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL,
+    archived_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_outbox_archive_aggregate_id ON outbox_messages_archive(aggregate_id);
+CREATE INDEX idx_outbox_archive_archived_at ON outbox_messages_archive(archived_at);
+
+COMMENT ON TABLE outbox_messages_archive IS 'Published outbox messages moved out of outbox_messages by the cleanup job';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_outbox_archive_archived_at;
+DROP INDEX IF EXISTS idx_outbox_archive_aggregate_id;
+
+DROP TABLE IF EXISTS outbox_messages_archive;
+-- +goose StatementEnd
@@ -0,0 +1,22 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE outbox_messages ADD COLUMN failed_at TIMESTAMP;
+ALTER TABLE outbox_messages ADD COLUMN last_error TEXT;
+
+-- Index for dead-letter listing
+CREATE INDEX idx_outbox_failed_at ON outbox_messages(failed_at) WHERE failed_at IS NOT NULL;
+
+-- Messages the publisher already gave up on before this migration are dead letters too
+UPDATE outbox_messages SET failed_at = NOW() WHERE published = FALSE AND retries >= max_retries;
+
+COMMENT ON COLUMN outbox_messages.failed_at IS 'When the message exhausted its retries and was moved to the dead-letter state';
+COMMENT ON COLUMN outbox_messages.last_error IS 'Last publish error recorded for the message';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_outbox_failed_at;
+
+ALTER TABLE outbox_messages DROP COLUMN last_error;
+ALTER TABLE outbox_messages DROP COLUMN failed_at;
+-- +goose StatementEnd
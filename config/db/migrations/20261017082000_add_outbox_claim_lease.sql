@@ -0,0 +1,21 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE outbox_messages ADD COLUMN locked_by VARCHAR(100);
+ALTER TABLE outbox_messages ADD COLUMN locked_until TIMESTAMP;
+
+-- Replace pending index so claim queries can skip leased rows cheaply
+DROP INDEX IF EXISTS idx_outbox_pending;
+CREATE INDEX idx_outbox_pending ON outbox_messages(created_at, locked_until) WHERE published = FALSE AND failed_at IS NULL;
+
+COMMENT ON COLUMN outbox_messages.locked_by IS 'Publisher instance currently holding the lease on this message';
+COMMENT ON COLUMN outbox_messages.locked_until IS 'Lease expiry; after this time another publisher instance may claim the message';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_outbox_pending;
+CREATE INDEX idx_outbox_pending ON outbox_messages(published, retries, created_at) WHERE published = FALSE;
+
+ALTER TABLE outbox_messages DROP COLUMN locked_until;
+ALTER TABLE outbox_messages DROP COLUMN locked_by;
+-- +goose StatementEnd
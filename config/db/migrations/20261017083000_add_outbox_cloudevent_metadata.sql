@@ -0,0 +1,14 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE outbox_messages ADD COLUMN event_id UUID;
+ALTER TABLE outbox_messages ADD COLUMN correlation_id VARCHAR(100);
+
+COMMENT ON COLUMN outbox_messages.event_id IS 'CloudEvents id of the envelope stored in payload; used as AMQP message id';
+COMMENT ON COLUMN outbox_messages.correlation_id IS 'X-Request-ID of the call that produced the event';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE outbox_messages DROP COLUMN correlation_id;
+ALTER TABLE outbox_messages DROP COLUMN event_id;
+-- +goose StatementEnd
@@ -0,0 +1,22 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE outbox_aggregate_sequences (
+    aggregate_id UUID PRIMARY KEY,
+    last_sequence BIGINT NOT NULL DEFAULT 0
+);
+
+ALTER TABLE outbox_messages ADD COLUMN sequence BIGINT NOT NULL DEFAULT 0;
+
+-- Lets the claim query find an earlier unpublished message of the same aggregate cheaply
+CREATE INDEX idx_outbox_aggregate_pending ON outbox_messages(aggregate_id, id) WHERE published = FALSE AND failed_at IS NULL;
+
+COMMENT ON TABLE outbox_aggregate_sequences IS 'Last event sequence number handed out per aggregate';
+COMMENT ON COLUMN outbox_messages.sequence IS 'Per-aggregate, gap-free, monotonically increasing event sequence (0 for rows created before sequencing)';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_outbox_aggregate_pending;
+ALTER TABLE outbox_messages DROP COLUMN sequence;
+DROP TABLE IF EXISTS outbox_aggregate_sequences;
+-- +goose StatementEnd
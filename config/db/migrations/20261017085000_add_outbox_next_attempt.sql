@@ -0,0 +1,11 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE outbox_messages ADD COLUMN next_attempt_at TIMESTAMP;
+
+COMMENT ON COLUMN outbox_messages.next_attempt_at IS 'Earliest time the publisher may retry this message (exponential backoff with jitter)';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE outbox_messages DROP COLUMN next_attempt_at;
+-- +goose StatementEnd
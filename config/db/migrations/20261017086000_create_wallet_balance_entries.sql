@@ -0,0 +1,46 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE wallet_balance_entries (
+    id BIGSERIAL PRIMARY KEY,
+    wallet_id uuid NOT NULL,
+    amount numeric(18,2) NOT NULL CHECK (amount > 0),
+    direction VARCHAR(6) NOT NULL CHECK (direction IN ('credit', 'debit')),
+    source_transaction_id VARCHAR(100),
+    reason VARCHAR(50) NOT NULL,
+    balance_after numeric(18,2) NOT NULL,
+    created_at timestamptz NOT NULL DEFAULT now(),
+    FOREIGN KEY (wallet_id) REFERENCES wallets(id) ON DELETE RESTRICT
+);
+
+CREATE INDEX idx_wallet_balance_entries_wallet_id ON wallet_balance_entries(wallet_id, id);
+-- A transaction moves money in or out of a wallet at most once
+CREATE UNIQUE INDEX idx_wallet_balance_entries_source ON wallet_balance_entries(wallet_id, source_transaction_id, direction) WHERE source_transaction_id IS NOT NULL;
+
+-- Ledger is append-only; corrections are new entries
+CREATE OR REPLACE FUNCTION wallet_balance_entries_append_only() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'wallet_balance_entries is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER trg_wallet_balance_entries_append_only
+    BEFORE UPDATE OR DELETE ON wallet_balance_entries
+    FOR EACH ROW EXECUTE FUNCTION wallet_balance_entries_append_only();
+
+-- Opening entries so every existing balance is explained by the ledger
+INSERT INTO wallet_balance_entries (wallet_id, amount, direction, reason, balance_after, created_at)
+SELECT id, ABS(balance), CASE WHEN balance > 0 THEN 'credit' ELSE 'debit' END, 'opening_balance', balance, COALESCE(created_at, now())
+FROM wallets
+WHERE balance <> 0;
+
+COMMENT ON TABLE wallet_balance_entries IS 'Append-only ledger; wallets.balance always equals balance_after of the latest entry';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TRIGGER IF EXISTS trg_wallet_balance_entries_append_only ON wallet_balance_entries;
+DROP FUNCTION IF EXISTS wallet_balance_entries_append_only();
+DROP INDEX IF EXISTS idx_wallet_balance_entries_source;
+DROP INDEX IF EXISTS idx_wallet_balance_entries_wallet_id;
+DROP TABLE IF EXISTS wallet_balance_entries;
+-- +goose StatementEnd
@@ -0,0 +1,13 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Transaction events can move one transaction in and out of a wallet several times (amount edits),
+-- so the ledger needs a lookup index per source transaction instead of a uniqueness guarantee.
+DROP INDEX IF EXISTS idx_wallet_balance_entries_source;
+CREATE INDEX idx_wallet_balance_entries_source ON wallet_balance_entries(source_transaction_id) WHERE source_transaction_id IS NOT NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_wallet_balance_entries_source;
+CREATE UNIQUE INDEX idx_wallet_balance_entries_source ON wallet_balance_entries(wallet_id, source_transaction_id, direction) WHERE source_transaction_id IS NOT NULL;
+-- +goose StatementEnd
@@ -0,0 +1,23 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE inbox_messages (
+    id BIGSERIAL PRIMARY KEY,
+    message_id VARCHAR(255) NOT NULL,
+    source VARCHAR(100) NOT NULL,
+    event_type VARCHAR(100) NOT NULL,
+    handled_at timestamptz NOT NULL DEFAULT now(),
+    CONSTRAINT uq_inbox_messages_source_message UNIQUE (source, message_id)
+);
+
+CREATE INDEX idx_inbox_messages_handled_at ON inbox_messages(handled_at);
+
+COMMENT ON TABLE inbox_messages IS 'Consumed messages, used to ignore at-least-once redeliveries';
+COMMENT ON COLUMN inbox_messages.message_id IS 'Broker message id (or CloudEvents id) unique per source';
+COMMENT ON COLUMN inbox_messages.source IS 'Logical producer of the message, e.g. the transaction service';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_inbox_messages_handled_at;
+DROP TABLE IF EXISTS inbox_messages;
+-- +goose StatementEnd
@@ -0,0 +1,29 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE wallet_creation_sagas (
+    id uuid PRIMARY KEY DEFAULT uuid_generate_v4(),
+    wallet_id uuid NOT NULL,
+    user_id uuid NOT NULL,
+    amount numeric(18,2) NOT NULL DEFAULT 0,
+    deposit_transaction_id VARCHAR(100),
+    status VARCHAR(20) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'deposit_created', 'committed', 'compensating', 'failed')),
+    attempts INT NOT NULL DEFAULT 0,
+    last_error TEXT,
+    next_attempt_at timestamptz,
+    created_at timestamptz NOT NULL DEFAULT now(),
+    updated_at timestamptz NOT NULL DEFAULT now()
+);
+
+-- Only unfinished sagas are ever scanned by the worker
+CREATE INDEX idx_wallet_creation_sagas_open ON wallet_creation_sagas(status, next_attempt_at) WHERE status IN ('pending', 'deposit_created', 'compensating');
+
+COMMENT ON TABLE wallet_creation_sagas IS 'Progress of CreateWallet across the local transaction and the remote initial deposit';
+COMMENT ON COLUMN wallet_creation_sagas.deposit_transaction_id IS 'Initial deposit transaction in the transaction service, to delete when compensating';
+COMMENT ON COLUMN wallet_creation_sagas.attempts IS 'Failed compensation attempts so far';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_wallet_creation_sagas_open;
+DROP TABLE IF EXISTS wallet_creation_sagas;
+-- +goose StatementEnd
@@ -0,0 +1,27 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE idempotency_keys (
+    id BIGSERIAL PRIMARY KEY,
+    idempotency_key VARCHAR(255) NOT NULL,
+    operation VARCHAR(100) NOT NULL,
+    user_id VARCHAR(100) NOT NULL DEFAULT '',
+    request_hash VARCHAR(64) NOT NULL,
+    response jsonb,
+    created_at timestamptz NOT NULL DEFAULT now(),
+    expires_at timestamptz NOT NULL,
+    CONSTRAINT uq_idempotency_keys_scope UNIQUE (operation, user_id, idempotency_key)
+);
+
+CREATE INDEX idx_idempotency_keys_expires_at ON idempotency_keys(expires_at);
+
+COMMENT ON TABLE idempotency_keys IS 'Client-supplied idempotency keys and the response of the request that first used them';
+COMMENT ON COLUMN idempotency_keys.request_hash IS 'SHA-256 of the request, to reject a key reused for a different request';
+COMMENT ON COLUMN idempotency_keys.response IS 'Response replayed to retries; NULL while the first request is still running';
+COMMENT ON COLUMN idempotency_keys.expires_at IS 'After this the key may be reused; short while in progress so a crashed request does not block retries';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_idempotency_keys_expires_at;
+DROP TABLE IF EXISTS idempotency_keys;
+-- +goose StatementEnd
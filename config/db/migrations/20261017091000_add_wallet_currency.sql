@@ -0,0 +1,47 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE wallets ADD COLUMN currency CHAR(3) NOT NULL DEFAULT 'IDR'
+	CONSTRAINT wallets_currency_iso4217 CHECK (currency ~ '^[A-Z]{3}$');
+
+COMMENT ON COLUMN wallets.currency IS 'ISO 4217 code of the wallet balance; fixed once the wallet is created';
+
+CREATE OR REPLACE VIEW view_user_wallets_group_by_type AS
+SELECT 
+	wallets.user_id,
+	wallet_types.type AS type,
+	JSON_AGG(
+		JSON_BUILD_OBJECT(
+			'id', wallets.id,
+			'name', wallets.name,
+			'number', wallets.number,
+			'balance', wallets.balance,
+			'currency', wallets.currency
+		)
+	) AS wallets 
+FROM wallets
+JOIN wallet_types ON wallet_types.id = wallets.wallet_type_id AND wallet_types.deleted_at IS NULL
+WHERE wallets.deleted_at IS NULL
+GROUP BY wallets.user_id, wallet_types.type;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+CREATE OR REPLACE VIEW view_user_wallets_group_by_type AS
+SELECT 
+	wallets.user_id,
+	wallet_types.type AS type,
+	JSON_AGG(
+		JSON_BUILD_OBJECT(
+			'id', wallets.id,
+			'name', wallets.name,
+			'number', wallets.number,
+			'balance', wallets.balance
+		)
+	) AS wallets 
+FROM wallets
+JOIN wallet_types ON wallet_types.id = wallets.wallet_type_id AND wallet_types.deleted_at IS NULL
+WHERE wallets.deleted_at IS NULL
+GROUP BY wallets.user_id, wallet_types.type;
+
+ALTER TABLE wallets DROP COLUMN currency;
+-- +goose StatementEnd
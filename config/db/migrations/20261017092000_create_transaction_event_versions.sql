@@ -0,0 +1,16 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE transaction_event_versions (
+    source_transaction_id VARCHAR(100) PRIMARY KEY,
+    last_version BIGINT NOT NULL,
+    updated_at timestamptz NOT NULL DEFAULT now()
+);
+
+COMMENT ON TABLE transaction_event_versions IS 'Newest event applied per source transaction, so late events of the same transaction are ignored';
+COMMENT ON COLUMN transaction_event_versions.last_version IS 'CloudEvents sequence of the event, or its time in microseconds when the producer sends no sequence';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS transaction_event_versions;
+-- +goose StatementEnd
@@ -0,0 +1,21 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE wallet_creation_sagas DROP CONSTRAINT wallet_creation_sagas_status_check;
+ALTER TABLE wallet_creation_sagas ADD CONSTRAINT wallet_creation_sagas_status_check
+    CHECK (status IN ('pending', 'deposit_created', 'committed', 'compensating', 'failed', 'needs_review'));
+
+-- Sagas waiting for an operator are listed separately from the ones the worker scans
+CREATE INDEX idx_wallet_creation_sagas_needs_review ON wallet_creation_sagas(updated_at) WHERE status = 'needs_review';
+
+COMMENT ON COLUMN wallet_creation_sagas.status IS 'needs_review: the initial deposit call ended without a known outcome and has to be checked manually';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_wallet_creation_sagas_needs_review;
+
+UPDATE wallet_creation_sagas SET status = 'failed' WHERE status = 'needs_review';
+ALTER TABLE wallet_creation_sagas DROP CONSTRAINT wallet_creation_sagas_status_check;
+ALTER TABLE wallet_creation_sagas ADD CONSTRAINT wallet_creation_sagas_status_check
+    CHECK (status IN ('pending', 'deposit_created', 'committed', 'compensating', 'failed'));
+-- +goose StatementEnd
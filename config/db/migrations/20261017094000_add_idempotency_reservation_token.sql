@@ -0,0 +1,11 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE idempotency_keys ADD COLUMN reservation_token uuid NOT NULL DEFAULT uuid_generate_v4();
+
+COMMENT ON COLUMN idempotency_keys.reservation_token IS 'Renewed on every reservation, so a request whose key expired and was reserved again cannot complete or release the new reservation';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE idempotency_keys DROP COLUMN reservation_token;
+-- +goose StatementEnd
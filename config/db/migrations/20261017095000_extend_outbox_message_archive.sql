@@ -0,0 +1,34 @@
+-- +goose Up
+-- +goose StatementBegin
+-- The archive keeps every column of outbox_messages so archived events can be replayed with their
+-- original event id and per-aggregate sequence
+ALTER TABLE outbox_messages_archive ADD COLUMN event_id UUID;
+ALTER TABLE outbox_messages_archive ADD COLUMN sequence BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE outbox_messages_archive ADD COLUMN published BOOLEAN NOT NULL DEFAULT TRUE;
+ALTER TABLE outbox_messages_archive ADD COLUMN max_retries INTEGER DEFAULT 3;
+ALTER TABLE outbox_messages_archive ADD COLUMN failed_at TIMESTAMP;
+ALTER TABLE outbox_messages_archive ADD COLUMN last_error TEXT;
+ALTER TABLE outbox_messages_archive ADD COLUMN correlation_id VARCHAR(100);
+ALTER TABLE outbox_messages_archive ADD COLUMN locked_by VARCHAR(100);
+ALTER TABLE outbox_messages_archive ADD COLUMN locked_until TIMESTAMP;
+ALTER TABLE outbox_messages_archive ADD COLUMN next_attempt_at TIMESTAMP;
+ALTER TABLE outbox_messages_archive ADD COLUMN updated_at TIMESTAMP;
+
+COMMENT ON COLUMN outbox_messages_archive.event_id IS 'CloudEvents id of the archived event; NULL for rows archived before this column existed';
+COMMENT ON COLUMN outbox_messages_archive.sequence IS 'Per-aggregate sequence of the archived event; 0 for rows archived before this column existed';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE outbox_messages_archive DROP COLUMN updated_at;
+ALTER TABLE outbox_messages_archive DROP COLUMN next_attempt_at;
+ALTER TABLE outbox_messages_archive DROP COLUMN locked_until;
+ALTER TABLE outbox_messages_archive DROP COLUMN locked_by;
+ALTER TABLE outbox_messages_archive DROP COLUMN correlation_id;
+ALTER TABLE outbox_messages_archive DROP COLUMN last_error;
+ALTER TABLE outbox_messages_archive DROP COLUMN failed_at;
+ALTER TABLE outbox_messages_archive DROP COLUMN max_retries;
+ALTER TABLE outbox_messages_archive DROP COLUMN published;
+ALTER TABLE outbox_messages_archive DROP COLUMN sequence;
+ALTER TABLE outbox_messages_archive DROP COLUMN event_id;
+-- +goose StatementEnd
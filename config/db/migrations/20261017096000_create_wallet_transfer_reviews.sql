@@ -0,0 +1,29 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE wallet_transfer_reviews (
+    id uuid PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id uuid NOT NULL,
+    from_wallet_id uuid NOT NULL,
+    to_wallet_id uuid NOT NULL,
+    amount numeric(18,2) NOT NULL,
+    admin_fee numeric(18,2) NOT NULL DEFAULT 0,
+    cash_out_transaction_id VARCHAR(100),
+    cash_in_transaction_id VARCHAR(100),
+    reason VARCHAR(30) NOT NULL CHECK (reason IN ('unknown_outcome', 'compensation_failed')),
+    last_error TEXT,
+    resolved_at timestamptz,
+    created_at timestamptz NOT NULL DEFAULT now()
+);
+
+-- Operators only look at the transfers nobody has resolved yet
+CREATE INDEX idx_wallet_transfer_reviews_open ON wallet_transfer_reviews(created_at) WHERE resolved_at IS NULL;
+
+COMMENT ON TABLE wallet_transfer_reviews IS 'Transfers whose fund transfer in the transaction service may exist without the matching wallet ledger entries';
+COMMENT ON COLUMN wallet_transfer_reviews.cash_out_transaction_id IS 'NULL when CreateFundTransfer ended without an answer';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_wallet_transfer_reviews_open;
+DROP TABLE IF EXISTS wallet_transfer_reviews;
+-- +goose StatementEnd
@@ -1,7 +1,10 @@
 package env
 
 import (
+	"fmt"
 	"os"
+	"strconv"
+	"time"
 
 	"github.com/joho/godotenv"
 	"github.com/spf13/viper"
@@ -30,15 +33,48 @@ type (
 		RMQVirtualHost string `env:"RABBITMQ_VIRTUAL_HOST"`
 	}
 
+	// GRPCConfig.TrustedCallerToken is the secret the BFF sends in x-caller-token; x-user-roles
+	// is only honored on calls that carry it. Without it no gRPC caller gets elevated roles.
 	GRPCConfig struct {
 		TransactionAddress string `env:"TRANSACTION_ADDRESS"`
+		TrustedCallerToken string `env:"GRPC_TRUSTED_CALLER_TOKEN"`
+	}
+
+	// Outbox holds optional tuning for the outbox worker; zero values fall back to defaults.
+	Outbox struct {
+		CleanupInterval  time.Duration `env:"OUTBOX_CLEANUP_INTERVAL"`
+		RetentionPeriod  time.Duration `env:"OUTBOX_RETENTION_PERIOD"`
+		CleanupBatchSize int           `env:"OUTBOX_CLEANUP_BATCH_SIZE"`
+		ArchiveEnabled   bool          `env:"OUTBOX_ARCHIVE_ENABLED"`
+		RetryBaseBackoff time.Duration `env:"OUTBOX_RETRY_BASE_BACKOFF"`
+		RetryMaxBackoff  time.Duration `env:"OUTBOX_RETRY_MAX_BACKOFF"`
+	}
+
+	// ExchangeRate configures currency conversion for wallet summaries. RatesFile is optional;
+	// without it only balances already in BaseCurrency can be converted.
+	ExchangeRate struct {
+		BaseCurrency string `env:"BASE_CURRENCY"`
+		RatesFile    string `env:"EXCHANGE_RATES_FILE"`
+	}
+
+	// Auth configures bearer-token authentication of the HTTP API. HS256 tokens are verified with
+	// JWTSecret and RS256 tokens with the keys of JWKSSource (a file path or an http(s) URL); at
+	// least one of them must be set. Issuer and Audience are only checked when set.
+	Auth struct {
+		JWTSecret  string `env:"JWT_SECRET"`
+		JWKSSource string `env:"JWT_JWKS_SOURCE"`
+		Issuer     string `env:"JWT_ISSUER"`
+		Audience   string `env:"JWT_AUDIENCE"`
 	}
 
 	Config struct {
-		Server     Server
-		Database   Database
-		RabbitMQ   RabbitMQ
-		GRPCConfig GRPCConfig
+		Server       Server
+		Database     Database
+		RabbitMQ     RabbitMQ
+		GRPCConfig   GRPCConfig
+		Outbox       Outbox
+		ExchangeRate ExchangeRate
+		Auth         Auth
 	}
 )
 
@@ -106,6 +142,31 @@ func LoadNative() ([]string, error) {
 	if Cfg.GRPCConfig.TransactionAddress, ok = os.LookupEnv("TRANSACTION_ADDRESS"); !ok {
 		missing = append(missing, "TRANSACTION_ADDRESS env is not set")
 	}
+	Cfg.GRPCConfig.TrustedCallerToken = os.Getenv("GRPC_TRUSTED_CALLER_TOKEN")
+	// ! ______________________________________________________
+
+	// ! Load Outbox configuration (optional) _________________
+	Cfg.Outbox.CleanupInterval = parseDuration("OUTBOX_CLEANUP_INTERVAL", os.Getenv("OUTBOX_CLEANUP_INTERVAL"), &missing)
+	Cfg.Outbox.RetentionPeriod = parseDuration("OUTBOX_RETENTION_PERIOD", os.Getenv("OUTBOX_RETENTION_PERIOD"), &missing)
+	Cfg.Outbox.CleanupBatchSize = parseInt("OUTBOX_CLEANUP_BATCH_SIZE", os.Getenv("OUTBOX_CLEANUP_BATCH_SIZE"), &missing)
+	Cfg.Outbox.ArchiveEnabled = parseBool("OUTBOX_ARCHIVE_ENABLED", os.Getenv("OUTBOX_ARCHIVE_ENABLED"), &missing)
+	Cfg.Outbox.RetryBaseBackoff = parseDuration("OUTBOX_RETRY_BASE_BACKOFF", os.Getenv("OUTBOX_RETRY_BASE_BACKOFF"), &missing)
+	Cfg.Outbox.RetryMaxBackoff = parseDuration("OUTBOX_RETRY_MAX_BACKOFF", os.Getenv("OUTBOX_RETRY_MAX_BACKOFF"), &missing)
+	// ! ______________________________________________________
+
+	// ! Load Exchange Rate configuration (optional) __________
+	Cfg.ExchangeRate.BaseCurrency = os.Getenv("BASE_CURRENCY")
+	Cfg.ExchangeRate.RatesFile = os.Getenv("EXCHANGE_RATES_FILE")
+	// ! ______________________________________________________
+
+	// ! Load Auth configuration ______________________________
+	Cfg.Auth.JWTSecret = os.Getenv("JWT_SECRET")
+	Cfg.Auth.JWKSSource = os.Getenv("JWT_JWKS_SOURCE")
+	Cfg.Auth.Issuer = os.Getenv("JWT_ISSUER")
+	Cfg.Auth.Audience = os.Getenv("JWT_AUDIENCE")
+	if Cfg.Auth.JWTSecret == "" && Cfg.Auth.JWKSSource == "" {
+		missing = append(missing, "JWT_SECRET or JWT_JWKS_SOURCE env is not set")
+	}
 	// ! ______________________________________________________
 
 	return missing, nil
@@ -177,7 +238,74 @@ func LoadByViper() ([]string, error) {
 	if Cfg.GRPCConfig.TransactionAddress = config.GetString("GRPC-CONFIG.TRANSACTION_ADDRESS"); Cfg.GRPCConfig.TransactionAddress == "" {
 		missing = append(missing, "GRPC-CONFIG.TRANSACTION_ADDRESS env is not set")
 	}
+	Cfg.GRPCConfig.TrustedCallerToken = config.GetString("GRPC-CONFIG.TRUSTED_CALLER_TOKEN")
+	// ! ______________________________________________________
+
+	// ! Load Outbox configuration (optional) _________________
+	Cfg.Outbox.CleanupInterval = parseDuration("OUTBOX.CLEANUP_INTERVAL", config.GetString("OUTBOX.CLEANUP_INTERVAL"), &missing)
+	Cfg.Outbox.RetentionPeriod = parseDuration("OUTBOX.RETENTION_PERIOD", config.GetString("OUTBOX.RETENTION_PERIOD"), &missing)
+	Cfg.Outbox.CleanupBatchSize = parseInt("OUTBOX.CLEANUP_BATCH_SIZE", config.GetString("OUTBOX.CLEANUP_BATCH_SIZE"), &missing)
+	Cfg.Outbox.ArchiveEnabled = parseBool("OUTBOX.ARCHIVE_ENABLED", config.GetString("OUTBOX.ARCHIVE_ENABLED"), &missing)
+	Cfg.Outbox.RetryBaseBackoff = parseDuration("OUTBOX.RETRY_BASE_BACKOFF", config.GetString("OUTBOX.RETRY_BASE_BACKOFF"), &missing)
+	Cfg.Outbox.RetryMaxBackoff = parseDuration("OUTBOX.RETRY_MAX_BACKOFF", config.GetString("OUTBOX.RETRY_MAX_BACKOFF"), &missing)
+	// ! ______________________________________________________
+
+	// ! Load Exchange Rate configuration (optional) __________
+	Cfg.ExchangeRate.BaseCurrency = config.GetString("EXCHANGE-RATE.BASE_CURRENCY")
+	Cfg.ExchangeRate.RatesFile = config.GetString("EXCHANGE-RATE.RATES_FILE")
+	// ! ______________________________________________________
+
+	// ! Load Auth configuration ______________________________
+	Cfg.Auth.JWTSecret = config.GetString("AUTH.JWT_SECRET")
+	Cfg.Auth.JWKSSource = config.GetString("AUTH.JWKS_SOURCE")
+	Cfg.Auth.Issuer = config.GetString("AUTH.ISSUER")
+	Cfg.Auth.Audience = config.GetString("AUTH.AUDIENCE")
+	if Cfg.Auth.JWTSecret == "" && Cfg.Auth.JWKSSource == "" {
+		missing = append(missing, "AUTH.JWT_SECRET or AUTH.JWKS_SOURCE env is not set")
+	}
 	// ! ______________________________________________________
 
 	return missing, nil
 }
+
+// parseDuration returns zero for empty or malformed values so callers can apply their own default.
+// A malformed value is reported in missing together with its key, so a typo does not pass silently.
+func parseDuration(key, raw string, missing *[]string) time.Duration {
+	if raw == "" {
+		return 0
+	}
+	d, err := time.ParseDuration(raw)
+	if err != nil {
+		*missing = append(*missing, malformedEnv(key, raw))
+		return 0
+	}
+	return d
+}
+
+func parseInt(key, raw string, missing *[]string) int {
+	if raw == "" {
+		return 0
+	}
+	n, err := strconv.Atoi(raw)
+	if err != nil {
+		*missing = append(*missing, malformedEnv(key, raw))
+		return 0
+	}
+	return n
+}
+
+func parseBool(key, raw string, missing *[]string) bool {
+	if raw == "" {
+		return false
+	}
+	b, err := strconv.ParseBool(raw)
+	if err != nil {
+		*missing = append(*missing, malformedEnv(key, raw))
+		return false
+	}
+	return b
+}
+
+func malformedEnv(key, raw string) string {
+	return fmt.Sprintf("%s env is malformed (%q), falling back to the default", key, raw)
+}
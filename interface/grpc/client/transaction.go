@@ -2,16 +2,21 @@ package client
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"time"
 
+	"refina-wallet/internal/types/money"
 	"refina-wallet/internal/utils/data"
 
 	tpb "github.com/MuhammadMiftaa/Refina-Protobuf/transaction"
 )
 
 type TransactionClient interface {
-	InitialDeposit(ctx context.Context, walletID string, amount float64) (*tpb.TransactionDetail, error)
+	InitialDeposit(ctx context.Context, walletID string, amount money.Amount) (*tpb.TransactionDetail, error)
 	CancelInitialDeposit(ctx context.Context, transactionID string) (*tpb.TransactionDetail, error)
+	CreateFundTransfer(ctx context.Context, req *tpb.CreateFundTransferRequest) (*tpb.FundTransferResponse, error)
+	CancelFundTransfer(ctx context.Context, transfer *tpb.FundTransferResponse) error
 }
 
 type transactionClientImpl struct {
@@ -24,13 +29,13 @@ func NewTransactionClient(grpcClient tpb.TransactionServiceClient) TransactionCl
 	}
 }
 
-func (t *transactionClientImpl) InitialDeposit(ctx context.Context, walletID string, amount float64) (*tpb.TransactionDetail, error) {
+func (t *transactionClientImpl) InitialDeposit(ctx context.Context, walletID string, amount money.Amount) (*tpb.TransactionDetail, error) {
 	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
 	defer cancel()
 
 	return t.client.CreateTransaction(ctx, &tpb.CreateTransactionRequest{
 		WalletId:        walletID,
-		Amount:          amount,
+		Amount:          amount.Float64(),
 		CategoryId:      data.INITIAL_DEPOSIT_CATEGORY_ID,
 		TransactionDate: time.Now().Format(time.RFC3339),
 		Description:     data.INITIAL_DEPOSIT_DESC,
@@ -44,3 +49,30 @@ func (t *transactionClientImpl) CancelInitialDeposit(ctx context.Context, transa
 
 	return t.client.DeleteTransaction(ctx, &tpb.TransactionID{Id: transactionID})
 }
+
+func (t *transactionClientImpl) CreateFundTransfer(ctx context.Context, req *tpb.CreateFundTransferRequest) (*tpb.FundTransferResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	return t.client.CreateFundTransfer(ctx, req)
+}
+
+// CancelFundTransfer deletes both sides of a fund transfer; it keeps going after a failure so
+// one stuck side does not leave the other behind.
+func (t *transactionClientImpl) CancelFundTransfer(ctx context.Context, transfer *tpb.FundTransferResponse) error {
+	var errs []error
+	for _, id := range []string{transfer.GetCashOutTransactionId(), transfer.GetCashInTransactionId()} {
+		if id == "" {
+			continue
+		}
+
+		deleteCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+		_, err := t.client.DeleteTransaction(deleteCtx, &tpb.TransactionID{Id: id})
+		cancel()
+		if err != nil {
+			errs = append(errs, fmt.Errorf("delete transaction [id=%s]: %w", id, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,74 @@
+package interceptor
+
+import (
+	"context"
+
+	"refina-wallet/config/log"
+	"refina-wallet/internal/service"
+	"refina-wallet/internal/utils"
+	"refina-wallet/internal/utils/data"
+
+	"google.golang.org/grpc"
+)
+
+// AuthorizationUnaryInterceptor checks the caller's roles against the permission policy declares
+// for the full method name. Chain it after UnaryServerInterceptor, which puts the roles in the context.
+func AuthorizationUnaryInterceptor(policy *service.AccessPolicy) grpc.UnaryServerInterceptor {
+	return func(
+		ctx context.Context,
+		req any,
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (any, error) {
+		if err := authorize(ctx, policy, info.FullMethod); err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// AuthorizationStreamInterceptor does the same for streaming RPCs.
+func AuthorizationStreamInterceptor(policy *service.AccessPolicy) grpc.StreamServerInterceptor {
+	return func(
+		srv any,
+		ss grpc.ServerStream,
+		info *grpc.StreamServerInfo,
+		handler grpc.StreamHandler,
+	) error {
+		if err := authorize(ss.Context(), policy, info.FullMethod); err != nil {
+			return err
+		}
+		return handler(srv, ss)
+	}
+}
+
+func authorize(ctx context.Context, policy *service.AccessPolicy, fullMethod string) error {
+	roles := service.ParseRoles(RolesFromContext(ctx))
+
+	permission, err := policy.Authorize(roles, fullMethod)
+	if err != nil {
+		LogAccessDenied(ctx, fullMethod, permission, err)
+		return StatusError(err)
+	}
+	return nil
+}
+
+// WalletAccessFromContext limits single-wallet calls to the wallets of the caller in ctx unless
+// its roles grant anyWallet. The HTTP handlers share it, their middleware fills the same context.
+func WalletAccessFromContext(ctx context.Context, anyWallet service.Permission) service.WalletAccess {
+	return service.WalletAccessFor(UserIDFromContext(ctx), RolesFromContext(ctx), anyWallet)
+}
+
+// LogAccessDenied writes the audit log line of a refused gRPC call
+func LogAccessDenied(ctx context.Context, operation string, permission service.Permission, err error) {
+	log.Warn(data.LogAccessDenied, map[string]any{
+		"service":             data.AuthService,
+		"transport":           "grpc",
+		"request_id":          utils.RequestIDFromContext(ctx),
+		"user_id":             UserIDFromContext(ctx),
+		"roles":               service.ParseRoles(RolesFromContext(ctx)),
+		"operation":           operation,
+		"required_permission": permission,
+		"error":               err.Error(),
+	})
+}
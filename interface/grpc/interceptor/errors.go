@@ -0,0 +1,77 @@
+package interceptor
+
+import (
+	"context"
+	"errors"
+
+	"refina-wallet/internal/types/domainerr"
+	"refina-wallet/internal/utils/data"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ErrorUnaryInterceptor converts the errors returned by handlers into gRPC statuses (see StatusError).
+// Chain it first so it also sees the errors of the other interceptors.
+func ErrorUnaryInterceptor() grpc.UnaryServerInterceptor {
+	return func(
+		ctx context.Context,
+		req any,
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (any, error) {
+		resp, err := handler(ctx, req)
+		if err != nil {
+			return resp, StatusError(err)
+		}
+		return resp, nil
+	}
+}
+
+// ErrorStreamInterceptor does the same for streaming RPCs.
+func ErrorStreamInterceptor() grpc.StreamServerInterceptor {
+	return func(
+		srv any,
+		ss grpc.ServerStream,
+		info *grpc.StreamServerInfo,
+		handler grpc.StreamHandler,
+	) error {
+		if err := handler(srv, ss); err != nil {
+			return StatusError(err)
+		}
+		return nil
+	}
+}
+
+// StatusError maps a domainerr error to the status of its kind, carrying the client-safe message
+// and a google.rpc.ErrorInfo with the reason. Errors that already are statuses pass through, and
+// unclassified errors become Internal without leaking their text; handlers log them in full.
+func StatusError(err error) error {
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return status.FromContextError(err).Err()
+	}
+
+	domainErr, ok := domainerr.As(err)
+	if !ok || domainErr.Kind == domainerr.Internal {
+		return status.Error(codes.Internal, "internal server error")
+	}
+
+	st := status.New(domainErr.Kind.GRPCCode(), domainErr.Message)
+	detailed, detailErr := st.WithDetails(&errdetails.ErrorInfo{
+		Reason: domainErr.Reason,
+		Domain: data.ERROR_INFO_DOMAIN,
+		Metadata: map[string]string{
+			"kind": domainErr.Kind.String(),
+		},
+	})
+	if detailErr != nil {
+		// Status tanpa detail tetap membawa code yang benar
+		return st.Err()
+	}
+	return detailed.Err()
+}
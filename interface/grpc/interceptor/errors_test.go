@@ -0,0 +1,117 @@
+package interceptor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"refina-wallet/internal/types/domainerr"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func errorInfo(t *testing.T, st *status.Status) *errdetails.ErrorInfo {
+	t.Helper()
+	for _, detail := range st.Details() {
+		if info, ok := detail.(*errdetails.ErrorInfo); ok {
+			return info
+		}
+	}
+	return nil
+}
+
+func TestStatusError_DomainErrors(t *testing.T) {
+	cases := []struct {
+		kind domainerr.Kind
+		code codes.Code
+	}{
+		{domainerr.NotFound, codes.NotFound},
+		{domainerr.InvalidArgument, codes.InvalidArgument},
+		{domainerr.Conflict, codes.Aborted},
+		{domainerr.FailedPrecondition, codes.FailedPrecondition},
+		{domainerr.PermissionDenied, codes.PermissionDenied},
+		{domainerr.Unauthenticated, codes.Unauthenticated},
+		{domainerr.Unavailable, codes.Unavailable},
+	}
+	for _, c := range cases {
+		sentinel := domainerr.New(c.kind, "SOME_REASON", "client-safe message")
+		// detail untuk log tidak boleh ikut terkirim ke client
+		err := fmt.Errorf("update wallet [id=1]: %w: db password in dsn", sentinel)
+
+		st, ok := status.FromError(StatusError(err))
+
+		assert.True(t, ok, c.kind.String())
+		assert.Equal(t, c.code, st.Code(), c.kind.String())
+		assert.Equal(t, "client-safe message", st.Message(), c.kind.String())
+
+		info := errorInfo(t, st)
+		if assert.NotNil(t, info, c.kind.String()) {
+			assert.Equal(t, "SOME_REASON", info.GetReason())
+			assert.Equal(t, "wallet.refina", info.GetDomain())
+			assert.Equal(t, map[string]string{"kind": c.kind.String()}, info.GetMetadata())
+		}
+	}
+}
+
+func TestStatusError_InternalAndUnclassifiedHideTheCause(t *testing.T) {
+	cases := []error{
+		errors.New("pq: connection refused"),
+		fmt.Errorf("create wallet: insert to db: %w", errors.New("duplicate key")),
+		domainerr.Wrap(errors.New("boom"), domainerr.Internal, "SOMETHING_BROKE", "something broke"),
+		fmt.Errorf("wrapped: %w", domainerr.New(domainerr.Internal, "SOMETHING_BROKE", "something broke")),
+	}
+	for _, err := range cases {
+		st, ok := status.FromError(StatusError(err))
+
+		assert.True(t, ok, err.Error())
+		assert.Equal(t, codes.Internal, st.Code(), err.Error())
+		assert.Equal(t, "internal server error", st.Message(), err.Error())
+		assert.Empty(t, st.Details(), err.Error())
+	}
+}
+
+func TestStatusError_StatusPassesThrough(t *testing.T) {
+	original := status.Error(codes.InvalidArgument, "invalid if-match metadata")
+
+	err := StatusError(original)
+
+	assert.Same(t, original, err)
+}
+
+func TestStatusError_ContextErrors(t *testing.T) {
+	cases := []struct {
+		err  error
+		code codes.Code
+	}{
+		{context.Canceled, codes.Canceled},
+		{context.DeadlineExceeded, codes.DeadlineExceeded},
+		{fmt.Errorf("get wallet: %w", context.DeadlineExceeded), codes.DeadlineExceeded},
+	}
+	for _, c := range cases {
+		assert.Equal(t, c.code, status.Code(StatusError(c.err)), c.err.Error())
+	}
+}
+
+func TestErrorUnaryInterceptor(t *testing.T) {
+	notFound := domainerr.New(domainerr.NotFound, "WALLET_NOT_FOUND", "wallet not found")
+	intercept := ErrorUnaryInterceptor()
+
+	resp, err := intercept(context.Background(), nil, &grpc.UnaryServerInfo{}, func(ctx context.Context, req any) (any, error) {
+		return nil, fmt.Errorf("get wallet: %w", notFound)
+	})
+
+	assert.Nil(t, resp)
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	resp, err = intercept(context.Background(), nil, &grpc.UnaryServerInfo{}, func(ctx context.Context, req any) (any, error) {
+		return "ok", nil
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "ok", resp)
+}
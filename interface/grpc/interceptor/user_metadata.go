@@ -2,17 +2,30 @@ package interceptor
 
 import (
 	"context"
+	"crypto/subtle"
+
+	"refina-wallet/config/log"
+	"refina-wallet/internal/utils"
+	"refina-wallet/internal/utils/data"
 
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/metadata"
 )
 
 // Metadata keys — must match the BFF client interceptor keys exactly.
+//
+// Trust boundary: the gRPC port is only reachable by the BFF, which authenticates the end user and
+// forwards the identity in x-user-*. Roles grant access beyond the caller's own wallets, so
+// x-user-roles is only honored on calls that also carry the shared secret of the BFF in
+// x-caller-token (env GRPC_TRUSTED_CALLER_TOKEN); on any other call it is dropped.
 const (
 	MDKeyUserID         = "x-user-id"
 	MDKeyUserEmail      = "x-user-email"
 	MDKeyUserProvider   = "x-user-provider"
 	MDKeyProviderUserID = "x-provider-user-id"
+	MDKeyUserRoles      = "x-user-roles"
+	MDKeyRequestID      = "x-request-id"
+	MDKeyCallerToken    = "x-caller-token"
 )
 
 // ── context keys ──
@@ -22,6 +35,7 @@ type (
 	userEmailKey      struct{}
 	userProviderKey   struct{}
 	providerUserIDKey struct{}
+	userRolesKey      struct{}
 )
 
 // ── context helpers ──
@@ -50,40 +64,48 @@ func ProviderUserIDFromContext(ctx context.Context) string {
 	return v
 }
 
+// RolesFromContext returns the raw role names injected by the server interceptor or the HTTP
+// auth middleware; service.ParseRoles normalizes them.
+func RolesFromContext(ctx context.Context) []string {
+	v, _ := ctx.Value(userRolesKey{}).([]string)
+	return v
+}
+
 // ── interceptors ──
 
 // UnaryServerInterceptor extracts user metadata from incoming gRPC metadata
 // and injects it into the Go context so downstream handlers / services can
-// access it via the *FromContext helpers.
-func UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+// access it via the *FromContext helpers. Roles are only kept for callers that
+// send trustedCallerToken; an empty token trusts nobody's roles.
+func UnaryServerInterceptor(trustedCallerToken string) grpc.UnaryServerInterceptor {
 	return func(
 		ctx context.Context,
 		req any,
 		info *grpc.UnaryServerInfo,
 		handler grpc.UnaryHandler,
 	) (any, error) {
-		ctx = extractUserMetadata(ctx)
+		ctx = extractUserMetadata(ctx, trustedCallerToken)
 		return handler(ctx, req)
 	}
 }
 
 // StreamServerInterceptor does the same for streaming RPCs.
-func StreamServerInterceptor() grpc.StreamServerInterceptor {
+func StreamServerInterceptor(trustedCallerToken string) grpc.StreamServerInterceptor {
 	return func(
 		srv any,
 		ss grpc.ServerStream,
 		info *grpc.StreamServerInfo,
 		handler grpc.StreamHandler,
 	) error {
-		ctx := extractUserMetadata(ss.Context())
+		ctx := extractUserMetadata(ss.Context(), trustedCallerToken)
 		wrapped := &wrappedServerStream{ServerStream: ss, ctx: ctx}
 		return handler(srv, wrapped)
 	}
 }
 
-// extractUserMetadata reads the x-user-* keys from incoming gRPC metadata
-// and stores them in the context.
-func extractUserMetadata(ctx context.Context) context.Context {
+// extractUserMetadata reads the x-user-* keys (and x-request-id) from incoming
+// gRPC metadata and stores them in the context.
+func extractUserMetadata(ctx context.Context, trustedCallerToken string) context.Context {
 	md, ok := metadata.FromIncomingContext(ctx)
 	if !ok {
 		return ctx
@@ -93,7 +115,39 @@ func extractUserMetadata(ctx context.Context) context.Context {
 	email := firstValue(md, MDKeyUserEmail)
 	provider := firstValue(md, MDKeyUserProvider)
 	providerUID := firstValue(md, MDKeyProviderUserID)
+	requestID := firstValue(md, MDKeyRequestID)
+
+	roles := md.Get(MDKeyUserRoles)
+	if len(roles) > 0 && !trustedCaller(md, trustedCallerToken) {
+		// Tanpa bukti dari BFF, role bisa saja dipalsukan oleh siapa pun yang menjangkau port gRPC
+		log.Warn(data.LogUntrustedRolesIgnored, map[string]any{
+			"service":    data.GRPCServerService,
+			"user_id":    userID,
+			"roles":      roles,
+			"request_id": requestID,
+		})
+		roles = nil
+	}
+
+	ctx = WithUser(ctx, userID, email, provider, providerUID)
+	ctx = WithRoles(ctx, roles)
+	ctx = utils.WithRequestID(ctx, requestID)
+
+	return ctx
+}
 
+// trustedCaller reports whether md carries trustedCallerToken, compared in constant time
+func trustedCaller(md metadata.MD, trustedCallerToken string) bool {
+	if trustedCallerToken == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(firstValue(md, MDKeyCallerToken)), []byte(trustedCallerToken)) == 1
+}
+
+// WithUser stores the caller identity under the same keys the server interceptor uses, so
+// requests authenticated elsewhere (e.g. the HTTP JWT middleware) are read back through the
+// *FromContext helpers. Empty values are left unset.
+func WithUser(ctx context.Context, userID, email, provider, providerUserID string) context.Context {
 	if userID != "" {
 		ctx = context.WithValue(ctx, userIDKey{}, userID)
 	}
@@ -103,13 +157,20 @@ func extractUserMetadata(ctx context.Context) context.Context {
 	if provider != "" {
 		ctx = context.WithValue(ctx, userProviderKey{}, provider)
 	}
-	if providerUID != "" {
-		ctx = context.WithValue(ctx, providerUserIDKey{}, providerUID)
+	if providerUserID != "" {
+		ctx = context.WithValue(ctx, providerUserIDKey{}, providerUserID)
 	}
-
 	return ctx
 }
 
+// WithRoles stores the caller's role names next to the identity stored by WithUser
+func WithRoles(ctx context.Context, roles []string) context.Context {
+	if len(roles) == 0 {
+		return ctx
+	}
+	return context.WithValue(ctx, userRolesKey{}, roles)
+}
+
 func firstValue(md metadata.MD, key string) string {
 	vals := md.Get(key)
 	if len(vals) > 0 {
@@ -0,0 +1,60 @@
+package interceptor
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+const trustedToken = "bff-secret"
+
+func incomingContext(pairs ...string) context.Context {
+	return metadata.NewIncomingContext(context.Background(), metadata.Pairs(pairs...))
+}
+
+func TestExtractUserMetadata_RolesNeedTrustedCaller(t *testing.T) {
+	cases := []struct {
+		name       string
+		configured string
+		sent       []string
+		wantRoles  []string
+	}{
+		{"trusted caller", trustedToken, []string{MDKeyCallerToken, trustedToken}, []string{"admin"}},
+		{"no caller token", trustedToken, nil, nil},
+		{"wrong caller token", trustedToken, []string{MDKeyCallerToken, "guess"}, nil},
+		{"token prefix", trustedToken, []string{MDKeyCallerToken, "bff-"}, nil},
+		{"no token configured", "", []string{MDKeyCallerToken, ""}, nil},
+	}
+	for _, c := range cases {
+		pairs := append([]string{MDKeyUserID, "user-1", MDKeyUserRoles, "admin", MDKeyRequestID, "req-1"}, c.sent...)
+
+		ctx := extractUserMetadata(incomingContext(pairs...), c.configured)
+
+		assert.Equal(t, c.wantRoles, RolesFromContext(ctx), c.name)
+		// identitas tetap dipakai, hanya role yang butuh bukti dari BFF
+		assert.Equal(t, "user-1", UserIDFromContext(ctx), c.name)
+	}
+}
+
+func TestExtractUserMetadata_WithoutMetadata(t *testing.T) {
+	ctx := extractUserMetadata(context.Background(), trustedToken)
+
+	assert.Empty(t, UserIDFromContext(ctx))
+	assert.Nil(t, RolesFromContext(ctx))
+}
+
+func TestUnaryServerInterceptor_DropsUntrustedRoles(t *testing.T) {
+	var roles []string
+	handler := func(ctx context.Context, req any) (any, error) {
+		roles = RolesFromContext(ctx)
+		return nil, nil
+	}
+
+	_, err := UnaryServerInterceptor(trustedToken)(incomingContext(MDKeyUserID, "user-1", MDKeyUserRoles, "admin"), nil, &grpc.UnaryServerInfo{}, handler)
+
+	assert.NoError(t, err)
+	assert.Nil(t, roles)
+}
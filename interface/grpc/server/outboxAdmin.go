@@ -0,0 +1,111 @@
+package server
+
+import (
+	"context"
+	"fmt"
+
+	"refina-wallet/config/log"
+	"refina-wallet/internal/service"
+	"refina-wallet/internal/types/dto"
+	"refina-wallet/internal/utils/data"
+
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// OutboxAdminServiceServer is served as data.GRPC_OUTBOX_ADMIN_SERVICE. Requests are a
+// dto.OutboxBackfillRequest or dto.OutboxReplayRequest and both responses a
+// dto.OutboxEnqueueResponse, as Struct.
+type OutboxAdminServiceServer interface {
+	BackfillWalletSnapshots(ctx context.Context, req *structpb.Struct) (*structpb.Struct, error)
+	ReplayPublished(ctx context.Context, req *structpb.Struct) (*structpb.Struct, error)
+}
+
+var outboxAdminServiceDesc = grpc.ServiceDesc{
+	ServiceName: data.GRPC_OUTBOX_ADMIN_SERVICE,
+	HandlerType: (*OutboxAdminServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "BackfillWalletSnapshots",
+			Handler: structUnaryHandler(data.GRPC_BACKFILL_WALLET_SNAPSHOTS_FULL_METHOD, func(srv any, ctx context.Context, req *structpb.Struct) (*structpb.Struct, error) {
+				return srv.(OutboxAdminServiceServer).BackfillWalletSnapshots(ctx, req)
+			}),
+		},
+		{
+			MethodName: "ReplayPublished",
+			Handler: structUnaryHandler(data.GRPC_REPLAY_PUBLISHED_FULL_METHOD, func(srv any, ctx context.Context, req *structpb.Struct) (*structpb.Struct, error) {
+				return srv.(OutboxAdminServiceServer).ReplayPublished(ctx, req)
+			}),
+		},
+	},
+	Streams: []grpc.StreamDesc{},
+}
+
+type outboxAdminServer struct {
+	outboxReplayService service.OutboxReplayService
+}
+
+// ── BackfillWalletSnapshots ──
+
+func (s *outboxAdminServer) BackfillWalletSnapshots(ctx context.Context, req *structpb.Struct) (*structpb.Struct, error) {
+	var backfillReq dto.OutboxBackfillRequest
+	if err := decodeStruct(req, &backfillReq); err != nil {
+		log.Warn(data.LogOutboxAdminBadRequest, map[string]any{
+			"service": data.GRPCServerService,
+			"error":   err.Error(),
+		})
+		return nil, err
+	}
+
+	enqueued, err := s.outboxReplayService.BackfillWalletSnapshots(ctx, backfillReq)
+	if err != nil {
+		log.Error(data.LogOutboxBackfillFailed, map[string]any{
+			"service":  data.GRPCServerService,
+			"user_id":  backfillReq.UserID,
+			"enqueued": enqueued,
+			"error":    err.Error(),
+		})
+		return nil, fmt.Errorf("backfill wallet snapshots: %w", err)
+	}
+
+	log.Info(data.LogOutboxBackfillCompleted, map[string]any{
+		"service":  data.GRPCServerService,
+		"user_id":  backfillReq.UserID,
+		"enqueued": enqueued,
+	})
+
+	return encodeStruct(dto.OutboxEnqueueResponse{Enqueued: enqueued})
+}
+
+// ── ReplayPublished ──
+
+func (s *outboxAdminServer) ReplayPublished(ctx context.Context, req *structpb.Struct) (*structpb.Struct, error) {
+	var replayReq dto.OutboxReplayRequest
+	if err := decodeStruct(req, &replayReq); err != nil {
+		log.Warn(data.LogOutboxAdminBadRequest, map[string]any{
+			"service": data.GRPCServerService,
+			"error":   err.Error(),
+		})
+		return nil, err
+	}
+
+	enqueued, err := s.outboxReplayService.ReplayPublished(ctx, replayReq)
+	if err != nil {
+		log.Error(data.LogOutboxReplayFailed, map[string]any{
+			"service": data.GRPCServerService,
+			"from_id": replayReq.FromID,
+			"to_id":   replayReq.ToID,
+			"error":   err.Error(),
+		})
+		return nil, fmt.Errorf("replay published outbox messages [%d..%d]: %w", replayReq.FromID, replayReq.ToID, err)
+	}
+
+	log.Info(data.LogOutboxReplayCompleted, map[string]any{
+		"service":  data.GRPCServerService,
+		"from_id":  replayReq.FromID,
+		"to_id":    replayReq.ToID,
+		"enqueued": enqueued,
+	})
+
+	return encodeStruct(dto.OutboxEnqueueResponse{Enqueued: enqueued})
+}
@@ -1,35 +1,53 @@
 package server
 
 import (
+	"fmt"
 	"net"
 
 	"refina-wallet/config/db"
 	"refina-wallet/config/env"
+	"refina-wallet/config/log"
 	"refina-wallet/interface/grpc/client"
 	"refina-wallet/interface/grpc/interceptor"
 	"refina-wallet/interface/queue"
 	"refina-wallet/internal/repository"
 	"refina-wallet/internal/service"
+	"refina-wallet/internal/utils/data"
 
 	wpb "github.com/MuhammadMiftaa/Refina-Protobuf/wallet"
 	"google.golang.org/grpc"
 )
 
-func SetupGRPCServer(dbInstance db.DatabaseClient, queueInstance queue.RabbitMQClient) (*grpc.Server, *net.Listener, error) {
+func SetupGRPCServer(dbInstance db.DatabaseClient, queueInstance queue.RabbitMQClient, exchangeRates service.ExchangeRateProvider, accessPolicy *service.AccessPolicy) (*grpc.Server, *net.Listener, error) {
 	lis, err := net.Listen("tcp", ":"+env.Cfg.Server.GRPCPort)
 	if err != nil {
 		return nil, nil, err
 	}
 
+	if env.Cfg.GRPCConfig.TrustedCallerToken == "" {
+		log.Warn(data.LogGRPCCallerTokenUnset, map[string]any{"service": data.GRPCServerService})
+	}
+
+	// Error interceptor paling luar agar error dari interceptor lain ikut dipetakan ke status gRPC;
+	// metadata user diekstrak sebelum otorisasi agar role-nya bisa dibaca
 	s := grpc.NewServer(
-		grpc.UnaryInterceptor(interceptor.UnaryServerInterceptor()),
-		grpc.StreamInterceptor(interceptor.StreamServerInterceptor()),
+		grpc.ChainUnaryInterceptor(
+			interceptor.ErrorUnaryInterceptor(),
+			interceptor.UnaryServerInterceptor(env.Cfg.GRPCConfig.TrustedCallerToken),
+			interceptor.AuthorizationUnaryInterceptor(accessPolicy),
+		),
+		grpc.ChainStreamInterceptor(
+			interceptor.ErrorStreamInterceptor(),
+			interceptor.StreamServerInterceptor(env.Cfg.GRPCConfig.TrustedCallerToken),
+			interceptor.AuthorizationStreamInterceptor(accessPolicy),
+		),
 	)
 
 	txManager := repository.NewTxManager(dbInstance.GetDB())
 	walletsRepo := repository.NewWalletRepository(dbInstance.GetDB())
 	walletTypesRepo := repository.NewWalletTypesRepository(dbInstance.GetDB())
 	outboxRepo := repository.NewOutboxRepository(dbInstance.GetDB())
+	sagaRepo := repository.NewWalletSagaRepository(dbInstance.GetDB())
 	transactionClient := client.NewTransactionClient(client.GetManager().GetTransactionClient())
 
 	walletService := service.NewWalletService(
@@ -37,16 +55,39 @@ func SetupGRPCServer(dbInstance db.DatabaseClient, queueInstance queue.RabbitMQC
 		walletsRepo,
 		walletTypesRepo,
 		outboxRepo,
+		sagaRepo,
 		transactionClient,
+		exchangeRates,
 		queueInstance,
 	)
 	walletTypesService := service.NewWalletTypesService(txManager, walletTypesRepo)
 
+	idempotencyService := service.NewIdempotencyService(repository.NewIdempotencyRepository(dbInstance.GetDB()))
+
 	walletServer := &walletServer{
 		walletService:      walletService,
 		walletTypesService: walletTypesService,
+		idempotencyService: idempotencyService,
 	}
 	wpb.RegisterWalletServiceServer(s, walletServer)
+	s.RegisterService(&walletTransferServiceDesc, &walletTransferServer{
+		walletService:      walletService,
+		idempotencyService: idempotencyService,
+	})
+	s.RegisterService(&outboxAdminServiceDesc, &outboxAdminServer{
+		outboxReplayService: service.NewOutboxReplayService(txManager, walletsRepo, outboxRepo),
+	})
+
+	var methods []string
+	for serviceName, info := range s.GetServiceInfo() {
+		for _, method := range info.Methods {
+			methods = append(methods, "/"+serviceName+"/"+method.Name)
+		}
+	}
+	if undeclared := accessPolicy.Undeclared(methods); len(undeclared) > 0 {
+		lis.Close()
+		return nil, nil, fmt.Errorf("access policy has no rule for %v", undeclared)
+	}
 
 	return s, &lis, nil
 }
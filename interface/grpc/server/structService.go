@@ -0,0 +1,70 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// RPCs that Refina-Protobuf has no messages for yet are served by hand-written services whose
+// request and response are google.protobuf.Struct carrying the JSON body of the matching HTTP
+// route, so they share the DTOs (and their validation) with the HTTP handlers.
+
+// structMethod is the server side of one unary Struct RPC.
+type structMethod func(srv any, ctx context.Context, req *structpb.Struct) (*structpb.Struct, error)
+
+// structUnaryHandler adapts call to a grpc.MethodHandler that runs through the server's
+// unary interceptor chain like any generated method.
+func structUnaryHandler(fullMethod string, call structMethod) grpc.MethodHandler {
+	return func(srv any, ctx context.Context, dec func(any) error, interceptor grpc.UnaryServerInterceptor) (any, error) {
+		in := new(structpb.Struct)
+		if err := dec(in); err != nil {
+			return nil, err
+		}
+		if interceptor == nil {
+			return call(srv, ctx, in)
+		}
+
+		info := &grpc.UnaryServerInfo{Server: srv, FullMethod: fullMethod}
+		handler := func(ctx context.Context, req any) (any, error) {
+			return call(srv, ctx, req.(*structpb.Struct))
+		}
+		return interceptor(ctx, in, info, handler)
+	}
+}
+
+// decodeStruct fills out from req the way the HTTP handlers bind a JSON body.
+func decodeStruct(req *structpb.Struct, out any) error {
+	body, err := json.Marshal(req.AsMap())
+	if err != nil {
+		return status.Errorf(codes.InvalidArgument, "invalid request: %v", err)
+	}
+	if err := json.Unmarshal(body, out); err != nil {
+		return status.Errorf(codes.InvalidArgument, "invalid request: %v", err)
+	}
+	return nil
+}
+
+// encodeStruct returns v as the Struct of its JSON encoding.
+func encodeStruct(v any) (*structpb.Struct, error) {
+	body, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("encode response: %w", err)
+	}
+
+	var fields map[string]any
+	if err := json.Unmarshal(body, &fields); err != nil {
+		return nil, fmt.Errorf("encode response: %w", err)
+	}
+
+	resp, err := structpb.NewStruct(fields)
+	if err != nil {
+		return nil, fmt.Errorf("encode response: %w", err)
+	}
+	return resp, nil
+}
@@ -0,0 +1,70 @@
+package server
+
+import (
+	"context"
+	"testing"
+
+	"refina-wallet/internal/types/dto"
+	"refina-wallet/internal/types/money"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+func TestDecodeStruct_BindsLikeTheHTTPBody(t *testing.T) {
+	req, err := structpb.NewStruct(map[string]any{
+		"from_wallet_id": "aaaaaaaa-aaaa-aaaa-aaaa-aaaaaaaaaaaa",
+		"to_wallet_id":   "eeeeeeee-eeee-eeee-eeee-eeeeeeeeeeee",
+		"amount":         "150000.50",
+		"admin_fee":      2500,
+	})
+	assert.NoError(t, err)
+
+	var got dto.WalletTransferRequest
+	assert.NoError(t, decodeStruct(req, &got))
+
+	assert.Equal(t, "aaaaaaaa-aaaa-aaaa-aaaa-aaaaaaaaaaaa", got.FromWalletID)
+	assert.Equal(t, "eeeeeeee-eeee-eeee-eeee-eeeeeeeeeeee", got.ToWalletID)
+	assert.Equal(t, money.MustParse("150000.50"), got.Amount)
+	assert.Equal(t, money.MustParse("2500"), got.AdminFee)
+}
+
+func TestDecodeStruct_InvalidArgument(t *testing.T) {
+	req, err := structpb.NewStruct(map[string]any{"amount": "1.234"})
+	assert.NoError(t, err)
+
+	var got dto.WalletTransferRequest
+	err = decodeStruct(req, &got)
+
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestEncodeStruct_UsesTheJSONShape(t *testing.T) {
+	resp, err := encodeStruct(dto.OutboxEnqueueResponse{Enqueued: 3})
+
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]any{"enqueued": float64(3)}, resp.AsMap())
+}
+
+func TestStructUnaryHandler_RunsTheInterceptor(t *testing.T) {
+	handler := structUnaryHandler("/svc/Method", func(srv any, ctx context.Context, req *structpb.Struct) (*structpb.Struct, error) {
+		return req, nil
+	})
+	dec := func(in any) error {
+		in.(*structpb.Struct).Fields = map[string]*structpb.Value{"ok": structpb.NewBoolValue(true)}
+		return nil
+	}
+
+	var intercepted string
+	resp, err := handler(nil, context.Background(), dec, func(ctx context.Context, req any, info *grpc.UnaryServerInfo, next grpc.UnaryHandler) (any, error) {
+		intercepted = info.FullMethod
+		return next(ctx, req)
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "/svc/Method", intercepted)
+	assert.Equal(t, map[string]any{"ok": true}, resp.(*structpb.Struct).AsMap())
+}
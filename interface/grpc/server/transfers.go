@@ -0,0 +1,85 @@
+package server
+
+import (
+	"context"
+	"fmt"
+
+	"refina-wallet/config/log"
+	"refina-wallet/interface/grpc/interceptor"
+	"refina-wallet/internal/service"
+	"refina-wallet/internal/types/dto"
+	"refina-wallet/internal/utils/data"
+
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// WalletTransferServiceServer is served as data.GRPC_WALLET_TRANSFER_SERVICE. The request is a
+// dto.WalletTransferRequest and the response a dto.WalletTransferResponse, both as Struct;
+// idempotency-key metadata works as on the wallet service.
+type WalletTransferServiceServer interface {
+	TransferBetweenWallets(ctx context.Context, req *structpb.Struct) (*structpb.Struct, error)
+}
+
+var walletTransferServiceDesc = grpc.ServiceDesc{
+	ServiceName: data.GRPC_WALLET_TRANSFER_SERVICE,
+	HandlerType: (*WalletTransferServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "TransferBetweenWallets",
+			Handler: structUnaryHandler(data.GRPC_TRANSFER_BETWEEN_WALLETS_FULL_METHOD, func(srv any, ctx context.Context, req *structpb.Struct) (*structpb.Struct, error) {
+				return srv.(WalletTransferServiceServer).TransferBetweenWallets(ctx, req)
+			}),
+		},
+	},
+	Streams: []grpc.StreamDesc{},
+}
+
+type walletTransferServer struct {
+	walletService      service.WalletsService
+	idempotencyService service.IdempotencyService
+}
+
+// ── TransferBetweenWallets ──
+
+func (s *walletTransferServer) TransferBetweenWallets(ctx context.Context, req *structpb.Struct) (*structpb.Struct, error) {
+	userID := interceptor.UserIDFromContext(ctx)
+
+	var transferReq dto.WalletTransferRequest
+	if err := decodeStruct(req, &transferReq); err != nil {
+		log.Warn(data.LogTransferWalletBadRequest, map[string]any{
+			"service": data.GRPCServerService,
+			"user_id": userID,
+			"error":   err.Error(),
+		})
+		return nil, err
+	}
+
+	transfer, replayed, err := service.RunIdempotent(ctx, s.idempotencyService, service.IdempotencyRequest{
+		Key:       firstIncomingValue(ctx, MDKeyIdempotencyKey),
+		Operation: data.IDEMPOTENCY_OP_TRANSFER,
+		UserID:    userID,
+		Body:      transferReq,
+	}, func(ctx context.Context) (dto.WalletTransferResponse, error) {
+		return s.walletService.TransferBetweenWallets(ctx, interceptor.WalletAccessFromContext(ctx, service.PermissionWriteAnyWallet), transferReq)
+	})
+	if err != nil {
+		log.Error(data.LogTransferWalletFailed, map[string]any{
+			"service":        data.GRPCServerService,
+			"from_wallet_id": transferReq.FromWalletID,
+			"to_wallet_id":   transferReq.ToWalletID,
+			"error":          err.Error(),
+		})
+		return nil, fmt.Errorf("transfer from wallet [id=%s] to wallet [id=%s]: %w", transferReq.FromWalletID, transferReq.ToWalletID, err)
+	}
+	setIdempotentReplayedHeader(ctx, replayed)
+
+	log.Info(data.LogWalletTransferred, map[string]any{
+		"service":                 data.GRPCServerService,
+		"from_wallet_id":          transfer.FromWallet.ID,
+		"to_wallet_id":            transfer.ToWallet.ID,
+		"cash_out_transaction_id": transfer.CashOutTransactionID,
+	})
+
+	return encodeStruct(transfer)
+}
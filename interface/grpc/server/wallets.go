@@ -6,17 +6,38 @@ import (
 	"time"
 
 	"refina-wallet/config/log"
+	"refina-wallet/interface/grpc/interceptor"
 	"refina-wallet/internal/service"
 	"refina-wallet/internal/types/dto"
+	"refina-wallet/internal/types/money"
+	"refina-wallet/internal/utils"
 	"refina-wallet/internal/utils/data"
 
 	wpb "github.com/MuhammadMiftaa/Refina-Protobuf/wallet"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// The wallet protos have no version, idempotency or currency fields, so they ride on metadata
+// with the same semantics as the HTTP If-Match / ETag / Idempotency-Key headers.
+const (
+	MDKeyIfMatch            = "if-match"
+	MDKeyETag               = "etag"
+	MDKeyIdempotencyKey     = "idempotency-key"
+	MDKeyIdempotentReplayed = "idempotent-replayed"
+	// MDKeyCurrency sets the currency of a created wallet, MDKeyBaseCurrency the currency
+	// summaries are converted to; neither fits in the upstream protos.
+	MDKeyCurrency     = "currency"
+	MDKeyBaseCurrency = "base-currency"
 )
 
 type walletServer struct {
 	wpb.UnimplementedWalletServiceServer
 	walletService      service.WalletsService
 	walletTypesService service.WalletTypesService
+	idempotencyService service.IdempotencyService
 }
 
 // ── Helper: convert model wallet to proto Wallet ──
@@ -27,7 +48,7 @@ func walletToProto(w dto.WalletsResponse) *wpb.Wallet {
 		UserId:         w.UserID,
 		Name:           w.Name,
 		Number:         w.Number,
-		Balance:        w.Balance,
+		Balance:        w.Balance.Float64(),
 		WalletTypeId:   w.WalletTypeID,
 		WalletType:     w.WalletType,
 		WalletTypeName: w.WalletTypeName,
@@ -40,7 +61,7 @@ func walletToProtoFull(w dto.WalletsResponse, txnCount int32, createdAt, updated
 		UserId:           w.UserID,
 		Name:             w.Name,
 		Number:           w.Number,
-		Balance:          w.Balance,
+		Balance:          w.Balance.Float64(),
 		WalletTypeId:     w.WalletTypeID,
 		WalletType:       w.WalletType,
 		WalletTypeName:   w.WalletTypeName,
@@ -83,6 +104,9 @@ func (s *walletServer) GetWallets(req *wpb.GetWalletOptions, stream wpb.WalletSe
 
 func (s *walletServer) GetUserWallets(ctx context.Context, req *wpb.UserID) (*wpb.GetUserWalletsResponse, error) {
 	userID := req.GetId()
+	if err := authorizeUserScope(ctx, wpb.WalletService_GetUserWallets_FullMethodName, userID, service.PermissionReadAnyWallet); err != nil {
+		return nil, err
+	}
 
 	wallets, err := s.walletService.GetWalletsByUserID(ctx, userID)
 	if err != nil {
@@ -101,7 +125,7 @@ func (s *walletServer) GetUserWallets(ctx context.Context, req *wpb.UserID) (*wp
 			UserId:         wallet.UserID,
 			Name:           wallet.Name,
 			Number:         wallet.Number,
-			Balance:        wallet.Balance,
+			Balance:        wallet.Balance.Float64(),
 			WalletTypeId:   wallet.WalletTypeID,
 			WalletType:     wallet.WalletType,
 			WalletTypeName: wallet.WalletTypeName,
@@ -131,7 +155,7 @@ func (s *walletServer) GetUserWallets(ctx context.Context, req *wpb.UserID) (*wp
 func (s *walletServer) GetWalletByID(ctx context.Context, req *wpb.WalletID) (*wpb.Wallet, error) {
 	walletID := req.GetId()
 
-	wallet, err := s.walletService.GetWalletByID(ctx, walletID)
+	wallet, err := s.walletService.GetWalletByID(ctx, interceptor.WalletAccessFromContext(ctx, service.PermissionReadAnyWallet), walletID)
 	if err != nil {
 		log.Error(data.LogGetWalletByIDFailed, map[string]any{
 			"service":   data.GRPCServerService,
@@ -140,13 +164,14 @@ func (s *walletServer) GetWalletByID(ctx context.Context, req *wpb.WalletID) (*w
 		})
 		return nil, fmt.Errorf("get wallet [id=%s]: %w", walletID, err)
 	}
+	setETagHeader(ctx, wallet.Version)
 
 	return &wpb.Wallet{
 		Id:             wallet.ID,
 		UserId:         wallet.UserID,
 		Name:           wallet.Name,
 		Number:         wallet.Number,
-		Balance:        wallet.Balance,
+		Balance:        wallet.Balance.Float64(),
 		WalletTypeId:   wallet.WalletTypeID,
 		WalletType:     wallet.WalletType,
 		WalletTypeName: wallet.WalletTypeName,
@@ -165,17 +190,30 @@ func (s *walletServer) GetWalletByID(ctx context.Context, req *wpb.WalletID) (*w
 
 func (s *walletServer) CreateWallet(ctx context.Context, req *wpb.CreateWalletRequest) (*wpb.Wallet, error) {
 	userID := req.GetUserId()
+	// Wallet untuk user lain hanya boleh dibuat oleh role yang boleh menulis wallet siapa pun
+	if err := authorizeUserScope(ctx, wpb.WalletService_CreateWallet_FullMethodName, userID, service.PermissionWriteAnyWallet); err != nil {
+		return nil, err
+	}
 
 	walletReq := dto.WalletsRequest{
 		UserID:       userID,
 		WalletTypeID: req.GetWalletTypeId(),
 		Name:         req.GetName(),
 		Number:       req.GetNumber(),
-		Balance:      req.GetBalance(),
+		Balance:      money.FromFloat(req.GetBalance()),
+		Currency:     firstIncomingValue(ctx, MDKeyCurrency),
 	}
 
-	// The service handles tx management, outbox, and initial deposit via gRPC
-	result, err := s.walletService.CreateWalletGRPC(ctx, walletReq)
+	// The service handles tx management, outbox, and initial deposit via gRPC.
+	// Kunci idempotensi milik pemanggil, bukan user tujuan wallet, seperti pada HTTP
+	result, replayed, err := service.RunIdempotent(ctx, s.idempotencyService, service.IdempotencyRequest{
+		Key:       firstIncomingValue(ctx, MDKeyIdempotencyKey),
+		Operation: data.IDEMPOTENCY_OP_CREATE_WALLET,
+		UserID:    interceptor.UserIDFromContext(ctx),
+		Body:      walletReq,
+	}, func(ctx context.Context) (dto.WalletsResponse, error) {
+		return s.walletService.CreateWalletGRPC(ctx, walletReq)
+	})
 	if err != nil {
 		log.Error(data.LogCreateWalletFailed, map[string]any{
 			"service": data.GRPCServerService,
@@ -184,6 +222,8 @@ func (s *walletServer) CreateWallet(ctx context.Context, req *wpb.CreateWalletRe
 		})
 		return nil, fmt.Errorf("create wallet for user [id=%s]: %w", userID, err)
 	}
+	setIdempotentReplayedHeader(ctx, replayed)
+	setETagHeader(ctx, result.Version)
 
 	log.Info(data.LogWalletCreated, map[string]any{
 		"service":   data.GRPCServerService,
@@ -194,6 +234,55 @@ func (s *walletServer) CreateWallet(ctx context.Context, req *wpb.CreateWalletRe
 	return walletToProto(result), nil
 }
 
+// ── Helper: optimistic concurrency and idempotency over metadata ──
+
+func firstIncomingValue(ctx context.Context, key string) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+	if vals := md.Get(key); len(vals) > 0 {
+		return vals[0]
+	}
+	return ""
+}
+
+func expectedVersionFromMetadata(ctx context.Context) (int64, error) {
+	version, err := utils.ParseETag(firstIncomingValue(ctx, MDKeyIfMatch))
+	if err != nil {
+		return 0, status.Errorf(codes.InvalidArgument, "invalid %s metadata: %v", MDKeyIfMatch, err)
+	}
+	return version, nil
+}
+
+func setETagHeader(ctx context.Context, version int64) {
+	// Gagal mengirim header tidak menggagalkan RPC; client cukup membaca ulang wallet
+	_ = grpc.SetHeader(ctx, metadata.Pairs(MDKeyETag, utils.FormatETag(version)))
+}
+
+func setIdempotentReplayedHeader(ctx context.Context, replayed bool) {
+	if replayed {
+		_ = grpc.SetHeader(ctx, metadata.Pairs(MDKeyIdempotentReplayed, "true"))
+	}
+}
+
+// authorizeUserScope lets callers act on the user-level data of another user only with anyUser,
+// e.g. support looking up a customer with PermissionReadAnyWallet.
+func authorizeUserScope(ctx context.Context, fullMethod, userID string, anyUser service.Permission) error {
+	if userID != "" && userID == interceptor.UserIDFromContext(ctx) {
+		return nil
+	}
+
+	roles := service.ParseRoles(interceptor.RolesFromContext(ctx))
+	if service.HasPermission(roles, anyUser) {
+		return nil
+	}
+
+	err := fmt.Errorf("%w: wallets of user [id=%s] belong to another caller", service.ErrPermissionDenied, userID)
+	interceptor.LogAccessDenied(ctx, fullMethod, anyUser, err)
+	return err
+}
+
 // ── UpdateWallet ──
 
 func (s *walletServer) UpdateWallet(ctx context.Context, req *wpb.UpdateWalletRequest) (*wpb.Wallet, error) {
@@ -203,10 +292,22 @@ func (s *walletServer) UpdateWallet(ctx context.Context, req *wpb.UpdateWalletRe
 		Name:         req.GetName(),
 		Number:       req.GetNumber(),
 		WalletTypeID: req.GetWalletTypeId(),
-		Balance:      req.GetBalance(),
+		Balance:      money.FromFloat(req.GetBalance()),
+	}
+
+	expectedVersion, err := expectedVersionFromMetadata(ctx)
+	if err != nil {
+		return nil, err
 	}
 
-	result, err := s.walletService.UpdateWallet(ctx, walletID, walletReq)
+	result, replayed, err := service.RunIdempotent(ctx, s.idempotencyService, service.IdempotencyRequest{
+		Key:       firstIncomingValue(ctx, MDKeyIdempotencyKey),
+		Operation: data.IDEMPOTENCY_OP_UPDATE_WALLET,
+		UserID:    interceptor.UserIDFromContext(ctx),
+		Body:      map[string]any{"id": walletID, "wallet": walletReq, "version": expectedVersion},
+	}, func(ctx context.Context) (dto.WalletsResponse, error) {
+		return s.walletService.UpdateWallet(ctx, interceptor.WalletAccessFromContext(ctx, service.PermissionWriteAnyWallet), walletID, walletReq, expectedVersion)
+	})
 	if err != nil {
 		log.Error(data.LogUpdateWalletFailed, map[string]any{
 			"service":   data.GRPCServerService,
@@ -215,6 +316,8 @@ func (s *walletServer) UpdateWallet(ctx context.Context, req *wpb.UpdateWalletRe
 		})
 		return nil, fmt.Errorf("update wallet [id=%s]: %w", walletID, err)
 	}
+	setIdempotentReplayedHeader(ctx, replayed)
+	setETagHeader(ctx, result.Version)
 
 	log.Info(data.LogWalletUpdated, map[string]any{
 		"service":   data.GRPCServerService,
@@ -229,7 +332,19 @@ func (s *walletServer) UpdateWallet(ctx context.Context, req *wpb.UpdateWalletRe
 func (s *walletServer) DeleteWallet(ctx context.Context, req *wpb.WalletID) (*wpb.Wallet, error) {
 	walletID := req.GetId()
 
-	result, err := s.walletService.DeleteWallet(ctx, walletID)
+	expectedVersion, err := expectedVersionFromMetadata(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	result, replayed, err := service.RunIdempotent(ctx, s.idempotencyService, service.IdempotencyRequest{
+		Key:       firstIncomingValue(ctx, MDKeyIdempotencyKey),
+		Operation: data.IDEMPOTENCY_OP_DELETE_WALLET,
+		UserID:    interceptor.UserIDFromContext(ctx),
+		Body:      map[string]any{"id": walletID, "version": expectedVersion},
+	}, func(ctx context.Context) (dto.WalletsResponse, error) {
+		return s.walletService.DeleteWallet(ctx, interceptor.WalletAccessFromContext(ctx, service.PermissionWriteAnyWallet), walletID, expectedVersion)
+	})
 	if err != nil {
 		log.Error(data.LogDeleteWalletFailed, map[string]any{
 			"service":   data.GRPCServerService,
@@ -238,6 +353,7 @@ func (s *walletServer) DeleteWallet(ctx context.Context, req *wpb.WalletID) (*wp
 		})
 		return nil, fmt.Errorf("delete wallet [id=%s]: %w", walletID, err)
 	}
+	setIdempotentReplayedHeader(ctx, replayed)
 
 	log.Info(data.LogWalletDeleted, map[string]any{
 		"service":   data.GRPCServerService,
@@ -281,8 +397,12 @@ func (s *walletServer) GetWalletTypes(ctx context.Context, req *wpb.Empty) (*wpb
 
 func (s *walletServer) GetWalletSummary(ctx context.Context, req *wpb.UserID) (*wpb.WalletSummary, error) {
 	userID := req.GetId()
+	if err := authorizeUserScope(ctx, wpb.WalletService_GetWalletSummary_FullMethodName, userID, service.PermissionReadAnyWallet); err != nil {
+		return nil, err
+	}
 
-	wallets, err := s.walletService.GetWalletsByUserID(ctx, userID)
+	// TotalBalance dikonversi ke base currency; rincian per mata uang hanya tersedia lewat HTTP
+	summary, err := s.walletService.GetWalletSummary(ctx, userID, firstIncomingValue(ctx, MDKeyBaseCurrency))
 	if err != nil {
 		log.Error(data.LogGetWalletSummaryFailed, map[string]any{
 			"service": data.GRPCServerService,
@@ -292,21 +412,18 @@ func (s *walletServer) GetWalletSummary(ctx context.Context, req *wpb.UserID) (*
 		return nil, fmt.Errorf("get wallet summary for user [id=%s]: %w", userID, err)
 	}
 
-	var totalBalance float64
 	var totalTransactions int32
-	for _, w := range wallets {
-		totalBalance += w.Balance
-	}
 
 	log.Info(data.LogGetWalletSummarySuccess, map[string]any{
-		"service":      data.GRPCServerService,
-		"user_id":      userID,
-		"wallet_count": len(wallets),
+		"service":       data.GRPCServerService,
+		"user_id":       userID,
+		"wallet_count":  summary.TotalWallets,
+		"base_currency": summary.BaseCurrency,
 	})
 
 	return &wpb.WalletSummary{
-		TotalWallets:      int32(len(wallets)),
-		TotalBalance:      totalBalance,
+		TotalWallets:      int32(summary.TotalWallets),
+		TotalBalance:      summary.TotalBalance.Float64(),
 		TotalTransactions: totalTransactions,
 	}, nil
 }
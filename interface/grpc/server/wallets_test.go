@@ -0,0 +1,53 @@
+package server
+
+import (
+	"context"
+	"testing"
+
+	"refina-wallet/interface/grpc/interceptor"
+	"refina-wallet/internal/service"
+	"refina-wallet/internal/types/dto"
+
+	wpb "github.com/MuhammadMiftaa/Refina-Protobuf/wallet"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/metadata"
+)
+
+type recordingIdempotency struct {
+	service.IdempotencyService
+	requests []service.IdempotencyRequest
+}
+
+func (r *recordingIdempotency) Execute(ctx context.Context, req service.IdempotencyRequest, out any, fn func(ctx context.Context) (any, error)) (bool, error) {
+	r.requests = append(r.requests, req)
+	result, err := fn(ctx)
+	if err == nil {
+		*out.(*dto.WalletsResponse) = result.(dto.WalletsResponse)
+	}
+	return false, err
+}
+
+type stubWalletsService struct {
+	service.WalletsService
+}
+
+func (stubWalletsService) CreateWalletGRPC(ctx context.Context, req dto.WalletsRequest) (dto.WalletsResponse, error) {
+	return dto.WalletsResponse{ID: "wallet-1", UserID: req.UserID}, nil
+}
+
+func TestCreateWallet_IdempotencyKeyBelongsToTheCaller(t *testing.T) {
+	idem := &recordingIdempotency{}
+	srv := &walletServer{walletService: stubWalletsService{}, idempotencyService: idem}
+
+	ctx := interceptor.WithUser(context.Background(), "admin-1", "", "", "")
+	ctx = interceptor.WithRoles(ctx, []string{"admin"})
+	ctx = metadata.NewIncomingContext(ctx, metadata.Pairs(MDKeyIdempotencyKey, "key-1"))
+
+	_, err := srv.CreateWallet(ctx, &wpb.CreateWalletRequest{UserId: "user-2", Name: "Cash"})
+
+	assert.NoError(t, err)
+	if assert.Len(t, idem.requests, 1) {
+		assert.Equal(t, "admin-1", idem.requests[0].UserID)
+		assert.Equal(t, "key-1", idem.requests[0].Key)
+	}
+}
@@ -0,0 +1,65 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"refina-wallet/config/log"
+	"refina-wallet/interface/queue"
+	"refina-wallet/internal/utils/data"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type healthHandler struct {
+	db    *gorm.DB
+	queue queue.RabbitMQClient
+}
+
+func NewHealthHandler(db *gorm.DB, queue queue.RabbitMQClient) *healthHandler {
+	return &healthHandler{db, queue}
+}
+
+// Health melaporkan status koneksi database dan RabbitMQ; 503 jika salah satunya tidak sehat.
+func (health_handler *healthHandler) Health(c *gin.Context) {
+	requestID, _ := c.Get(data.REQUEST_ID_LOCAL_KEY)
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 2*time.Second)
+	defer cancel()
+
+	healthy := true
+
+	databaseStatus := "up"
+	if sqlDB, err := health_handler.db.DB(); err != nil || sqlDB.PingContext(ctx) != nil {
+		databaseStatus = "down"
+		healthy = false
+	}
+
+	rabbitmqState := health_handler.queue.State()
+	if rabbitmqState != queue.StateConnected {
+		healthy = false
+	}
+
+	statusCode := http.StatusOK
+	if !healthy {
+		statusCode = http.StatusServiceUnavailable
+		log.Warn(data.LogHealthCheckUnhealthy, map[string]any{
+			"service":    data.HealthService,
+			"request_id": requestID,
+			"database":   databaseStatus,
+			"rabbitmq":   rabbitmqState.String(),
+		})
+	}
+
+	c.JSON(statusCode, gin.H{
+		"statusCode": statusCode,
+		"status":     healthy,
+		"message":    "Health check",
+		"data": gin.H{
+			"database": databaseStatus,
+			"rabbitmq": rabbitmqState.String(),
+		},
+	})
+}
@@ -0,0 +1,181 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"refina-wallet/config/log"
+	"refina-wallet/internal/service"
+	"refina-wallet/internal/utils/data"
+
+	"github.com/gin-gonic/gin"
+)
+
+type outboxAdminHandler struct {
+	outboxAdminService service.OutboxAdminService
+}
+
+func NewOutboxAdminHandler(outboxAdminService service.OutboxAdminService) *outboxAdminHandler {
+	return &outboxAdminHandler{outboxAdminService}
+}
+
+func (outbox_handler *outboxAdminHandler) GetDeadLetters(c *gin.Context) {
+	ctx := c.Request.Context()
+	requestID, _ := c.Get(data.REQUEST_ID_LOCAL_KEY)
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+
+	messages, err := outbox_handler.outboxAdminService.GetDeadLetters(ctx, page)
+	if err != nil {
+		log.Error(data.LogGetDeadLettersFailed, map[string]any{
+			"service":    data.OutboxAdminService,
+			"request_id": requestID,
+			"error":      err.Error(),
+		})
+		statusCode, message := mapServiceError(err)
+		c.JSON(statusCode, gin.H{
+			"statusCode": statusCode,
+			"status":     false,
+			"message":    message,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"statusCode": 200,
+		"status":     true,
+		"message":    "Get dead letter messages",
+		"data":       messages,
+	})
+}
+
+func (outbox_handler *outboxAdminHandler) GetDeadLetterByID(c *gin.Context) {
+	ctx := c.Request.Context()
+	requestID, _ := c.Get(data.REQUEST_ID_LOCAL_KEY)
+
+	id, ok := parseOutboxID(c, requestID)
+	if !ok {
+		return
+	}
+
+	message, err := outbox_handler.outboxAdminService.GetDeadLetterByID(ctx, id)
+	if err != nil {
+		log.Error(data.LogGetDeadLetterFailed, map[string]any{
+			"service":     data.OutboxAdminService,
+			"request_id":  requestID,
+			"document_id": id,
+			"error":       err.Error(),
+		})
+		statusCode, message := mapServiceError(err)
+		c.JSON(statusCode, gin.H{
+			"statusCode": statusCode,
+			"status":     false,
+			"message":    message,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"statusCode": 200,
+		"status":     true,
+		"message":    "Get dead letter message by ID",
+		"data":       message,
+	})
+}
+
+func (outbox_handler *outboxAdminHandler) RequeueDeadLetter(c *gin.Context) {
+	ctx := c.Request.Context()
+	requestID, _ := c.Get(data.REQUEST_ID_LOCAL_KEY)
+
+	id, ok := parseOutboxID(c, requestID)
+	if !ok {
+		return
+	}
+
+	if err := outbox_handler.outboxAdminService.RequeueDeadLetter(ctx, id); err != nil {
+		log.Error(data.LogRequeueDeadLetterFailed, map[string]any{
+			"service":     data.OutboxAdminService,
+			"request_id":  requestID,
+			"document_id": id,
+			"error":       err.Error(),
+		})
+		statusCode, message := mapServiceError(err)
+		c.JSON(statusCode, gin.H{
+			"statusCode": statusCode,
+			"status":     false,
+			"message":    message,
+		})
+		return
+	}
+
+	log.Info(data.LogDeadLetterRequeued, map[string]any{
+		"service":     data.OutboxAdminService,
+		"request_id":  requestID,
+		"document_id": id,
+	})
+
+	c.JSON(http.StatusOK, gin.H{
+		"statusCode": 200,
+		"status":     true,
+		"message":    "Requeue dead letter message",
+	})
+}
+
+func (outbox_handler *outboxAdminHandler) DiscardDeadLetter(c *gin.Context) {
+	ctx := c.Request.Context()
+	requestID, _ := c.Get(data.REQUEST_ID_LOCAL_KEY)
+
+	id, ok := parseOutboxID(c, requestID)
+	if !ok {
+		return
+	}
+
+	if err := outbox_handler.outboxAdminService.DiscardDeadLetter(ctx, id); err != nil {
+		log.Error(data.LogDiscardDeadLetterFailed, map[string]any{
+			"service":     data.OutboxAdminService,
+			"request_id":  requestID,
+			"document_id": id,
+			"error":       err.Error(),
+		})
+		statusCode, message := mapServiceError(err)
+		c.JSON(statusCode, gin.H{
+			"statusCode": statusCode,
+			"status":     false,
+			"message":    message,
+		})
+		return
+	}
+
+	log.Info(data.LogDeadLetterDiscarded, map[string]any{
+		"service":     data.OutboxAdminService,
+		"request_id":  requestID,
+		"document_id": id,
+	})
+
+	c.JSON(http.StatusOK, gin.H{
+		"statusCode": 200,
+		"status":     true,
+		"message":    "Discard dead letter message",
+	})
+}
+
+// parseOutboxID membaca path param :id sebagai outbox id dan langsung membalas 400 jika tidak valid
+func parseOutboxID(c *gin.Context, requestID any) (uint, bool) {
+	raw := c.Param("id")
+	id, err := strconv.ParseUint(raw, 10, 64)
+	if err != nil || id == 0 {
+		log.Warn(data.LogOutboxAdminBadRequest, map[string]any{
+			"service":     data.OutboxAdminService,
+			"request_id":  requestID,
+			"document_id": raw,
+		})
+		c.JSON(http.StatusBadRequest, gin.H{
+			"statusCode": 400,
+			"status":     false,
+			"message":    "invalid request",
+		})
+		return 0, false
+	}
+
+	return uint(id), true
+}
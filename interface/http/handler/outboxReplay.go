@@ -0,0 +1,125 @@
+package handler
+
+import (
+	"net/http"
+
+	"refina-wallet/config/log"
+	"refina-wallet/internal/service"
+	"refina-wallet/internal/types/dto"
+	"refina-wallet/internal/utils/data"
+
+	"github.com/gin-gonic/gin"
+)
+
+type outboxReplayHandler struct {
+	outboxReplayService service.OutboxReplayService
+}
+
+func NewOutboxReplayHandler(outboxReplayService service.OutboxReplayService) *outboxReplayHandler {
+	return &outboxReplayHandler{outboxReplayService}
+}
+
+func (replay_handler *outboxReplayHandler) BackfillWalletSnapshots(c *gin.Context) {
+	ctx := c.Request.Context()
+	requestID, _ := c.Get(data.REQUEST_ID_LOCAL_KEY)
+
+	var req dto.OutboxBackfillRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		log.Warn(data.LogOutboxAdminBadRequest, map[string]any{
+			"service":    data.OutboxAdminService,
+			"request_id": requestID,
+			"error":      err.Error(),
+		})
+		c.JSON(http.StatusBadRequest, gin.H{
+			"statusCode": 400,
+			"status":     false,
+			"message":    "invalid request",
+		})
+		return
+	}
+
+	enqueued, err := replay_handler.outboxReplayService.BackfillWalletSnapshots(ctx, req)
+	if err != nil {
+		log.Error(data.LogOutboxBackfillFailed, map[string]any{
+			"service":    data.OutboxAdminService,
+			"request_id": requestID,
+			"user_id":    req.UserID,
+			"enqueued":   enqueued,
+			"error":      err.Error(),
+		})
+		statusCode, message := mapServiceError(err)
+		c.JSON(statusCode, gin.H{
+			"statusCode": statusCode,
+			"status":     false,
+			"message":    message,
+		})
+		return
+	}
+
+	log.Info(data.LogOutboxBackfillCompleted, map[string]any{
+		"service":    data.OutboxAdminService,
+		"request_id": requestID,
+		"user_id":    req.UserID,
+		"enqueued":   enqueued,
+	})
+
+	c.JSON(http.StatusOK, gin.H{
+		"statusCode": 200,
+		"status":     true,
+		"message":    "Backfill wallet snapshot events",
+		"data":       dto.OutboxEnqueueResponse{Enqueued: enqueued},
+	})
+}
+
+func (replay_handler *outboxReplayHandler) ReplayPublished(c *gin.Context) {
+	ctx := c.Request.Context()
+	requestID, _ := c.Get(data.REQUEST_ID_LOCAL_KEY)
+
+	var req dto.OutboxReplayRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		log.Warn(data.LogOutboxAdminBadRequest, map[string]any{
+			"service":    data.OutboxAdminService,
+			"request_id": requestID,
+			"error":      err.Error(),
+		})
+		c.JSON(http.StatusBadRequest, gin.H{
+			"statusCode": 400,
+			"status":     false,
+			"message":    "invalid request",
+		})
+		return
+	}
+
+	enqueued, err := replay_handler.outboxReplayService.ReplayPublished(ctx, req)
+	if err != nil {
+		log.Error(data.LogOutboxReplayFailed, map[string]any{
+			"service":    data.OutboxAdminService,
+			"request_id": requestID,
+			"from_id":    req.FromID,
+			"to_id":      req.ToID,
+			"error":      err.Error(),
+		})
+		statusCode, message := mapServiceError(err)
+		c.JSON(statusCode, gin.H{
+			"statusCode": statusCode,
+			"status":     false,
+			"message":    message,
+		})
+		return
+	}
+
+	log.Info(data.LogOutboxReplayCompleted, map[string]any{
+		"service":    data.OutboxAdminService,
+		"request_id": requestID,
+		"from_id":    req.FromID,
+		"to_id":      req.ToID,
+		"enqueued":   enqueued,
+	})
+
+	c.JSON(http.StatusOK, gin.H{
+		"statusCode": 200,
+		"status":     true,
+		"message":    "Replay published outbox messages",
+		"data":       dto.OutboxEnqueueResponse{Enqueued: enqueued},
+	})
+}
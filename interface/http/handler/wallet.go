@@ -1,24 +1,29 @@
 package handler
 
 import (
+	"context"
 	"net/http"
-	"strings"
+	"strconv"
+	"time"
 
 	"refina-wallet/config/log"
 	"refina-wallet/interface/grpc/interceptor"
 	"refina-wallet/internal/service"
+	"refina-wallet/internal/types/domainerr"
 	"refina-wallet/internal/types/dto"
+	"refina-wallet/internal/utils"
 	"refina-wallet/internal/utils/data"
 
 	"github.com/gin-gonic/gin"
 )
 
 type walletHandler struct {
-	walletService service.WalletsService
+	walletService      service.WalletsService
+	idempotencyService service.IdempotencyService
 }
 
-func NewWalletHandler(walletService service.WalletsService) *walletHandler {
-	return &walletHandler{walletService}
+func NewWalletHandler(walletService service.WalletsService, idempotencyService service.IdempotencyService) *walletHandler {
+	return &walletHandler{walletService, idempotencyService}
 }
 
 func (wallet_handler *walletHandler) GetAllWallets(c *gin.Context) {
@@ -55,7 +60,7 @@ func (wallet_handler *walletHandler) GetWalletByID(c *gin.Context) {
 
 	id := c.Param("id")
 
-	wallet, err := wallet_handler.walletService.GetWalletByID(ctx, id)
+	wallet, err := wallet_handler.walletService.GetWalletByID(ctx, interceptor.WalletAccessFromContext(ctx, service.PermissionReadAnyWallet), id)
 	if err != nil {
 		log.Error(data.LogGetWalletByIDFailed, map[string]any{
 			"service":    data.WalletService,
@@ -72,6 +77,7 @@ func (wallet_handler *walletHandler) GetWalletByID(c *gin.Context) {
 		return
 	}
 
+	c.Header("ETag", utils.FormatETag(wallet.Version))
 	c.JSON(http.StatusOK, gin.H{
 		"statusCode": 200,
 		"status":     true,
@@ -114,7 +120,7 @@ func (wallet_handler *walletHandler) GetWalletsByUserIDGroupByType(c *gin.Contex
 	userID := interceptor.UserIDFromContext(ctx)
 	requestID, _ := c.Get(data.REQUEST_ID_LOCAL_KEY)
 
-	userWallets, err := wallet_handler.walletService.GetWalletsByUserIDGroupByType(ctx, userID)
+	userWallets, err := wallet_handler.walletService.GetWalletsByUserIDGroupByType(ctx, userID, c.Query(data.BASE_CURRENCY_QUERY))
 	if err != nil {
 		log.Error(data.LogGetWalletsByUserIDGroupTypeFailed, map[string]any{
 			"service":    data.WalletService,
@@ -138,6 +144,35 @@ func (wallet_handler *walletHandler) GetWalletsByUserIDGroupByType(c *gin.Contex
 	})
 }
 
+func (wallet_handler *walletHandler) GetWalletSummary(c *gin.Context) {
+	ctx := c.Request.Context()
+	userID := interceptor.UserIDFromContext(ctx)
+	requestID, _ := c.Get(data.REQUEST_ID_LOCAL_KEY)
+
+	summary, err := wallet_handler.walletService.GetWalletSummary(ctx, userID, c.Query(data.BASE_CURRENCY_QUERY))
+	if err != nil {
+		log.Error(data.LogGetWalletSummaryFailed, map[string]any{
+			"service":    data.WalletService,
+			"request_id": requestID,
+			"error":      err.Error(),
+		})
+		statusCode, message := mapServiceError(err)
+		c.JSON(statusCode, gin.H{
+			"statusCode": statusCode,
+			"status":     false,
+			"message":    message,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"statusCode": 200,
+		"status":     true,
+		"message":    "Get user wallet summary",
+		"data":       summary,
+	})
+}
+
 func (wallet_handler *walletHandler) CreateWallet(c *gin.Context) {
 	ctx := c.Request.Context()
 	userID := interceptor.UserIDFromContext(ctx)
@@ -158,7 +193,14 @@ func (wallet_handler *walletHandler) CreateWallet(c *gin.Context) {
 		return
 	}
 
-	wallet, err := wallet_handler.walletService.CreateWallet(ctx, userID, walletRequest)
+	wallet, replayed, err := service.RunIdempotent(ctx, wallet_handler.idempotencyService, service.IdempotencyRequest{
+		Key:       c.GetHeader(data.IDEMPOTENCY_KEY_HEADER),
+		Operation: data.IDEMPOTENCY_OP_CREATE_WALLET,
+		UserID:    userID,
+		Body:      walletRequest,
+	}, func(ctx context.Context) (dto.WalletsResponse, error) {
+		return wallet_handler.walletService.CreateWallet(ctx, userID, walletRequest)
+	})
 	if err != nil {
 		log.Error(data.LogCreateWalletFailed, map[string]any{
 			"service":        data.WalletService,
@@ -183,6 +225,8 @@ func (wallet_handler *walletHandler) CreateWallet(c *gin.Context) {
 		"type":       wallet.WalletType,
 	})
 
+	setIdempotentReplayed(c, replayed)
+	c.Header("ETag", utils.FormatETag(wallet.Version))
 	c.JSON(http.StatusCreated, gin.H{
 		"statusCode": 201,
 		"status":     true,
@@ -213,7 +257,30 @@ func (wallet_handler *walletHandler) UpdateWallet(c *gin.Context) {
 		return
 	}
 
-	wallet, err := wallet_handler.walletService.UpdateWallet(ctx, id, walletRequest)
+	expectedVersion, err := utils.ParseETag(c.GetHeader("If-Match"))
+	if err != nil {
+		log.Warn(data.LogUpdateWalletBadRequest, map[string]any{
+			"service":    data.WalletService,
+			"request_id": requestID,
+			"wallet_id":  id,
+			"error":      err.Error(),
+		})
+		c.JSON(http.StatusBadRequest, gin.H{
+			"statusCode": 400,
+			"status":     false,
+			"message":    "invalid If-Match header",
+		})
+		return
+	}
+
+	wallet, replayed, err := service.RunIdempotent(ctx, wallet_handler.idempotencyService, service.IdempotencyRequest{
+		Key:       c.GetHeader(data.IDEMPOTENCY_KEY_HEADER),
+		Operation: data.IDEMPOTENCY_OP_UPDATE_WALLET,
+		UserID:    interceptor.UserIDFromContext(ctx),
+		Body:      map[string]any{"id": id, "wallet": walletRequest, "version": expectedVersion},
+	}, func(ctx context.Context) (dto.WalletsResponse, error) {
+		return wallet_handler.walletService.UpdateWallet(ctx, interceptor.WalletAccessFromContext(ctx, service.PermissionWriteAnyWallet), id, walletRequest, expectedVersion)
+	})
 	if err != nil {
 		log.Error(data.LogUpdateWalletFailed, map[string]any{
 			"service":    data.WalletService,
@@ -230,6 +297,8 @@ func (wallet_handler *walletHandler) UpdateWallet(c *gin.Context) {
 		return
 	}
 
+	setIdempotentReplayed(c, replayed)
+	c.Header("ETag", utils.FormatETag(wallet.Version))
 	c.JSON(http.StatusOK, gin.H{
 		"statusCode": 200,
 		"status":     true,
@@ -244,7 +313,30 @@ func (wallet_handler *walletHandler) DeleteWallet(c *gin.Context) {
 
 	id := c.Param("id")
 
-	wallet, err := wallet_handler.walletService.DeleteWallet(ctx, id)
+	expectedVersion, err := utils.ParseETag(c.GetHeader("If-Match"))
+	if err != nil {
+		log.Warn(data.LogDeleteWalletBadRequest, map[string]any{
+			"service":    data.WalletService,
+			"request_id": requestID,
+			"wallet_id":  id,
+			"error":      err.Error(),
+		})
+		c.JSON(http.StatusBadRequest, gin.H{
+			"statusCode": 400,
+			"status":     false,
+			"message":    "invalid If-Match header",
+		})
+		return
+	}
+
+	wallet, replayed, err := service.RunIdempotent(ctx, wallet_handler.idempotencyService, service.IdempotencyRequest{
+		Key:       c.GetHeader(data.IDEMPOTENCY_KEY_HEADER),
+		Operation: data.IDEMPOTENCY_OP_DELETE_WALLET,
+		UserID:    interceptor.UserIDFromContext(ctx),
+		Body:      map[string]any{"id": id, "version": expectedVersion},
+	}, func(ctx context.Context) (dto.WalletsResponse, error) {
+		return wallet_handler.walletService.DeleteWallet(ctx, interceptor.WalletAccessFromContext(ctx, service.PermissionWriteAnyWallet), id, expectedVersion)
+	})
 	if err != nil {
 		log.Error(data.LogDeleteWalletFailed, map[string]any{
 			"service":    data.WalletService,
@@ -261,6 +353,7 @@ func (wallet_handler *walletHandler) DeleteWallet(c *gin.Context) {
 		return
 	}
 
+	setIdempotentReplayed(c, replayed)
 	c.JSON(http.StatusOK, gin.H{
 		"statusCode": 200,
 		"status":     true,
@@ -269,18 +362,164 @@ func (wallet_handler *walletHandler) DeleteWallet(c *gin.Context) {
 	})
 }
 
+func (wallet_handler *walletHandler) TransferBetweenWallets(c *gin.Context) {
+	ctx := c.Request.Context()
+	userID := interceptor.UserIDFromContext(ctx)
+	requestID, _ := c.Get(data.REQUEST_ID_LOCAL_KEY)
+
+	var transferRequest dto.WalletTransferRequest
+	if err := c.ShouldBindJSON(&transferRequest); err != nil {
+		log.Warn(data.LogTransferWalletBadRequest, map[string]any{
+			"service":    data.WalletService,
+			"request_id": requestID,
+			"error":      err.Error(),
+		})
+		c.JSON(http.StatusBadRequest, gin.H{
+			"statusCode": 400,
+			"status":     false,
+			"message":    "invalid request body",
+		})
+		return
+	}
+
+	transfer, replayed, err := service.RunIdempotent(ctx, wallet_handler.idempotencyService, service.IdempotencyRequest{
+		Key:       c.GetHeader(data.IDEMPOTENCY_KEY_HEADER),
+		Operation: data.IDEMPOTENCY_OP_TRANSFER,
+		UserID:    userID,
+		Body:      transferRequest,
+	}, func(ctx context.Context) (dto.WalletTransferResponse, error) {
+		return wallet_handler.walletService.TransferBetweenWallets(ctx, interceptor.WalletAccessFromContext(ctx, service.PermissionWriteAnyWallet), transferRequest)
+	})
+	if err != nil {
+		log.Error(data.LogTransferWalletFailed, map[string]any{
+			"service":        data.WalletService,
+			"request_id":     requestID,
+			"from_wallet_id": transferRequest.FromWalletID,
+			"to_wallet_id":   transferRequest.ToWalletID,
+			"error":          err.Error(),
+		})
+		statusCode, message := mapServiceError(err)
+		c.JSON(statusCode, gin.H{
+			"statusCode": statusCode,
+			"status":     false,
+			"message":    message,
+		})
+		return
+	}
+
+	log.Info(data.LogWalletTransferred, map[string]any{
+		"service":                 data.WalletService,
+		"request_id":              requestID,
+		"from_wallet_id":          transfer.FromWallet.ID,
+		"to_wallet_id":            transfer.ToWallet.ID,
+		"cash_out_transaction_id": transfer.CashOutTransactionID,
+	})
+
+	setIdempotentReplayed(c, replayed)
+	c.JSON(http.StatusCreated, gin.H{
+		"statusCode": 201,
+		"status":     true,
+		"message":    "Transfer between wallets",
+		"data":       transfer,
+	})
+}
+
+func (wallet_handler *walletHandler) GetWalletBalanceEntries(c *gin.Context) {
+	ctx := c.Request.Context()
+	requestID, _ := c.Get(data.REQUEST_ID_LOCAL_KEY)
+
+	id := c.Param("id")
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+
+	entries, err := wallet_handler.walletService.GetWalletBalanceEntries(ctx, interceptor.WalletAccessFromContext(ctx, service.PermissionReadAnyWallet), id, page)
+	if err != nil {
+		log.Error(data.LogGetWalletBalanceEntriesFailed, map[string]any{
+			"service":    data.WalletService,
+			"request_id": requestID,
+			"wallet_id":  id,
+			"error":      err.Error(),
+		})
+		statusCode, message := mapServiceError(err)
+		c.JSON(statusCode, gin.H{
+			"statusCode": statusCode,
+			"status":     false,
+			"message":    message,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"statusCode": 200,
+		"status":     true,
+		"message":    "Get wallet balance entries",
+		"data":       entries,
+	})
+}
+
+func (wallet_handler *walletHandler) GetWalletBalance(c *gin.Context) {
+	ctx := c.Request.Context()
+	requestID, _ := c.Get(data.REQUEST_ID_LOCAL_KEY)
+
+	id := c.Param("id")
+
+	asOf := time.Now()
+	if raw := c.Query("as_of"); raw != "" {
+		parsed, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			log.Warn(data.LogGetWalletBalanceBadRequest, map[string]any{
+				"service":    data.WalletService,
+				"request_id": requestID,
+				"wallet_id":  id,
+				"error":      err.Error(),
+			})
+			c.JSON(http.StatusBadRequest, gin.H{
+				"statusCode": 400,
+				"status":     false,
+				"message":    "as_of must be an RFC3339 timestamp",
+			})
+			return
+		}
+		asOf = parsed
+	}
+
+	balance, err := wallet_handler.walletService.GetWalletBalanceAsOf(ctx, interceptor.WalletAccessFromContext(ctx, service.PermissionReadAnyWallet), id, asOf)
+	if err != nil {
+		log.Error(data.LogGetWalletBalanceFailed, map[string]any{
+			"service":    data.WalletService,
+			"request_id": requestID,
+			"wallet_id":  id,
+			"error":      err.Error(),
+		})
+		statusCode, message := mapServiceError(err)
+		c.JSON(statusCode, gin.H{
+			"statusCode": statusCode,
+			"status":     false,
+			"message":    message,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"statusCode": 200,
+		"status":     true,
+		"message":    "Get wallet balance",
+		"data":       balance,
+	})
+}
+
+// setIdempotentReplayed menandai response yang diambil dari request sebelumnya dengan idempotency key yang sama
+func setIdempotentReplayed(c *gin.Context, replayed bool) {
+	if replayed {
+		c.Header(data.IDEMPOTENCY_REPLAYED_HEADER, "true")
+	}
+}
+
 // mapServiceError menerjemahkan error dari service ke HTTP status + pesan aman untuk client
+// berdasarkan domainerr.Kind; error yang tidak terklasifikasi selalu menjadi 500.
 func mapServiceError(err error) (int, string) {
-	msg := err.Error()
-	switch {
-	case strings.Contains(msg, "not found"):
-		return http.StatusNotFound, "resource not found"
-	case strings.Contains(msg, "invalid user id"),
-		strings.Contains(msg, "invalid wallet type id"):
-		return http.StatusBadRequest, "invalid request"
-	case strings.Contains(msg, "balance must be zero"):
-		return http.StatusUnprocessableEntity, "wallet balance must be zero before deletion"
-	default:
+	domainErr, ok := domainerr.As(err)
+	if !ok || domainErr.Kind == domainerr.Internal {
 		return http.StatusInternalServerError, "internal server error"
 	}
+	return domainErr.Kind.HTTPStatus(), domainErr.Message
 }
@@ -0,0 +1,74 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+
+	"refina-wallet/config/log"
+	"refina-wallet/interface/grpc/interceptor"
+	"refina-wallet/internal/service"
+	"refina-wallet/internal/types/dto"
+	"refina-wallet/internal/utils/data"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AuthMiddleware menolak request tanpa bearer token yang valid. Identitas dari token disimpan
+// di context request (interceptor.UserIDFromContext dkk.) dan di Gin locals untuk logger.
+func AuthMiddleware(verifier service.TokenVerifier) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requestID, _ := c.Get(data.REQUEST_ID_LOCAL_KEY)
+
+		token, ok := bearerToken(c.GetHeader(data.AUTHORIZATION_HEADER))
+		if !ok {
+			log.Warn(data.LogAuthTokenMissing, map[string]any{
+				"service":    data.AuthService,
+				"request_id": requestID,
+				"path":       c.FullPath(),
+			})
+			abortUnauthorized(c, "missing bearer token")
+			return
+		}
+
+		claims, err := verifier.Verify(c.Request.Context(), token)
+		if err != nil {
+			log.Warn(data.LogAuthTokenRejected, map[string]any{
+				"service":    data.AuthService,
+				"request_id": requestID,
+				"path":       c.FullPath(),
+				"error":      err.Error(),
+			})
+			abortUnauthorized(c, "invalid or expired token")
+			return
+		}
+
+		ctx := interceptor.WithUser(c.Request.Context(), claims.Subject, claims.Email, claims.Provider, "")
+		ctx = interceptor.WithRoles(ctx, claims.Roles)
+		c.Request = c.Request.WithContext(ctx)
+		c.Set(data.USER_DATA_LOCAL_KEY, dto.UserData{
+			ID:       claims.Subject,
+			Username: claims.Username,
+			Email:    claims.Email,
+		})
+
+		c.Next()
+	}
+}
+
+func bearerToken(header string) (string, bool) {
+	scheme, token, ok := strings.Cut(strings.TrimSpace(header), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+	token = strings.TrimSpace(token)
+	return token, token != ""
+}
+
+func abortUnauthorized(c *gin.Context, message string) {
+	c.Header("WWW-Authenticate", `Bearer realm="refina-wallet"`)
+	c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+		"statusCode": http.StatusUnauthorized,
+		"status":     false,
+		"message":    message,
+	})
+}
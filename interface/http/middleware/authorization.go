@@ -0,0 +1,46 @@
+package middleware
+
+import (
+	"net/http"
+
+	"refina-wallet/config/log"
+	"refina-wallet/interface/grpc/interceptor"
+	"refina-wallet/internal/service"
+	"refina-wallet/internal/utils/data"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AuthorizationMiddleware mencocokkan role caller dengan permission yang dideklarasikan policy
+// untuk route ini. Harus dipasang setelah AuthMiddleware yang mengisi identitas dan role.
+func AuthorizationMiddleware(policy *service.AccessPolicy) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+		roles := service.ParseRoles(interceptor.RolesFromContext(ctx))
+		operation := service.HTTPOperation(c.Request.Method, c.FullPath())
+
+		permission, err := policy.Authorize(roles, operation)
+		if err != nil {
+			requestID, _ := c.Get(data.REQUEST_ID_LOCAL_KEY)
+			log.Warn(data.LogAccessDenied, map[string]any{
+				"service":             data.AuthService,
+				"transport":           "http",
+				"request_id":          requestID,
+				"user_id":             interceptor.UserIDFromContext(ctx),
+				"roles":               roles,
+				"operation":           operation,
+				"required_permission": permission,
+				"client_ip":           c.ClientIP(),
+				"error":               err.Error(),
+			})
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+				"statusCode": http.StatusForbidden,
+				"status":     false,
+				"message":    "permission denied",
+			})
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -49,7 +49,7 @@ func httpRequest(c *gin.Context, latency time.Duration, statusCode int) {
 
 	// Baca user_id jika sudah login (disimpan oleh AuthMiddleware)
 	userID := ""
-	if userData, exists := c.Get("user_data"); exists {
+	if userData, exists := c.Get(data.USER_DATA_LOCAL_KEY); exists {
 		if u, ok := userData.(dto.UserData); ok {
 			userID = u.ID
 		}
@@ -1,6 +1,7 @@
 package middleware
 
 import (
+	"refina-wallet/internal/utils"
 	"refina-wallet/internal/utils/data"
 
 	"github.com/gin-gonic/gin"
@@ -16,6 +17,7 @@ func RequestIDMiddleware() gin.HandlerFunc {
 
 		ctx.Set(data.REQUEST_ID_LOCAL_KEY, requestID)
 		ctx.Header(data.REQUEST_ID_HEADER, requestID)
+		ctx.Request = ctx.Request.WithContext(utils.WithRequestID(ctx.Request.Context(), requestID))
 
 		ctx.Next()
 	}
@@ -5,14 +5,18 @@ import (
 
 	"refina-wallet/config/db"
 	"refina-wallet/config/env"
+	"refina-wallet/config/log"
+	"refina-wallet/interface/http/handler"
 	"refina-wallet/interface/http/middleware"
 	"refina-wallet/interface/http/routes"
 	"refina-wallet/interface/queue"
+	"refina-wallet/internal/service"
+	"refina-wallet/internal/utils/data"
 
 	"github.com/gin-gonic/gin"
 )
 
-func SetupHTTPServer(dbInstance db.DatabaseClient, queueInstance queue.RabbitMQClient) *http.Server {
+func SetupHTTPServer(dbInstance db.DatabaseClient, queueInstance queue.RabbitMQClient, exchangeRates service.ExchangeRateProvider, tokenVerifier service.TokenVerifier, accessPolicy *service.AccessPolicy) *http.Server {
 	router := gin.New()
 
 	router.Use(
@@ -27,8 +31,33 @@ func SetupHTTPServer(dbInstance db.DatabaseClient, queueInstance queue.RabbitMQC
 		})
 	})
 
-	routes.WalletRoutes(router, dbInstance.GetDB(), queueInstance)
-	routes.WalletTypesRoutes(router, dbInstance.GetDB())
+	router.GET("health", handler.NewHealthHandler(dbInstance.GetDB(), queueInstance).Health)
+
+	publicRoutes := map[string]bool{}
+	for _, route := range router.Routes() {
+		publicRoutes[service.HTTPOperation(route.Method, route.Path)] = true
+	}
+
+	// Semua route di bawah ini wajib membawa bearer token dan permission sesuai access policy
+	authenticated := router.Group("",
+		middleware.AuthMiddleware(tokenVerifier),
+		middleware.AuthorizationMiddleware(accessPolicy),
+	)
+
+	routes.WalletRoutes(authenticated, dbInstance.GetDB(), queueInstance, exchangeRates)
+	routes.WalletTypesRoutes(authenticated, dbInstance.GetDB())
+	routes.OutboxAdminRoutes(authenticated, dbInstance.GetDB())
+
+	// Route tanpa aturan di policy akan selalu ditolak, jadi lebih baik gagal saat startup
+	var operations []string
+	for _, route := range router.Routes() {
+		if operation := service.HTTPOperation(route.Method, route.Path); !publicRoutes[operation] {
+			operations = append(operations, operation)
+		}
+	}
+	if undeclared := accessPolicy.Undeclared(operations); len(undeclared) > 0 {
+		log.Fatal(data.LogAccessPolicyIncomplete, map[string]any{"service": data.HTTPServerService, "operations": undeclared})
+	}
 
 	return &http.Server{
 		Addr:    ":" + env.Cfg.Server.HTTPPort,
@@ -0,0 +1,32 @@
+package routes
+
+import (
+	"refina-wallet/interface/http/handler"
+	"refina-wallet/internal/repository"
+	"refina-wallet/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+func OutboxAdminRoutes(version *gin.RouterGroup, db *gorm.DB) {
+	txManager := repository.NewTxManager(db)
+	walletRepo := repository.NewWalletRepository(db)
+	outboxRepo := repository.NewOutboxRepository(db)
+	outboxAdminServ := service.NewOutboxAdminService(outboxRepo)
+	outboxAdminHandler := handler.NewOutboxAdminHandler(outboxAdminServ)
+	outboxReplayServ := service.NewOutboxReplayService(txManager, walletRepo, outboxRepo)
+	outboxReplayHandler := handler.NewOutboxReplayHandler(outboxReplayServ)
+
+	outbox := version.Group("/admin/outbox")
+
+	outbox.POST("backfill", outboxReplayHandler.BackfillWalletSnapshots)
+	outbox.POST("replay", outboxReplayHandler.ReplayPublished)
+
+	deadLetters := outbox.Group("/dead-letters")
+
+	deadLetters.GET("", outboxAdminHandler.GetDeadLetters)
+	deadLetters.GET(":id", outboxAdminHandler.GetDeadLetterByID)
+	deadLetters.POST(":id/requeue", outboxAdminHandler.RequeueDeadLetter)
+	deadLetters.DELETE(":id", outboxAdminHandler.DiscardDeadLetter)
+}
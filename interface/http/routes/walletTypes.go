@@ -9,7 +9,7 @@ import (
 	"gorm.io/gorm"
 )
 
-func WalletTypesRoutes(version *gin.Engine, db *gorm.DB) {
+func WalletTypesRoutes(version *gin.RouterGroup, db *gorm.DB) {
 	txManager := repository.NewTxManager(db)
 	WalletTypesRepo := repository.NewWalletTypesRepository(db)
 	WalletTypesServ := service.NewWalletTypesService(txManager, WalletTypesRepo)
@@ -11,23 +11,29 @@ import (
 	"gorm.io/gorm"
 )
 
-func WalletRoutes(version *gin.Engine, db *gorm.DB, queueInstance queue.RabbitMQClient) {
+func WalletRoutes(version *gin.RouterGroup, db *gorm.DB, queueInstance queue.RabbitMQClient, exchangeRates service.ExchangeRateProvider) {
 	txManager := repository.NewTxManager(db)
 	walletRepo := repository.NewWalletRepository(db)
 	walletTypeRepo := repository.NewWalletTypesRepository(db)
 	outboxRepo := repository.NewOutboxRepository(db)
+	sagaRepo := repository.NewWalletSagaRepository(db)
 	transactionRepo := client.NewTransactionClient(client.GetManager().GetTransactionClient())
 
-	walletServ := service.NewWalletService(txManager, walletRepo, walletTypeRepo, outboxRepo, transactionRepo, queueInstance)
-	walletHandler := handler.NewWalletHandler(walletServ)
+	walletServ := service.NewWalletService(txManager, walletRepo, walletTypeRepo, outboxRepo, sagaRepo, transactionRepo, exchangeRates, queueInstance)
+	idempotencyServ := service.NewIdempotencyService(repository.NewIdempotencyRepository(db))
+	walletHandler := handler.NewWalletHandler(walletServ, idempotencyServ)
 
 	wallets := version.Group("/wallets")
 
 	wallets.GET("", walletHandler.GetAllWallets)
 	wallets.GET(":id", walletHandler.GetWalletByID)
+	wallets.GET(":id/balance", walletHandler.GetWalletBalance)
+	wallets.GET(":id/balance-entries", walletHandler.GetWalletBalanceEntries)
 	wallets.GET("user", walletHandler.GetWalletsByUserID)
 	wallets.GET("user-by-type", walletHandler.GetWalletsByUserIDGroupByType)
+	wallets.GET("user-summary", walletHandler.GetWalletSummary)
 	wallets.POST("", walletHandler.CreateWallet)
+	wallets.POST("transfers", walletHandler.TransferBetweenWallets)
 	wallets.PUT(":id", walletHandler.UpdateWallet)
 	wallets.DELETE(":id", walletHandler.DeleteWallet)
 }
@@ -0,0 +1,101 @@
+package queue
+
+import (
+	"context"
+
+	"github.com/rabbitmq/amqp091-go"
+)
+
+// pooledChannel is a confirm-mode channel together with its basic.return listener.
+type pooledChannel struct {
+	channel *amqp091.Channel
+	returns chan amqp091.Return
+}
+
+// drainReturns discards returns left over from an earlier publish on this channel.
+func (pc *pooledChannel) drainReturns() {
+	for {
+		select {
+		case _, ok := <-pc.returns:
+			if !ok {
+				return
+			}
+		default:
+			return
+		}
+	}
+}
+
+// channelPool hands out at most size channels at a time and keeps idle ones for reuse,
+// so publishers no longer pay a channel open/close round-trip per message.
+type channelPool struct {
+	idle  chan *pooledChannel
+	slots chan struct{}
+	open  func() (*pooledChannel, error)
+}
+
+func newChannelPool(size int, open func() (*pooledChannel, error)) *channelPool {
+	return &channelPool{
+		idle:  make(chan *pooledChannel, size),
+		slots: make(chan struct{}, size),
+		open:  open,
+	}
+}
+
+// acquire returns an idle channel, opens a new one if the pool is below capacity,
+// or blocks until a channel is released or ctx is done.
+func (p *channelPool) acquire(ctx context.Context) (*pooledChannel, error) {
+	for {
+		select {
+		case pc := <-p.idle:
+			if pc.channel.IsClosed() {
+				<-p.slots
+				continue
+			}
+			return pc, nil
+		default:
+		}
+
+		select {
+		case pc := <-p.idle:
+			if pc.channel.IsClosed() {
+				<-p.slots
+				continue
+			}
+			return pc, nil
+		case p.slots <- struct{}{}:
+			pc, err := p.open()
+			if err != nil {
+				<-p.slots
+				return nil, err
+			}
+			return pc, nil
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// release puts a channel back for reuse, or closes it when it is broken or in doubt.
+func (p *channelPool) release(pc *pooledChannel, healthy bool) {
+	if !healthy || pc.channel.IsClosed() {
+		pc.channel.Close()
+		<-p.slots
+		return
+	}
+
+	p.idle <- pc
+}
+
+// drain closes every idle channel, e.g. after the underlying connection was lost.
+func (p *channelPool) drain() {
+	for {
+		select {
+		case pc := <-p.idle:
+			pc.channel.Close()
+			<-p.slots
+		default:
+			return
+		}
+	}
+}
@@ -0,0 +1,252 @@
+package queue
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"refina-wallet/config/log"
+	"refina-wallet/internal/utils/data"
+
+	"github.com/rabbitmq/amqp091-go"
+)
+
+// ErrRejectMessage marks a handler error as permanent: the delivery is dead-lettered instead of requeued.
+var ErrRejectMessage = errors.New("message rejected")
+
+// Message is the transport-neutral view of a delivery handed to consumer handlers.
+type Message struct {
+	// ID is the broker message id, the CloudEvents id header, or a hash of the body, in that order,
+	// so redeliveries of the same message always carry the same ID.
+	ID            string
+	Type          string
+	RoutingKey    string
+	CorrelationID string
+	Headers       map[string]any
+	Body          []byte
+	Redelivered   bool
+}
+
+// HandlerFunc processes one message. Returning nil acks it; an error wrapping ErrRejectMessage
+// dead-letters it; any other error requeues it for another attempt.
+type HandlerFunc func(ctx context.Context, msg Message) error
+
+// Subscription describes the queue a consumer owns and what it is bound to.
+type Subscription struct {
+	Queue       string
+	Exchange    string
+	RoutingKeys []string
+	// DeadLetterQueue receives rejected messages; empty disables dead-lettering.
+	DeadLetterQueue string
+	Prefetch        int
+}
+
+func (s Subscription) exchange() string {
+	if s.Exchange == "" {
+		return data.OUTBOX_PUBLISH_EXCHANGE
+	}
+	return s.Exchange
+}
+
+// Consumer runs one subscription on its own channel and keeps it alive across reconnects.
+type Consumer struct {
+	client     RabbitMQClient
+	sub        Subscription
+	handler    HandlerFunc
+	retryDelay time.Duration
+}
+
+func NewConsumer(client RabbitMQClient, sub Subscription, handler HandlerFunc) *Consumer {
+	if sub.Prefetch <= 0 {
+		sub.Prefetch = data.CONSUMER_PREFETCH
+	}
+
+	return &Consumer{
+		client:     client,
+		sub:        sub,
+		handler:    handler,
+		retryDelay: data.CONSUMER_RETRY_DELAY,
+	}
+}
+
+// Run consumes until ctx is cancelled. When the channel or connection drops it waits for the
+// client to reconnect and subscribes again with backoff.
+func (c *Consumer) Run(ctx context.Context) {
+	backoff := data.RABBITMQ_RECONNECT_MIN_BACKOFF
+
+	for {
+		started, err := c.consume(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		if started {
+			backoff = data.RABBITMQ_RECONNECT_MIN_BACKOFF
+		}
+
+		fields := map[string]any{
+			"service": data.ConsumerService,
+			"queue":   c.sub.Queue,
+			"backoff": backoff.String(),
+		}
+		if err != nil {
+			fields["error"] = err.Error()
+		}
+		log.Warn(data.LogConsumerStopped, fields)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, data.RABBITMQ_RECONNECT_MAX_BACKOFF)
+	}
+}
+
+// consume declares the topology and handles deliveries until the channel closes.
+// started reports whether deliveries were flowing, which resets the caller's backoff.
+func (c *Consumer) consume(ctx context.Context) (started bool, err error) {
+	channel, err := c.client.GetChannel()
+	if err != nil {
+		return false, err
+	}
+	defer channel.Close()
+
+	if err := c.declare(channel); err != nil {
+		return false, err
+	}
+
+	if err := channel.Qos(c.sub.Prefetch, 0, false); err != nil {
+		return false, fmt.Errorf("failed to set prefetch: %w", err)
+	}
+
+	deliveries, err := channel.ConsumeWithContext(ctx, c.sub.Queue, "", false, false, false, false, nil)
+	if err != nil {
+		return false, fmt.Errorf("failed to start consuming: %w", err)
+	}
+
+	log.Info(data.LogConsumerStarted, map[string]any{
+		"service":      data.ConsumerService,
+		"queue":        c.sub.Queue,
+		"routing_keys": c.sub.RoutingKeys,
+	})
+
+	for {
+		select {
+		case <-ctx.Done():
+			return true, nil
+		case delivery, ok := <-deliveries:
+			if !ok {
+				return true, errors.New("delivery channel closed")
+			}
+			c.handle(ctx, delivery)
+		}
+	}
+}
+
+func (c *Consumer) declare(channel *amqp091.Channel) error {
+	var args amqp091.Table
+	if c.sub.DeadLetterQueue != "" {
+		if _, err := channel.QueueDeclare(c.sub.DeadLetterQueue, true, false, false, false, nil); err != nil {
+			return fmt.Errorf("failed to declare dead letter queue: %w", err)
+		}
+		if err := channel.QueueBind(c.sub.DeadLetterQueue, c.sub.DeadLetterQueue, c.sub.exchange(), false, nil); err != nil {
+			return fmt.Errorf("failed to bind dead letter queue: %w", err)
+		}
+		args = amqp091.Table{
+			"x-dead-letter-exchange":    c.sub.exchange(),
+			"x-dead-letter-routing-key": c.sub.DeadLetterQueue,
+		}
+	}
+
+	if _, err := channel.QueueDeclare(c.sub.Queue, true, false, false, false, args); err != nil {
+		return fmt.Errorf("failed to declare queue: %w", err)
+	}
+
+	for _, key := range c.sub.RoutingKeys {
+		if err := channel.QueueBind(c.sub.Queue, key, c.sub.exchange(), false, nil); err != nil {
+			return fmt.Errorf("failed to bind queue to %s: %w", key, err)
+		}
+	}
+
+	return nil
+}
+
+// handle runs the handler and settles the delivery. Acks are only sent after the handler returned,
+// i.e. after its database transaction committed.
+func (c *Consumer) handle(ctx context.Context, delivery amqp091.Delivery) {
+	msg := newMessage(delivery)
+
+	err := c.handler(ctx, msg)
+	if err == nil {
+		if ackErr := delivery.Ack(false); ackErr != nil {
+			log.Warn(data.LogConsumerAckFailed, map[string]any{
+				"service":    data.ConsumerService,
+				"queue":      c.sub.Queue,
+				"message_id": msg.ID,
+				"error":      ackErr.Error(),
+			})
+		}
+		return
+	}
+
+	requeue := !errors.Is(err, ErrRejectMessage)
+	log.Error(data.LogConsumerHandleFailed, map[string]any{
+		"service":    data.ConsumerService,
+		"queue":      c.sub.Queue,
+		"message_id": msg.ID,
+		"type":       msg.Type,
+		"requeue":    requeue,
+		"error":      err.Error(),
+	})
+
+	if requeue {
+		// Hindari hot loop ketika error bersifat sementara (mis. database sedang down)
+		select {
+		case <-ctx.Done():
+		case <-time.After(c.retryDelay):
+		}
+	}
+
+	if nackErr := delivery.Nack(false, requeue); nackErr != nil {
+		log.Warn(data.LogConsumerAckFailed, map[string]any{
+			"service":    data.ConsumerService,
+			"queue":      c.sub.Queue,
+			"message_id": msg.ID,
+			"error":      nackErr.Error(),
+		})
+	}
+}
+
+func newMessage(delivery amqp091.Delivery) Message {
+	msg := Message{
+		ID:            delivery.MessageId,
+		Type:          delivery.Type,
+		RoutingKey:    delivery.RoutingKey,
+		CorrelationID: delivery.CorrelationId,
+		Headers:       delivery.Headers,
+		Body:          delivery.Body,
+		Redelivered:   delivery.Redelivered,
+	}
+
+	if msg.ID == "" {
+		if id, ok := delivery.Headers[data.CLOUDEVENTS_HEADER_PREFIX+"id"].(string); ok {
+			msg.ID = id
+		}
+	}
+	if msg.ID == "" {
+		sum := sha256.Sum256(delivery.Body)
+		msg.ID = hex.EncodeToString(sum[:])
+	}
+
+	if eventType, ok := delivery.Headers[data.CLOUDEVENTS_HEADER_PREFIX+"type"].(string); ok && eventType != "" {
+		msg.Type = eventType
+	}
+	if msg.Type == "" {
+		msg.Type = delivery.RoutingKey
+	}
+
+	return msg
+}
@@ -0,0 +1,58 @@
+package queue
+
+import (
+	"strconv"
+	"time"
+
+	"refina-wallet/internal/utils/data"
+
+	"github.com/rabbitmq/amqp091-go"
+)
+
+// PublishOptions describes how a message is published. The zero value publishes a persistent
+// JSON message to data.OUTBOX_PUBLISH_EXCHANGE without expiry.
+type PublishOptions struct {
+	Exchange      string
+	Headers       amqp091.Table
+	MessageID     string
+	CorrelationID string
+	ContentType   string
+	// Transient opts out of persistent delivery for messages that may be lost on a broker restart.
+	Transient bool
+	// Expiration is the per-message TTL; zero means the message never expires.
+	Expiration time.Duration
+}
+
+func (o PublishOptions) exchange() string {
+	if o.Exchange == "" {
+		return data.OUTBOX_PUBLISH_EXCHANGE
+	}
+	return o.Exchange
+}
+
+// NewPublishing builds the amqp091.Publishing for body according to opts.
+func NewPublishing(body []byte, opts PublishOptions) amqp091.Publishing {
+	message := amqp091.Publishing{
+		Headers:       opts.Headers,
+		ContentType:   opts.ContentType,
+		Body:          body,
+		DeliveryMode:  amqp091.Persistent,
+		Timestamp:     time.Now(),
+		MessageId:     opts.MessageID,
+		CorrelationId: opts.CorrelationID,
+	}
+
+	if message.ContentType == "" {
+		message.ContentType = "application/json"
+	}
+
+	if opts.Transient {
+		message.DeliveryMode = amqp091.Transient
+	}
+
+	if opts.Expiration > 0 {
+		message.Expiration = strconv.FormatInt(opts.Expiration.Milliseconds(), 10)
+	}
+
+	return message
+}
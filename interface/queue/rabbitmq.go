@@ -2,8 +2,11 @@ package queue
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"refina-wallet/config/env"
 	"refina-wallet/config/log"
@@ -15,12 +18,53 @@ import (
 type RabbitMQClient interface {
 	GetChannel() (*amqp091.Channel, error)
 	Close() error
-	Publish(ctx context.Context, routingKey string, body []byte) error
+	Publish(ctx context.Context, routingKey string, body []byte, opts PublishOptions) error
+	PublishWithConfirm(ctx context.Context, exchange, routingKey string, message amqp091.Publishing) error
+	State() ConnectionState
+}
+
+var (
+	// ErrPublishNacked is returned when the broker negatively acknowledges a confirmed publish.
+	ErrPublishNacked = errors.New("message nacked by broker")
+	// ErrPublishReturned is returned when a mandatory publish could not be routed to any queue.
+	ErrPublishReturned = errors.New("message returned by broker")
+	// ErrNotConnected is returned while the client is (re)connecting or after it was closed.
+	ErrNotConnected = errors.New("RabbitMQ connection is not available")
+)
+
+// ConnectionState reports the health of the supervised broker connection.
+type ConnectionState int32
+
+const (
+	StateConnecting ConnectionState = iota
+	StateConnected
+	StateDisconnected
+	StateClosed
+)
+
+func (s ConnectionState) String() string {
+	switch s {
+	case StateConnecting:
+		return "connecting"
+	case StateConnected:
+		return "connected"
+	case StateDisconnected:
+		return "disconnected"
+	case StateClosed:
+		return "closed"
+	default:
+		return "unknown"
+	}
 }
 
 type rabbitMQClient struct {
+	url        string
 	connection *amqp091.Connection
 	mu         sync.RWMutex
+	state      atomic.Int32
+	pool       *channelPool
+	done       chan struct{}
+	closeOnce  sync.Once
 }
 
 var (
@@ -37,15 +81,47 @@ func NewRabbitMQClient(cfg env.RabbitMQ) (RabbitMQClient, error) {
 		cfg.RMQVirtualHost,
 	)
 
-	conn, err := amqp091.Dial(connectionString)
+	client := &rabbitMQClient{
+		url:  connectionString,
+		done: make(chan struct{}),
+	}
+	client.pool = newChannelPool(data.RABBITMQ_CHANNEL_POOL_SIZE, client.openChannel)
+	client.state.Store(int32(StateConnecting))
+
+	if err := client.connect(); err != nil {
+		return nil, err
+	}
+
+	go client.supervise()
+
+	return client, nil
+}
+
+func GetInstance(cfg env.RabbitMQ) RabbitMQClient {
+	once.Do(func() {
+		client, err := NewRabbitMQClient(cfg)
+		if err != nil {
+			log.Fatal(data.LogRabbitmqInitFailed, map[string]any{"service": data.RabbitmqService, "error": err.Error()})
+		}
+		instance = client
+	})
+
+	return instance
+}
+
+// connect dials the broker and (re)declares the exchange every service publishes to.
+func (r *rabbitMQClient) connect() error {
+	conn, err := amqp091.Dial(r.url)
 	if err != nil {
-		return nil, fmt.Errorf("failed to connect to RabbitMQ: %w", err)
+		return fmt.Errorf("failed to connect to RabbitMQ: %w", err)
 	}
 
 	channel, err := conn.Channel()
 	if err != nil {
-		return nil, fmt.Errorf("failed to open a channel: %w", err)
+		conn.Close()
+		return fmt.Errorf("failed to open a channel: %w", err)
 	}
+	defer channel.Close()
 
 	err = channel.ExchangeDeclare(
 		data.OUTBOX_PUBLISH_EXCHANGE,
@@ -57,32 +133,113 @@ func NewRabbitMQClient(cfg env.RabbitMQ) (RabbitMQClient, error) {
 		nil,
 	)
 	if err != nil {
-		return nil, fmt.Errorf("failed to declare exchange: %w", err)
+		conn.Close()
+		return fmt.Errorf("failed to declare exchange: %w", err)
 	}
 
-	return &rabbitMQClient{
-		connection: conn,
-	}, nil
+	r.mu.Lock()
+	r.connection = conn
+	r.mu.Unlock()
+	r.state.Store(int32(StateConnected))
+
+	return nil
 }
 
-func GetInstance(cfg env.RabbitMQ) RabbitMQClient {
-	once.Do(func() {
-		client, err := NewRabbitMQClient(cfg)
-		if err != nil {
-			log.Fatal(data.LogRabbitmqInitFailed, map[string]any{"service": data.RabbitmqService, "error": err.Error()})
+// supervise watches the connection and reconnects with backoff whenever the broker drops it.
+func (r *rabbitMQClient) supervise() {
+	for {
+		closed, ok := r.notifyClose()
+		if !ok {
+			return
 		}
-		instance = client
-	})
 
-	return instance
+		select {
+		case <-r.done:
+			return
+		case amqpErr := <-closed:
+			select {
+			case <-r.done:
+				return
+			default:
+			}
+
+			r.state.Store(int32(StateDisconnected))
+			r.pool.drain()
+
+			fields := map[string]any{"service": data.RabbitmqService}
+			if amqpErr != nil {
+				fields["error"] = amqpErr.Error()
+			}
+			log.Warn(data.LogRabbitmqConnectionLost, fields)
+
+			if !r.reconnect() {
+				return
+			}
+		}
+	}
 }
 
-func (r *rabbitMQClient) GetChannel() (*amqp091.Channel, error) {
+// notifyClose subscribes to the close of the current connection. It holds the lock that Close
+// takes, so it either sees the client closed (and reports false) or registers before the
+// connection is closed, in which case the returned channel is closed along with it.
+func (r *rabbitMQClient) notifyClose() (chan *amqp091.Error, bool) {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 
+	select {
+	case <-r.done:
+		return nil, false
+	default:
+	}
 	if r.connection == nil {
-		return nil, fmt.Errorf("RabbitMQ connection is not initialized")
+		return nil, false
+	}
+
+	return r.connection.NotifyClose(make(chan *amqp091.Error, 1)), true
+}
+
+func (r *rabbitMQClient) reconnect() bool {
+	backoff := data.RABBITMQ_RECONNECT_MIN_BACKOFF
+
+	for attempt := 1; ; attempt++ {
+		select {
+		case <-r.done:
+			return false
+		case <-time.After(backoff):
+		}
+
+		r.state.Store(int32(StateConnecting))
+		if err := r.connect(); err != nil {
+			r.state.Store(int32(StateDisconnected))
+			log.Warn(data.LogRabbitmqReconnectFailed, map[string]any{
+				"service": data.RabbitmqService,
+				"attempt": attempt,
+				"backoff": backoff.String(),
+				"error":   err.Error(),
+			})
+			backoff = min(backoff*2, data.RABBITMQ_RECONNECT_MAX_BACKOFF)
+			continue
+		}
+
+		log.Info(data.LogRabbitmqReconnected, map[string]any{
+			"service": data.RabbitmqService,
+			"attempt": attempt,
+		})
+		return true
+	}
+}
+
+func (r *rabbitMQClient) State() ConnectionState {
+	return ConnectionState(r.state.Load())
+}
+
+// GetChannel opens a dedicated channel outside the pool; the caller owns and must close it.
+func (r *rabbitMQClient) GetChannel() (*amqp091.Channel, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if r.connection == nil || r.State() != StateConnected {
+		return nil, ErrNotConnected
 	}
 
 	channel, err := r.connection.Channel()
@@ -93,35 +250,92 @@ func (r *rabbitMQClient) GetChannel() (*amqp091.Channel, error) {
 	return channel, nil
 }
 
+// openChannel creates a confirm-mode channel for the pool.
+func (r *rabbitMQClient) openChannel() (*pooledChannel, error) {
+	channel, err := r.GetChannel()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := channel.Confirm(false); err != nil {
+		channel.Close()
+		return nil, fmt.Errorf("failed to put channel into confirm mode: %w", err)
+	}
+
+	return &pooledChannel{
+		channel: channel,
+		returns: channel.NotifyReturn(make(chan amqp091.Return, 1)),
+	}, nil
+}
+
 func (r *rabbitMQClient) Close() error {
-	r.mu.Lock()
-	defer r.mu.Unlock()
+	var err error
+
+	r.closeOnce.Do(func() {
+		close(r.done)
+		r.state.Store(int32(StateClosed))
+		r.pool.drain()
+
+		r.mu.Lock()
+		defer r.mu.Unlock()
 
-	if r.connection != nil {
-		if err := r.connection.Close(); err != nil {
-			return fmt.Errorf("failed to close RabbitMQ connection: %w", err)
+		if r.connection != nil {
+			if closeErr := r.connection.Close(); closeErr != nil && !errors.Is(closeErr, amqp091.ErrClosed) {
+				err = fmt.Errorf("failed to close RabbitMQ connection: %w", closeErr)
+			}
+			r.connection = nil
 		}
-		r.connection = nil
-	}
+	})
 
-	return nil
+	return err
 }
 
-func (r *rabbitMQClient) Publish(ctx context.Context, routingKey string, body []byte) error {
-	channel, err := r.GetChannel()
+// Publish sends body with the given options through the same confirmed path as PublishWithConfirm,
+// so a nil error always means the broker accepted and routed the message.
+func (r *rabbitMQClient) Publish(ctx context.Context, routingKey string, body []byte, opts PublishOptions) error {
+	return r.PublishWithConfirm(ctx, opts.exchange(), routingKey, NewPublishing(body, opts))
+}
+
+// PublishWithConfirm publishes a mandatory message on a confirm-mode channel and only returns nil
+// once the broker has acked it. Nacks and basic.return (unroutable) are reported as errors.
+func (r *rabbitMQClient) PublishWithConfirm(ctx context.Context, exchange, routingKey string, message amqp091.Publishing) error {
+	pc, err := r.pool.acquire(ctx)
 	if err != nil {
 		return err
 	}
-	defer channel.Close()
 
-	message := amqp091.Publishing{
-		ContentType: "application/json",
-		Body:        body,
-	}
+	// A channel is only handed back to the pool once its confirm round-trip finished cleanly,
+	// otherwise a late ack or return could be attributed to the next publish.
+	healthy := false
+	defer func() {
+		r.pool.release(pc, healthy)
+	}()
+
+	pc.drainReturns()
 
-	if err := channel.PublishWithContext(ctx, "refina", routingKey, false, false, message); err != nil {
+	confirmation, err := pc.channel.PublishWithDeferredConfirmWithContext(ctx, exchange, routingKey, true, false, message)
+	if err != nil {
 		return fmt.Errorf("failed to publish message: %w", err)
 	}
 
+	ack, err := confirmation.WaitContext(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to wait for publisher confirm: %w", err)
+	}
+	healthy = true
+
+	if !ack {
+		return ErrPublishNacked
+	}
+
+	// basic.return is dispatched before the matching ack, so it is already buffered if it happened
+	select {
+	case ret, ok := <-pc.returns:
+		if ok {
+			return fmt.Errorf("%w: %d %s", ErrPublishReturned, ret.ReplyCode, ret.ReplyText)
+		}
+	default:
+	}
+
 	return nil
 }
@@ -0,0 +1,33 @@
+package queue
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSupervise_ReturnsWhenClosedOrWithoutConnection(t *testing.T) {
+	closed := &rabbitMQClient{done: make(chan struct{})}
+	close(closed.done)
+
+	// Close sets connection to nil; supervise must not dereference it
+	withoutConnection := &rabbitMQClient{done: make(chan struct{})}
+
+	for _, client := range []*rabbitMQClient{closed, withoutConnection} {
+		finished := make(chan struct{})
+		go func() {
+			client.supervise()
+			close(finished)
+		}()
+
+		select {
+		case <-finished:
+		case <-time.After(time.Second):
+			t.Fatal("supervise did not return")
+		}
+	}
+
+	_, ok := closed.notifyClose()
+	assert.False(t, ok)
+}
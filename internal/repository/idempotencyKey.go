@@ -0,0 +1,107 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"refina-wallet/internal/types/model"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type IdempotencyRepository interface {
+	Reserve(ctx context.Context, record *model.IdempotencyKey) (model.IdempotencyKey, bool, error)
+	Complete(ctx context.Context, id uint, reservationToken uuid.UUID, response []byte, expiresAt time.Time) error
+	Release(ctx context.Context, id uint, reservationToken uuid.UUID) error
+	DeleteExpiredBefore(ctx context.Context, before time.Time, limit int) (int64, error)
+}
+
+// ErrIdempotencyReservationLost is returned by Complete and Release when the key expired and was
+// reserved again by another request; the row then belongs to that request.
+var ErrIdempotencyReservationLost = errors.New("idempotency key is no longer reserved by this request")
+
+type idempotencyRepository struct {
+	db *gorm.DB
+}
+
+func NewIdempotencyRepository(db *gorm.DB) IdempotencyRepository {
+	return &idempotencyRepository{db: db}
+}
+
+// Reserve claims record's key for the caller and reports whether it did. An expired key is
+// claimed again under a new reservation token; otherwise the row already holding the key is
+// returned with false.
+func (r *idempotencyRepository) Reserve(ctx context.Context, record *model.IdempotencyKey) (model.IdempotencyKey, bool, error) {
+	db := r.db.WithContext(ctx)
+
+	var reserved model.IdempotencyKey
+	result := db.Raw(`
+		INSERT INTO idempotency_keys (idempotency_key, operation, user_id, request_hash, created_at, expires_at, reservation_token)
+		VALUES (?, ?, ?, ?, NOW(), ?, uuid_generate_v4())
+		ON CONFLICT (operation, user_id, idempotency_key) DO UPDATE
+		SET request_hash = EXCLUDED.request_hash,
+			response = NULL,
+			created_at = EXCLUDED.created_at,
+			expires_at = EXCLUDED.expires_at,
+			reservation_token = EXCLUDED.reservation_token
+		WHERE idempotency_keys.expires_at < NOW()
+		RETURNING *`,
+		record.Key, record.Operation, record.UserID, record.RequestHash, record.ExpiresAt,
+	).Scan(&reserved)
+	if result.Error != nil {
+		return model.IdempotencyKey{}, false, result.Error
+	}
+	if result.RowsAffected == 1 {
+		return reserved, true, nil
+	}
+
+	var existing model.IdempotencyKey
+	if err := db.Where("operation = ? AND user_id = ? AND idempotency_key = ?", record.Operation, record.UserID, record.Key).
+		First(&existing).Error; err != nil {
+		return model.IdempotencyKey{}, false, err
+	}
+	return existing, false, nil
+}
+
+// Complete stores the response of a reserved key and keeps it until expiresAt
+func (r *idempotencyRepository) Complete(ctx context.Context, id uint, reservationToken uuid.UUID, response []byte, expiresAt time.Time) error {
+	result := r.db.WithContext(ctx).Model(&model.IdempotencyKey{}).
+		Where("id = ? AND reservation_token = ? AND response IS NULL", id, reservationToken).
+		Updates(map[string]any{"response": response, "expires_at": expiresAt})
+	return reservedUpdate(result)
+}
+
+// Release drops a reservation whose request failed, so the client may retry with the same key
+func (r *idempotencyRepository) Release(ctx context.Context, id uint, reservationToken uuid.UUID) error {
+	result := r.db.WithContext(ctx).
+		Where("id = ? AND reservation_token = ? AND response IS NULL", id, reservationToken).
+		Delete(&model.IdempotencyKey{})
+	return reservedUpdate(result)
+}
+
+func reservedUpdate(result *gorm.DB) error {
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrIdempotencyReservationLost
+	}
+	return nil
+}
+
+// DeleteExpiredBefore removes at most limit keys that expired before before
+// and returns the number of rows deleted.
+func (r *idempotencyRepository) DeleteExpiredBefore(ctx context.Context, before time.Time, limit int) (int64, error) {
+	result := r.db.WithContext(ctx).Exec(`
+		DELETE FROM idempotency_keys
+		WHERE id IN (
+			SELECT id FROM idempotency_keys
+			WHERE expires_at < ?
+			ORDER BY expires_at
+			LIMIT ?
+		)`, before, limit)
+
+	return result.RowsAffected, result.Error
+}
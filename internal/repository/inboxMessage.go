@@ -0,0 +1,68 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"refina-wallet/internal/types/model"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type InboxRepository interface {
+	MarkHandled(ctx context.Context, tx Transaction, message *model.InboxMessage) (bool, error)
+	DeleteHandledBefore(ctx context.Context, before time.Time, limit int) (int64, error)
+}
+
+type inboxRepository struct {
+	db *gorm.DB
+}
+
+func NewInboxRepository(db *gorm.DB) InboxRepository {
+	return &inboxRepository{db: db}
+}
+
+func (r *inboxRepository) getDB(ctx context.Context, tx Transaction) (*gorm.DB, error) {
+	if tx != nil {
+		gormTx, ok := tx.(*GormTx)
+		if !ok {
+			return nil, errors.New("invalid transaction type")
+		}
+		return gormTx.db.WithContext(ctx), nil
+	}
+	return r.db.WithContext(ctx), nil
+}
+
+// MarkHandled records message as consumed and reports whether it is seen for the first time.
+// A concurrent insert of the same message blocks on the unique constraint until the other
+// transaction ends, so only one consumer ever gets true.
+func (r *inboxRepository) MarkHandled(ctx context.Context, tx Transaction, message *model.InboxMessage) (bool, error) {
+	db, err := r.getDB(ctx, tx)
+	if err != nil {
+		return false, err
+	}
+
+	result := db.Clauses(clause.OnConflict{DoNothing: true}).Create(message)
+	if result.Error != nil {
+		return false, result.Error
+	}
+
+	return result.RowsAffected == 1, nil
+}
+
+// DeleteHandledBefore removes at most limit inbox rows handled before before
+// and returns the number of rows deleted.
+func (r *inboxRepository) DeleteHandledBefore(ctx context.Context, before time.Time, limit int) (int64, error) {
+	result := r.db.WithContext(ctx).Exec(`
+		DELETE FROM inbox_messages
+		WHERE id IN (
+			SELECT id FROM inbox_messages
+			WHERE handled_at < ?
+			ORDER BY handled_at
+			LIMIT ?
+		)`, before, limit)
+
+	return result.RowsAffected, result.Error
+}
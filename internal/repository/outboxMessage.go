@@ -3,19 +3,40 @@ package repository
 import (
 	"context"
 	"errors"
+	"fmt"
+	"sort"
+	"time"
 
+	"refina-wallet/internal/types/domainerr"
 	"refina-wallet/internal/types/model"
+	"refina-wallet/internal/utils/data"
 
 	"gorm.io/gorm"
 )
 
 type OutboxRepository interface {
 	Create(ctx context.Context, tx Transaction, outbox *model.OutboxMessage) error
-	GetPendingMessages(ctx context.Context, limit int) ([]model.OutboxMessage, error)
-	MarkAsPublished(ctx context.Context, id uint) error
-	IncrementRetries(ctx context.Context, id uint) error
+	NextSequence(ctx context.Context, tx Transaction, aggregateID string) (int64, error)
+	ClaimPendingMessages(ctx context.Context, owner string, lease time.Duration, limit int) ([]model.OutboxMessage, error)
+	RenewClaims(ctx context.Context, ids []uint, owner string, lease time.Duration) ([]uint, error)
+	MarkAsPublished(ctx context.Context, id uint, owner string) error
+	IncrementRetries(ctx context.Context, id uint, owner string, nextAttemptAt time.Time, lastError string) error
+	DeletePublishedBefore(ctx context.Context, before time.Time, limit int) (int64, error)
+	ArchivePublishedBefore(ctx context.Context, before time.Time, limit int) (int64, error)
+	GetDeadLetters(ctx context.Context, limit, offset int) ([]model.OutboxMessage, error)
+	GetDeadLetterByID(ctx context.Context, id uint) (model.OutboxMessage, error)
+	RequeueDeadLetter(ctx context.Context, id uint) error
+	DiscardDeadLetter(ctx context.Context, id uint) error
+	ReplayPublishedRange(ctx context.Context, fromID, toID uint) (int64, error)
 }
 
+// ErrDeadLetterNotFound is returned for dead-letter operations on a message that is missing or not dead.
+var ErrDeadLetterNotFound = domainerr.New(domainerr.NotFound, "DEAD_LETTER_NOT_FOUND", "dead letter not found")
+
+// ErrOutboxClaimLost is returned by the publisher state transitions when the lease of owner expired
+// and another publisher claimed the message in the meantime; the row is then left to that publisher.
+var ErrOutboxClaimLost = errors.New("outbox message is no longer claimed by this publisher")
+
 type outboxRepository struct {
 	db *gorm.DB
 }
@@ -41,35 +62,296 @@ func (r *outboxRepository) Create(ctx context.Context, tx Transaction, outbox *m
 		return err
 	}
 
-	return db.Create(outbox).Error
+	if err := db.Create(outbox).Error; err != nil {
+		return err
+	}
+
+	return notifyOutbox(db)
 }
 
-func (r *outboxRepository) GetPendingMessages(ctx context.Context, limit int) ([]model.OutboxMessage, error) {
+// notifyOutbox wakes listening publishers. Inside a transaction Postgres only delivers the
+// NOTIFY on commit (and drops it on rollback), so publishers never wake for invisible rows.
+func notifyOutbox(db *gorm.DB) error {
+	return db.Exec("SELECT pg_notify(?, '')", data.OUTBOX_NOTIFY_CHANNEL).Error
+}
+
+// NextSequence hands out the next event sequence number of aggregateID. The counter row stays
+// locked until tx ends, so concurrent writers for the same aggregate are serialized and a rolled
+// back transaction does not leave a gap.
+func (r *outboxRepository) NextSequence(ctx context.Context, tx Transaction, aggregateID string) (int64, error) {
+	db, err := r.getDB(ctx, tx)
+	if err != nil {
+		return 0, err
+	}
+
+	var sequence int64
+	err = db.Raw(`
+		INSERT INTO outbox_aggregate_sequences (aggregate_id, last_sequence)
+		VALUES (?, 1)
+		ON CONFLICT (aggregate_id) DO UPDATE
+		SET last_sequence = outbox_aggregate_sequences.last_sequence + 1
+		RETURNING last_sequence`, aggregateID).
+		Scan(&sequence).Error
+	if err != nil {
+		return 0, err
+	}
+
+	return sequence, nil
+}
+
+// ClaimPendingMessages leases up to limit pending messages to owner in a single statement.
+// FOR UPDATE SKIP LOCKED keeps concurrent claimers from blocking on or grabbing the same rows,
+// and the lease makes other instances ignore the batch until it is released or expires.
+// Only the oldest unpublished message of each aggregate is eligible, so a message that is
+// in flight, waiting for a retry or dead-lettered holds back everything after it for the same
+// wallet until it is published, requeued or discarded; consumers never see a gap that a
+// requeue fills later. Only a row left with exhausted retries but no failed_at (written before
+// the dead-letter transition was atomic) is skipped, as no admin endpoint could release it.
+func (r *outboxRepository) ClaimPendingMessages(ctx context.Context, owner string, lease time.Duration, limit int) ([]model.OutboxMessage, error) {
+	var messages []model.OutboxMessage
+
+	err := r.db.WithContext(ctx).Raw(`
+		UPDATE outbox_messages
+		SET locked_by = ?, locked_until = NOW() + (? * INTERVAL '1 millisecond')
+		WHERE id IN (
+			SELECT id FROM outbox_messages
+			WHERE published = FALSE
+			  AND failed_at IS NULL
+			  AND retries < max_retries
+			  AND (locked_until IS NULL OR locked_until < NOW())
+			  AND (next_attempt_at IS NULL OR next_attempt_at <= NOW())
+			  AND NOT EXISTS (
+				SELECT 1 FROM outbox_messages earlier
+				WHERE earlier.aggregate_id = outbox_messages.aggregate_id
+				  AND earlier.published = FALSE
+				  AND (earlier.failed_at IS NOT NULL OR earlier.retries < earlier.max_retries)
+				  AND earlier.id < outbox_messages.id
+			  )
+			ORDER BY created_at ASC
+			LIMIT ?
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING *`, owner, lease.Milliseconds(), limit).
+		Scan(&messages).Error
+	if err != nil {
+		return nil, err
+	}
+
+	// RETURNING does not preserve the subquery order
+	sort.Slice(messages, func(i, j int) bool {
+		return messages[i].CreatedAt.Before(messages[j].CreatedAt)
+	})
+
+	return messages, nil
+}
+
+// RenewClaims extends the lease of owner on the given messages and returns the ids it still holds.
+// A message whose lease expired and was claimed by another publisher is left out.
+func (r *outboxRepository) RenewClaims(ctx context.Context, ids []uint, owner string, lease time.Duration) ([]uint, error) {
+	var held []uint
+	if len(ids) == 0 {
+		return held, nil
+	}
+
+	err := r.db.WithContext(ctx).Raw(`
+		UPDATE outbox_messages
+		SET locked_until = NOW() + (? * INTERVAL '1 millisecond')
+		WHERE id IN ? AND locked_by = ? AND published = FALSE
+		RETURNING id`, lease.Milliseconds(), ids, owner).
+		Scan(&held).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return held, nil
+}
+
+// MarkAsPublished releases the claim of owner on a message the broker confirmed.
+func (r *outboxRepository) MarkAsPublished(ctx context.Context, id uint, owner string) error {
+	return claimedUpdate(r.db.WithContext(ctx).
+		Model(&model.OutboxMessage{}).
+		Where("id = ? AND locked_by = ?", id, owner).
+		Updates(map[string]any{
+			"published":    true,
+			"published_at": gorm.Expr("NOW()"),
+			"locked_by":    nil,
+			"locked_until": nil,
+		}))
+}
+
+// IncrementRetries records a failed attempt and schedules the next one no earlier than nextAttemptAt.
+// The attempt that exhausts max_retries parks the message as a dead letter in the same statement,
+// so a crash in between can never leave an exhausted row that is neither retried nor dead.
+func (r *outboxRepository) IncrementRetries(ctx context.Context, id uint, owner string, nextAttemptAt time.Time, lastError string) error {
+	return claimedUpdate(r.db.WithContext(ctx).
+		Model(&model.OutboxMessage{}).
+		Where("id = ? AND locked_by = ?", id, owner).
+		Updates(map[string]any{
+			"retries":         gorm.Expr("retries + 1"),
+			"failed_at":       gorm.Expr("CASE WHEN retries + 1 >= max_retries THEN NOW() END"),
+			"next_attempt_at": nextAttemptAt,
+			"last_error":      lastError,
+			"locked_by":       nil,
+			"locked_until":    nil,
+		}))
+}
+
+// claimedUpdate turns an update guarded by locked_by that matched no row into ErrOutboxClaimLost.
+func claimedUpdate(result *gorm.DB) error {
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrOutboxClaimLost
+	}
+	return nil
+}
+
+// DeletePublishedBefore removes at most limit published messages older than before
+// and returns the number of rows deleted.
+func (r *outboxRepository) DeletePublishedBefore(ctx context.Context, before time.Time, limit int) (int64, error) {
+	result := r.db.WithContext(ctx).Exec(`
+		DELETE FROM outbox_messages
+		WHERE id IN (
+			SELECT id FROM outbox_messages
+			WHERE published = TRUE AND published_at < ?
+			ORDER BY published_at
+			LIMIT ?
+		)`, before, limit)
+
+	return result.RowsAffected, result.Error
+}
+
+// outboxColumns lists every column of outbox_messages, in the order outbox_messages_archive copies them
+const outboxColumns = `id, aggregate_id, event_id, event_type, sequence, payload, published, published_at, retries, max_retries,
+	failed_at, last_error, correlation_id, locked_by, locked_until, next_attempt_at, created_at, updated_at`
+
+// ArchivePublishedBefore moves at most limit published messages older than before
+// into outbox_messages_archive, with all their columns, and returns the number of rows moved.
+func (r *outboxRepository) ArchivePublishedBefore(ctx context.Context, before time.Time, limit int) (int64, error) {
+	result := r.db.WithContext(ctx).Exec(`
+		WITH moved AS (
+			DELETE FROM outbox_messages
+			WHERE id IN (
+				SELECT id FROM outbox_messages
+				WHERE published = TRUE AND published_at < ?
+				ORDER BY published_at
+				LIMIT ?
+			)
+			RETURNING `+outboxColumns+`
+		)
+		INSERT INTO outbox_messages_archive (`+outboxColumns+`)
+		SELECT `+outboxColumns+` FROM moved
+		ON CONFLICT (id) DO NOTHING`, before, limit)
+
+	return result.RowsAffected, result.Error
+}
+
+func (r *outboxRepository) GetDeadLetters(ctx context.Context, limit, offset int) ([]model.OutboxMessage, error) {
 	var messages []model.OutboxMessage
 
 	err := r.db.WithContext(ctx).
 		Where("published = ?", false).
-		Where("retries < max_retries").
-		Order("created_at ASC").
+		Where("failed_at IS NOT NULL").
+		Order("failed_at DESC").
 		Limit(limit).
+		Offset(offset).
 		Find(&messages).Error
 
 	return messages, err
 }
 
-func (r *outboxRepository) MarkAsPublished(ctx context.Context, id uint) error {
-	return r.db.WithContext(ctx).
+func (r *outboxRepository) GetDeadLetterByID(ctx context.Context, id uint) (model.OutboxMessage, error) {
+	var message model.OutboxMessage
+
+	err := r.db.WithContext(ctx).
+		Where("id = ?", id).
+		Where("published = ?", false).
+		Where("failed_at IS NOT NULL").
+		First(&message).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return message, fmt.Errorf("%w: %w", ErrDeadLetterNotFound, err)
+	}
+
+	return message, err
+}
+
+// RequeueDeadLetter resets the retry state of a dead message so the publisher picks it up again.
+func (r *outboxRepository) RequeueDeadLetter(ctx context.Context, id uint) error {
+	result := r.db.WithContext(ctx).
 		Model(&model.OutboxMessage{}).
 		Where("id = ?", id).
+		Where("published = ?", false).
+		Where("failed_at IS NOT NULL").
 		Updates(map[string]any{
-			"published":    true,
-			"published_at": gorm.Expr("NOW()"),
-		}).Error
+			"retries":         0,
+			"failed_at":       nil,
+			"last_error":      nil,
+			"next_attempt_at": nil,
+			"locked_by":       nil,
+			"locked_until":    nil,
+			"updated_at":      gorm.Expr("NOW()"),
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("%w: %w", ErrDeadLetterNotFound, gorm.ErrRecordNotFound)
+	}
+
+	return nil
 }
 
-func (r *outboxRepository) IncrementRetries(ctx context.Context, id uint) error {
-	return r.db.WithContext(ctx).
-		Model(&model.OutboxMessage{}).
+func (r *outboxRepository) DiscardDeadLetter(ctx context.Context, id uint) error {
+	result := r.db.WithContext(ctx).
 		Where("id = ?", id).
-		Update("retries", gorm.Expr("retries + 1")).Error
+		Where("published = ?", false).
+		Where("failed_at IS NOT NULL").
+		Delete(&model.OutboxMessage{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("%w: %w", ErrDeadLetterNotFound, gorm.ErrRecordNotFound)
+	}
+
+	return nil
+}
+
+// ReplayPublishedRange re-enqueues copies of the published messages with fromID <= id <= toID,
+// archived ones included. The originals are kept as history; copies keep event_id so consumers
+// can deduplicate.
+func (r *outboxRepository) ReplayPublishedRange(ctx context.Context, fromID, toID uint) (int64, error) {
+	var replayed int64
+
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Exec(`
+			INSERT INTO outbox_messages (aggregate_id, event_id, event_type, sequence, payload, correlation_id, max_retries, created_at, updated_at)
+			SELECT aggregate_id, event_id, event_type, sequence, payload, correlation_id, max_retries, NOW(), NOW()
+			FROM (
+				SELECT id, aggregate_id, event_id, event_type, sequence, payload, correlation_id, max_retries
+				FROM outbox_messages
+				WHERE published = TRUE AND id BETWEEN ? AND ?
+				UNION ALL
+				SELECT id, aggregate_id, event_id, event_type, sequence, payload, correlation_id, COALESCE(max_retries, 3)
+				FROM outbox_messages_archive
+				WHERE id BETWEEN ? AND ?
+			) replayed
+			ORDER BY id`, fromID, toID, fromID, toID)
+		if result.Error != nil {
+			return result.Error
+		}
+
+		replayed = result.RowsAffected
+		if replayed == 0 {
+			return nil
+		}
+
+		return notifyOutbox(tx)
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return replayed, nil
 }
@@ -0,0 +1,124 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"refina-wallet/internal/types/model"
+
+	"gorm.io/gorm"
+)
+
+// WalletSagaRepository never joins the caller's transaction: saga state has to survive the
+// rollback of the wallet it describes.
+type WalletSagaRepository interface {
+	Create(ctx context.Context, saga *model.WalletCreationSaga) error
+	MarkDepositCreated(ctx context.Context, id string, depositTransactionID string) error
+	MarkCommitted(ctx context.Context, id string) error
+	MarkFailed(ctx context.Context, id string) error
+	MarkNeedsReview(ctx context.Context, id string, lastError string) error
+	ScheduleCompensation(ctx context.Context, id string, nextAttemptAt time.Time, lastError string) error
+	ClaimDueCompensations(ctx context.Context, lease time.Duration, limit int) ([]model.WalletCreationSaga, error)
+	GetStale(ctx context.Context, before time.Time, limit int) ([]model.WalletCreationSaga, error)
+	RecordTransferReview(ctx context.Context, review *model.WalletTransferReview) error
+}
+
+type walletSagaRepository struct {
+	db *gorm.DB
+}
+
+func NewWalletSagaRepository(db *gorm.DB) WalletSagaRepository {
+	return &walletSagaRepository{db: db}
+}
+
+func (r *walletSagaRepository) Create(ctx context.Context, saga *model.WalletCreationSaga) error {
+	return r.db.WithContext(ctx).Create(saga).Error
+}
+
+func (r *walletSagaRepository) updateStatus(ctx context.Context, id string, status model.SagaStatus, fields map[string]any) error {
+	if fields == nil {
+		fields = map[string]any{}
+	}
+	fields["status"] = status
+	fields["updated_at"] = time.Now()
+
+	result := r.db.WithContext(ctx).Model(&model.WalletCreationSaga{}).Where("id = ?", id).Updates(fields)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func (r *walletSagaRepository) MarkDepositCreated(ctx context.Context, id string, depositTransactionID string) error {
+	return r.updateStatus(ctx, id, model.SagaDepositCreated, map[string]any{"deposit_transaction_id": depositTransactionID})
+}
+
+func (r *walletSagaRepository) MarkCommitted(ctx context.Context, id string) error {
+	return r.updateStatus(ctx, id, model.SagaCommitted, nil)
+}
+
+// MarkFailed ends a saga whose wallet was not created and whose deposit (if any) is gone.
+func (r *walletSagaRepository) MarkFailed(ctx context.Context, id string) error {
+	return r.updateStatus(ctx, id, model.SagaFailed, map[string]any{"next_attempt_at": nil})
+}
+
+// MarkNeedsReview parks a saga whose initial deposit has an unknown outcome, e.g. after a timeout.
+// The worker never picks it up again; an operator decides whether the deposit has to be cancelled.
+func (r *walletSagaRepository) MarkNeedsReview(ctx context.Context, id string, lastError string) error {
+	return r.updateStatus(ctx, id, model.SagaNeedsReview, map[string]any{"next_attempt_at": nil, "last_error": lastError})
+}
+
+// ScheduleCompensation moves a saga to compensating and records a failed attempt (if lastError is set).
+func (r *walletSagaRepository) ScheduleCompensation(ctx context.Context, id string, nextAttemptAt time.Time, lastError string) error {
+	fields := map[string]any{"next_attempt_at": nextAttemptAt}
+	if lastError != "" {
+		fields["last_error"] = lastError
+		fields["attempts"] = gorm.Expr("attempts + 1")
+	}
+	return r.updateStatus(ctx, id, model.SagaCompensating, fields)
+}
+
+// ClaimDueCompensations leases up to limit compensating sagas whose next attempt is due by pushing
+// next_attempt_at past the lease, so concurrent workers never pick the same saga.
+func (r *walletSagaRepository) ClaimDueCompensations(ctx context.Context, lease time.Duration, limit int) ([]model.WalletCreationSaga, error) {
+	var sagas []model.WalletCreationSaga
+	err := r.db.WithContext(ctx).Raw(`
+		UPDATE wallet_creation_sagas
+		SET next_attempt_at = NOW() + (? * INTERVAL '1 millisecond'), updated_at = NOW()
+		WHERE id IN (
+			SELECT id FROM wallet_creation_sagas
+			WHERE status = 'compensating' AND (next_attempt_at IS NULL OR next_attempt_at <= NOW())
+			ORDER BY next_attempt_at NULLS FIRST
+			LIMIT ?
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING *`, lease.Milliseconds(), limit).Scan(&sagas).Error
+	if err != nil {
+		return nil, err
+	}
+	return sagas, nil
+}
+
+// GetStale returns sagas stuck in pending or deposit_created since before, i.e. whose
+// CreateWallet call died before it could record the outcome.
+func (r *walletSagaRepository) GetStale(ctx context.Context, before time.Time, limit int) ([]model.WalletCreationSaga, error) {
+	var sagas []model.WalletCreationSaga
+	err := r.db.WithContext(ctx).
+		Where("status IN ? AND updated_at < ?", []model.SagaStatus{model.SagaPending, model.SagaDepositCreated}, before).
+		Order("updated_at asc").
+		Limit(limit).
+		Find(&sagas).Error
+	if err != nil {
+		return nil, err
+	}
+	return sagas, nil
+}
+
+// RecordTransferReview stores a transfer an operator has to reconcile. Like saga state it is written
+// outside the caller's transaction, which has usually been rolled back by then.
+func (r *walletSagaRepository) RecordTransferReview(ctx context.Context, review *model.WalletTransferReview) error {
+	return r.db.WithContext(ctx).Create(review).Error
+}
@@ -3,7 +3,9 @@ package repository
 import (
 	"context"
 	"errors"
+	"fmt"
 
+	"refina-wallet/internal/types/domainerr"
 	"refina-wallet/internal/types/model"
 
 	"gorm.io/gorm"
@@ -16,6 +18,10 @@ type WalletTypesRepository interface {
 	UpdateWalletType(ctx context.Context, tx Transaction, walletType model.WalletTypes) (model.WalletTypes, error)
 	DeleteWalletType(ctx context.Context, tx Transaction, walletType model.WalletTypes) (model.WalletTypes, error)
 }
+
+// ErrWalletTypeNotFound is returned for reads of a missing wallet type.
+var ErrWalletTypeNotFound = domainerr.New(domainerr.NotFound, "WALLET_TYPE_NOT_FOUND", "wallet type not found")
+
 type walletTypesRepository struct {
 	db *gorm.DB
 }
@@ -57,6 +63,9 @@ func (wallet_type_repo *walletTypesRepository) GetWalletTypeByID(ctx context.Con
 
 	var walletType model.WalletTypes
 	if err := db.Where("id = ?", id).First(&walletType).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return model.WalletTypes{}, fmt.Errorf("%w: %w", ErrWalletTypeNotFound, err)
+		}
 		return model.WalletTypes{}, err
 	}
 	return walletType, nil
@@ -5,11 +5,15 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"time"
 
+	"refina-wallet/internal/types/domainerr"
 	"refina-wallet/internal/types/model"
+	"refina-wallet/internal/types/money"
 	"refina-wallet/internal/types/view"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type WalletsRepository interface {
@@ -20,6 +24,27 @@ type WalletsRepository interface {
 	CreateWallet(ctx context.Context, tx Transaction, wallet model.Wallets) (model.Wallets, error)
 	UpdateWallet(ctx context.Context, tx Transaction, wallet model.Wallets) (model.Wallets, error)
 	DeleteWallet(ctx context.Context, tx Transaction, wallet model.Wallets) (model.Wallets, error)
+	GetWalletsPage(ctx context.Context, tx Transaction, filter WalletFilter, afterID string, limit int) ([]model.Wallets, error)
+	AppendBalanceEntry(ctx context.Context, tx Transaction, entry model.WalletBalanceEntries) (model.WalletBalanceEntries, error)
+	GetBalanceEntries(ctx context.Context, tx Transaction, walletID string, limit, offset int) ([]model.WalletBalanceEntries, error)
+	GetBalanceAsOf(ctx context.Context, tx Transaction, walletID string, asOf time.Time) (money.Amount, error)
+	GetTransactionContributions(ctx context.Context, tx Transaction, sourceTransactionID string) (map[string]money.Amount, error)
+	LockWallets(ctx context.Context, tx Transaction, ids ...string) ([]model.Wallets, error)
+	AdvanceTransactionVersion(ctx context.Context, tx Transaction, sourceTransactionID string, version int64) (bool, error)
+	WalletEverCreated(ctx context.Context, tx Transaction, id string) (bool, error)
+}
+
+// ErrWalletNotFound is returned for reads and balance writes of a missing or deleted wallet.
+var ErrWalletNotFound = domainerr.New(domainerr.NotFound, "WALLET_NOT_FOUND", "wallet not found")
+
+// ErrWalletVersionConflict is returned when a wallet changed since the version the caller read.
+var ErrWalletVersionConflict = domainerr.New(domainerr.Conflict, "WALLET_VERSION_CONFLICT", "wallet version conflict")
+
+// WalletFilter narrows bulk wallet reads; zero-valued fields are not applied.
+type WalletFilter struct {
+	UserID      string
+	CreatedFrom *time.Time
+	CreatedTo   *time.Time
 }
 
 type walletsRepository struct {
@@ -64,11 +89,28 @@ func (wallet_repo *walletsRepository) GetWalletByID(ctx context.Context, tx Tran
 
 	var wallet model.Wallets
 	if err := db.Preload("WalletType").Where("id = ?", id).First(&wallet).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return model.Wallets{}, fmt.Errorf("%w: %w", ErrWalletNotFound, err)
+		}
 		return model.Wallets{}, err
 	}
 	return wallet, nil
 }
 
+// WalletEverCreated reports whether a wallet row with id exists, soft-deleted ones included.
+func (wallet_repo *walletsRepository) WalletEverCreated(ctx context.Context, tx Transaction, id string) (bool, error) {
+	db, err := wallet_repo.getDB(ctx, tx)
+	if err != nil {
+		return false, err
+	}
+
+	var count int64
+	if err := db.Unscoped().Model(&model.Wallets{}).Where("id = ?", id).Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
 func (wallet_repo *walletsRepository) GetWalletsByUserID(ctx context.Context, tx Transaction, id string) ([]model.Wallets, error) {
 	db, err := wallet_repo.getDB(ctx, tx)
 	if err != nil {
@@ -78,7 +120,7 @@ func (wallet_repo *walletsRepository) GetWalletsByUserID(ctx context.Context, tx
 	var userWallets []model.Wallets
 	err = db.Preload("WalletType").Where("user_id = ?", id).Order("created_at desc").Find(&userWallets).Error
 	if err != nil {
-		return nil, errors.New("user wallets not found")
+		return nil, err
 	}
 
 	if len(userWallets) == 0 {
@@ -101,7 +143,7 @@ func (wallet_repo *walletsRepository) GetWalletsByUserIDGroupByType(ctx context.
 	}
 	err = db.Raw(`SELECT * FROM view_user_wallets_group_by_type WHERE user_id = $1`, id).Scan(&rawResults).Error
 	if err != nil {
-		return nil, errors.New("user wallets group by type not found")
+		return nil, err
 	}
 
 	var results []view.ViewUserWalletsGroupByType
@@ -137,17 +179,34 @@ func (wallet_repo *walletsRepository) CreateWallet(ctx context.Context, tx Trans
 	return wallet, nil
 }
 
+// UpdateWallet writes the editable fields of wallet only if its row still has wallet.Version,
+// bumping the version. Balance is left alone; it only moves through AppendBalanceEntry.
 func (wallet_repo *walletsRepository) UpdateWallet(ctx context.Context, tx Transaction, wallet model.Wallets) (model.Wallets, error) {
 	db, err := wallet_repo.getDB(ctx, tx)
 	if err != nil {
 		return model.Wallets{}, err
 	}
 
-	if err := db.Omit("User", "WalletType").Save(&wallet).Error; err != nil {
-		return model.Wallets{}, err
+	var updated model.Wallets
+	result := db.Model(&updated).
+		Clauses(clause.Returning{}).
+		Where("id = ? AND version = ?", wallet.ID, wallet.Version).
+		Updates(map[string]any{
+			"name":           wallet.Name,
+			"number":         wallet.Number,
+			"wallet_type_id": wallet.WalletTypeID,
+			"version":        gorm.Expr("version + 1"),
+			"updated_at":     time.Now(),
+		})
+	if result.Error != nil {
+		return model.Wallets{}, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return model.Wallets{}, ErrWalletVersionConflict
 	}
 
-	return wallet, nil
+	updated.WalletType = wallet.WalletType
+	return updated, nil
 }
 
 func (wallet_repo *walletsRepository) DeleteWallet(ctx context.Context, tx Transaction, wallet model.Wallets) (model.Wallets, error) {
@@ -156,9 +215,200 @@ func (wallet_repo *walletsRepository) DeleteWallet(ctx context.Context, tx Trans
 		return model.Wallets{}, err
 	}
 
-	if err := db.Delete(&wallet).Error; err != nil {
-		return model.Wallets{}, err
+	result := db.Where("version = ?", wallet.Version).Delete(&wallet)
+	if result.Error != nil {
+		return model.Wallets{}, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return model.Wallets{}, ErrWalletVersionConflict
 	}
 
 	return wallet, nil
 }
+
+// GetWalletsPage returns up to limit wallets matching filter with id greater than afterID, ordered by id,
+// so callers can walk every wallet with keyset pagination.
+func (wallet_repo *walletsRepository) GetWalletsPage(ctx context.Context, tx Transaction, filter WalletFilter, afterID string, limit int) ([]model.Wallets, error) {
+	db, err := wallet_repo.getDB(ctx, tx)
+	if err != nil {
+		return nil, err
+	}
+
+	query := db.Preload("WalletType").Order("id asc").Limit(limit)
+	if afterID != "" {
+		query = query.Where("id > ?", afterID)
+	}
+	if filter.UserID != "" {
+		query = query.Where("user_id = ?", filter.UserID)
+	}
+	if filter.CreatedFrom != nil {
+		query = query.Where("created_at >= ?", *filter.CreatedFrom)
+	}
+	if filter.CreatedTo != nil {
+		query = query.Where("created_at < ?", *filter.CreatedTo)
+	}
+
+	var wallets []model.Wallets
+	if err := query.Find(&wallets).Error; err != nil {
+		return nil, err
+	}
+	return wallets, nil
+}
+
+// AppendBalanceEntry applies entry to the wallet balance and records it in the ledger. The balance
+// update takes the wallet row lock, so entries of one wallet are serialized and BalanceAfter is exact.
+// It also bumps the version, so an update or delete based on an earlier read sees the balance move;
+// the returned entry carries the new version in WalletVersion.
+// Without a transaction both writes run in their own one.
+func (wallet_repo *walletsRepository) AppendBalanceEntry(ctx context.Context, tx Transaction, entry model.WalletBalanceEntries) (model.WalletBalanceEntries, error) {
+	db, err := wallet_repo.getDB(ctx, tx)
+	if err != nil {
+		return model.WalletBalanceEntries{}, err
+	}
+
+	delta := entry.Amount
+	if entry.Direction == model.Debit {
+		delta = -delta
+	}
+
+	apply := func(db *gorm.DB) error {
+		var applied struct {
+			Balance money.Amount
+			Version int64
+		}
+		result := db.Raw(`UPDATE wallets SET balance = balance + ?, version = version + 1, updated_at = NOW() WHERE id = ? AND deleted_at IS NULL RETURNING balance, version`, delta, entry.WalletID).Scan(&applied)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrWalletNotFound
+		}
+
+		entry.BalanceAfter = applied.Balance
+		entry.WalletVersion = applied.Version
+		return db.Create(&entry).Error
+	}
+
+	if tx != nil {
+		err = apply(db)
+	} else {
+		err = db.Transaction(apply)
+	}
+	if err != nil {
+		return model.WalletBalanceEntries{}, err
+	}
+
+	return entry, nil
+}
+
+// GetBalanceEntries returns the ledger of a wallet, newest entry first.
+func (wallet_repo *walletsRepository) GetBalanceEntries(ctx context.Context, tx Transaction, walletID string, limit, offset int) ([]model.WalletBalanceEntries, error) {
+	db, err := wallet_repo.getDB(ctx, tx)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []model.WalletBalanceEntries
+	if err := db.Where("wallet_id = ?", walletID).Order("id desc").Limit(limit).Offset(offset).Find(&entries).Error; err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// GetBalanceAsOf returns the running balance after the last entry created at or before asOf,
+// or zero when the wallet had no entries yet.
+func (wallet_repo *walletsRepository) GetBalanceAsOf(ctx context.Context, tx Transaction, walletID string, asOf time.Time) (money.Amount, error) {
+	db, err := wallet_repo.getDB(ctx, tx)
+	if err != nil {
+		return 0, err
+	}
+
+	var balances []money.Amount
+	err = db.Model(&model.WalletBalanceEntries{}).
+		Where("wallet_id = ? AND created_at <= ?", walletID, asOf).
+		Order("id desc").
+		Limit(1).
+		Pluck("balance_after", &balances).Error
+	if err != nil {
+		return 0, err
+	}
+
+	if len(balances) == 0 {
+		return 0, nil
+	}
+	return balances[0], nil
+}
+
+// GetTransactionContributions returns the net signed amount sourceTransactionID has put into each wallet.
+// It takes a transaction-scoped advisory lock on sourceTransactionID first, so two events of the same
+// transaction cannot both read the old contributions and apply the same delta twice.
+func (wallet_repo *walletsRepository) GetTransactionContributions(ctx context.Context, tx Transaction, sourceTransactionID string) (map[string]money.Amount, error) {
+	db, err := wallet_repo.getDB(ctx, tx)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := db.Exec("SELECT pg_advisory_xact_lock(hashtext(?))", sourceTransactionID).Error; err != nil {
+		return nil, err
+	}
+
+	var rows []struct {
+		WalletID string
+		Net      money.Amount
+	}
+	err = db.Raw(`
+		SELECT wallet_id, SUM(CASE WHEN direction = 'credit' THEN amount ELSE -amount END) AS net
+		FROM wallet_balance_entries
+		WHERE source_transaction_id = ?
+		GROUP BY wallet_id`, sourceTransactionID).Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	contributions := make(map[string]money.Amount, len(rows))
+	for _, row := range rows {
+		contributions[row.WalletID] = row.Net
+	}
+	return contributions, nil
+}
+
+// LockWallets reads the given wallets with FOR UPDATE, always in id order so two transactions
+// locking the same pair of wallets cannot deadlock. The rows stay locked until tx ends.
+func (wallet_repo *walletsRepository) LockWallets(ctx context.Context, tx Transaction, ids ...string) ([]model.Wallets, error) {
+	db, err := wallet_repo.getDB(ctx, tx)
+	if err != nil {
+		return nil, err
+	}
+
+	var wallets []model.Wallets
+	err = db.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("id IN ?", ids).
+		Order("id asc").
+		Find(&wallets).Error
+	if err != nil {
+		return nil, err
+	}
+	return wallets, nil
+}
+
+// AdvanceTransactionVersion records version as the newest event applied for sourceTransactionID and
+// reports false, changing nothing, when an event with the same or a newer version was applied before.
+// The upsert locks the row until tx ends, so concurrent events of one transaction are serialized.
+func (wallet_repo *walletsRepository) AdvanceTransactionVersion(ctx context.Context, tx Transaction, sourceTransactionID string, version int64) (bool, error) {
+	db, err := wallet_repo.getDB(ctx, tx)
+	if err != nil {
+		return false, err
+	}
+
+	result := db.Exec(`
+		INSERT INTO transaction_event_versions (source_transaction_id, last_version, updated_at)
+		VALUES (?, ?, NOW())
+		ON CONFLICT (source_transaction_id) DO UPDATE
+		SET last_version = EXCLUDED.last_version, updated_at = NOW()
+		WHERE transaction_event_versions.last_version < EXCLUDED.last_version`, sourceTransactionID, version)
+	if result.Error != nil {
+		return false, result.Error
+	}
+
+	return result.RowsAffected > 0, nil
+}
@@ -0,0 +1,173 @@
+package service
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"refina-wallet/internal/types/domainerr"
+	"refina-wallet/internal/utils/data"
+
+	wpb "github.com/MuhammadMiftaa/Refina-Protobuf/wallet"
+)
+
+// ErrPermissionDenied is returned when none of the caller's roles grants the permission an
+// operation requires.
+var ErrPermissionDenied = domainerr.New(domainerr.PermissionDenied, "PERMISSION_DENIED", "permission denied")
+
+type Role string
+
+const (
+	RoleUser    Role = "user"
+	RoleSupport Role = "support"
+	RoleAdmin   Role = "admin"
+)
+
+type Permission string
+
+const (
+	// PermissionOwnWallets covers reading and changing the caller's own wallets
+	PermissionOwnWallets Permission = "wallets:own"
+	// PermissionReadAnyWallet lifts the ownership check of wallet reads
+	PermissionReadAnyWallet Permission = "wallets:read_any"
+	// PermissionWriteAnyWallet lifts the ownership check of wallet updates, deletes and transfers,
+	// and lets the caller create wallets for another user
+	PermissionWriteAnyWallet    Permission = "wallets:write_any"
+	PermissionReadWalletTypes   Permission = "wallet_types:read"
+	PermissionManageWalletTypes Permission = "wallet_types:manage"
+	PermissionManageOutbox      Permission = "outbox:manage"
+)
+
+// rolePermissions grants every role its permissions; support can look at any wallet but
+// only admins can change other users' wallets or the shared wallet types.
+var rolePermissions = map[Role][]Permission{
+	RoleUser: {
+		PermissionOwnWallets,
+		PermissionReadWalletTypes,
+	},
+	RoleSupport: {
+		PermissionOwnWallets,
+		PermissionReadWalletTypes,
+		PermissionReadAnyWallet,
+	},
+	RoleAdmin: {
+		PermissionOwnWallets,
+		PermissionReadWalletTypes,
+		PermissionReadAnyWallet,
+		PermissionWriteAnyWallet,
+		PermissionManageWalletTypes,
+		PermissionManageOutbox,
+	},
+}
+
+// ParseRoles normalizes role names from token claims or metadata. Unknown names are dropped,
+// and a caller left without a known role is a plain user.
+func ParseRoles(raw []string) []Role {
+	var roles []Role
+	for _, value := range raw {
+		for _, name := range strings.Split(value, ",") {
+			role := Role(strings.ToLower(strings.TrimSpace(name)))
+			if _, ok := rolePermissions[role]; ok && !slices.Contains(roles, role) {
+				roles = append(roles, role)
+			}
+		}
+	}
+
+	if len(roles) == 0 {
+		return []Role{RoleUser}
+	}
+	return roles
+}
+
+// HasPermission reports whether any of the roles grants permission
+func HasPermission(roles []Role, permission Permission) bool {
+	for _, role := range roles {
+		if slices.Contains(rolePermissions[role], permission) {
+			return true
+		}
+	}
+	return false
+}
+
+// AccessPolicy declares the permission each operation requires. Operations are HTTP routes
+// ("GET /wallets/:id", see HTTPOperation) and gRPC full method names. Undeclared operations
+// are denied.
+type AccessPolicy struct {
+	required map[string]Permission
+}
+
+func NewAccessPolicy(required map[string]Permission) *AccessPolicy {
+	return &AccessPolicy{required: required}
+}
+
+// HTTPOperation is the policy key of a Gin route, e.g. HTTPOperation("GET", "/wallets/:id")
+func HTTPOperation(method, route string) string {
+	return method + " " + route
+}
+
+// DefaultAccessPolicy covers every route of the HTTP API and every method of the gRPC services.
+func DefaultAccessPolicy() *AccessPolicy {
+	return NewAccessPolicy(map[string]Permission{
+		HTTPOperation(http.MethodGet, "/wallets"):                                PermissionReadAnyWallet,
+		HTTPOperation(http.MethodGet, "/wallets/:id"):                            PermissionOwnWallets,
+		HTTPOperation(http.MethodGet, "/wallets/:id/balance"):                    PermissionOwnWallets,
+		HTTPOperation(http.MethodGet, "/wallets/:id/balance-entries"):            PermissionOwnWallets,
+		HTTPOperation(http.MethodGet, "/wallets/user"):                           PermissionOwnWallets,
+		HTTPOperation(http.MethodGet, "/wallets/user-by-type"):                   PermissionOwnWallets,
+		HTTPOperation(http.MethodGet, "/wallets/user-summary"):                   PermissionOwnWallets,
+		HTTPOperation(http.MethodPost, "/wallets"):                               PermissionOwnWallets,
+		HTTPOperation(http.MethodPost, "/wallets/transfers"):                     PermissionOwnWallets,
+		HTTPOperation(http.MethodPut, "/wallets/:id"):                            PermissionOwnWallets,
+		HTTPOperation(http.MethodDelete, "/wallets/:id"):                         PermissionOwnWallets,
+		HTTPOperation(http.MethodGet, "/wallet-types"):                           PermissionReadWalletTypes,
+		HTTPOperation(http.MethodGet, "/wallet-types/:id"):                       PermissionReadWalletTypes,
+		HTTPOperation(http.MethodPost, "/wallet-types"):                          PermissionManageWalletTypes,
+		HTTPOperation(http.MethodPut, "/wallet-types/:id"):                       PermissionManageWalletTypes,
+		HTTPOperation(http.MethodDelete, "/wallet-types/:id"):                    PermissionManageWalletTypes,
+		HTTPOperation(http.MethodPost, "/admin/outbox/backfill"):                 PermissionManageOutbox,
+		HTTPOperation(http.MethodPost, "/admin/outbox/replay"):                   PermissionManageOutbox,
+		HTTPOperation(http.MethodGet, "/admin/outbox/dead-letters"):              PermissionManageOutbox,
+		HTTPOperation(http.MethodGet, "/admin/outbox/dead-letters/:id"):          PermissionManageOutbox,
+		HTTPOperation(http.MethodPost, "/admin/outbox/dead-letters/:id/requeue"): PermissionManageOutbox,
+		HTTPOperation(http.MethodDelete, "/admin/outbox/dead-letters/:id"):       PermissionManageOutbox,
+
+		wpb.WalletService_GetWallets_FullMethodName:       PermissionReadAnyWallet,
+		wpb.WalletService_GetUserWallets_FullMethodName:   PermissionOwnWallets,
+		wpb.WalletService_GetWalletByID_FullMethodName:    PermissionOwnWallets,
+		wpb.WalletService_CreateWallet_FullMethodName:     PermissionOwnWallets,
+		wpb.WalletService_UpdateWallet_FullMethodName:     PermissionOwnWallets,
+		wpb.WalletService_DeleteWallet_FullMethodName:     PermissionOwnWallets,
+		wpb.WalletService_GetWalletTypes_FullMethodName:   PermissionReadWalletTypes,
+		wpb.WalletService_GetWalletSummary_FullMethodName: PermissionOwnWallets,
+
+		data.GRPC_TRANSFER_BETWEEN_WALLETS_FULL_METHOD:  PermissionOwnWallets,
+		data.GRPC_BACKFILL_WALLET_SNAPSHOTS_FULL_METHOD: PermissionManageOutbox,
+		data.GRPC_REPLAY_PUBLISHED_FULL_METHOD:          PermissionManageOutbox,
+	})
+}
+
+// Authorize checks roles against the permission operation requires and returns that permission
+// for audit logs; the error wraps ErrPermissionDenied.
+func (p *AccessPolicy) Authorize(roles []Role, operation string) (Permission, error) {
+	permission, ok := p.required[operation]
+	if !ok {
+		return "", fmt.Errorf("%w: no access rule for %s", ErrPermissionDenied, operation)
+	}
+	if !HasPermission(roles, permission) {
+		return permission, fmt.Errorf("%w: %s requires %s", ErrPermissionDenied, operation, permission)
+	}
+	return permission, nil
+}
+
+// Undeclared returns the operations the policy has no rule for, so servers can refuse to start
+// with a route that would deny every caller.
+func (p *AccessPolicy) Undeclared(operations []string) []string {
+	var missing []string
+	for _, operation := range operations {
+		if _, ok := p.required[operation]; !ok {
+			missing = append(missing, operation)
+		}
+	}
+	return missing
+}
@@ -0,0 +1,106 @@
+package service
+
+import (
+	"net/http"
+	"testing"
+
+	"refina-wallet/internal/utils/data"
+
+	wpb "github.com/MuhammadMiftaa/Refina-Protobuf/wallet"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseRoles_NormalizesAndDropsUnknown(t *testing.T) {
+	roles := ParseRoles([]string{" Admin ", "support,superuser", "admin"})
+
+	assert.Equal(t, []Role{RoleAdmin, RoleSupport}, roles)
+}
+
+func TestParseRoles_DefaultsToUser(t *testing.T) {
+	assert.Equal(t, []Role{RoleUser}, ParseRoles(nil))
+	assert.Equal(t, []Role{RoleUser}, ParseRoles([]string{"root"}))
+}
+
+func TestHasPermission_RoleGrants(t *testing.T) {
+	cases := []struct {
+		role       Role
+		permission Permission
+		want       bool
+	}{
+		{RoleUser, PermissionOwnWallets, true},
+		{RoleUser, PermissionReadAnyWallet, false},
+		{RoleUser, PermissionManageWalletTypes, false},
+		{RoleSupport, PermissionReadAnyWallet, true},
+		{RoleSupport, PermissionWriteAnyWallet, false},
+		{RoleSupport, PermissionManageOutbox, false},
+		{RoleAdmin, PermissionWriteAnyWallet, true},
+		{RoleAdmin, PermissionManageWalletTypes, true},
+		{RoleAdmin, PermissionManageOutbox, true},
+	}
+	for _, c := range cases {
+		assert.Equal(t, c.want, HasPermission([]Role{c.role}, c.permission), "%s / %s", c.role, c.permission)
+	}
+}
+
+func TestAuthorize_AllowsGrantedOperation(t *testing.T) {
+	policy := DefaultAccessPolicy()
+
+	permission, err := policy.Authorize([]Role{RoleAdmin}, HTTPOperation(http.MethodPost, "/wallet-types"))
+
+	assert.NoError(t, err)
+	assert.Equal(t, PermissionManageWalletTypes, permission)
+}
+
+func TestAuthorize_DeniesMissingPermission(t *testing.T) {
+	policy := DefaultAccessPolicy()
+
+	_, err := policy.Authorize([]Role{RoleUser}, HTTPOperation(http.MethodGet, "/wallets"))
+	assert.ErrorIs(t, err, ErrPermissionDenied)
+
+	_, err = policy.Authorize([]Role{RoleSupport}, wpb.WalletService_GetWallets_FullMethodName)
+	assert.NoError(t, err)
+
+	_, err = policy.Authorize([]Role{RoleUser, RoleSupport}, HTTPOperation(http.MethodDelete, "/wallet-types/:id"))
+	assert.ErrorIs(t, err, ErrPermissionDenied)
+}
+
+func TestAuthorize_DeniesUndeclaredOperation(t *testing.T) {
+	policy := NewAccessPolicy(map[string]Permission{})
+
+	_, err := policy.Authorize([]Role{RoleAdmin}, HTTPOperation(http.MethodGet, "/wallets"))
+
+	assert.ErrorIs(t, err, ErrPermissionDenied)
+}
+
+func TestDefaultAccessPolicy_DeclaresEveryWalletServiceMethod(t *testing.T) {
+	var methods []string
+	for _, m := range wpb.WalletService_ServiceDesc.Methods {
+		methods = append(methods, "/"+wpb.WalletService_ServiceDesc.ServiceName+"/"+m.MethodName)
+	}
+	for _, s := range wpb.WalletService_ServiceDesc.Streams {
+		methods = append(methods, "/"+wpb.WalletService_ServiceDesc.ServiceName+"/"+s.StreamName)
+	}
+
+	assert.NotEmpty(t, methods)
+	assert.Empty(t, DefaultAccessPolicy().Undeclared(methods))
+}
+
+func TestDefaultAccessPolicy_TransferRPCNeedsOwnWallets(t *testing.T) {
+	permission, err := DefaultAccessPolicy().Authorize([]Role{RoleUser}, data.GRPC_TRANSFER_BETWEEN_WALLETS_FULL_METHOD)
+
+	assert.NoError(t, err)
+	assert.Equal(t, PermissionOwnWallets, permission)
+}
+
+func TestDefaultAccessPolicy_OutboxAdminRPCsNeedManageOutbox(t *testing.T) {
+	policy := DefaultAccessPolicy()
+
+	for _, method := range []string{data.GRPC_BACKFILL_WALLET_SNAPSHOTS_FULL_METHOD, data.GRPC_REPLAY_PUBLISHED_FULL_METHOD} {
+		_, err := policy.Authorize([]Role{RoleUser, RoleSupport}, method)
+		assert.ErrorIs(t, err, ErrPermissionDenied, method)
+
+		permission, err := policy.Authorize([]Role{RoleAdmin}, method)
+		assert.NoError(t, err, method)
+		assert.Equal(t, PermissionManageOutbox, permission, method)
+	}
+}
@@ -0,0 +1,231 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"refina-wallet/config/log"
+	"refina-wallet/interface/queue"
+	"refina-wallet/internal/repository"
+	"refina-wallet/internal/types/dto"
+	"refina-wallet/internal/types/money"
+	"refina-wallet/internal/utils"
+	"refina-wallet/internal/utils/data"
+)
+
+// BalanceSyncService keeps wallet balances in step with the transactions recorded by the transaction service.
+type BalanceSyncService interface {
+	HandleTransactionEvent(ctx context.Context, msg queue.Message) error
+}
+
+type balanceSyncService struct {
+	inbox             InboxProcessor
+	walletsRepository repository.WalletsRepository
+}
+
+func NewBalanceSyncService(inbox InboxProcessor, walletsRepository repository.WalletsRepository) BalanceSyncService {
+	return &balanceSyncService{
+		inbox:             inbox,
+		walletsRepository: walletsRepository,
+	}
+}
+
+// HandleTransactionEvent reconciles the ledger with one transaction event. Rather than applying the
+// event amount blindly, it moves every wallet from what the transaction has contributed so far to
+// what it should contribute now, so created/updated/deleted events (and the initial deposit already
+// booked by CreateWallet) converge to the same balances. Deleting a transfer's cash-out also reverses
+// its admin fee entry. An event older than the newest one applied for its transaction is ignored, so
+// a late update cannot undo a delete. Malformed events are rejected permanently.
+func (sync_serv *balanceSyncService) HandleTransactionEvent(ctx context.Context, msg queue.Message) error {
+	event, version, err := decodeTransactionEvent(msg.Body)
+	if err != nil {
+		return fmt.Errorf("handle transaction event: %w: %v", queue.ErrRejectMessage, err)
+	}
+
+	target, known, err := transactionContribution(msg.Type, event)
+	if err != nil {
+		return fmt.Errorf("handle transaction event: %w: %v", queue.ErrRejectMessage, err)
+	}
+	if !known {
+		log.Info(data.LogTransactionEventSkipped, map[string]any{
+			"service":        data.BalanceSyncService,
+			"message_id":     msg.ID,
+			"type":           msg.Type,
+			"transaction_id": event.ID,
+			"category_type":  event.CategoryType,
+		})
+		return nil
+	}
+
+	return sync_serv.inbox.Process(ctx, data.TRANSACTION_EVENT_SOURCE, msg, func(ctx context.Context, tx repository.Transaction, msg queue.Message) error {
+		// Event tanpa versi (body polos) tidak bisa diurutkan dan selalu diterapkan
+		if version > 0 {
+			newer, err := sync_serv.walletsRepository.AdvanceTransactionVersion(ctx, tx, event.ID, version)
+			if err != nil {
+				return fmt.Errorf("handle transaction event: advance version: %w", err)
+			}
+			if !newer {
+				log.Info(data.LogTransactionEventStale, map[string]any{
+					"service":        data.BalanceSyncService,
+					"message_id":     msg.ID,
+					"type":           msg.Type,
+					"transaction_id": event.ID,
+					"version":        version,
+				})
+				return nil
+			}
+		}
+
+		if err := sync_serv.applyContribution(ctx, tx, msg, event.ID, target); err != nil {
+			return err
+		}
+		if msg.Type == data.TRANSACTION_EVENT_DELETED {
+			// Biaya admin transfer punya source sendiri; ikut dibatalkan saat transaksi cash-out dihapus
+			feeSourceID := event.ID + data.TRANSFER_FEE_SOURCE_SUFFIX
+			if err := sync_serv.applyContribution(ctx, tx, msg, feeSourceID, map[string]money.Amount{}); err != nil {
+				return err
+			}
+		}
+
+		log.Info(data.LogTransactionEventApplied, map[string]any{
+			"service":        data.BalanceSyncService,
+			"message_id":     msg.ID,
+			"type":           msg.Type,
+			"transaction_id": event.ID,
+		})
+		return nil
+	})
+}
+
+// applyContribution appends the ledger entries that move each wallet from its current contribution
+// of transactionID to target.
+func (sync_serv *balanceSyncService) applyContribution(ctx context.Context, tx repository.Transaction, msg queue.Message, transactionID string, target map[string]money.Amount) error {
+	current, err := sync_serv.walletsRepository.GetTransactionContributions(ctx, tx, transactionID)
+	if err != nil {
+		return fmt.Errorf("handle transaction event: get contributions: %w", err)
+	}
+
+	// Urutkan wallet agar lock baris wallet selalu diambil dengan urutan yang sama (hindari deadlock)
+	walletIDs := make([]string, 0, len(target)+len(current))
+	for walletID := range current {
+		walletIDs = append(walletIDs, walletID)
+	}
+	for walletID := range target {
+		if _, ok := current[walletID]; !ok {
+			walletIDs = append(walletIDs, walletID)
+		}
+	}
+	sort.Strings(walletIDs)
+
+	for _, walletID := range walletIDs {
+		id, err := utils.ParseUUID(walletID)
+		if err != nil {
+			return fmt.Errorf("handle transaction event: %w: invalid wallet id: %v", queue.ErrRejectMessage, err)
+		}
+
+		entry, ok := newBalanceEntry(id, target[walletID]-current[walletID], data.BALANCE_REASON_TRANSACTION, transactionID)
+		if !ok {
+			continue
+		}
+
+		if _, err := sync_serv.walletsRepository.AppendBalanceEntry(ctx, tx, entry); err != nil {
+			if errors.Is(err, repository.ErrWalletNotFound) {
+				// Wallet sudah dihapus; tidak ada saldo yang perlu disesuaikan
+				log.Warn(data.LogTransactionEventWalletAbsent, map[string]any{
+					"service":        data.BalanceSyncService,
+					"message_id":     msg.ID,
+					"transaction_id": transactionID,
+					"wallet_id":      walletID,
+				})
+				continue
+			}
+			return fmt.Errorf("handle transaction event: append balance entry: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// decodeTransactionEvent accepts both a CloudEvents envelope and a bare transaction body. version
+// orders the events of one transaction: the envelope sequence, else its time in microseconds, else 0
+// for a bare body.
+func decodeTransactionEvent(body []byte) (dto.TransactionEvent, int64, error) {
+	var envelope dto.CloudEvent
+	if err := json.Unmarshal(body, &envelope); err != nil {
+		return dto.TransactionEvent{}, 0, fmt.Errorf("decode body: %w", err)
+	}
+
+	var version int64
+	if envelope.SpecVersion != "" && len(envelope.Data) > 0 {
+		body = envelope.Data
+
+		v, err := envelopeVersion(envelope)
+		if err != nil {
+			return dto.TransactionEvent{}, 0, err
+		}
+		version = v
+	}
+
+	var event dto.TransactionEvent
+	if err := json.Unmarshal(body, &event); err != nil {
+		return dto.TransactionEvent{}, 0, fmt.Errorf("decode transaction: %w", err)
+	}
+	if event.ID == "" {
+		return dto.TransactionEvent{}, 0, errors.New("transaction id is empty")
+	}
+
+	return event, version, nil
+}
+
+func envelopeVersion(envelope dto.CloudEvent) (int64, error) {
+	if envelope.Sequence != "" {
+		sequence, err := strconv.ParseInt(envelope.Sequence, 10, 64)
+		if err != nil || sequence <= 0 {
+			return 0, fmt.Errorf("invalid sequence %q", envelope.Sequence)
+		}
+		return sequence, nil
+	}
+
+	if envelope.Time != "" {
+		eventTime, err := time.Parse(time.RFC3339Nano, envelope.Time)
+		if err != nil {
+			return 0, fmt.Errorf("invalid time %q: %w", envelope.Time, err)
+		}
+		return eventTime.UnixMicro(), nil
+	}
+
+	return 0, nil
+}
+
+// transactionContribution returns the signed amount per wallet the transaction should contribute
+// after eventType. known is false for transactions that do not move a wallet balance by themselves.
+func transactionContribution(eventType string, event dto.TransactionEvent) (map[string]money.Amount, bool, error) {
+	switch eventType {
+	case data.TRANSACTION_EVENT_DELETED:
+		return map[string]money.Amount{}, true, nil
+	case data.TRANSACTION_EVENT_CREATED, data.TRANSACTION_EVENT_UPDATED:
+	default:
+		return nil, false, fmt.Errorf("unsupported event type %q", eventType)
+	}
+
+	if event.WalletID == "" {
+		return nil, false, errors.New("wallet id is empty")
+	}
+	if event.Amount < 0 {
+		return nil, false, errors.New("amount is negative")
+	}
+
+	switch event.CategoryType {
+	case data.TRANSACTION_CATEGORY_INCOME:
+		return map[string]money.Amount{event.WalletID: event.Amount}, true, nil
+	case data.TRANSACTION_CATEGORY_EXPENSE:
+		return map[string]money.Amount{event.WalletID: -event.Amount}, true, nil
+	default:
+		return nil, false, nil
+	}
+}
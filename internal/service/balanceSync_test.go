@@ -0,0 +1,389 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"refina-wallet/interface/queue"
+	"refina-wallet/internal/repository"
+	"refina-wallet/internal/service/mocks"
+	"refina-wallet/internal/types/model"
+	"refina-wallet/internal/types/money"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// ---------- helpers ----------
+
+type balanceSyncTestDeps struct {
+	txManager   *mocks.MockTxManager
+	walletsRepo *mocks.MockWalletsRepository
+	inboxRepo   *mocks.MockInboxRepository
+	tx          *mocks.MockTransaction
+}
+
+func newBalanceSyncTestDeps() *balanceSyncTestDeps {
+	return &balanceSyncTestDeps{
+		txManager:   new(mocks.MockTxManager),
+		walletsRepo: new(mocks.MockWalletsRepository),
+		inboxRepo:   new(mocks.MockInboxRepository),
+		tx:          new(mocks.MockTransaction),
+	}
+}
+
+func (d *balanceSyncTestDeps) service() BalanceSyncService {
+	return NewBalanceSyncService(NewInboxProcessor(d.txManager, d.inboxRepo), d.walletsRepo)
+}
+
+func (d *balanceSyncTestDeps) assertAll(t *testing.T) {
+	d.txManager.AssertExpectations(t)
+	d.walletsRepo.AssertExpectations(t)
+	d.inboxRepo.AssertExpectations(t)
+	d.tx.AssertExpectations(t)
+}
+
+const otherWalletID = "dddddddd-dddd-dddd-dddd-dddddddddddd"
+
+func transactionMessage(eventType, body string) queue.Message {
+	return queue.Message{ID: "msg-1", Type: eventType, Body: []byte(body)}
+}
+
+// =====================================================================
+// HandleTransactionEvent
+// =====================================================================
+
+func TestHandleTransactionEvent_CreatedExpenseDebitsWallet(t *testing.T) {
+	d := newBalanceSyncTestDeps()
+	svc := d.service()
+
+	msg := transactionMessage("transaction.created",
+		`{"specversion":"1.0","type":"transaction.created","data":{"id":"trx-1","wallet_id":"`+walletID.String()+`","amount":25000,"category_type":"expense"}}`)
+
+	d.txManager.On("Begin", mock.Anything).Return(d.tx, nil)
+	d.inboxRepo.On("MarkHandled", mock.Anything, d.tx, mock.MatchedBy(func(m *model.InboxMessage) bool {
+		return m.MessageID == "msg-1" && m.Source == "/refina/transaction" && m.EventType == "transaction.created"
+	})).Return(true, nil)
+	d.walletsRepo.On("GetTransactionContributions", mock.Anything, d.tx, "trx-1").Return(map[string]money.Amount{}, nil)
+	d.walletsRepo.On("AppendBalanceEntry", mock.Anything, d.tx, mock.MatchedBy(func(e model.WalletBalanceEntries) bool {
+		return e.WalletID == walletID && e.Amount == money.MustParse("25000") && e.Direction == model.Debit &&
+			e.Reason == "transaction" && *e.SourceTransactionID == "trx-1"
+	})).Return(model.WalletBalanceEntries{BalanceAfter: money.MustParse("75000")}, nil)
+	d.tx.On("Commit").Return(nil)
+	d.tx.On("Rollback").Return(nil)
+
+	err := svc.HandleTransactionEvent(context.Background(), msg)
+
+	assert.NoError(t, err)
+	d.assertAll(t)
+}
+
+func TestHandleTransactionEvent_DecimalStringAmountIsExact(t *testing.T) {
+	d := newBalanceSyncTestDeps()
+	svc := d.service()
+
+	msg := transactionMessage("transaction.created",
+		`{"id":"trx-1","wallet_id":"`+walletID.String()+`","amount":"0.29","category_type":"income"}`)
+
+	d.txManager.On("Begin", mock.Anything).Return(d.tx, nil)
+	d.inboxRepo.On("MarkHandled", mock.Anything, d.tx, mock.Anything).Return(true, nil)
+	d.walletsRepo.On("GetTransactionContributions", mock.Anything, d.tx, "trx-1").Return(map[string]money.Amount{}, nil)
+	d.walletsRepo.On("AppendBalanceEntry", mock.Anything, d.tx, mock.MatchedBy(func(e model.WalletBalanceEntries) bool {
+		return e.Amount == money.FromMinor(29) && e.Direction == model.Credit
+	})).Return(model.WalletBalanceEntries{BalanceAfter: money.FromMinor(29)}, nil)
+	d.tx.On("Commit").Return(nil)
+	d.tx.On("Rollback").Return(nil)
+
+	err := svc.HandleTransactionEvent(context.Background(), msg)
+
+	assert.NoError(t, err)
+	d.assertAll(t)
+}
+
+func TestHandleTransactionEvent_UpdatedMovesToAnotherWallet(t *testing.T) {
+	d := newBalanceSyncTestDeps()
+	svc := d.service()
+
+	msg := transactionMessage("transaction.updated",
+		`{"id":"trx-1","wallet_id":"`+otherWalletID+`","amount":30000,"category_type":"income"}`)
+
+	d.txManager.On("Begin", mock.Anything).Return(d.tx, nil)
+	d.inboxRepo.On("MarkHandled", mock.Anything, d.tx, mock.Anything).Return(true, nil)
+	d.walletsRepo.On("GetTransactionContributions", mock.Anything, d.tx, "trx-1").
+		Return(map[string]money.Amount{walletID.String(): money.MustParse("20000")}, nil)
+	d.walletsRepo.On("AppendBalanceEntry", mock.Anything, d.tx, mock.MatchedBy(func(e model.WalletBalanceEntries) bool {
+		return e.WalletID == walletID && e.Amount == money.MustParse("20000") && e.Direction == model.Debit
+	})).Return(model.WalletBalanceEntries{}, nil).Once()
+	d.walletsRepo.On("AppendBalanceEntry", mock.Anything, d.tx, mock.MatchedBy(func(e model.WalletBalanceEntries) bool {
+		return e.WalletID.String() == otherWalletID && e.Amount == money.MustParse("30000") && e.Direction == model.Credit
+	})).Return(model.WalletBalanceEntries{}, nil).Once()
+	d.tx.On("Commit").Return(nil)
+	d.tx.On("Rollback").Return(nil)
+
+	err := svc.HandleTransactionEvent(context.Background(), msg)
+
+	assert.NoError(t, err)
+	d.assertAll(t)
+}
+
+func TestHandleTransactionEvent_AlreadyBookedIsNoop(t *testing.T) {
+	d := newBalanceSyncTestDeps()
+	svc := d.service()
+
+	// Initial deposit already booked by CreateWallet
+	msg := transactionMessage("transaction.created",
+		`{"id":"trx-1","wallet_id":"`+walletID.String()+`","amount":100000,"category_type":"income"}`)
+
+	d.txManager.On("Begin", mock.Anything).Return(d.tx, nil)
+	d.inboxRepo.On("MarkHandled", mock.Anything, d.tx, mock.Anything).Return(true, nil)
+	d.walletsRepo.On("GetTransactionContributions", mock.Anything, d.tx, "trx-1").
+		Return(map[string]money.Amount{walletID.String(): money.MustParse("100000")}, nil)
+	d.tx.On("Commit").Return(nil)
+	d.tx.On("Rollback").Return(nil)
+
+	err := svc.HandleTransactionEvent(context.Background(), msg)
+
+	assert.NoError(t, err)
+	d.walletsRepo.AssertNotCalled(t, "AppendBalanceEntry", mock.Anything, mock.Anything, mock.Anything)
+	d.assertAll(t)
+}
+
+func TestHandleTransactionEvent_DeletedReversesContribution(t *testing.T) {
+	d := newBalanceSyncTestDeps()
+	svc := d.service()
+
+	msg := transactionMessage("transaction.deleted", `{"id":"trx-1"}`)
+
+	d.txManager.On("Begin", mock.Anything).Return(d.tx, nil)
+	d.inboxRepo.On("MarkHandled", mock.Anything, d.tx, mock.Anything).Return(true, nil)
+	d.walletsRepo.On("GetTransactionContributions", mock.Anything, d.tx, "trx-1").
+		Return(map[string]money.Amount{walletID.String(): money.MustParse("-15000")}, nil)
+	d.walletsRepo.On("AppendBalanceEntry", mock.Anything, d.tx, mock.MatchedBy(func(e model.WalletBalanceEntries) bool {
+		return e.Amount == money.MustParse("15000") && e.Direction == model.Credit
+	})).Return(model.WalletBalanceEntries{}, nil)
+	d.walletsRepo.On("GetTransactionContributions", mock.Anything, d.tx, "trx-1:admin_fee").Return(map[string]money.Amount{}, nil)
+	d.tx.On("Commit").Return(nil)
+	d.tx.On("Rollback").Return(nil)
+
+	err := svc.HandleTransactionEvent(context.Background(), msg)
+
+	assert.NoError(t, err)
+	d.assertAll(t)
+}
+
+func TestHandleTransactionEvent_DeletedCashOutReversesTransferFee(t *testing.T) {
+	d := newBalanceSyncTestDeps()
+	svc := d.service()
+
+	msg := transactionMessage("transaction.deleted", `{"id":"trx-out"}`)
+
+	d.txManager.On("Begin", mock.Anything).Return(d.tx, nil)
+	d.inboxRepo.On("MarkHandled", mock.Anything, d.tx, mock.Anything).Return(true, nil)
+	d.walletsRepo.On("GetTransactionContributions", mock.Anything, d.tx, "trx-out").
+		Return(map[string]money.Amount{walletID.String(): money.MustParse("-50000")}, nil)
+	d.walletsRepo.On("GetTransactionContributions", mock.Anything, d.tx, "trx-out:admin_fee").
+		Return(map[string]money.Amount{walletID.String(): money.MustParse("-2500")}, nil)
+	d.walletsRepo.On("AppendBalanceEntry", mock.Anything, d.tx, mock.MatchedBy(func(e model.WalletBalanceEntries) bool {
+		return e.SourceTransactionID != nil && *e.SourceTransactionID == "trx-out" && e.Amount == money.MustParse("50000") && e.Direction == model.Credit
+	})).Return(model.WalletBalanceEntries{}, nil)
+	d.walletsRepo.On("AppendBalanceEntry", mock.Anything, d.tx, mock.MatchedBy(func(e model.WalletBalanceEntries) bool {
+		return e.SourceTransactionID != nil && *e.SourceTransactionID == "trx-out:admin_fee" && e.Amount == money.MustParse("2500") && e.Direction == model.Credit
+	})).Return(model.WalletBalanceEntries{}, nil)
+	d.tx.On("Commit").Return(nil)
+	d.tx.On("Rollback").Return(nil)
+
+	err := svc.HandleTransactionEvent(context.Background(), msg)
+
+	assert.NoError(t, err)
+	d.assertAll(t)
+}
+
+func TestHandleTransactionEvent_DuplicateMessageSkipped(t *testing.T) {
+	d := newBalanceSyncTestDeps()
+	svc := d.service()
+
+	msg := transactionMessage("transaction.created",
+		`{"id":"trx-1","wallet_id":"`+walletID.String()+`","amount":100,"category_type":"income"}`)
+
+	d.txManager.On("Begin", mock.Anything).Return(d.tx, nil)
+	d.inboxRepo.On("MarkHandled", mock.Anything, d.tx, mock.Anything).Return(false, nil)
+	d.tx.On("Rollback").Return(nil)
+
+	err := svc.HandleTransactionEvent(context.Background(), msg)
+
+	assert.NoError(t, err)
+	d.walletsRepo.AssertNotCalled(t, "GetTransactionContributions", mock.Anything, mock.Anything, mock.Anything)
+	d.tx.AssertNotCalled(t, "Commit")
+	d.assertAll(t)
+}
+
+func TestHandleTransactionEvent_NewerSequenceApplied(t *testing.T) {
+	d := newBalanceSyncTestDeps()
+	svc := d.service()
+
+	msg := transactionMessage("transaction.updated",
+		`{"specversion":"1.0","sequence":"4","data":{"id":"trx-1","wallet_id":"`+walletID.String()+`","amount":300,"category_type":"income"}}`)
+
+	d.txManager.On("Begin", mock.Anything).Return(d.tx, nil)
+	d.inboxRepo.On("MarkHandled", mock.Anything, d.tx, mock.Anything).Return(true, nil)
+	d.walletsRepo.On("AdvanceTransactionVersion", mock.Anything, d.tx, "trx-1", int64(4)).Return(true, nil)
+	d.walletsRepo.On("GetTransactionContributions", mock.Anything, d.tx, "trx-1").
+		Return(map[string]money.Amount{walletID.String(): money.MustParse("100")}, nil)
+	d.walletsRepo.On("AppendBalanceEntry", mock.Anything, d.tx, mock.MatchedBy(func(e model.WalletBalanceEntries) bool {
+		return e.Amount == money.MustParse("200") && e.Direction == model.Credit
+	})).Return(model.WalletBalanceEntries{}, nil)
+	d.tx.On("Commit").Return(nil)
+	d.tx.On("Rollback").Return(nil)
+
+	err := svc.HandleTransactionEvent(context.Background(), msg)
+
+	assert.NoError(t, err)
+	d.assertAll(t)
+}
+
+func TestHandleTransactionEvent_StaleEventIgnored(t *testing.T) {
+	d := newBalanceSyncTestDeps()
+	svc := d.service()
+
+	// An update overtaken by the delete of the same transaction must not book the amount again
+	msg := transactionMessage("transaction.updated",
+		`{"specversion":"1.0","sequence":"2","data":{"id":"trx-1","wallet_id":"`+walletID.String()+`","amount":300,"category_type":"income"}}`)
+
+	d.txManager.On("Begin", mock.Anything).Return(d.tx, nil)
+	d.inboxRepo.On("MarkHandled", mock.Anything, d.tx, mock.Anything).Return(true, nil)
+	d.walletsRepo.On("AdvanceTransactionVersion", mock.Anything, d.tx, "trx-1", int64(2)).Return(false, nil)
+	d.tx.On("Commit").Return(nil)
+	d.tx.On("Rollback").Return(nil)
+
+	err := svc.HandleTransactionEvent(context.Background(), msg)
+
+	assert.NoError(t, err)
+	d.walletsRepo.AssertNotCalled(t, "GetTransactionContributions", mock.Anything, mock.Anything, mock.Anything)
+	d.walletsRepo.AssertNotCalled(t, "AppendBalanceEntry", mock.Anything, mock.Anything, mock.Anything)
+	d.assertAll(t)
+}
+
+func TestHandleTransactionEvent_TimeOrdersEventsWithoutSequence(t *testing.T) {
+	d := newBalanceSyncTestDeps()
+	svc := d.service()
+
+	eventTime := time.Date(2026, 10, 17, 8, 30, 0, 123456000, time.UTC)
+	msg := transactionMessage("transaction.deleted",
+		`{"specversion":"1.0","time":"`+eventTime.Format(time.RFC3339Nano)+`","data":{"id":"trx-1"}}`)
+
+	d.txManager.On("Begin", mock.Anything).Return(d.tx, nil)
+	d.inboxRepo.On("MarkHandled", mock.Anything, d.tx, mock.Anything).Return(true, nil)
+	d.walletsRepo.On("AdvanceTransactionVersion", mock.Anything, d.tx, "trx-1", eventTime.UnixMicro()).Return(true, nil)
+	d.walletsRepo.On("GetTransactionContributions", mock.Anything, d.tx, "trx-1").Return(map[string]money.Amount{}, nil)
+	d.walletsRepo.On("GetTransactionContributions", mock.Anything, d.tx, "trx-1:admin_fee").Return(map[string]money.Amount{}, nil)
+	d.tx.On("Commit").Return(nil)
+	d.tx.On("Rollback").Return(nil)
+
+	err := svc.HandleTransactionEvent(context.Background(), msg)
+
+	assert.NoError(t, err)
+	d.assertAll(t)
+}
+
+func TestHandleTransactionEvent_AdvanceVersionErrorIsRetried(t *testing.T) {
+	d := newBalanceSyncTestDeps()
+	svc := d.service()
+
+	msg := transactionMessage("transaction.deleted", `{"specversion":"1.0","sequence":"3","data":{"id":"trx-1"}}`)
+
+	d.txManager.On("Begin", mock.Anything).Return(d.tx, nil)
+	d.inboxRepo.On("MarkHandled", mock.Anything, d.tx, mock.Anything).Return(true, nil)
+	d.walletsRepo.On("AdvanceTransactionVersion", mock.Anything, d.tx, "trx-1", int64(3)).Return(false, errors.New("connection reset"))
+	d.tx.On("Rollback").Return(nil)
+
+	err := svc.HandleTransactionEvent(context.Background(), msg)
+
+	assert.Error(t, err)
+	assert.NotErrorIs(t, err, queue.ErrRejectMessage)
+	d.tx.AssertNotCalled(t, "Commit")
+	d.assertAll(t)
+}
+
+func TestHandleTransactionEvent_InvalidSequenceRejected(t *testing.T) {
+	d := newBalanceSyncTestDeps()
+	svc := d.service()
+
+	msg := transactionMessage("transaction.deleted", `{"specversion":"1.0","sequence":"abc","data":{"id":"trx-1"}}`)
+
+	err := svc.HandleTransactionEvent(context.Background(), msg)
+
+	assert.ErrorIs(t, err, queue.ErrRejectMessage)
+	d.txManager.AssertNotCalled(t, "Begin", mock.Anything)
+	d.assertAll(t)
+}
+
+func TestHandleTransactionEvent_MalformedBodyRejected(t *testing.T) {
+	d := newBalanceSyncTestDeps()
+	svc := d.service()
+
+	err := svc.HandleTransactionEvent(context.Background(), transactionMessage("transaction.created", `not-json`))
+
+	assert.ErrorIs(t, err, queue.ErrRejectMessage)
+	d.txManager.AssertNotCalled(t, "Begin", mock.Anything)
+	d.assertAll(t)
+}
+
+func TestHandleTransactionEvent_UnknownCategorySkipped(t *testing.T) {
+	d := newBalanceSyncTestDeps()
+	svc := d.service()
+
+	msg := transactionMessage("transaction.created",
+		`{"id":"trx-1","wallet_id":"`+walletID.String()+`","amount":100,"category_type":"fund_transfer"}`)
+
+	err := svc.HandleTransactionEvent(context.Background(), msg)
+
+	assert.NoError(t, err)
+	d.txManager.AssertNotCalled(t, "Begin", mock.Anything)
+	d.assertAll(t)
+}
+
+func TestHandleTransactionEvent_DeletedWalletIgnored(t *testing.T) {
+	d := newBalanceSyncTestDeps()
+	svc := d.service()
+
+	msg := transactionMessage("transaction.created",
+		`{"id":"trx-1","wallet_id":"`+walletID.String()+`","amount":100,"category_type":"income"}`)
+
+	d.txManager.On("Begin", mock.Anything).Return(d.tx, nil)
+	d.inboxRepo.On("MarkHandled", mock.Anything, d.tx, mock.Anything).Return(true, nil)
+	d.walletsRepo.On("GetTransactionContributions", mock.Anything, d.tx, "trx-1").Return(map[string]money.Amount{}, nil)
+	d.walletsRepo.On("AppendBalanceEntry", mock.Anything, d.tx, mock.Anything).
+		Return(model.WalletBalanceEntries{}, repository.ErrWalletNotFound)
+	d.tx.On("Commit").Return(nil)
+	d.tx.On("Rollback").Return(nil)
+
+	err := svc.HandleTransactionEvent(context.Background(), msg)
+
+	assert.NoError(t, err)
+	d.assertAll(t)
+}
+
+func TestHandleTransactionEvent_AppendErrorIsRetried(t *testing.T) {
+	d := newBalanceSyncTestDeps()
+	svc := d.service()
+
+	msg := transactionMessage("transaction.created",
+		`{"id":"trx-1","wallet_id":"`+walletID.String()+`","amount":100,"category_type":"income"}`)
+
+	d.txManager.On("Begin", mock.Anything).Return(d.tx, nil)
+	d.inboxRepo.On("MarkHandled", mock.Anything, d.tx, mock.Anything).Return(true, nil)
+	d.walletsRepo.On("GetTransactionContributions", mock.Anything, d.tx, "trx-1").Return(map[string]money.Amount{}, nil)
+	d.walletsRepo.On("AppendBalanceEntry", mock.Anything, d.tx, mock.Anything).
+		Return(model.WalletBalanceEntries{}, errors.New("connection reset"))
+	d.tx.On("Rollback").Return(nil)
+
+	err := svc.HandleTransactionEvent(context.Background(), msg)
+
+	assert.Error(t, err)
+	assert.NotErrorIs(t, err, queue.ErrRejectMessage)
+	assert.Contains(t, err.Error(), "append balance entry")
+	d.tx.AssertNotCalled(t, "Commit")
+	d.assertAll(t)
+}
@@ -0,0 +1,117 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+
+	"refina-wallet/config/env"
+	"refina-wallet/internal/types/domainerr"
+	"refina-wallet/internal/utils"
+	"refina-wallet/internal/utils/data"
+)
+
+// ErrExchangeRateUnavailable is returned when a provider has no rate for a currency pair.
+var ErrExchangeRateUnavailable = domainerr.New(domainerr.FailedPrecondition, "EXCHANGE_RATE_UNAVAILABLE", "exchange rate unavailable")
+
+// ExchangeRateProvider quotes currency conversions for wallet summaries. Implementations may
+// be backed by a static table, a file or a remote rates API.
+type ExchangeRateProvider interface {
+	// BaseCurrency is the currency totals are converted to when the caller names none.
+	BaseCurrency() string
+	// Rate returns how many units of to one unit of from is worth.
+	Rate(ctx context.Context, from, to string) (float64, error)
+}
+
+// StaticExchangeRateProvider quotes from a fixed table of rates against its base currency.
+type StaticExchangeRateProvider struct {
+	base  string
+	rates map[string]float64
+}
+
+// NewStaticExchangeRateProvider builds a provider from rates expressed as units of base per
+// one unit of each currency, e.g. {"USD": 16250} for base IDR.
+func NewStaticExchangeRateProvider(base string, rates map[string]float64) (*StaticExchangeRateProvider, error) {
+	base, err := utils.NormalizeCurrency(base)
+	if err != nil {
+		return nil, fmt.Errorf("exchange rate base: %w", err)
+	}
+
+	table := map[string]float64{base: 1}
+	for currency, rate := range rates {
+		code, err := utils.NormalizeCurrency(currency)
+		if err != nil {
+			return nil, fmt.Errorf("exchange rate %q: %w", currency, err)
+		}
+		if rate <= 0 || math.IsInf(rate, 0) || math.IsNaN(rate) {
+			return nil, fmt.Errorf("exchange rate %s: must be a positive number, got %v", code, rate)
+		}
+		if code != base {
+			table[code] = rate
+		}
+	}
+
+	return &StaticExchangeRateProvider{base: base, rates: table}, nil
+}
+
+// exchangeRatesFile is the layout of EXCHANGE_RATES_FILE
+type exchangeRatesFile struct {
+	Base  string             `json:"base"`
+	Rates map[string]float64 `json:"rates"`
+}
+
+// NewFileExchangeRateProvider loads a static rates table from a JSON file of the form
+// {"base": "IDR", "rates": {"USD": 16250}}. defaultBase is used when the file omits base.
+func NewFileExchangeRateProvider(path, defaultBase string) (*StaticExchangeRateProvider, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read exchange rates file: %w", err)
+	}
+
+	var file exchangeRatesFile
+	if err := json.Unmarshal(raw, &file); err != nil {
+		return nil, fmt.Errorf("decode exchange rates file %s: %w", path, err)
+	}
+	if file.Base == "" {
+		file.Base = defaultBase
+	}
+
+	return NewStaticExchangeRateProvider(file.Base, file.Rates)
+}
+
+// NewExchangeRateProvider builds the provider described by cfg. Without a rates file only the
+// base currency is known, so summaries of wallets in other currencies fail until one is set.
+func NewExchangeRateProvider(cfg env.ExchangeRate) (ExchangeRateProvider, error) {
+	base := cfg.BaseCurrency
+	if base == "" {
+		base = data.DEFAULT_CURRENCY
+	}
+
+	if cfg.RatesFile != "" {
+		return NewFileExchangeRateProvider(cfg.RatesFile, base)
+	}
+	return NewStaticExchangeRateProvider(base, nil)
+}
+
+func (p *StaticExchangeRateProvider) BaseCurrency() string {
+	return p.base
+}
+
+func (p *StaticExchangeRateProvider) Rate(ctx context.Context, from, to string) (float64, error) {
+	if from == to {
+		return 1, nil
+	}
+
+	fromRate, ok := p.rates[from]
+	if !ok {
+		return 0, fmt.Errorf("%w: %s to %s", ErrExchangeRateUnavailable, from, to)
+	}
+	toRate, ok := p.rates[to]
+	if !ok {
+		return 0, fmt.Errorf("%w: %s to %s", ErrExchangeRateUnavailable, from, to)
+	}
+
+	return fromRate / toRate, nil
+}
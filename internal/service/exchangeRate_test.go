@@ -0,0 +1,76 @@
+package service
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"refina-wallet/config/env"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStaticExchangeRateProvider_CrossRates(t *testing.T) {
+	rates, err := NewStaticExchangeRateProvider("idr", map[string]float64{"usd": 16000, "EUR": 17600})
+	assert.NoError(t, err)
+
+	cases := []struct {
+		from, to string
+		want     float64
+	}{
+		{"IDR", "IDR", 1},
+		{"USD", "IDR", 16000},
+		{"IDR", "USD", 1.0 / 16000},
+		{"EUR", "USD", 1.1},
+	}
+	for _, c := range cases {
+		got, err := rates.Rate(context.Background(), c.from, c.to)
+		assert.NoError(t, err)
+		assert.InDelta(t, c.want, got, 1e-12, "%s to %s", c.from, c.to)
+	}
+
+	assert.Equal(t, "IDR", rates.BaseCurrency())
+}
+
+func TestStaticExchangeRateProvider_UnknownCurrency(t *testing.T) {
+	rates, _ := NewStaticExchangeRateProvider("IDR", nil)
+
+	_, err := rates.Rate(context.Background(), "USD", "IDR")
+
+	assert.ErrorIs(t, err, ErrExchangeRateUnavailable)
+}
+
+func TestStaticExchangeRateProvider_RejectsInvalidRates(t *testing.T) {
+	_, err := NewStaticExchangeRateProvider("IDR", map[string]float64{"USD": 0})
+	assert.Error(t, err)
+
+	_, err = NewStaticExchangeRateProvider("IDR", map[string]float64{"DOLLAR": 16000})
+	assert.Error(t, err)
+}
+
+func TestNewExchangeRateProvider_FromFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rates.json")
+	assert.NoError(t, os.WriteFile(path, []byte(`{"rates": {"USD": 16000}}`), 0o600))
+
+	rates, err := NewExchangeRateProvider(env.ExchangeRate{BaseCurrency: "IDR", RatesFile: path})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "IDR", rates.BaseCurrency())
+	rate, err := rates.Rate(context.Background(), "USD", "IDR")
+	assert.NoError(t, err)
+	assert.Equal(t, float64(16000), rate)
+}
+
+func TestNewExchangeRateProvider_MissingFile(t *testing.T) {
+	_, err := NewExchangeRateProvider(env.ExchangeRate{RatesFile: filepath.Join(t.TempDir(), "missing.json")})
+
+	assert.Error(t, err)
+}
+
+func TestNewExchangeRateProvider_DefaultsToBaseOnly(t *testing.T) {
+	rates, err := NewExchangeRateProvider(env.ExchangeRate{})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "IDR", rates.BaseCurrency())
+}
@@ -0,0 +1,211 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"refina-wallet/config/log"
+	"refina-wallet/internal/repository"
+	"refina-wallet/internal/types/domainerr"
+	"refina-wallet/internal/types/model"
+	"refina-wallet/internal/utils"
+	"refina-wallet/internal/utils/data"
+)
+
+var (
+	// ErrIdempotencyKeyReused is returned when a key comes back with a different request.
+	ErrIdempotencyKeyReused = domainerr.New(domainerr.FailedPrecondition, "IDEMPOTENCY_KEY_REUSED", "idempotency key reused with a different request")
+	// ErrIdempotencyInProgress is returned while the first request of a key is still running.
+	ErrIdempotencyInProgress = domainerr.New(domainerr.Conflict, "IDEMPOTENCY_IN_PROGRESS", "idempotency key request still in progress")
+	ErrInvalidIdempotencyKey = domainerr.New(domainerr.InvalidArgument, "INVALID_IDEMPOTENCY_KEY", "invalid idempotency key")
+)
+
+// IdempotencyRequest identifies one logical client request. Key is scoped to Operation and
+// UserID; Body is hashed to recognise a key reused for something else.
+type IdempotencyRequest struct {
+	Key       string
+	Operation string
+	UserID    string
+	Body      any
+}
+
+// IdempotencyService runs a mutation at most once per idempotency key and replays its
+// response to retries within data.IDEMPOTENCY_KEY_TTL.
+type IdempotencyService interface {
+	// Execute runs fn, or decodes the stored response of an earlier run into out. replayed
+	// reports the latter. An empty key runs fn without any bookkeeping.
+	Execute(ctx context.Context, req IdempotencyRequest, out any, fn func(ctx context.Context) (any, error)) (replayed bool, err error)
+	StartCleanupJob(ctx context.Context)
+}
+
+type idempotencyService struct {
+	idempotencyRepository repository.IdempotencyRepository
+	ttl                   time.Duration
+	inProgressTTL         time.Duration
+	cleanupInterval       time.Duration
+	cleanupBatch          int
+}
+
+func NewIdempotencyService(idempotencyRepository repository.IdempotencyRepository) IdempotencyService {
+	return &idempotencyService{
+		idempotencyRepository: idempotencyRepository,
+		ttl:                   data.IDEMPOTENCY_KEY_TTL,
+		inProgressTTL:         data.IDEMPOTENCY_IN_PROGRESS_TTL,
+		cleanupInterval:       data.IDEMPOTENCY_CLEANUP_INTERVAL,
+		cleanupBatch:          data.IDEMPOTENCY_CLEANUP_BATCH,
+	}
+}
+
+// RunIdempotent is the typed form of IdempotencyService.Execute.
+func RunIdempotent[T any](ctx context.Context, idem IdempotencyService, req IdempotencyRequest, fn func(ctx context.Context) (T, error)) (T, bool, error) {
+	var result T
+	replayed, err := idem.Execute(ctx, req, &result, func(ctx context.Context) (any, error) {
+		var err error
+		result, err = fn(ctx)
+		return result, err
+	})
+	if err != nil {
+		var zero T
+		return zero, false, err
+	}
+	return result, replayed, nil
+}
+
+func (idem_serv *idempotencyService) Execute(ctx context.Context, req IdempotencyRequest, out any, fn func(ctx context.Context) (any, error)) (bool, error) {
+	if req.Key == "" {
+		_, err := fn(ctx)
+		return false, err
+	}
+	if len(req.Key) > data.IDEMPOTENCY_KEY_MAX_LENGTH {
+		return false, fmt.Errorf("%w: longer than %d characters", ErrInvalidIdempotencyKey, data.IDEMPOTENCY_KEY_MAX_LENGTH)
+	}
+
+	requestHash, err := hashIdempotentRequest(req)
+	if err != nil {
+		return false, fmt.Errorf("idempotent %s: hash request: %w", req.Operation, err)
+	}
+
+	record, reserved, err := idem_serv.idempotencyRepository.Reserve(ctx, &model.IdempotencyKey{
+		Key:         req.Key,
+		Operation:   req.Operation,
+		UserID:      req.UserID,
+		RequestHash: requestHash,
+		ExpiresAt:   time.Now().Add(idem_serv.inProgressTTL),
+	})
+	if err != nil {
+		return false, fmt.Errorf("idempotent %s: reserve key: %w", req.Operation, err)
+	}
+
+	if !reserved {
+		return true, idem_serv.replay(req, record, requestHash, out)
+	}
+
+	result, err := fn(ctx)
+	if err != nil {
+		// Request gagal tanpa hasil yang perlu diulang; key dilepas agar client boleh mencoba lagi
+		if releaseErr := idem_serv.idempotencyRepository.Release(context.WithoutCancel(ctx), record.ID, record.ReservationToken); releaseErr != nil {
+			log.Warn(data.LogIdempotencyReleaseFailed, map[string]any{
+				"service":   data.IdempotencyService,
+				"operation": req.Operation,
+				"key":       req.Key,
+				"error":     releaseErr.Error(),
+			})
+		}
+		return false, err
+	}
+
+	response, err := json.Marshal(result)
+	if err == nil {
+		err = idem_serv.idempotencyRepository.Complete(context.WithoutCancel(ctx), record.ID, record.ReservationToken, response, time.Now().Add(idem_serv.ttl))
+	}
+	if err != nil {
+		// Mutasi sudah berhasil; retry akan menunggu key kedaluwarsa dari status in-progress.
+		// ErrIdempotencyReservationLost berarti key sudah kedaluwarsa dan dipakai request lain
+		log.Warn(data.LogIdempotencyCompleteFailed, map[string]any{
+			"service":   data.IdempotencyService,
+			"operation": req.Operation,
+			"key":       req.Key,
+			"error":     err.Error(),
+		})
+	}
+
+	return false, nil
+}
+
+func (idem_serv *idempotencyService) replay(req IdempotencyRequest, record model.IdempotencyKey, requestHash string, out any) error {
+	if record.RequestHash != requestHash {
+		return fmt.Errorf("idempotent %s [key=%s]: %w", req.Operation, req.Key, ErrIdempotencyKeyReused)
+	}
+	if record.Response == nil {
+		return fmt.Errorf("idempotent %s [key=%s]: %w", req.Operation, req.Key, ErrIdempotencyInProgress)
+	}
+
+	if err := json.Unmarshal(record.Response, out); err != nil {
+		return fmt.Errorf("idempotent %s [key=%s]: decode stored response: %w", req.Operation, req.Key, err)
+	}
+
+	log.Info(data.LogIdempotentReplay, map[string]any{
+		"service":   data.IdempotencyService,
+		"operation": req.Operation,
+		"key":       req.Key,
+		"user_id":   req.UserID,
+	})
+	return nil
+}
+
+func hashIdempotentRequest(req IdempotencyRequest) (string, error) {
+	body, err := json.Marshal(req.Body)
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(append([]byte(req.Operation+"\n"), body...))
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// StartCleanupJob removes expired idempotency keys
+func (idem_serv *idempotencyService) StartCleanupJob(ctx context.Context) {
+	ticker := time.NewTicker(idem_serv.cleanupInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := idem_serv.cleanupExpiredKeys(ctx); err != nil {
+				log.Error(data.LogIdempotencyCleanupFailed, map[string]any{"service": data.IdempotencyService, "error": err.Error()})
+			}
+		}
+	}
+}
+
+func (idem_serv *idempotencyService) cleanupExpiredKeys(ctx context.Context) error {
+	startTime := time.Now()
+
+	var purged int64
+	for ctx.Err() == nil {
+		n, err := idem_serv.idempotencyRepository.DeleteExpiredBefore(ctx, startTime, idem_serv.cleanupBatch)
+		if err != nil {
+			return fmt.Errorf("failed to cleanup idempotency keys (purged %d): %w", purged, err)
+		}
+		purged += n
+		if n < int64(idem_serv.cleanupBatch) {
+			break
+		}
+	}
+
+	if purged > 0 {
+		log.Info(data.LogIdempotencyCleanupCompleted, map[string]any{
+			"service":  data.IdempotencyService,
+			"purged":   purged,
+			"duration": utils.Ms(time.Since(startTime)),
+		})
+	}
+
+	return nil
+}
@@ -0,0 +1,49 @@
+//go:build integration
+
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"refina-wallet/internal/repository"
+	"refina-wallet/internal/types/model"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestComplete_IntegrationRejectsExpiredReservation(t *testing.T) {
+	db := openIntegrationDB(t)
+	repo := repository.NewIdempotencyRepository(db)
+	ctx := context.Background()
+
+	newRecord := func(expiresAt time.Time) *model.IdempotencyKey {
+		return &model.IdempotencyKey{
+			Key:         "key-1",
+			Operation:   "wallet.create",
+			UserID:      userID.String(),
+			RequestHash: "hash",
+			ExpiresAt:   expiresAt,
+		}
+	}
+
+	// reservasi pertama sudah kedaluwarsa, sehingga retry boleh mengambil alih key-nya
+	stale, reserved, err := repo.Reserve(ctx, newRecord(time.Now().Add(-time.Second)))
+	assert.NoError(t, err)
+	assert.True(t, reserved)
+
+	fresh, reserved, err := repo.Reserve(ctx, newRecord(time.Now().Add(time.Minute)))
+	assert.NoError(t, err)
+	assert.True(t, reserved)
+	assert.Equal(t, stale.ID, fresh.ID)
+	assert.NotEqual(t, stale.ReservationToken, fresh.ReservationToken)
+
+	assert.ErrorIs(t, repo.Complete(ctx, stale.ID, stale.ReservationToken, []byte(`{"id":"stale"}`), time.Now().Add(time.Hour)), repository.ErrIdempotencyReservationLost)
+	assert.ErrorIs(t, repo.Release(ctx, stale.ID, stale.ReservationToken), repository.ErrIdempotencyReservationLost)
+	assert.NoError(t, repo.Complete(ctx, fresh.ID, fresh.ReservationToken, []byte(`{"id":"fresh"}`), time.Now().Add(time.Hour)))
+
+	var row model.IdempotencyKey
+	assert.NoError(t, db.First(&row, fresh.ID).Error)
+	assert.JSONEq(t, `{"id":"fresh"}`, string(row.Response))
+}
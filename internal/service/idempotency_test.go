@@ -0,0 +1,255 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"refina-wallet/internal/repository"
+	"refina-wallet/internal/service/mocks"
+	"refina-wallet/internal/types/domainerr"
+	"refina-wallet/internal/types/dto"
+	"refina-wallet/internal/types/model"
+	"refina-wallet/internal/types/money"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+var reservationToken = uuid.MustParse("dddddddd-dddd-dddd-dddd-dddddddddddd")
+
+func createWalletIdempotencyRequest(key string, body dto.WalletsRequest) IdempotencyRequest {
+	return IdempotencyRequest{
+		Key:       key,
+		Operation: "wallet.create",
+		UserID:    userID.String(),
+		Body:      body,
+	}
+}
+
+// =====================================================================
+// RunIdempotent
+// =====================================================================
+
+func TestRunIdempotent_FirstRequestStoresResponse(t *testing.T) {
+	repo := new(mocks.MockIdempotencyRepository)
+	svc := NewIdempotencyService(repo)
+
+	req := createWalletIdempotencyRequest("key-1", sampleWalletRequest())
+	created := dto.WalletsResponse{ID: walletID.String(), Name: "My BCA", Version: 1}
+	expected, _ := json.Marshal(created)
+
+	repo.On("Reserve", mock.Anything, mock.MatchedBy(func(r *model.IdempotencyKey) bool {
+		return r.Key == "key-1" && r.Operation == "wallet.create" && r.UserID == userID.String() &&
+			len(r.RequestHash) == 64 && r.ExpiresAt.After(time.Now())
+	})).Return(model.IdempotencyKey{ID: 7, ReservationToken: reservationToken}, true, nil)
+	repo.On("Complete", mock.Anything, uint(7), reservationToken, expected, mock.AnythingOfType("time.Time")).Return(nil)
+
+	calls := 0
+	result, replayed, err := RunIdempotent(context.Background(), svc, req, func(ctx context.Context) (dto.WalletsResponse, error) {
+		calls++
+		return created, nil
+	})
+
+	assert.NoError(t, err)
+	assert.False(t, replayed)
+	assert.Equal(t, created, result)
+	assert.Equal(t, 1, calls)
+	repo.AssertExpectations(t)
+}
+
+func TestRunIdempotent_RetryReplaysStoredResponse(t *testing.T) {
+	repo := new(mocks.MockIdempotencyRepository)
+	svc := NewIdempotencyService(repo)
+
+	req := createWalletIdempotencyRequest("key-1", sampleWalletRequest())
+	hash, _ := hashIdempotentRequest(req)
+	stored, _ := json.Marshal(dto.WalletsResponse{ID: walletID.String(), Name: "My BCA", Version: 1})
+
+	repo.On("Reserve", mock.Anything, mock.Anything).
+		Return(model.IdempotencyKey{ID: 7, RequestHash: hash, Response: stored}, false, nil)
+
+	result, replayed, err := RunIdempotent(context.Background(), svc, req, func(ctx context.Context) (dto.WalletsResponse, error) {
+		t.Fatal("mutation must not run again for a replayed key")
+		return dto.WalletsResponse{}, nil
+	})
+
+	assert.NoError(t, err)
+	assert.True(t, replayed)
+	assert.Equal(t, walletID.String(), result.ID)
+	assert.Equal(t, int64(1), result.Version)
+	repo.AssertNotCalled(t, "Complete", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	repo.AssertExpectations(t)
+}
+
+func TestRunIdempotent_DifferentBodySameKeyRejected(t *testing.T) {
+	repo := new(mocks.MockIdempotencyRepository)
+	svc := NewIdempotencyService(repo)
+
+	original := createWalletIdempotencyRequest("key-1", sampleWalletRequest())
+	hash, _ := hashIdempotentRequest(original)
+
+	changed := sampleWalletRequest()
+	changed.Balance = money.MustParse("1")
+	req := createWalletIdempotencyRequest("key-1", changed)
+
+	repo.On("Reserve", mock.Anything, mock.Anything).
+		Return(model.IdempotencyKey{ID: 7, RequestHash: hash, Response: []byte(`{}`)}, false, nil)
+
+	_, _, err := RunIdempotent(context.Background(), svc, req, func(ctx context.Context) (dto.WalletsResponse, error) {
+		t.Fatal("mutation must not run for a reused key")
+		return dto.WalletsResponse{}, nil
+	})
+
+	assert.ErrorIs(t, err, ErrIdempotencyKeyReused)
+	repo.AssertExpectations(t)
+}
+
+func TestRunIdempotent_ConcurrentRetryWhileInProgress(t *testing.T) {
+	repo := new(mocks.MockIdempotencyRepository)
+	svc := NewIdempotencyService(repo)
+
+	req := createWalletIdempotencyRequest("key-1", sampleWalletRequest())
+	hash, _ := hashIdempotentRequest(req)
+
+	repo.On("Reserve", mock.Anything, mock.Anything).
+		Return(model.IdempotencyKey{ID: 7, RequestHash: hash}, false, nil)
+
+	_, _, err := RunIdempotent(context.Background(), svc, req, func(ctx context.Context) (dto.WalletsResponse, error) {
+		t.Fatal("mutation must not run while the first request is in progress")
+		return dto.WalletsResponse{}, nil
+	})
+
+	assert.ErrorIs(t, err, ErrIdempotencyInProgress)
+	repo.AssertExpectations(t)
+}
+
+func TestRunIdempotent_FailureReleasesKey(t *testing.T) {
+	repo := new(mocks.MockIdempotencyRepository)
+	svc := NewIdempotencyService(repo)
+
+	req := createWalletIdempotencyRequest("key-1", sampleWalletRequest())
+
+	repo.On("Reserve", mock.Anything, mock.Anything).Return(model.IdempotencyKey{ID: 7, ReservationToken: reservationToken}, true, nil)
+	repo.On("Release", mock.Anything, uint(7), reservationToken).Return(nil)
+
+	_, replayed, err := RunIdempotent(context.Background(), svc, req, func(ctx context.Context) (dto.WalletsResponse, error) {
+		return dto.WalletsResponse{}, errors.New("create wallet: insert to db: boom")
+	})
+
+	assert.Error(t, err)
+	assert.False(t, replayed)
+	repo.AssertNotCalled(t, "Complete", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	repo.AssertExpectations(t)
+}
+
+func TestRunIdempotent_CompleteErrorStillReturnsResult(t *testing.T) {
+	repo := new(mocks.MockIdempotencyRepository)
+	svc := NewIdempotencyService(repo)
+
+	req := createWalletIdempotencyRequest("key-1", sampleWalletRequest())
+
+	repo.On("Reserve", mock.Anything, mock.Anything).Return(model.IdempotencyKey{ID: 7, ReservationToken: reservationToken}, true, nil)
+	repo.On("Complete", mock.Anything, uint(7), reservationToken, mock.Anything, mock.Anything).Return(errors.New("db down"))
+
+	result, _, err := RunIdempotent(context.Background(), svc, req, func(ctx context.Context) (dto.WalletsResponse, error) {
+		return dto.WalletsResponse{ID: walletID.String()}, nil
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, walletID.String(), result.ID)
+	repo.AssertExpectations(t)
+}
+
+func TestRunIdempotent_ReservationLostAfterExpiryStillReturnsResult(t *testing.T) {
+	repo := new(mocks.MockIdempotencyRepository)
+	svc := NewIdempotencyService(repo)
+
+	req := createWalletIdempotencyRequest("key-1", sampleWalletRequest())
+
+	// Key kedaluwarsa selama request berjalan dan sudah direservasi ulang oleh retry
+	repo.On("Reserve", mock.Anything, mock.Anything).Return(model.IdempotencyKey{ID: 7, ReservationToken: reservationToken}, true, nil)
+	repo.On("Complete", mock.Anything, uint(7), reservationToken, mock.Anything, mock.Anything).
+		Return(repository.ErrIdempotencyReservationLost)
+
+	result, replayed, err := RunIdempotent(context.Background(), svc, req, func(ctx context.Context) (dto.WalletsResponse, error) {
+		return dto.WalletsResponse{ID: walletID.String()}, nil
+	})
+
+	assert.NoError(t, err)
+	assert.False(t, replayed)
+	assert.Equal(t, walletID.String(), result.ID)
+	repo.AssertExpectations(t)
+}
+
+func TestRunIdempotent_FailureAfterReservationLostKeepsNewReservation(t *testing.T) {
+	repo := new(mocks.MockIdempotencyRepository)
+	svc := NewIdempotencyService(repo)
+
+	req := createWalletIdempotencyRequest("key-1", sampleWalletRequest())
+
+	repo.On("Reserve", mock.Anything, mock.Anything).Return(model.IdempotencyKey{ID: 7, ReservationToken: reservationToken}, true, nil)
+	repo.On("Release", mock.Anything, uint(7), reservationToken).Return(repository.ErrIdempotencyReservationLost)
+
+	_, _, err := RunIdempotent(context.Background(), svc, req, func(ctx context.Context) (dto.WalletsResponse, error) {
+		return dto.WalletsResponse{}, errors.New("create wallet: insert to db: boom")
+	})
+
+	assert.Error(t, err)
+	assert.NotErrorIs(t, err, repository.ErrIdempotencyReservationLost)
+	repo.AssertExpectations(t)
+}
+
+func TestRunIdempotent_NoKeyRunsDirectly(t *testing.T) {
+	repo := new(mocks.MockIdempotencyRepository)
+	svc := NewIdempotencyService(repo)
+
+	result, replayed, err := RunIdempotent(context.Background(), svc, createWalletIdempotencyRequest("", sampleWalletRequest()),
+		func(ctx context.Context) (dto.WalletsResponse, error) {
+			return dto.WalletsResponse{ID: walletID.String()}, nil
+		})
+
+	assert.NoError(t, err)
+	assert.False(t, replayed)
+	assert.Equal(t, walletID.String(), result.ID)
+	repo.AssertNotCalled(t, "Reserve", mock.Anything, mock.Anything)
+}
+
+func TestRunIdempotent_KeyTooLong(t *testing.T) {
+	repo := new(mocks.MockIdempotencyRepository)
+	svc := NewIdempotencyService(repo)
+
+	key := string(make([]byte, 256))
+	_, _, err := RunIdempotent(context.Background(), svc, createWalletIdempotencyRequest(key, sampleWalletRequest()),
+		func(ctx context.Context) (dto.WalletsResponse, error) {
+			t.Fatal("mutation must not run with an invalid key")
+			return dto.WalletsResponse{}, nil
+		})
+
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid idempotency key")
+	assert.ErrorIs(t, err, ErrInvalidIdempotencyKey)
+	assert.Equal(t, domainerr.InvalidArgument, domainerr.KindOf(err))
+	repo.AssertNotCalled(t, "Reserve", mock.Anything, mock.Anything)
+}
+
+// =====================================================================
+// cleanupExpiredKeys
+// =====================================================================
+
+func TestCleanupExpiredKeys_DeletesInBatches(t *testing.T) {
+	repo := new(mocks.MockIdempotencyRepository)
+	svc := NewIdempotencyService(repo).(*idempotencyService)
+	svc.cleanupBatch = 2
+
+	repo.On("DeleteExpiredBefore", mock.Anything, mock.Anything, 2).Return(int64(2), nil).Once()
+	repo.On("DeleteExpiredBefore", mock.Anything, mock.Anything, 2).Return(int64(1), nil).Once()
+
+	err := svc.cleanupExpiredKeys(context.Background())
+
+	assert.NoError(t, err)
+	repo.AssertExpectations(t)
+}
@@ -0,0 +1,136 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"refina-wallet/config/log"
+	"refina-wallet/interface/queue"
+	"refina-wallet/internal/repository"
+	"refina-wallet/internal/types/model"
+	"refina-wallet/internal/utils"
+	"refina-wallet/internal/utils/data"
+)
+
+// InboxHandlerFunc is a consumer callback whose side effects must be written through tx.
+type InboxHandlerFunc func(ctx context.Context, tx repository.Transaction, msg queue.Message) error
+
+// InboxProcessor makes at-least-once deliveries effectively exactly-once: a callback only runs for a
+// message id not seen before, and the inbox row commits (or rolls back) together with its effects.
+type InboxProcessor interface {
+	Process(ctx context.Context, source string, msg queue.Message, fn InboxHandlerFunc) error
+	Handler(source string, fn InboxHandlerFunc) queue.HandlerFunc
+	StartCleanupJob(ctx context.Context)
+}
+
+type inboxProcessor struct {
+	txManager       repository.TxManager
+	inboxRepository repository.InboxRepository
+	retention       time.Duration
+	cleanupInterval time.Duration
+	cleanupBatch    int
+}
+
+func NewInboxProcessor(txManager repository.TxManager, inboxRepository repository.InboxRepository) InboxProcessor {
+	return &inboxProcessor{
+		txManager:       txManager,
+		inboxRepository: inboxRepository,
+		retention:       data.INBOX_RETENTION_PERIOD,
+		cleanupInterval: data.INBOX_CLEANUP_INTERVAL,
+		cleanupBatch:    data.INBOX_CLEANUP_BATCH,
+	}
+}
+
+// Process runs fn inside one transaction after recording msg in the inbox. A message already
+// recorded for source is acknowledged without calling fn. Errors from fn roll everything back,
+// including the inbox row, so the redelivery is processed again.
+func (inbox_proc *inboxProcessor) Process(ctx context.Context, source string, msg queue.Message, fn InboxHandlerFunc) error {
+	tx, err := inbox_proc.txManager.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("process inbox message: begin transaction: %w", err)
+	}
+
+	defer func() {
+		tx.Rollback()
+	}()
+
+	fresh, err := inbox_proc.inboxRepository.MarkHandled(ctx, tx, &model.InboxMessage{
+		MessageID: msg.ID,
+		Source:    source,
+		EventType: msg.Type,
+	})
+	if err != nil {
+		return fmt.Errorf("process inbox message: save inbox message: %w", err)
+	}
+	if !fresh {
+		log.Info(data.LogInboxMessageDuplicate, map[string]any{
+			"service":    data.InboxService,
+			"source":     source,
+			"message_id": msg.ID,
+			"type":       msg.Type,
+		})
+		return nil
+	}
+
+	if err := fn(ctx, tx, msg); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("process inbox message: commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// Handler adapts fn into a queue consumer handler that goes through Process.
+func (inbox_proc *inboxProcessor) Handler(source string, fn InboxHandlerFunc) queue.HandlerFunc {
+	return func(ctx context.Context, msg queue.Message) error {
+		return inbox_proc.Process(ctx, source, msg, fn)
+	}
+}
+
+// StartCleanupJob removes inbox rows older than the retention window
+func (inbox_proc *inboxProcessor) StartCleanupJob(ctx context.Context) {
+	ticker := time.NewTicker(inbox_proc.cleanupInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := inbox_proc.cleanupHandledMessages(ctx); err != nil {
+				log.Error(data.LogInboxCleanupFailed, map[string]any{"service": data.InboxService, "error": err.Error()})
+			}
+		}
+	}
+}
+
+func (inbox_proc *inboxProcessor) cleanupHandledMessages(ctx context.Context) error {
+	startTime := time.Now()
+	before := startTime.Add(-inbox_proc.retention)
+
+	var purged int64
+	for ctx.Err() == nil {
+		n, err := inbox_proc.inboxRepository.DeleteHandledBefore(ctx, before, inbox_proc.cleanupBatch)
+		if err != nil {
+			return fmt.Errorf("failed to cleanup inbox messages (purged %d): %w", purged, err)
+		}
+		purged += n
+		if n < int64(inbox_proc.cleanupBatch) {
+			break
+		}
+	}
+
+	if purged > 0 {
+		log.Info(data.LogInboxCleanupCompleted, map[string]any{
+			"service":  data.InboxService,
+			"purged":   purged,
+			"duration": utils.Ms(time.Since(startTime)),
+		})
+	}
+
+	return nil
+}
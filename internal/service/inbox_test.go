@@ -0,0 +1,178 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"refina-wallet/interface/queue"
+	"refina-wallet/internal/repository"
+	"refina-wallet/internal/service/mocks"
+	"refina-wallet/internal/types/model"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// ---------- helpers ----------
+
+type inboxTestDeps struct {
+	txManager *mocks.MockTxManager
+	inboxRepo *mocks.MockInboxRepository
+	tx        *mocks.MockTransaction
+}
+
+func newInboxTestDeps() *inboxTestDeps {
+	return &inboxTestDeps{
+		txManager: new(mocks.MockTxManager),
+		inboxRepo: new(mocks.MockInboxRepository),
+		tx:        new(mocks.MockTransaction),
+	}
+}
+
+func (d *inboxTestDeps) processor() *inboxProcessor {
+	return NewInboxProcessor(d.txManager, d.inboxRepo).(*inboxProcessor)
+}
+
+func (d *inboxTestDeps) assertAll(t *testing.T) {
+	d.txManager.AssertExpectations(t)
+	d.inboxRepo.AssertExpectations(t)
+	d.tx.AssertExpectations(t)
+}
+
+// =====================================================================
+// Process
+// =====================================================================
+
+func TestInboxProcess_FirstDeliveryRunsCallbackAndCommits(t *testing.T) {
+	d := newInboxTestDeps()
+	proc := d.processor()
+
+	msg := queue.Message{ID: "msg-1", Type: "transaction.created"}
+
+	d.txManager.On("Begin", mock.Anything).Return(d.tx, nil)
+	d.inboxRepo.On("MarkHandled", mock.Anything, d.tx, &model.InboxMessage{
+		MessageID: "msg-1",
+		Source:    "/refina/transaction",
+		EventType: "transaction.created",
+	}).Return(true, nil)
+	d.tx.On("Commit").Return(nil)
+	d.tx.On("Rollback").Return(nil)
+
+	var calledWith repository.Transaction
+	err := proc.Process(context.Background(), "/refina/transaction", msg, func(ctx context.Context, tx repository.Transaction, msg queue.Message) error {
+		calledWith = tx
+		return nil
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, d.tx, calledWith)
+	d.assertAll(t)
+}
+
+func TestInboxProcess_DuplicateSkipsCallback(t *testing.T) {
+	d := newInboxTestDeps()
+	proc := d.processor()
+
+	d.txManager.On("Begin", mock.Anything).Return(d.tx, nil)
+	d.inboxRepo.On("MarkHandled", mock.Anything, d.tx, mock.Anything).Return(false, nil)
+	d.tx.On("Rollback").Return(nil)
+
+	called := false
+	err := proc.Process(context.Background(), "src", queue.Message{ID: "msg-1"}, func(ctx context.Context, tx repository.Transaction, msg queue.Message) error {
+		called = true
+		return nil
+	})
+
+	assert.NoError(t, err)
+	assert.False(t, called)
+	d.tx.AssertNotCalled(t, "Commit")
+	d.assertAll(t)
+}
+
+func TestInboxProcess_CallbackErrorRollsBackInboxRow(t *testing.T) {
+	d := newInboxTestDeps()
+	proc := d.processor()
+
+	d.txManager.On("Begin", mock.Anything).Return(d.tx, nil)
+	d.inboxRepo.On("MarkHandled", mock.Anything, d.tx, mock.Anything).Return(true, nil)
+	d.tx.On("Rollback").Return(nil)
+
+	err := proc.Process(context.Background(), "src", queue.Message{ID: "msg-1"}, func(ctx context.Context, tx repository.Transaction, msg queue.Message) error {
+		return errors.New("side effect failed")
+	})
+
+	assert.EqualError(t, err, "side effect failed")
+	d.tx.AssertNotCalled(t, "Commit")
+	d.assertAll(t)
+}
+
+func TestInboxProcess_MarkHandledError(t *testing.T) {
+	d := newInboxTestDeps()
+	proc := d.processor()
+
+	d.txManager.On("Begin", mock.Anything).Return(d.tx, nil)
+	d.inboxRepo.On("MarkHandled", mock.Anything, d.tx, mock.Anything).Return(false, errors.New("db down"))
+	d.tx.On("Rollback").Return(nil)
+
+	err := proc.Process(context.Background(), "src", queue.Message{ID: "msg-1"}, func(ctx context.Context, tx repository.Transaction, msg queue.Message) error {
+		t.Fatal("callback must not run")
+		return nil
+	})
+
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "save inbox message")
+	d.assertAll(t)
+}
+
+func TestInboxHandler_AdaptsToQueueHandler(t *testing.T) {
+	d := newInboxTestDeps()
+	proc := d.processor()
+
+	d.txManager.On("Begin", mock.Anything).Return(d.tx, nil)
+	d.inboxRepo.On("MarkHandled", mock.Anything, d.tx, mock.MatchedBy(func(m *model.InboxMessage) bool {
+		return m.Source == "src" && m.MessageID == "msg-2"
+	})).Return(true, nil)
+	d.tx.On("Commit").Return(nil)
+	d.tx.On("Rollback").Return(nil)
+
+	handler := proc.Handler("src", func(ctx context.Context, tx repository.Transaction, msg queue.Message) error {
+		return nil
+	})
+
+	assert.NoError(t, handler(context.Background(), queue.Message{ID: "msg-2"}))
+	d.assertAll(t)
+}
+
+// =====================================================================
+// cleanupHandledMessages
+// =====================================================================
+
+func TestInboxCleanup_DeletesInBatches(t *testing.T) {
+	d := newInboxTestDeps()
+	proc := d.processor()
+	proc.cleanupBatch = 2
+
+	d.inboxRepo.On("DeleteHandledBefore", mock.Anything, mock.AnythingOfType("time.Time"), 2).Return(int64(2), nil).Once()
+	d.inboxRepo.On("DeleteHandledBefore", mock.Anything, mock.AnythingOfType("time.Time"), 2).Return(int64(1), nil).Once()
+
+	err := proc.cleanupHandledMessages(context.Background())
+
+	assert.NoError(t, err)
+	d.assertAll(t)
+}
+
+func TestInboxCleanup_UsesRetentionWindow(t *testing.T) {
+	d := newInboxTestDeps()
+	proc := d.processor()
+
+	d.inboxRepo.On("DeleteHandledBefore", mock.Anything, mock.MatchedBy(func(before time.Time) bool {
+		return time.Since(before) >= 30*24*time.Hour
+	}), 1000).Return(int64(0), nil)
+
+	err := proc.cleanupHandledMessages(context.Background())
+
+	assert.NoError(t, err)
+	d.assertAll(t)
+}
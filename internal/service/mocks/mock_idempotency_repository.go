@@ -0,0 +1,35 @@
+package mocks
+
+import (
+	"context"
+	"time"
+
+	"refina-wallet/internal/types/model"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockIdempotencyRepository struct {
+	mock.Mock
+}
+
+func (m *MockIdempotencyRepository) Reserve(ctx context.Context, record *model.IdempotencyKey) (model.IdempotencyKey, bool, error) {
+	args := m.Called(ctx, record)
+	return args.Get(0).(model.IdempotencyKey), args.Bool(1), args.Error(2)
+}
+
+func (m *MockIdempotencyRepository) Complete(ctx context.Context, id uint, reservationToken uuid.UUID, response []byte, expiresAt time.Time) error {
+	args := m.Called(ctx, id, reservationToken, response, expiresAt)
+	return args.Error(0)
+}
+
+func (m *MockIdempotencyRepository) Release(ctx context.Context, id uint, reservationToken uuid.UUID) error {
+	args := m.Called(ctx, id, reservationToken)
+	return args.Error(0)
+}
+
+func (m *MockIdempotencyRepository) DeleteExpiredBefore(ctx context.Context, before time.Time, limit int) (int64, error) {
+	args := m.Called(ctx, before, limit)
+	return args.Get(0).(int64), args.Error(1)
+}
@@ -0,0 +1,25 @@
+package mocks
+
+import (
+	"context"
+	"time"
+
+	"refina-wallet/internal/repository"
+	"refina-wallet/internal/types/model"
+
+	"github.com/stretchr/testify/mock"
+)
+
+type MockInboxRepository struct {
+	mock.Mock
+}
+
+func (m *MockInboxRepository) MarkHandled(ctx context.Context, tx repository.Transaction, message *model.InboxMessage) (bool, error) {
+	args := m.Called(ctx, tx, message)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockInboxRepository) DeleteHandledBefore(ctx context.Context, before time.Time, limit int) (int64, error) {
+	args := m.Called(ctx, before, limit)
+	return args.Get(0).(int64), args.Error(1)
+}
@@ -2,6 +2,7 @@ package mocks
 
 import (
 	"context"
+	"time"
 
 	"refina-wallet/internal/repository"
 	"refina-wallet/internal/types/model"
@@ -18,17 +19,65 @@ func (m *MockOutboxRepository) Create(ctx context.Context, tx repository.Transac
 	return args.Error(0)
 }
 
-func (m *MockOutboxRepository) GetPendingMessages(ctx context.Context, limit int) ([]model.OutboxMessage, error) {
-	args := m.Called(ctx, limit)
+func (m *MockOutboxRepository) NextSequence(ctx context.Context, tx repository.Transaction, aggregateID string) (int64, error) {
+	args := m.Called(ctx, tx, aggregateID)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockOutboxRepository) ClaimPendingMessages(ctx context.Context, owner string, lease time.Duration, limit int) ([]model.OutboxMessage, error) {
+	args := m.Called(ctx, owner, lease, limit)
+	return args.Get(0).([]model.OutboxMessage), args.Error(1)
+}
+
+func (m *MockOutboxRepository) RenewClaims(ctx context.Context, ids []uint, owner string, lease time.Duration) ([]uint, error) {
+	args := m.Called(ctx, ids, owner, lease)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]uint), args.Error(1)
+}
+
+func (m *MockOutboxRepository) MarkAsPublished(ctx context.Context, id uint, owner string) error {
+	args := m.Called(ctx, id, owner)
+	return args.Error(0)
+}
+
+func (m *MockOutboxRepository) IncrementRetries(ctx context.Context, id uint, owner string, nextAttemptAt time.Time, lastError string) error {
+	args := m.Called(ctx, id, owner, nextAttemptAt, lastError)
+	return args.Error(0)
+}
+
+func (m *MockOutboxRepository) DeletePublishedBefore(ctx context.Context, before time.Time, limit int) (int64, error) {
+	args := m.Called(ctx, before, limit)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockOutboxRepository) ArchivePublishedBefore(ctx context.Context, before time.Time, limit int) (int64, error) {
+	args := m.Called(ctx, before, limit)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockOutboxRepository) GetDeadLetters(ctx context.Context, limit, offset int) ([]model.OutboxMessage, error) {
+	args := m.Called(ctx, limit, offset)
 	return args.Get(0).([]model.OutboxMessage), args.Error(1)
 }
 
-func (m *MockOutboxRepository) MarkAsPublished(ctx context.Context, id uint) error {
+func (m *MockOutboxRepository) GetDeadLetterByID(ctx context.Context, id uint) (model.OutboxMessage, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(model.OutboxMessage), args.Error(1)
+}
+
+func (m *MockOutboxRepository) RequeueDeadLetter(ctx context.Context, id uint) error {
 	args := m.Called(ctx, id)
 	return args.Error(0)
 }
 
-func (m *MockOutboxRepository) IncrementRetries(ctx context.Context, id uint) error {
+func (m *MockOutboxRepository) DiscardDeadLetter(ctx context.Context, id uint) error {
 	args := m.Called(ctx, id)
 	return args.Error(0)
 }
+
+func (m *MockOutboxRepository) ReplayPublishedRange(ctx context.Context, fromID, toID uint) (int64, error) {
+	args := m.Called(ctx, fromID, toID)
+	return args.Get(0).(int64), args.Error(1)
+}
@@ -3,6 +3,8 @@ package mocks
 import (
 	"context"
 
+	"refina-wallet/interface/queue"
+
 	"github.com/rabbitmq/amqp091-go"
 	"github.com/stretchr/testify/mock"
 )
@@ -24,7 +26,17 @@ func (m *MockRabbitMQClient) Close() error {
 	return args.Error(0)
 }
 
-func (m *MockRabbitMQClient) Publish(ctx context.Context, routingKey string, body []byte) error {
-	args := m.Called(ctx, routingKey, body)
+func (m *MockRabbitMQClient) Publish(ctx context.Context, routingKey string, body []byte, opts queue.PublishOptions) error {
+	args := m.Called(ctx, routingKey, body, opts)
+	return args.Error(0)
+}
+
+func (m *MockRabbitMQClient) PublishWithConfirm(ctx context.Context, exchange, routingKey string, message amqp091.Publishing) error {
+	args := m.Called(ctx, exchange, routingKey, message)
 	return args.Error(0)
 }
+
+func (m *MockRabbitMQClient) State() queue.ConnectionState {
+	args := m.Called()
+	return args.Get(0).(queue.ConnectionState)
+}
@@ -3,6 +3,8 @@ package mocks
 import (
 	"context"
 
+	"refina-wallet/internal/types/money"
+
 	tpb "github.com/MuhammadMiftaa/Refina-Protobuf/transaction"
 	"github.com/stretchr/testify/mock"
 )
@@ -11,7 +13,7 @@ type MockTransactionClient struct {
 	mock.Mock
 }
 
-func (m *MockTransactionClient) InitialDeposit(ctx context.Context, walletID string, amount float64) (*tpb.TransactionDetail, error) {
+func (m *MockTransactionClient) InitialDeposit(ctx context.Context, walletID string, amount money.Amount) (*tpb.TransactionDetail, error) {
 	args := m.Called(ctx, walletID, amount)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
@@ -26,3 +28,16 @@ func (m *MockTransactionClient) CancelInitialDeposit(ctx context.Context, transa
 	}
 	return args.Get(0).(*tpb.TransactionDetail), args.Error(1)
 }
+
+func (m *MockTransactionClient) CreateFundTransfer(ctx context.Context, req *tpb.CreateFundTransferRequest) (*tpb.FundTransferResponse, error) {
+	args := m.Called(ctx, req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*tpb.FundTransferResponse), args.Error(1)
+}
+
+func (m *MockTransactionClient) CancelFundTransfer(ctx context.Context, transfer *tpb.FundTransferResponse) error {
+	args := m.Called(ctx, transfer)
+	return args.Error(0)
+}
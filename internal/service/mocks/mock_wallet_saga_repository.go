@@ -0,0 +1,59 @@
+package mocks
+
+import (
+	"context"
+	"time"
+
+	"refina-wallet/internal/types/model"
+
+	"github.com/stretchr/testify/mock"
+)
+
+type MockWalletSagaRepository struct {
+	mock.Mock
+}
+
+func (m *MockWalletSagaRepository) Create(ctx context.Context, saga *model.WalletCreationSaga) error {
+	args := m.Called(ctx, saga)
+	return args.Error(0)
+}
+
+func (m *MockWalletSagaRepository) MarkDepositCreated(ctx context.Context, id string, depositTransactionID string) error {
+	args := m.Called(ctx, id, depositTransactionID)
+	return args.Error(0)
+}
+
+func (m *MockWalletSagaRepository) MarkCommitted(ctx context.Context, id string) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *MockWalletSagaRepository) MarkFailed(ctx context.Context, id string) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *MockWalletSagaRepository) MarkNeedsReview(ctx context.Context, id string, lastError string) error {
+	args := m.Called(ctx, id, lastError)
+	return args.Error(0)
+}
+
+func (m *MockWalletSagaRepository) ScheduleCompensation(ctx context.Context, id string, nextAttemptAt time.Time, lastError string) error {
+	args := m.Called(ctx, id, nextAttemptAt, lastError)
+	return args.Error(0)
+}
+
+func (m *MockWalletSagaRepository) ClaimDueCompensations(ctx context.Context, lease time.Duration, limit int) ([]model.WalletCreationSaga, error) {
+	args := m.Called(ctx, lease, limit)
+	return args.Get(0).([]model.WalletCreationSaga), args.Error(1)
+}
+
+func (m *MockWalletSagaRepository) GetStale(ctx context.Context, before time.Time, limit int) ([]model.WalletCreationSaga, error) {
+	args := m.Called(ctx, before, limit)
+	return args.Get(0).([]model.WalletCreationSaga), args.Error(1)
+}
+
+func (m *MockWalletSagaRepository) RecordTransferReview(ctx context.Context, review *model.WalletTransferReview) error {
+	args := m.Called(ctx, review)
+	return args.Error(0)
+}
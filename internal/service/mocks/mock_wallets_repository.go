@@ -2,9 +2,11 @@ package mocks
 
 import (
 	"context"
+	"time"
 
 	"refina-wallet/internal/repository"
 	"refina-wallet/internal/types/model"
+	"refina-wallet/internal/types/money"
 	"refina-wallet/internal/types/view"
 
 	"github.com/stretchr/testify/mock"
@@ -24,6 +26,11 @@ func (m *MockWalletsRepository) GetWalletByID(ctx context.Context, tx repository
 	return args.Get(0).(model.Wallets), args.Error(1)
 }
 
+func (m *MockWalletsRepository) WalletEverCreated(ctx context.Context, tx repository.Transaction, id string) (bool, error) {
+	args := m.Called(ctx, tx, id)
+	return args.Bool(0), args.Error(1)
+}
+
 func (m *MockWalletsRepository) GetWalletsByUserID(ctx context.Context, tx repository.Transaction, id string) ([]model.Wallets, error) {
 	args := m.Called(ctx, tx, id)
 	return args.Get(0).([]model.Wallets), args.Error(1)
@@ -48,3 +55,50 @@ func (m *MockWalletsRepository) DeleteWallet(ctx context.Context, tx repository.
 	args := m.Called(ctx, tx, wallet)
 	return args.Get(0).(model.Wallets), args.Error(1)
 }
+
+func (m *MockWalletsRepository) GetWalletsPage(ctx context.Context, tx repository.Transaction, filter repository.WalletFilter, afterID string, limit int) ([]model.Wallets, error) {
+	args := m.Called(ctx, tx, filter, afterID, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]model.Wallets), args.Error(1)
+}
+
+func (m *MockWalletsRepository) AppendBalanceEntry(ctx context.Context, tx repository.Transaction, entry model.WalletBalanceEntries) (model.WalletBalanceEntries, error) {
+	args := m.Called(ctx, tx, entry)
+	return args.Get(0).(model.WalletBalanceEntries), args.Error(1)
+}
+
+func (m *MockWalletsRepository) GetBalanceEntries(ctx context.Context, tx repository.Transaction, walletID string, limit, offset int) ([]model.WalletBalanceEntries, error) {
+	args := m.Called(ctx, tx, walletID, limit, offset)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]model.WalletBalanceEntries), args.Error(1)
+}
+
+func (m *MockWalletsRepository) GetBalanceAsOf(ctx context.Context, tx repository.Transaction, walletID string, asOf time.Time) (money.Amount, error) {
+	args := m.Called(ctx, tx, walletID, asOf)
+	return args.Get(0).(money.Amount), args.Error(1)
+}
+
+func (m *MockWalletsRepository) GetTransactionContributions(ctx context.Context, tx repository.Transaction, sourceTransactionID string) (map[string]money.Amount, error) {
+	args := m.Called(ctx, tx, sourceTransactionID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(map[string]money.Amount), args.Error(1)
+}
+
+func (m *MockWalletsRepository) LockWallets(ctx context.Context, tx repository.Transaction, ids ...string) ([]model.Wallets, error) {
+	args := m.Called(ctx, tx, ids)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]model.Wallets), args.Error(1)
+}
+
+func (m *MockWalletsRepository) AdvanceTransactionVersion(ctx context.Context, tx repository.Transaction, sourceTransactionID string, version int64) (bool, error) {
+	args := m.Called(ctx, tx, sourceTransactionID, version)
+	return args.Bool(0), args.Error(1)
+}
@@ -0,0 +1,74 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"refina-wallet/internal/repository"
+	"refina-wallet/internal/types/dto"
+	"refina-wallet/internal/utils"
+	"refina-wallet/internal/utils/data"
+)
+
+// OutboxAdminService exposes on-call operations over outbox messages that exhausted their retries.
+type OutboxAdminService interface {
+	GetDeadLetters(ctx context.Context, page int) ([]dto.OutboxMessageResponse, error)
+	GetDeadLetterByID(ctx context.Context, id uint) (dto.OutboxMessageResponse, error)
+	RequeueDeadLetter(ctx context.Context, id uint) error
+	DiscardDeadLetter(ctx context.Context, id uint) error
+}
+
+type outboxAdminService struct {
+	outboxRepository repository.OutboxRepository
+}
+
+func NewOutboxAdminService(outboxRepository repository.OutboxRepository) OutboxAdminService {
+	return &outboxAdminService{
+		outboxRepository: outboxRepository,
+	}
+}
+
+func (outbox_serv *outboxAdminService) GetDeadLetters(ctx context.Context, page int) ([]dto.OutboxMessageResponse, error) {
+	if page < 1 {
+		page = 1
+	}
+	offset := (page - 1) * data.OUTBOX_DEAD_LETTER_PAGE_SIZE
+
+	messages, err := outbox_serv.outboxRepository.GetDeadLetters(ctx, data.OUTBOX_DEAD_LETTER_PAGE_SIZE, offset)
+	if err != nil {
+		return nil, fmt.Errorf("get dead letters: %w", err)
+	}
+
+	var messagesResponse []dto.OutboxMessageResponse
+	for _, message := range messages {
+		messageResponse := utils.ConvertToResponseType(message).(dto.OutboxMessageResponse)
+		messagesResponse = append(messagesResponse, messageResponse)
+	}
+
+	return messagesResponse, nil
+}
+
+func (outbox_serv *outboxAdminService) GetDeadLetterByID(ctx context.Context, id uint) (dto.OutboxMessageResponse, error) {
+	message, err := outbox_serv.outboxRepository.GetDeadLetterByID(ctx, id)
+	if err != nil {
+		return dto.OutboxMessageResponse{}, fmt.Errorf("dead letter not found [id=%d]: %w", id, err)
+	}
+
+	return utils.ConvertToResponseType(message).(dto.OutboxMessageResponse), nil
+}
+
+func (outbox_serv *outboxAdminService) RequeueDeadLetter(ctx context.Context, id uint) error {
+	if err := outbox_serv.outboxRepository.RequeueDeadLetter(ctx, id); err != nil {
+		return fmt.Errorf("requeue dead letter [id=%d]: %w", id, err)
+	}
+
+	return nil
+}
+
+func (outbox_serv *outboxAdminService) DiscardDeadLetter(ctx context.Context, id uint) error {
+	if err := outbox_serv.outboxRepository.DiscardDeadLetter(ctx, id); err != nil {
+		return fmt.Errorf("discard dead letter [id=%d]: %w", id, err)
+	}
+
+	return nil
+}
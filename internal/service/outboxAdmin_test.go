@@ -0,0 +1,188 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"refina-wallet/internal/repository"
+	"refina-wallet/internal/service/mocks"
+	"refina-wallet/internal/types/domainerr"
+	"refina-wallet/internal/types/model"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"gorm.io/gorm"
+)
+
+// ---------- helpers ----------
+
+func sampleDeadLetter() model.OutboxMessage {
+	failedAt := fixedTime.Add(time.Minute)
+	return model.OutboxMessage{
+		ID:          7,
+		AggregateID: walletID.String(),
+		EventType:   "wallet.updated",
+		Payload:     []byte(`{"id":"aaaaaaaa-aaaa-aaaa-aaaa-aaaaaaaaaaaa"}`),
+		Retries:     5,
+		MaxRetries:  5,
+		FailedAt:    &failedAt,
+		LastError:   "channel error",
+		CreatedAt:   fixedTime,
+	}
+}
+
+// =====================================================================
+// GetDeadLetters
+// =====================================================================
+
+func TestGetDeadLetters_Success(t *testing.T) {
+	repo := new(mocks.MockOutboxRepository)
+	svc := NewOutboxAdminService(repo)
+
+	repo.On("GetDeadLetters", mock.Anything, 50, 0).Return([]model.OutboxMessage{sampleDeadLetter()}, nil)
+
+	result, err := svc.GetDeadLetters(context.Background(), 1)
+
+	assert.NoError(t, err)
+	assert.Len(t, result, 1)
+	assert.Equal(t, uint(7), result[0].ID)
+	assert.Equal(t, "channel error", result[0].LastError)
+	assert.NotEmpty(t, result[0].FailedAt)
+	repo.AssertExpectations(t)
+}
+
+func TestGetDeadLetters_PageOffset(t *testing.T) {
+	repo := new(mocks.MockOutboxRepository)
+	svc := NewOutboxAdminService(repo)
+
+	repo.On("GetDeadLetters", mock.Anything, 50, 100).Return([]model.OutboxMessage{}, nil)
+
+	result, err := svc.GetDeadLetters(context.Background(), 3)
+
+	assert.NoError(t, err)
+	assert.Empty(t, result)
+	repo.AssertExpectations(t)
+}
+
+func TestGetDeadLetters_InvalidPageDefaultsToFirst(t *testing.T) {
+	repo := new(mocks.MockOutboxRepository)
+	svc := NewOutboxAdminService(repo)
+
+	repo.On("GetDeadLetters", mock.Anything, 50, 0).Return([]model.OutboxMessage{}, nil)
+
+	_, err := svc.GetDeadLetters(context.Background(), 0)
+
+	assert.NoError(t, err)
+	repo.AssertExpectations(t)
+}
+
+func TestGetDeadLetters_RepositoryError(t *testing.T) {
+	repo := new(mocks.MockOutboxRepository)
+	svc := NewOutboxAdminService(repo)
+
+	repo.On("GetDeadLetters", mock.Anything, 50, 0).Return([]model.OutboxMessage{}, errors.New("db error"))
+
+	result, err := svc.GetDeadLetters(context.Background(), 1)
+
+	assert.Error(t, err)
+	assert.Nil(t, result)
+	assert.Contains(t, err.Error(), "get dead letters")
+	repo.AssertExpectations(t)
+}
+
+// =====================================================================
+// GetDeadLetterByID
+// =====================================================================
+
+func TestGetDeadLetterByID_Success(t *testing.T) {
+	repo := new(mocks.MockOutboxRepository)
+	svc := NewOutboxAdminService(repo)
+
+	repo.On("GetDeadLetterByID", mock.Anything, uint(7)).Return(sampleDeadLetter(), nil)
+
+	result, err := svc.GetDeadLetterByID(context.Background(), 7)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "wallet.updated", result.EventType)
+	assert.JSONEq(t, `{"id":"aaaaaaaa-aaaa-aaaa-aaaa-aaaaaaaaaaaa"}`, string(result.Payload))
+	repo.AssertExpectations(t)
+}
+
+func TestGetDeadLetterByID_NotFound(t *testing.T) {
+	repo := new(mocks.MockOutboxRepository)
+	svc := NewOutboxAdminService(repo)
+
+	repo.On("GetDeadLetterByID", mock.Anything, uint(7)).
+		Return(model.OutboxMessage{}, fmt.Errorf("%w: %w", repository.ErrDeadLetterNotFound, gorm.ErrRecordNotFound))
+
+	result, err := svc.GetDeadLetterByID(context.Background(), 7)
+
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "not found")
+	assert.ErrorIs(t, err, repository.ErrDeadLetterNotFound)
+	assert.Equal(t, domainerr.NotFound, domainerr.KindOf(err))
+	assert.Zero(t, result.ID)
+	repo.AssertExpectations(t)
+}
+
+// =====================================================================
+// RequeueDeadLetter
+// =====================================================================
+
+func TestRequeueDeadLetter_Success(t *testing.T) {
+	repo := new(mocks.MockOutboxRepository)
+	svc := NewOutboxAdminService(repo)
+
+	repo.On("RequeueDeadLetter", mock.Anything, uint(7)).Return(nil)
+
+	err := svc.RequeueDeadLetter(context.Background(), 7)
+
+	assert.NoError(t, err)
+	repo.AssertExpectations(t)
+}
+
+func TestRequeueDeadLetter_NotFound(t *testing.T) {
+	repo := new(mocks.MockOutboxRepository)
+	svc := NewOutboxAdminService(repo)
+
+	repo.On("RequeueDeadLetter", mock.Anything, uint(7)).Return(gorm.ErrRecordNotFound)
+
+	err := svc.RequeueDeadLetter(context.Background(), 7)
+
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "requeue dead letter")
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+	repo.AssertExpectations(t)
+}
+
+// =====================================================================
+// DiscardDeadLetter
+// =====================================================================
+
+func TestDiscardDeadLetter_Success(t *testing.T) {
+	repo := new(mocks.MockOutboxRepository)
+	svc := NewOutboxAdminService(repo)
+
+	repo.On("DiscardDeadLetter", mock.Anything, uint(7)).Return(nil)
+
+	err := svc.DiscardDeadLetter(context.Background(), 7)
+
+	assert.NoError(t, err)
+	repo.AssertExpectations(t)
+}
+
+func TestDiscardDeadLetter_RepositoryError(t *testing.T) {
+	repo := new(mocks.MockOutboxRepository)
+	svc := NewOutboxAdminService(repo)
+
+	repo.On("DiscardDeadLetter", mock.Anything, uint(7)).Return(errors.New("db error"))
+
+	err := svc.DiscardDeadLetter(context.Background(), 7)
+
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "discard dead letter")
+	repo.AssertExpectations(t)
+}
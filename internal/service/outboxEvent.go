@@ -0,0 +1,75 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"refina-wallet/internal/repository"
+	"refina-wallet/internal/types/dto"
+	"refina-wallet/internal/types/model"
+	"refina-wallet/internal/utils"
+	"refina-wallet/internal/utils/data"
+
+	"github.com/google/uuid"
+)
+
+// enqueueOutboxEvent stamps the next sequence number of aggregateID on a new event and writes it
+// to the outbox within tx, so the event commits (or rolls back) together with the state change.
+func enqueueOutboxEvent(ctx context.Context, outboxRepository repository.OutboxRepository, tx repository.Transaction, eventType, aggregateID string, payload any) error {
+	sequence, err := outboxRepository.NextSequence(ctx, tx, aggregateID)
+	if err != nil {
+		return fmt.Errorf("next sequence [aggregate_id=%s]: %w", aggregateID, err)
+	}
+
+	outboxMsg, err := newOutboxEvent(ctx, eventType, aggregateID, sequence, payload)
+	if err != nil {
+		return err
+	}
+
+	return outboxRepository.Create(ctx, tx, outboxMsg)
+}
+
+// newOutboxEvent wraps payload in a CloudEvents envelope and returns the outbox row that carries it.
+// The X-Request-ID found in ctx becomes the correlation id of the event.
+func newOutboxEvent(ctx context.Context, eventType, aggregateID string, sequence int64, payload any) (*model.OutboxMessage, error) {
+	eventData, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("marshal event data: %w", err)
+	}
+
+	eventID := uuid.New().String()
+	eventTime := time.Now().UTC()
+	correlationID := utils.RequestIDFromContext(ctx)
+
+	envelope, err := json.Marshal(dto.CloudEvent{
+		ID:              eventID,
+		Source:          data.OUTBOX_EVENT_SOURCE,
+		Type:            eventType,
+		SpecVersion:     data.CLOUDEVENTS_SPEC_VERSION,
+		Time:            eventTime.Format(time.RFC3339Nano),
+		Subject:         aggregateID,
+		Sequence:        strconv.FormatInt(sequence, 10),
+		DataContentType: data.OUTBOX_EVENT_DATA_CONTENT_TYPE,
+		SchemaVersion:   data.OUTBOX_EVENT_SCHEMA_VERSION,
+		CorrelationID:   correlationID,
+		Data:            eventData,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("marshal event envelope: %w", err)
+	}
+
+	return &model.OutboxMessage{
+		AggregateID:   aggregateID,
+		EventID:       eventID,
+		EventType:     eventType,
+		Sequence:      sequence,
+		Payload:       envelope,
+		Published:     false,
+		MaxRetries:    data.OUTBOX_PUBLISH_MAX_RETRIES,
+		CorrelationID: correlationID,
+		CreatedAt:     eventTime,
+	}, nil
+}
@@ -0,0 +1,83 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"refina-wallet/internal/service/mocks"
+	"refina-wallet/internal/types/dto"
+	"refina-wallet/internal/types/model"
+	"refina-wallet/internal/utils"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestNewOutboxEvent_WrapsPayloadInCloudEvent(t *testing.T) {
+	ctx := utils.WithRequestID(context.Background(), "req-123")
+	wallet := dto.WalletsResponse{ID: walletID.String(), Name: "BCA"}
+
+	msg, err := newOutboxEvent(ctx, "wallet.created", wallet.ID, 3, wallet)
+
+	assert.NoError(t, err)
+	assert.NotEmpty(t, msg.EventID)
+	assert.Equal(t, "req-123", msg.CorrelationID)
+	assert.Equal(t, wallet.ID, msg.AggregateID)
+	assert.Equal(t, "wallet.created", msg.EventType)
+	assert.Equal(t, int64(3), msg.Sequence)
+
+	var event dto.CloudEvent
+	assert.NoError(t, json.Unmarshal(msg.Payload, &event))
+	assert.Equal(t, msg.EventID, event.ID)
+	assert.Equal(t, "/refina/wallet", event.Source)
+	assert.Equal(t, "wallet.created", event.Type)
+	assert.Equal(t, "1.0", event.SpecVersion)
+	assert.Equal(t, wallet.ID, event.Subject)
+	assert.Equal(t, "3", event.Sequence)
+	assert.Equal(t, "application/json", event.DataContentType)
+	assert.Equal(t, "2", event.SchemaVersion)
+	assert.Equal(t, "req-123", event.CorrelationID)
+
+	var data dto.WalletsResponse
+	assert.NoError(t, json.Unmarshal(event.Data, &data))
+	assert.Equal(t, wallet, data)
+}
+
+func TestNewOutboxEvent_WithoutRequestID(t *testing.T) {
+	msg, err := newOutboxEvent(context.Background(), "wallet.deleted", walletID.String(), 1, dto.WalletsResponse{})
+
+	assert.NoError(t, err)
+	assert.Empty(t, msg.CorrelationID)
+	assert.NotContains(t, string(msg.Payload), "correlationid")
+}
+
+func TestEnqueueOutboxEvent_StampsNextSequence(t *testing.T) {
+	repo := new(mocks.MockOutboxRepository)
+	tx := new(mocks.MockTransaction)
+
+	repo.On("NextSequence", mock.Anything, tx, walletID.String()).Return(int64(42), nil)
+	repo.On("Create", mock.Anything, tx, mock.MatchedBy(func(msg *model.OutboxMessage) bool {
+		return msg.Sequence == 42 && strings.Contains(string(msg.Payload), `"sequence":"42"`)
+	})).Return(nil)
+
+	err := enqueueOutboxEvent(context.Background(), repo, tx, "wallet.updated", walletID.String(), dto.WalletsResponse{})
+
+	assert.NoError(t, err)
+	repo.AssertExpectations(t)
+}
+
+func TestEnqueueOutboxEvent_SequenceError(t *testing.T) {
+	repo := new(mocks.MockOutboxRepository)
+	tx := new(mocks.MockTransaction)
+
+	repo.On("NextSequence", mock.Anything, tx, walletID.String()).Return(int64(0), errors.New("db error"))
+
+	err := enqueueOutboxEvent(context.Background(), repo, tx, "wallet.updated", walletID.String(), dto.WalletsResponse{})
+
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "next sequence")
+	repo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything, mock.Anything)
+}
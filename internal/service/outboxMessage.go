@@ -2,16 +2,24 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"math/rand/v2"
+	"os"
+	"strconv"
+	"sync/atomic"
 	"time"
 
+	"refina-wallet/config/env"
 	"refina-wallet/config/log"
 	"refina-wallet/interface/queue"
 	"refina-wallet/internal/repository"
 	"refina-wallet/internal/types/model"
+	"refina-wallet/internal/utils"
 	"refina-wallet/internal/utils/data"
 
 	"github.com/rabbitmq/amqp091-go"
+	"github.com/rs/xid"
 )
 
 type OutboxPublisher struct {
@@ -19,18 +27,74 @@ type OutboxPublisher struct {
 	queue      queue.RabbitMQClient
 	interval   time.Duration
 	batchSize  int
+	instanceID string
+	claimLease time.Duration
+	wakeup     <-chan struct{}
+
+	retryBaseBackoff time.Duration
+	retryMaxBackoff  time.Duration
+
+	cleanupInterval  time.Duration
+	retention        time.Duration
+	cleanupBatchSize int
+	archive          bool
+	purgedTotal      atomic.Int64
 }
 
 func NewOutboxPublisher(
 	outboxRepo repository.OutboxRepository,
 	rabbitMQ queue.RabbitMQClient,
+	cfg env.Outbox,
 ) *OutboxPublisher {
-	return &OutboxPublisher{
-		outboxRepo: outboxRepo,
-		queue:      rabbitMQ,
-		interval:   data.OUTBOX_PUBLISH_INTERVAL,
-		batchSize:  data.OUTBOX_PUBLISH_BATCH,
+	p := &OutboxPublisher{
+		outboxRepo:       outboxRepo,
+		queue:            rabbitMQ,
+		interval:         data.OUTBOX_PUBLISH_INTERVAL,
+		batchSize:        data.OUTBOX_PUBLISH_BATCH,
+		instanceID:       newInstanceID(),
+		claimLease:       data.OUTBOX_CLAIM_LEASE,
+		retryBaseBackoff: data.OUTBOX_RETRY_BASE_BACKOFF,
+		retryMaxBackoff:  data.OUTBOX_RETRY_MAX_BACKOFF,
+		cleanupInterval:  data.OUTBOX_CLEANUP_INTERVAL,
+		retention:        data.OUTBOX_RETENTION_PERIOD,
+		cleanupBatchSize: data.OUTBOX_CLEANUP_BATCH,
+		archive:          cfg.ArchiveEnabled,
+	}
+
+	if cfg.CleanupInterval > 0 {
+		p.cleanupInterval = cfg.CleanupInterval
+	}
+	if cfg.RetentionPeriod > 0 {
+		p.retention = cfg.RetentionPeriod
+	}
+	if cfg.CleanupBatchSize > 0 {
+		p.cleanupBatchSize = cfg.CleanupBatchSize
+	}
+	if cfg.RetryBaseBackoff > 0 {
+		p.retryBaseBackoff = cfg.RetryBaseBackoff
 	}
+	if cfg.RetryMaxBackoff > 0 {
+		p.retryMaxBackoff = cfg.RetryMaxBackoff
+	}
+
+	return p
+}
+
+// newInstanceID identifies this publisher when it leases outbox rows, so replicas never share a claim.
+func newInstanceID() string {
+	hostname, err := os.Hostname()
+	if err != nil || hostname == "" {
+		hostname = "wallet"
+	}
+	return hostname + "-" + xid.New().String()
+}
+
+// WithWakeup lets the publisher drain as soon as wakeup is signalled (e.g. by a Postgres
+// LISTEN on OUTBOX_NOTIFY_CHANNEL); the ticker then only runs as a slower safety net.
+func (p *OutboxPublisher) WithWakeup(wakeup <-chan struct{}) *OutboxPublisher {
+	p.wakeup = wakeup
+	p.interval = data.OUTBOX_PUBLISH_FALLBACK_INTERVAL
+	return p
 }
 
 // Start begins the outbox publisher worker
@@ -42,25 +106,64 @@ func (p *OutboxPublisher) Start(ctx context.Context) {
 		select {
 		case <-ctx.Done():
 			return
+		case <-p.wakeup:
+			p.drain(ctx)
 		case <-ticker.C:
-			if err := p.publishPendingMessages(ctx); err != nil {
-				log.Error(data.LogOutboxPublishPendingFailed, map[string]any{"service": data.OutboxService, "error": err.Error()})
-			}
+			p.drain(ctx)
+		}
+	}
+}
+
+// drain keeps publishing batches while they go out cleanly. A batch holds at most one message
+// per aggregate, so later messages of a busy wallet only become claimable in the next round.
+func (p *OutboxPublisher) drain(ctx context.Context) {
+	for ctx.Err() == nil {
+		more, err := p.publishBatch(ctx)
+		if err != nil {
+			log.Error(data.LogOutboxPublishPendingFailed, map[string]any{"service": data.OutboxService, "error": err.Error()})
+			return
+		}
+		if !more {
+			return
 		}
 	}
 }
 
 func (p *OutboxPublisher) publishPendingMessages(ctx context.Context) error {
-	messages, err := p.outboxRepo.GetPendingMessages(ctx, p.batchSize)
+	_, err := p.publishBatch(ctx)
+	return err
+}
+
+// publishBatch publishes one claimed batch and reports whether another round is worthwhile:
+// something was claimed and nothing failed, so draining further cannot hot-loop on a failing row.
+func (p *OutboxPublisher) publishBatch(ctx context.Context) (bool, error) {
+	// Diukur sebelum klaim supaya perkiraan akhir lease tidak pernah lebih lambat dari di database
+	leaseEnd := time.Now().Add(p.claimLease)
+	messages, err := p.outboxRepo.ClaimPendingMessages(ctx, p.instanceID, p.claimLease, p.batchSize)
 	if err != nil {
-		return fmt.Errorf("failed to get pending messages: %w", err)
+		return false, fmt.Errorf("failed to claim pending messages: %w", err)
 	}
 
 	if len(messages) == 0 {
-		return nil
+		return false, nil
 	}
 
-	for _, msg := range messages {
+	failed := false
+	lost := make(map[uint]bool)
+	for i, msg := range messages {
+		if time.Until(leaseEnd) < data.OUTBOX_CLAIM_RENEW_MARGIN {
+			renewedAt := time.Now()
+			if err := p.renewClaims(ctx, messages[i:], lost); err != nil {
+				// The rest of the batch is released when its short lease runs out
+				return false, fmt.Errorf("failed to renew outbox claims: %w", err)
+			}
+			leaseEnd = renewedAt.Add(p.claimLease)
+		}
+		if lost[msg.ID] {
+			p.logClaimLost(msg)
+			continue
+		}
+
 		if err := p.publishMessage(ctx, msg); err != nil {
 			log.Error(data.LogOutboxMessagePublishFailed, map[string]any{
 				"service":     data.OutboxService,
@@ -69,36 +172,51 @@ func (p *OutboxPublisher) publishPendingMessages(ctx context.Context) error {
 				"error":       err.Error(),
 			})
 
-			if msg.Retries >= msg.MaxRetries-1 {
-				log.Error(data.LogOutboxMessageMaxRetries, map[string]any{
+			failed = true
+
+			// Increment retry count and back off before the next attempt; the last attempt
+			// parks the message as a dead letter in the same update
+			nextAttemptAt := time.Now().Add(p.retryDelay(msg.Retries))
+			if err := p.outboxRepo.IncrementRetries(ctx, msg.ID, p.instanceID, nextAttemptAt, err.Error()); err != nil {
+				if errors.Is(err, repository.ErrOutboxClaimLost) {
+					p.logClaimLost(msg)
+					continue
+				}
+				log.Error(data.LogOutboxIncrementRetriesFailed, map[string]any{
 					"service":     data.OutboxService,
 					"document_id": msg.ID,
 					"event_type":  msg.EventType,
-					"retries":     msg.Retries,
+					"error":       err.Error(),
 				})
+				continue
 			}
 
-			// Increment retry count
-			if err := p.outboxRepo.IncrementRetries(ctx, msg.ID); err != nil {
-				log.Error(data.LogOutboxIncrementRetriesFailed, map[string]any{
+			if msg.Retries >= msg.MaxRetries-1 {
+				log.Error(data.LogOutboxMessageMaxRetries, map[string]any{
 					"service":     data.OutboxService,
 					"document_id": msg.ID,
 					"event_type":  msg.EventType,
-					"error":       err.Error(),
+					"retries":     msg.Retries,
 				})
+				p.notifyDeadLetter(ctx, msg, err)
 			}
-
 			continue
 		}
 
 		// Mark as published
-		if err := p.outboxRepo.MarkAsPublished(ctx, msg.ID); err != nil {
+		if err := p.outboxRepo.MarkAsPublished(ctx, msg.ID, p.instanceID); err != nil {
+			if errors.Is(err, repository.ErrOutboxClaimLost) {
+				// Publisher yang mengklaim ulang akan mengirim pesan ini lagi; consumer harus idempotent
+				p.logClaimLost(msg)
+				continue
+			}
 			log.Error(data.LogOutboxMarkPublishedFailed, map[string]any{
 				"service":     data.OutboxService,
 				"document_id": msg.ID,
 				"event_type":  msg.EventType,
 				"error":       err.Error(),
 			})
+			failed = true
 			continue
 		}
 
@@ -109,51 +227,144 @@ func (p *OutboxPublisher) publishPendingMessages(ctx context.Context) error {
 		})
 	}
 
-	return nil
+	return !failed, nil
 }
 
-func (p *OutboxPublisher) publishMessage(ctx context.Context, msg model.OutboxMessage) error {
-	ch, err := p.queue.GetChannel()
+// renewClaims extends the lease on the messages not yet published from this batch and marks the
+// ones another publisher took over in lost, so they are skipped instead of published twice.
+func (p *OutboxPublisher) renewClaims(ctx context.Context, pending []model.OutboxMessage, lost map[uint]bool) error {
+	ids := make([]uint, 0, len(pending))
+	for _, msg := range pending {
+		if !lost[msg.ID] {
+			ids = append(ids, msg.ID)
+		}
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+
+	held, err := p.outboxRepo.RenewClaims(ctx, ids, p.instanceID, p.claimLease)
 	if err != nil {
 		return err
 	}
-	defer ch.Close()
-
-	// Declare exchange
-	err = ch.ExchangeDeclare(
-		data.OUTBOX_PUBLISH_EXCHANGE,
-		"topic",
-		true,  // durable
-		false, // auto-deleted
-		false, // internal
-		false, // no-wait
-		nil,
-	)
-	if err != nil {
-		return fmt.Errorf("failed to declare exchange: %w", err)
+
+	stillHeld := make(map[uint]bool, len(held))
+	for _, id := range held {
+		stillHeld[id] = true
+	}
+	for _, id := range ids {
+		if !stillHeld[id] {
+			lost[id] = true
+		}
 	}
+	return nil
+}
 
-	message := amqp091.Publishing{
-		ContentType:  "application/json",
-		Body:         msg.Payload,
-		DeliveryMode: amqp091.Persistent,
-		Timestamp:    time.Now(),
-		MessageId:    fmt.Sprintf("%d", msg.ID),
+// logClaimLost reports a message whose lease expired while it was in flight. Another publisher
+// claimed it since, so this one leaves the row alone instead of overwriting that claim.
+func (p *OutboxPublisher) logClaimLost(msg model.OutboxMessage) {
+	log.Warn(data.LogOutboxClaimLost, map[string]any{
+		"service":     data.OutboxService,
+		"document_id": msg.ID,
+		"event_type":  msg.EventType,
+		"instance_id": p.instanceID,
+	})
+}
+
+// retryDelay is the wait before attempt retries+1: retryBaseBackoff doubled per earlier retry,
+// capped at retryMaxBackoff, with equal jitter so messages failed by one outage spread out.
+func (p *OutboxPublisher) retryDelay(retries int) time.Duration {
+	delay := p.retryBaseBackoff
+	for i := 0; i < retries && delay < p.retryMaxBackoff; i++ {
+		delay *= 2
 	}
+	delay = min(delay, p.retryMaxBackoff)
+
+	half := delay / 2
+	return half + rand.N(delay-half+1)
+}
 
-	return ch.PublishWithContext(
-		ctx,
-		data.OUTBOX_PUBLISH_EXCHANGE,
-		msg.EventType,
-		false, // mandatory
-		false, // immediate
-		message,
-	)
+func (p *OutboxPublisher) publishMessage(ctx context.Context, msg model.OutboxMessage) error {
+	return p.publishTo(ctx, msg.EventType, msg, nil)
+}
+
+// notifyDeadLetter reports a message that IncrementRetries just parked and, best effort, forwards
+// it to the dead-letter routing key so on-call can see it without polling the database.
+func (p *OutboxPublisher) notifyDeadLetter(ctx context.Context, msg model.OutboxMessage, cause error) {
+	log.Warn(data.LogOutboxMessageDeadLettered, map[string]any{
+		"service":     data.OutboxService,
+		"document_id": msg.ID,
+		"event_type":  msg.EventType,
+		"last_error":  cause.Error(),
+	})
+
+	headers := amqp091.Table{
+		"x-original-routing-key": msg.EventType,
+		"x-aggregate-id":         msg.AggregateID,
+		"x-last-error":           cause.Error(),
+	}
+	if err := p.publishTo(ctx, data.OUTBOX_DEAD_LETTER_ROUTING_KEY, msg, headers); err != nil {
+		log.Warn(data.LogOutboxDeadLetterNotifyFailed, map[string]any{
+			"service":     data.OutboxService,
+			"document_id": msg.ID,
+			"event_type":  msg.EventType,
+			"error":       err.Error(),
+		})
+	}
+}
+
+// publishTo only returns nil once the broker confirmed the message, so callers can safely
+// mark it published; nacks and unroutable returns surface as errors and count as a retry.
+func (p *OutboxPublisher) publishTo(ctx context.Context, routingKey string, msg model.OutboxMessage, headers amqp091.Table) error {
+	ctx, cancel := context.WithTimeout(ctx, data.OUTBOX_CONFIRM_TIMEOUT)
+	defer cancel()
+
+	opts := queue.PublishOptions{
+		Headers:   headers,
+		MessageID: fmt.Sprintf("%d", msg.ID),
+	}
+
+	// Rows written before the CloudEvents envelope was introduced keep the legacy raw payload
+	if msg.EventID != "" {
+		opts.MessageID = msg.EventID
+		opts.CorrelationID = msg.CorrelationID
+		opts.ContentType = data.CLOUDEVENTS_CONTENT_TYPE
+		opts.Headers = cloudEventHeaders(msg, headers)
+	}
+
+	message := queue.NewPublishing(msg.Payload, opts)
+
+	return p.queue.PublishWithConfirm(ctx, data.OUTBOX_PUBLISH_EXCHANGE, routingKey, message)
+}
+
+// cloudEventHeaders mirrors the envelope attributes as AMQP headers (CloudEvents AMQP binding)
+// so consumers can route and filter without decoding the body.
+func cloudEventHeaders(msg model.OutboxMessage, extra amqp091.Table) amqp091.Table {
+	headers := amqp091.Table{
+		data.CLOUDEVENTS_HEADER_PREFIX + "id":              msg.EventID,
+		data.CLOUDEVENTS_HEADER_PREFIX + "source":          data.OUTBOX_EVENT_SOURCE,
+		data.CLOUDEVENTS_HEADER_PREFIX + "type":            msg.EventType,
+		data.CLOUDEVENTS_HEADER_PREFIX + "specversion":     data.CLOUDEVENTS_SPEC_VERSION,
+		data.CLOUDEVENTS_HEADER_PREFIX + "time":            msg.CreatedAt.UTC().Format(time.RFC3339Nano),
+		data.CLOUDEVENTS_HEADER_PREFIX + "subject":         msg.AggregateID,
+		data.CLOUDEVENTS_HEADER_PREFIX + "sequence":        strconv.FormatInt(msg.Sequence, 10),
+		data.CLOUDEVENTS_HEADER_PREFIX + "datacontenttype": data.OUTBOX_EVENT_DATA_CONTENT_TYPE,
+		data.CLOUDEVENTS_HEADER_PREFIX + "schemaversion":   data.OUTBOX_EVENT_SCHEMA_VERSION,
+	}
+	if msg.CorrelationID != "" {
+		headers[data.CLOUDEVENTS_HEADER_PREFIX+"correlationid"] = msg.CorrelationID
+	}
+
+	for k, v := range extra {
+		headers[k] = v
+	}
+
+	return headers
 }
 
 // StartCleanupJob removes old published messages
 func (p *OutboxPublisher) StartCleanupJob(ctx context.Context) {
-	ticker := time.NewTicker(1 * time.Hour)
+	ticker := time.NewTicker(p.cleanupInterval)
 	defer ticker.Stop()
 
 	for {
@@ -168,7 +379,53 @@ func (p *OutboxPublisher) StartCleanupJob(ctx context.Context) {
 	}
 }
 
+// PurgedTotal returns the number of outbox rows removed by the cleanup job since startup.
+func (p *OutboxPublisher) PurgedTotal() int64 {
+	return p.purgedTotal.Load()
+}
+
+// cleanupOldMessages deletes (or archives) published messages older than the retention
+// window in batches, so a large backlog never holds one long-running lock on the table.
 func (p *OutboxPublisher) cleanupOldMessages(ctx context.Context) error {
-	// This would need a method in the repository
+	startTime := time.Now()
+	before := startTime.Add(-p.retention)
+
+	var purged int64
+	for {
+		if ctx.Err() != nil {
+			break
+		}
+
+		var (
+			n   int64
+			err error
+		)
+		if p.archive {
+			n, err = p.outboxRepo.ArchivePublishedBefore(ctx, before, p.cleanupBatchSize)
+		} else {
+			n, err = p.outboxRepo.DeletePublishedBefore(ctx, before, p.cleanupBatchSize)
+		}
+		if err != nil {
+			p.purgedTotal.Add(purged)
+			return fmt.Errorf("failed to cleanup published messages (purged %d): %w", purged, err)
+		}
+
+		purged += n
+		if n < int64(p.cleanupBatchSize) {
+			break
+		}
+	}
+
+	p.purgedTotal.Add(purged)
+
+	log.Info(data.LogOutboxCleanupCompleted, map[string]any{
+		"service":      data.OutboxService,
+		"purged":       purged,
+		"purged_total": p.purgedTotal.Load(),
+		"archived":     p.archive,
+		"before":       before.Format(time.RFC3339),
+		"duration":     utils.Ms(time.Since(startTime)),
+	})
+
 	return nil
 }
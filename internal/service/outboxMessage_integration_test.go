@@ -0,0 +1,396 @@
+//go:build integration
+
+package service
+
+// These tests run the outbox SQL against a real Postgres. They are skipped unless
+// TEST_DATABASE_DSN points at a database the test may create schemas in, e.g.
+//
+//	TEST_DATABASE_DSN="host=localhost user=postgres password=postgres dbname=postgres port=5432 sslmode=disable" \
+//		go test -tags integration ./internal/service/ -run Integration
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"refina-wallet/config/env"
+	"refina-wallet/internal/repository"
+	"refina-wallet/internal/service/mocks"
+	"refina-wallet/internal/types/model"
+	"refina-wallet/internal/utils/data"
+
+	"github.com/google/uuid"
+	"github.com/rabbitmq/amqp091-go"
+	"github.com/rs/xid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// openIntegrationDB migrates a fresh schema and returns a connection pool scoped to it.
+// The schema is dropped when the test ends.
+func openIntegrationDB(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	dsn := os.Getenv("TEST_DATABASE_DSN")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_DSN is not set")
+	}
+
+	admin, err := gorm.Open(postgres.Open(dsn), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatalf("failed to connect: %v", err)
+	}
+
+	schema := "it_" + xid.New().String()
+	if err := admin.Exec("CREATE SCHEMA " + schema).Error; err != nil {
+		t.Fatalf("failed to create schema: %v", err)
+	}
+	t.Cleanup(func() {
+		admin.Exec("DROP SCHEMA " + schema + " CASCADE")
+		if sqlDB, err := admin.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+
+	// public stays on the path for the uuid-ossp functions
+	db, err := gorm.Open(postgres.Open(dsn+" search_path="+schema+",public"), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatalf("failed to connect to schema %s: %v", schema, err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+
+	applyMigrations(t, db)
+
+	return db
+}
+
+// applyMigrations runs the goose Up sections of config/db/migrations in order.
+func applyMigrations(t *testing.T, db *gorm.DB) {
+	t.Helper()
+
+	files, err := filepath.Glob(filepath.Join("..", "..", "config", "db", "migrations", "*.sql"))
+	if err != nil || len(files) == 0 {
+		t.Fatalf("no migrations found: %v", err)
+	}
+	sort.Strings(files)
+
+	for _, file := range files {
+		content, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatalf("failed to read %s: %v", file, err)
+		}
+
+		up, _, _ := strings.Cut(string(content), "-- +goose Down")
+		if err := db.Exec(up).Error; err != nil {
+			t.Fatalf("migration %s failed: %v", filepath.Base(file), err)
+		}
+	}
+}
+
+func insertOutboxMessages(t *testing.T, db *gorm.DB, aggregates, perAggregate int) map[string]uint {
+	t.Helper()
+
+	ids := make(map[string]uint)
+	for i := 0; i < aggregates; i++ {
+		aggregateID := uuid.NewString()
+		for j := 0; j < perAggregate; j++ {
+			msg := model.OutboxMessage{
+				AggregateID: aggregateID,
+				EventID:     uuid.NewString(),
+				EventType:   "wallet.updated",
+				Sequence:    int64(j + 1),
+				Payload:     []byte(fmt.Sprintf(`{"seq":%d}`, j+1)),
+				MaxRetries:  5,
+			}
+			if err := db.Create(&msg).Error; err != nil {
+				t.Fatalf("failed to insert outbox message: %v", err)
+			}
+			ids[msg.EventID] = msg.ID
+		}
+	}
+	return ids
+}
+
+func TestPublishPendingMessages_IntegrationConcurrentPublishersPublishEachRowOnce(t *testing.T) {
+	db := openIntegrationDB(t)
+	repo := repository.NewOutboxRepository(db)
+
+	const aggregates, perAggregate = 20, 5
+	eventIDs := insertOutboxMessages(t, db, aggregates, perAggregate)
+
+	var (
+		mu        sync.Mutex
+		published = make(map[uint]int)
+		sequences = make(map[string][]string)
+	)
+	rabbitMQ := new(mocks.MockRabbitMQClient)
+	rabbitMQ.On("PublishWithConfirm", mock.Anything, mock.Anything, "wallet.updated", mock.Anything).
+		Return(nil).
+		Run(func(args mock.Arguments) {
+			message := args.Get(3).(amqp091.Publishing)
+
+			mu.Lock()
+			defer mu.Unlock()
+			published[eventIDs[message.MessageId]]++
+			subject, _ := message.Headers[data.CLOUDEVENTS_HEADER_PREFIX+"subject"].(string)
+			sequences[subject] = append(sequences[subject], string(message.Body))
+		})
+
+	publishers := []*OutboxPublisher{
+		NewOutboxPublisher(repo, rabbitMQ, env.Outbox{}),
+		NewOutboxPublisher(repo, rabbitMQ, env.Outbox{}),
+		NewOutboxPublisher(repo, rabbitMQ, env.Outbox{}),
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	for _, p := range publishers {
+		p.batchSize = 7
+		wg.Add(1)
+		go func(p *OutboxPublisher) {
+			defer wg.Done()
+			for ctx.Err() == nil {
+				var pending int64
+				if err := db.Model(&model.OutboxMessage{}).Where("published = FALSE").Count(&pending).Error; err != nil || pending == 0 {
+					return
+				}
+				p.drain(ctx)
+			}
+		}(p)
+	}
+	wg.Wait()
+
+	assert.NoError(t, ctx.Err(), "publishers did not drain the outbox in time")
+
+	var rows []model.OutboxMessage
+	assert.NoError(t, db.Order("id").Find(&rows).Error)
+
+	expected := make(map[uint]int)
+	for _, id := range eventIDs {
+		expected[id] = 1
+	}
+	assert.Equal(t, expected, published, "every message must be published exactly once")
+
+	for _, row := range rows {
+		assert.True(t, row.Published, "message %d not marked published", row.ID)
+		assert.Zero(t, row.Retries, "message %d was retried", row.ID)
+		assert.Nil(t, row.LockedBy, "message %d still leased", row.ID)
+	}
+
+	// each aggregate is delivered in sequence order even when its messages land on different publishers
+	inOrder := make([]string, perAggregate)
+	for i := range inOrder {
+		inOrder[i] = fmt.Sprintf(`{"seq":%d}`, i+1)
+	}
+	assert.Len(t, sequences, aggregates)
+	for aggregateID, bodies := range sequences {
+		assert.Equal(t, inOrder, bodies, "aggregate %s delivered out of order", aggregateID)
+	}
+}
+
+func TestMarkAsPublished_IntegrationRejectsExpiredClaim(t *testing.T) {
+	db := openIntegrationDB(t)
+	repo := repository.NewOutboxRepository(db)
+	ctx := context.Background()
+
+	insertOutboxMessages(t, db, 1, 1)
+
+	// a zero lease expires right away, so the second publisher may take the row over
+	stale, err := repo.ClaimPendingMessages(ctx, "publisher-a", 0, 10)
+	assert.NoError(t, err)
+	if !assert.Len(t, stale, 1) {
+		return
+	}
+
+	fresh, err := repo.ClaimPendingMessages(ctx, "publisher-b", time.Minute, 10)
+	assert.NoError(t, err)
+	if assert.Len(t, fresh, 1) {
+		assert.Equal(t, stale[0].ID, fresh[0].ID)
+	}
+
+	assert.ErrorIs(t, repo.MarkAsPublished(ctx, stale[0].ID, "publisher-a"), repository.ErrOutboxClaimLost)
+	assert.ErrorIs(t, repo.IncrementRetries(ctx, stale[0].ID, "publisher-a", time.Now(), "late failure"), repository.ErrOutboxClaimLost)
+	assert.NoError(t, repo.MarkAsPublished(ctx, stale[0].ID, "publisher-b"))
+
+	var row model.OutboxMessage
+	assert.NoError(t, db.First(&row, stale[0].ID).Error)
+	assert.True(t, row.Published)
+	assert.Zero(t, row.Retries)
+	assert.Nil(t, row.FailedAt)
+}
+
+func TestRenewClaims_IntegrationKeepsOnlyRowsStillHeld(t *testing.T) {
+	db := openIntegrationDB(t)
+	repo := repository.NewOutboxRepository(db)
+	ctx := context.Background()
+
+	insertOutboxMessages(t, db, 2, 1)
+
+	claimed, err := repo.ClaimPendingMessages(ctx, "publisher-a", 0, 10)
+	assert.NoError(t, err)
+	if !assert.Len(t, claimed, 2) {
+		return
+	}
+
+	// publisher-b takes over one expired row before publisher-a renews
+	assert.NoError(t, db.Model(&model.OutboxMessage{}).Where("id = ?", claimed[1].ID).Update("locked_by", "publisher-b").Error)
+
+	held, err := repo.RenewClaims(ctx, []uint{claimed[0].ID, claimed[1].ID}, "publisher-a", time.Minute)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []uint{claimed[0].ID}, held)
+
+	var row model.OutboxMessage
+	assert.NoError(t, db.First(&row, claimed[0].ID).Error)
+	assert.Equal(t, "publisher-a", *row.LockedBy)
+	assert.NotNil(t, row.LockedUntil)
+}
+
+func TestIncrementRetries_IntegrationLastAttemptDeadLetters(t *testing.T) {
+	db := openIntegrationDB(t)
+	repo := repository.NewOutboxRepository(db)
+	ctx := context.Background()
+
+	insertOutboxMessages(t, db, 1, 1)
+	assert.NoError(t, db.Model(&model.OutboxMessage{}).Where("1 = 1").Update("max_retries", 2).Error)
+
+	for attempt := 1; attempt <= 2; attempt++ {
+		claimed, err := repo.ClaimPendingMessages(ctx, "publisher-a", time.Minute, 10)
+		assert.NoError(t, err)
+		if !assert.Len(t, claimed, 1, "attempt %d", attempt) {
+			return
+		}
+		// a zero next attempt keeps the row claimable right away
+		assert.NoError(t, repo.IncrementRetries(ctx, claimed[0].ID, "publisher-a", time.Time{}, "channel error"))
+
+		var row model.OutboxMessage
+		assert.NoError(t, db.First(&row, claimed[0].ID).Error)
+		assert.Equal(t, attempt, row.Retries)
+		if attempt < 2 {
+			assert.Nil(t, row.FailedAt, "attempt %d must not dead-letter", attempt)
+		} else {
+			assert.NotNil(t, row.FailedAt, "the last attempt must dead-letter")
+		}
+	}
+
+	deadLetters, err := repo.GetDeadLetters(ctx, 10, 0)
+	assert.NoError(t, err)
+	assert.Len(t, deadLetters, 1)
+}
+
+func TestClaimPendingMessages_IntegrationExhaustedRowDoesNotHoldBackAggregate(t *testing.T) {
+	db := openIntegrationDB(t)
+	repo := repository.NewOutboxRepository(db)
+	ctx := context.Background()
+
+	insertOutboxMessages(t, db, 1, 2)
+
+	var rows []model.OutboxMessage
+	assert.NoError(t, db.Order("id").Find(&rows).Error)
+	if !assert.Len(t, rows, 2) {
+		return
+	}
+
+	// out of retries without failed_at, as left behind before the dead-letter transition was atomic
+	assert.NoError(t, db.Model(&model.OutboxMessage{}).Where("id = ?", rows[0].ID).Update("retries", gorm.Expr("max_retries")).Error)
+
+	claimed, err := repo.ClaimPendingMessages(ctx, "publisher-a", time.Minute, 10)
+
+	assert.NoError(t, err)
+	if assert.Len(t, claimed, 1) {
+		assert.Equal(t, rows[1].ID, claimed[0].ID)
+	}
+}
+
+func TestArchivePublishedBefore_IntegrationKeepsEventIDAndSequenceForReplay(t *testing.T) {
+	db := openIntegrationDB(t)
+	repo := repository.NewOutboxRepository(db)
+	ctx := context.Background()
+
+	insertOutboxMessages(t, db, 1, 2)
+
+	var original []model.OutboxMessage
+	assert.NoError(t, db.Order("id").Find(&original).Error)
+	if !assert.Len(t, original, 2) {
+		return
+	}
+	assert.NoError(t, db.Model(&model.OutboxMessage{}).Where("1 = 1").Updates(map[string]any{
+		"published":      true,
+		"published_at":   time.Now().Add(-48 * time.Hour),
+		"correlation_id": "corr-1",
+	}).Error)
+
+	moved, err := repo.ArchivePublishedBefore(ctx, time.Now().Add(-24*time.Hour), 10)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), moved)
+
+	var archived struct {
+		EventID       string
+		Sequence      int64
+		MaxRetries    int
+		CorrelationID string
+	}
+	assert.NoError(t, db.Raw(`SELECT event_id, sequence, max_retries, correlation_id FROM outbox_messages_archive WHERE id = ?`, original[1].ID).Scan(&archived).Error)
+	assert.Equal(t, original[1].EventID, archived.EventID)
+	assert.Equal(t, int64(2), archived.Sequence)
+	assert.Equal(t, 5, archived.MaxRetries)
+	assert.Equal(t, "corr-1", archived.CorrelationID)
+
+	replayed, err := repo.ReplayPublishedRange(ctx, original[0].ID, original[1].ID)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), replayed)
+
+	var copies []model.OutboxMessage
+	assert.NoError(t, db.Where("published = FALSE").Order("id").Find(&copies).Error)
+	if assert.Len(t, copies, 2) {
+		for i := range copies {
+			assert.Equal(t, original[i].EventID, copies[i].EventID)
+			assert.Equal(t, original[i].Sequence, copies[i].Sequence)
+		}
+	}
+}
+
+func TestClaimPendingMessages_IntegrationDeadRowHoldsBackAggregateUntilRequeued(t *testing.T) {
+	db := openIntegrationDB(t)
+	repo := repository.NewOutboxRepository(db)
+	ctx := context.Background()
+
+	insertOutboxMessages(t, db, 1, 2)
+
+	var rows []model.OutboxMessage
+	assert.NoError(t, db.Order("id").Find(&rows).Error)
+	if !assert.Len(t, rows, 2) {
+		return
+	}
+	assert.NoError(t, db.Model(&model.OutboxMessage{}).Where("id = ?", rows[0].ID).Updates(map[string]any{
+		"retries":   gorm.Expr("max_retries"),
+		"failed_at": gorm.Expr("NOW()"),
+	}).Error)
+
+	claimed, err := repo.ClaimPendingMessages(ctx, "publisher-a", time.Minute, 10)
+	assert.NoError(t, err)
+	assert.Empty(t, claimed, "sequence 2 must wait for the dead-lettered sequence 1")
+
+	assert.NoError(t, repo.RequeueDeadLetter(ctx, rows[0].ID))
+
+	claimed, err = repo.ClaimPendingMessages(ctx, "publisher-a", time.Minute, 10)
+	assert.NoError(t, err)
+	if assert.Len(t, claimed, 1) {
+		assert.Equal(t, rows[0].ID, claimed[0].ID)
+	}
+}
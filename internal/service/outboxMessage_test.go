@@ -3,12 +3,18 @@ package service
 import (
 	"context"
 	"errors"
+	"fmt"
 	"testing"
 	"time"
 
+	"refina-wallet/config/env"
+	"refina-wallet/interface/queue"
+	"refina-wallet/internal/repository"
 	"refina-wallet/internal/service/mocks"
 	"refina-wallet/internal/types/model"
+	"refina-wallet/internal/utils/data"
 
+	"github.com/rabbitmq/amqp091-go"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 )
@@ -19,7 +25,7 @@ func newOutboxPublisher(
 	outboxRepo *mocks.MockOutboxRepository,
 	rabbitMQ *mocks.MockRabbitMQClient,
 ) *OutboxPublisher {
-	return NewOutboxPublisher(outboxRepo, rabbitMQ)
+	return NewOutboxPublisher(outboxRepo, rabbitMQ, env.Outbox{})
 }
 
 func sampleOutboxMessages() []model.OutboxMessage {
@@ -52,6 +58,38 @@ func TestNewOutboxPublisher(t *testing.T) {
 	assert.Equal(t, rabbitMQ, publisher.queue)
 	assert.Equal(t, 100, publisher.batchSize)
 	assert.Equal(t, 5*time.Second, publisher.interval)
+	assert.NotEmpty(t, publisher.instanceID)
+	// the lease stays short but must outlast one message, which is renewed before it is published
+	assert.Greater(t, publisher.claimLease, data.OUTBOX_CLAIM_RENEW_MARGIN)
+	assert.Greater(t, data.OUTBOX_CLAIM_RENEW_MARGIN, 2*data.OUTBOX_CONFIRM_TIMEOUT)
+	assert.LessOrEqual(t, publisher.claimLease, 2*time.Minute)
+	assert.Equal(t, time.Hour, publisher.cleanupInterval)
+	assert.Equal(t, 7*24*time.Hour, publisher.retention)
+	assert.Equal(t, 1000, publisher.cleanupBatchSize)
+	assert.False(t, publisher.archive)
+	assert.Equal(t, 30*time.Second, publisher.retryBaseBackoff)
+	assert.Equal(t, 30*time.Minute, publisher.retryMaxBackoff)
+}
+
+func TestNewOutboxPublisher_WithConfig(t *testing.T) {
+	repo := new(mocks.MockOutboxRepository)
+	rabbitMQ := new(mocks.MockRabbitMQClient)
+
+	publisher := NewOutboxPublisher(repo, rabbitMQ, env.Outbox{
+		CleanupInterval:  10 * time.Minute,
+		RetentionPeriod:  48 * time.Hour,
+		CleanupBatchSize: 50,
+		ArchiveEnabled:   true,
+		RetryBaseBackoff: time.Second,
+		RetryMaxBackoff:  time.Minute,
+	})
+
+	assert.Equal(t, 10*time.Minute, publisher.cleanupInterval)
+	assert.Equal(t, 48*time.Hour, publisher.retention)
+	assert.Equal(t, 50, publisher.cleanupBatchSize)
+	assert.True(t, publisher.archive)
+	assert.Equal(t, time.Second, publisher.retryBaseBackoff)
+	assert.Equal(t, time.Minute, publisher.retryMaxBackoff)
 }
 
 // =====================================================================
@@ -64,14 +102,14 @@ func TestPublishPendingMessages_NoPendingMessages(t *testing.T) {
 
 	publisher := newOutboxPublisher(repo, rabbitMQ)
 
-	repo.On("GetPendingMessages", mock.Anything, publisher.batchSize).
+	repo.On("ClaimPendingMessages", mock.Anything, publisher.instanceID, publisher.claimLease, publisher.batchSize).
 		Return([]model.OutboxMessage{}, nil)
 
 	err := publisher.publishPendingMessages(context.Background())
 
 	assert.NoError(t, err)
 	repo.AssertExpectations(t)
-	rabbitMQ.AssertNotCalled(t, "GetChannel")
+	rabbitMQ.AssertNotCalled(t, "PublishWithConfirm")
 }
 
 func TestPublishPendingMessages_GetPendingError(t *testing.T) {
@@ -80,26 +118,84 @@ func TestPublishPendingMessages_GetPendingError(t *testing.T) {
 
 	publisher := newOutboxPublisher(repo, rabbitMQ)
 
-	repo.On("GetPendingMessages", mock.Anything, publisher.batchSize).
+	repo.On("ClaimPendingMessages", mock.Anything, publisher.instanceID, publisher.claimLease, publisher.batchSize).
 		Return([]model.OutboxMessage{}, errors.New("db error"))
 
 	err := publisher.publishPendingMessages(context.Background())
 
 	assert.Error(t, err)
-	assert.Contains(t, err.Error(), "failed to get pending messages")
+	assert.Contains(t, err.Error(), "failed to claim pending messages")
+	repo.AssertExpectations(t)
+}
+
+func TestPublishPendingMessages_Success(t *testing.T) {
+	repo := new(mocks.MockOutboxRepository)
+	rabbitMQ := new(mocks.MockRabbitMQClient)
+
+	publisher := newOutboxPublisher(repo, rabbitMQ)
+
+	messages := sampleOutboxMessages()
+	repo.On("ClaimPendingMessages", mock.Anything, publisher.instanceID, publisher.claimLease, publisher.batchSize).Return(messages, nil)
+	rabbitMQ.On("PublishWithConfirm", mock.Anything, "refina_microservice", "wallet.created", mock.MatchedBy(func(m amqp091.Publishing) bool {
+		return m.MessageId == "1" && m.DeliveryMode == amqp091.Persistent && string(m.Body) == string(messages[0].Payload)
+	})).Return(nil)
+	repo.On("MarkAsPublished", mock.Anything, messages[0].ID, publisher.instanceID).Return(nil)
+
+	err := publisher.publishPendingMessages(context.Background())
+
+	assert.NoError(t, err)
+	repo.AssertExpectations(t)
+	rabbitMQ.AssertExpectations(t)
+	repo.AssertNotCalled(t, "IncrementRetries")
+}
+
+func TestPublishPendingMessages_NackedCountsAsRetry(t *testing.T) {
+	repo := new(mocks.MockOutboxRepository)
+	rabbitMQ := new(mocks.MockRabbitMQClient)
+
+	publisher := newOutboxPublisher(repo, rabbitMQ)
+
+	messages := sampleOutboxMessages()
+	repo.On("ClaimPendingMessages", mock.Anything, publisher.instanceID, publisher.claimLease, publisher.batchSize).Return(messages, nil)
+	rabbitMQ.On("PublishWithConfirm", mock.Anything, mock.Anything, "wallet.created", mock.Anything).Return(queue.ErrPublishNacked)
+	repo.On("IncrementRetries", mock.Anything, messages[0].ID, publisher.instanceID, mock.Anything, mock.Anything).Return(nil)
+
+	err := publisher.publishPendingMessages(context.Background())
+
+	assert.NoError(t, err)
+	repo.AssertExpectations(t)
+	repo.AssertNotCalled(t, "MarkAsPublished")
+}
+
+func TestPublishPendingMessages_ReturnedCountsAsRetry(t *testing.T) {
+	repo := new(mocks.MockOutboxRepository)
+	rabbitMQ := new(mocks.MockRabbitMQClient)
+
+	publisher := newOutboxPublisher(repo, rabbitMQ)
+
+	messages := sampleOutboxMessages()
+	repo.On("ClaimPendingMessages", mock.Anything, publisher.instanceID, publisher.claimLease, publisher.batchSize).Return(messages, nil)
+	rabbitMQ.On("PublishWithConfirm", mock.Anything, mock.Anything, "wallet.created", mock.Anything).
+		Return(fmt.Errorf("%w: 312 NO_ROUTE", queue.ErrPublishReturned))
+	repo.On("IncrementRetries", mock.Anything, messages[0].ID, publisher.instanceID, mock.Anything, mock.Anything).Return(nil)
+
+	err := publisher.publishPendingMessages(context.Background())
+
+	assert.NoError(t, err)
 	repo.AssertExpectations(t)
+	repo.AssertNotCalled(t, "MarkAsPublished")
 }
 
-func TestPublishPendingMessages_GetChannelError(t *testing.T) {
+func TestPublishPendingMessages_PublishError(t *testing.T) {
 	repo := new(mocks.MockOutboxRepository)
 	rabbitMQ := new(mocks.MockRabbitMQClient)
 
 	publisher := newOutboxPublisher(repo, rabbitMQ)
 
 	messages := sampleOutboxMessages()
-	repo.On("GetPendingMessages", mock.Anything, publisher.batchSize).Return(messages, nil)
-	rabbitMQ.On("GetChannel").Return(nil, errors.New("channel error"))
-	repo.On("IncrementRetries", mock.Anything, messages[0].ID).Return(nil)
+	repo.On("ClaimPendingMessages", mock.Anything, publisher.instanceID, publisher.claimLease, publisher.batchSize).Return(messages, nil)
+	rabbitMQ.On("PublishWithConfirm", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(errors.New("channel error"))
+	repo.On("IncrementRetries", mock.Anything, messages[0].ID, publisher.instanceID, mock.Anything, mock.Anything).Return(nil)
 
 	err := publisher.publishPendingMessages(context.Background())
 
@@ -108,6 +204,55 @@ func TestPublishPendingMessages_GetChannelError(t *testing.T) {
 	rabbitMQ.AssertExpectations(t)
 }
 
+func TestPublishPendingMessages_FailureSchedulesBackoffAndRecordsError(t *testing.T) {
+	repo := new(mocks.MockOutboxRepository)
+	rabbitMQ := new(mocks.MockRabbitMQClient)
+
+	publisher := newOutboxPublisher(repo, rabbitMQ)
+
+	messages := sampleOutboxMessages()
+	messages[0].Retries = 2
+	repo.On("ClaimPendingMessages", mock.Anything, publisher.instanceID, publisher.claimLease, publisher.batchSize).Return(messages, nil)
+	rabbitMQ.On("PublishWithConfirm", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(errors.New("channel error"))
+
+	before := time.Now()
+	repo.On("IncrementRetries", mock.Anything, messages[0].ID, publisher.instanceID, mock.MatchedBy(func(next time.Time) bool {
+		// third attempt: 30s * 2^2 = 2m, equal jitter keeps it within [1m, 2m]
+		wait := next.Sub(before)
+		return wait >= time.Minute && wait <= 2*time.Minute+time.Second
+	}), "channel error").Return(nil)
+
+	err := publisher.publishPendingMessages(context.Background())
+
+	assert.NoError(t, err)
+	repo.AssertExpectations(t)
+}
+
+func TestRetryDelay_GrowsExponentiallyWithJitterAndCap(t *testing.T) {
+	publisher := newOutboxPublisher(new(mocks.MockOutboxRepository), new(mocks.MockRabbitMQClient))
+	publisher.retryBaseBackoff = time.Second
+	publisher.retryMaxBackoff = 10 * time.Second
+
+	cases := []struct {
+		retries  int
+		min, max time.Duration
+	}{
+		{0, 500 * time.Millisecond, time.Second},
+		{1, time.Second, 2 * time.Second},
+		{3, 4 * time.Second, 8 * time.Second},
+		{4, 5 * time.Second, 10 * time.Second},
+		{100, 5 * time.Second, 10 * time.Second},
+	}
+
+	for _, tc := range cases {
+		for range 50 {
+			delay := publisher.retryDelay(tc.retries)
+			assert.GreaterOrEqual(t, delay, tc.min, "retries=%d", tc.retries)
+			assert.LessOrEqual(t, delay, tc.max, "retries=%d", tc.retries)
+		}
+	}
+}
+
 func TestPublishPendingMessages_IncrementRetriesError(t *testing.T) {
 	repo := new(mocks.MockOutboxRepository)
 	rabbitMQ := new(mocks.MockRabbitMQClient)
@@ -115,9 +260,9 @@ func TestPublishPendingMessages_IncrementRetriesError(t *testing.T) {
 	publisher := newOutboxPublisher(repo, rabbitMQ)
 
 	messages := sampleOutboxMessages()
-	repo.On("GetPendingMessages", mock.Anything, publisher.batchSize).Return(messages, nil)
-	rabbitMQ.On("GetChannel").Return(nil, errors.New("channel error"))
-	repo.On("IncrementRetries", mock.Anything, messages[0].ID).Return(errors.New("db error"))
+	repo.On("ClaimPendingMessages", mock.Anything, publisher.instanceID, publisher.claimLease, publisher.batchSize).Return(messages, nil)
+	rabbitMQ.On("PublishWithConfirm", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(errors.New("channel error"))
+	repo.On("IncrementRetries", mock.Anything, messages[0].ID, publisher.instanceID, mock.Anything, mock.Anything).Return(errors.New("db error"))
 
 	err := publisher.publishPendingMessages(context.Background())
 
@@ -135,23 +280,167 @@ func TestPublishPendingMessages_MaxRetriesExceeded(t *testing.T) {
 	messages[0].Retries = 4 // at MaxRetries-1 = 4, should log max retries
 	messages[0].MaxRetries = 5
 
-	repo.On("GetPendingMessages", mock.Anything, publisher.batchSize).Return(messages, nil)
-	rabbitMQ.On("GetChannel").Return(nil, errors.New("channel error"))
-	repo.On("IncrementRetries", mock.Anything, messages[0].ID).Return(nil)
+	repo.On("ClaimPendingMessages", mock.Anything, publisher.instanceID, publisher.claimLease, publisher.batchSize).Return(messages, nil)
+	rabbitMQ.On("PublishWithConfirm", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(errors.New("channel error"))
+	repo.On("IncrementRetries", mock.Anything, messages[0].ID, publisher.instanceID, mock.Anything, mock.Anything).Return(nil)
+
+	err := publisher.publishPendingMessages(context.Background())
+
+	assert.NoError(t, err)
+	repo.AssertExpectations(t)
+	// once for the original publish, once for the dead-letter notification
+	rabbitMQ.AssertNumberOfCalls(t, "PublishWithConfirm", 2)
+}
+
+func TestPublishPendingMessages_BelowMaxRetriesNotDeadLettered(t *testing.T) {
+	repo := new(mocks.MockOutboxRepository)
+	rabbitMQ := new(mocks.MockRabbitMQClient)
+
+	publisher := newOutboxPublisher(repo, rabbitMQ)
+
+	messages := sampleOutboxMessages()
+	messages[0].Retries = 2
+	messages[0].MaxRetries = 5
+
+	repo.On("ClaimPendingMessages", mock.Anything, publisher.instanceID, publisher.claimLease, publisher.batchSize).Return(messages, nil)
+	rabbitMQ.On("PublishWithConfirm", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(errors.New("channel error"))
+	repo.On("IncrementRetries", mock.Anything, messages[0].ID, publisher.instanceID, mock.Anything, mock.Anything).Return(nil)
 
 	err := publisher.publishPendingMessages(context.Background())
 
 	assert.NoError(t, err)
 	repo.AssertExpectations(t)
+	// no dead-letter notification
+	rabbitMQ.AssertNumberOfCalls(t, "PublishWithConfirm", 1)
+}
+
+func TestPublishPendingMessages_LastRetryNotRecordedSkipsDeadLetterNotification(t *testing.T) {
+	repo := new(mocks.MockOutboxRepository)
+	rabbitMQ := new(mocks.MockRabbitMQClient)
+
+	publisher := newOutboxPublisher(repo, rabbitMQ)
+
+	messages := sampleOutboxMessages()
+	messages[0].Retries = 4
+	messages[0].MaxRetries = 5
+
+	repo.On("ClaimPendingMessages", mock.Anything, publisher.instanceID, publisher.claimLease, publisher.batchSize).Return(messages, nil)
+	rabbitMQ.On("PublishWithConfirm", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(errors.New("channel error")).Once()
+	repo.On("IncrementRetries", mock.Anything, messages[0].ID, publisher.instanceID, mock.Anything, mock.Anything).Return(errors.New("db error"))
+
+	err := publisher.publishPendingMessages(context.Background())
+
+	assert.NoError(t, err)
+	repo.AssertExpectations(t)
+	// dead-letter notification is skipped when the row could not be parked
+	rabbitMQ.AssertNumberOfCalls(t, "PublishWithConfirm", 1)
 }
 
 func TestPublishPendingMessages_MarkPublishedError(t *testing.T) {
-	// NOTE: This test cannot be fully exercised because publishMessage uses
-	// amqp091.Channel directly (not an interface), so we cannot mock a successful
-	// publish followed by a MarkAsPublished error without a real RabbitMQ connection.
-	// The GetChannel-error path is already covered by TestPublishPendingMessages_GetChannelError.
-	// If the amqp channel were wrapped behind an interface, this could be fully tested.
-	t.Skip("publishMessage requires a real amqp091.Channel; covered by integration tests")
+	repo := new(mocks.MockOutboxRepository)
+	rabbitMQ := new(mocks.MockRabbitMQClient)
+
+	publisher := newOutboxPublisher(repo, rabbitMQ)
+
+	messages := sampleOutboxMessages()
+	repo.On("ClaimPendingMessages", mock.Anything, publisher.instanceID, publisher.claimLease, publisher.batchSize).Return(messages, nil)
+	rabbitMQ.On("PublishWithConfirm", mock.Anything, mock.Anything, "wallet.created", mock.Anything).Return(nil)
+	repo.On("MarkAsPublished", mock.Anything, messages[0].ID, publisher.instanceID).Return(errors.New("db error"))
+
+	err := publisher.publishPendingMessages(context.Background())
+
+	assert.NoError(t, err) // errors are logged per-message, not returned
+	repo.AssertExpectations(t)
+	repo.AssertNotCalled(t, "IncrementRetries")
+}
+
+func TestPublishPendingMessages_ClaimLostAfterPublish(t *testing.T) {
+	repo := new(mocks.MockOutboxRepository)
+	rabbitMQ := new(mocks.MockRabbitMQClient)
+
+	publisher := newOutboxPublisher(repo, rabbitMQ)
+
+	messages := sampleOutboxMessages()
+	repo.On("ClaimPendingMessages", mock.Anything, publisher.instanceID, publisher.claimLease, publisher.batchSize).Return(messages, nil)
+	rabbitMQ.On("PublishWithConfirm", mock.Anything, mock.Anything, "wallet.created", mock.Anything).Return(nil)
+	repo.On("MarkAsPublished", mock.Anything, messages[0].ID, publisher.instanceID).Return(repository.ErrOutboxClaimLost)
+
+	more, err := publisher.publishBatch(context.Background())
+
+	assert.NoError(t, err)
+	// the row now belongs to another publisher, so it is not counted as a failure here
+	assert.True(t, more)
+	repo.AssertExpectations(t)
+}
+
+func TestPublishPendingMessages_ClaimLostSkipsDeadLetter(t *testing.T) {
+	repo := new(mocks.MockOutboxRepository)
+	rabbitMQ := new(mocks.MockRabbitMQClient)
+
+	publisher := newOutboxPublisher(repo, rabbitMQ)
+
+	messages := sampleOutboxMessages()
+	messages[0].Retries = 4
+	messages[0].MaxRetries = 5
+
+	repo.On("ClaimPendingMessages", mock.Anything, publisher.instanceID, publisher.claimLease, publisher.batchSize).Return(messages, nil)
+	rabbitMQ.On("PublishWithConfirm", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(errors.New("channel error")).Once()
+	repo.On("IncrementRetries", mock.Anything, messages[0].ID, publisher.instanceID, mock.Anything, mock.Anything).Return(repository.ErrOutboxClaimLost)
+
+	err := publisher.publishPendingMessages(context.Background())
+
+	assert.NoError(t, err)
+	repo.AssertExpectations(t)
+	rabbitMQ.AssertNumberOfCalls(t, "PublishWithConfirm", 1)
+}
+
+func TestPublishPendingMessages_RenewsClaimsNearLeaseEnd(t *testing.T) {
+	repo := new(mocks.MockOutboxRepository)
+	rabbitMQ := new(mocks.MockRabbitMQClient)
+
+	publisher := newOutboxPublisher(repo, rabbitMQ)
+	// a lease no longer than the margin forces a renewal before every message
+	publisher.claimLease = data.OUTBOX_CLAIM_RENEW_MARGIN
+
+	messages := append(sampleOutboxMessages(), model.OutboxMessage{
+		ID:          2,
+		AggregateID: "bbbbbbbb-bbbb-bbbb-bbbb-bbbbbbbbbbbb",
+		EventType:   "wallet.updated",
+		Payload:     []byte(`{}`),
+		MaxRetries:  5,
+	})
+	repo.On("ClaimPendingMessages", mock.Anything, publisher.instanceID, publisher.claimLease, publisher.batchSize).Return(messages, nil)
+	// the second message was taken over by another publisher while the first was in flight
+	repo.On("RenewClaims", mock.Anything, []uint{1, 2}, publisher.instanceID, publisher.claimLease).Return([]uint{1}, nil).Once()
+	rabbitMQ.On("PublishWithConfirm", mock.Anything, mock.Anything, "wallet.created", mock.Anything).Return(nil).Once()
+	repo.On("MarkAsPublished", mock.Anything, uint(1), publisher.instanceID).Return(nil)
+
+	more, err := publisher.publishBatch(context.Background())
+
+	assert.NoError(t, err)
+	assert.True(t, more)
+	repo.AssertExpectations(t)
+	rabbitMQ.AssertNumberOfCalls(t, "PublishWithConfirm", 1)
+	repo.AssertNotCalled(t, "MarkAsPublished", mock.Anything, uint(2), mock.Anything)
+}
+
+func TestPublishPendingMessages_RenewClaimsError(t *testing.T) {
+	repo := new(mocks.MockOutboxRepository)
+	rabbitMQ := new(mocks.MockRabbitMQClient)
+
+	publisher := newOutboxPublisher(repo, rabbitMQ)
+	publisher.claimLease = data.OUTBOX_CLAIM_RENEW_MARGIN
+
+	messages := sampleOutboxMessages()
+	repo.On("ClaimPendingMessages", mock.Anything, publisher.instanceID, publisher.claimLease, publisher.batchSize).Return(messages, nil)
+	repo.On("RenewClaims", mock.Anything, []uint{1}, publisher.instanceID, publisher.claimLease).Return(nil, errors.New("db error"))
+
+	more, err := publisher.publishBatch(context.Background())
+
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to renew outbox claims")
+	assert.False(t, more)
+	rabbitMQ.AssertNotCalled(t, "PublishWithConfirm")
 }
 
 // =====================================================================
@@ -167,8 +456,8 @@ func TestStart_ContextCancellation(t *testing.T) {
 
 	ctx, cancel := context.WithCancel(context.Background())
 
-	// Mock for any GetPendingMessages call that might fire during the tick
-	repo.On("GetPendingMessages", mock.Anything, publisher.batchSize).
+	// Mock for any ClaimPendingMessages call that might fire during the tick
+	repo.On("ClaimPendingMessages", mock.Anything, publisher.instanceID, publisher.claimLease, publisher.batchSize).
 		Return([]model.OutboxMessage{}, nil).Maybe()
 
 	done := make(chan struct{})
@@ -189,6 +478,76 @@ func TestStart_ContextCancellation(t *testing.T) {
 	}
 }
 
+func TestStart_WakeupPublishesWithoutWaitingForTicker(t *testing.T) {
+	repo := new(mocks.MockOutboxRepository)
+	rabbitMQ := new(mocks.MockRabbitMQClient)
+
+	wakeup := make(chan struct{}, 1)
+	publisher := newOutboxPublisher(repo, rabbitMQ).WithWakeup(wakeup)
+	assert.Equal(t, 30*time.Second, publisher.interval)
+
+	messages := sampleOutboxMessages()
+	published := make(chan struct{})
+	repo.On("ClaimPendingMessages", mock.Anything, publisher.instanceID, publisher.claimLease, publisher.batchSize).Return(messages, nil).Once()
+	repo.On("ClaimPendingMessages", mock.Anything, publisher.instanceID, publisher.claimLease, publisher.batchSize).Return([]model.OutboxMessage{}, nil).Maybe()
+	rabbitMQ.On("PublishWithConfirm", mock.Anything, mock.Anything, "wallet.created", mock.Anything).Return(nil).Once()
+	repo.On("MarkAsPublished", mock.Anything, messages[0].ID, publisher.instanceID).Return(nil).Once().Run(func(mock.Arguments) {
+		close(published)
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go publisher.Start(ctx)
+
+	wakeup <- struct{}{}
+
+	select {
+	case <-published:
+	case <-time.After(2 * time.Second):
+		t.Fatal("publisher did not react to wakeup")
+	}
+	repo.AssertExpectations(t)
+}
+
+func TestDrain_ContinuesUntilNothingIsClaimed(t *testing.T) {
+	repo := new(mocks.MockOutboxRepository)
+	rabbitMQ := new(mocks.MockRabbitMQClient)
+
+	publisher := newOutboxPublisher(repo, rabbitMQ)
+	publisher.batchSize = 1
+
+	first := sampleOutboxMessages()
+	second := sampleOutboxMessages()
+	second[0].ID = 2
+
+	repo.On("ClaimPendingMessages", mock.Anything, publisher.instanceID, publisher.claimLease, 1).Return(first, nil).Once()
+	repo.On("ClaimPendingMessages", mock.Anything, publisher.instanceID, publisher.claimLease, 1).Return(second, nil).Once()
+	repo.On("ClaimPendingMessages", mock.Anything, publisher.instanceID, publisher.claimLease, 1).Return([]model.OutboxMessage{}, nil).Once()
+	rabbitMQ.On("PublishWithConfirm", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	repo.On("MarkAsPublished", mock.Anything, mock.Anything, publisher.instanceID).Return(nil)
+
+	publisher.drain(context.Background())
+
+	repo.AssertNumberOfCalls(t, "ClaimPendingMessages", 3)
+	repo.AssertNumberOfCalls(t, "MarkAsPublished", 2)
+}
+
+func TestDrain_StopsAfterFailedMessage(t *testing.T) {
+	repo := new(mocks.MockOutboxRepository)
+	rabbitMQ := new(mocks.MockRabbitMQClient)
+
+	publisher := newOutboxPublisher(repo, rabbitMQ)
+	publisher.batchSize = 1
+
+	repo.On("ClaimPendingMessages", mock.Anything, publisher.instanceID, publisher.claimLease, 1).Return(sampleOutboxMessages(), nil).Once()
+	rabbitMQ.On("PublishWithConfirm", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(errors.New("channel error"))
+	repo.On("IncrementRetries", mock.Anything, uint(1), publisher.instanceID, mock.Anything, mock.Anything).Return(nil)
+
+	publisher.drain(context.Background())
+
+	repo.AssertNumberOfCalls(t, "ClaimPendingMessages", 1)
+}
+
 // =====================================================================
 // StartCleanupJob — context cancellation
 // =====================================================================
@@ -222,13 +581,139 @@ func TestStartCleanupJob_ContextCancellation(t *testing.T) {
 // cleanupOldMessages
 // =====================================================================
 
-func TestCleanupOldMessages(t *testing.T) {
+func TestCleanupOldMessages_NothingToPurge(t *testing.T) {
 	repo := new(mocks.MockOutboxRepository)
 	rabbitMQ := new(mocks.MockRabbitMQClient)
 
 	publisher := newOutboxPublisher(repo, rabbitMQ)
 
+	repo.On("DeletePublishedBefore", mock.Anything, mock.AnythingOfType("time.Time"), publisher.cleanupBatchSize).
+		Return(int64(0), nil).Once()
+
 	err := publisher.cleanupOldMessages(context.Background())
 
-	assert.NoError(t, err) // currently returns nil
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), publisher.PurgedTotal())
+	repo.AssertExpectations(t)
+	repo.AssertNotCalled(t, "ArchivePublishedBefore")
+}
+
+func TestCleanupOldMessages_MultipleBatches(t *testing.T) {
+	repo := new(mocks.MockOutboxRepository)
+	rabbitMQ := new(mocks.MockRabbitMQClient)
+
+	publisher := newOutboxPublisher(repo, rabbitMQ)
+	publisher.cleanupBatchSize = 2
+
+	repo.On("DeletePublishedBefore", mock.Anything, mock.AnythingOfType("time.Time"), 2).
+		Return(int64(2), nil).Twice()
+	repo.On("DeletePublishedBefore", mock.Anything, mock.AnythingOfType("time.Time"), 2).
+		Return(int64(1), nil).Once()
+
+	err := publisher.cleanupOldMessages(context.Background())
+
+	assert.NoError(t, err)
+	assert.Equal(t, int64(5), publisher.PurgedTotal())
+	repo.AssertExpectations(t)
+}
+
+func TestCleanupOldMessages_RespectsRetention(t *testing.T) {
+	repo := new(mocks.MockOutboxRepository)
+	rabbitMQ := new(mocks.MockRabbitMQClient)
+
+	publisher := newOutboxPublisher(repo, rabbitMQ)
+	publisher.retention = 24 * time.Hour
+
+	upper := time.Now().Add(-24 * time.Hour)
+	repo.On("DeletePublishedBefore", mock.Anything, mock.MatchedBy(func(before time.Time) bool {
+		return !before.Before(upper) && before.Before(upper.Add(time.Minute))
+	}), publisher.cleanupBatchSize).Return(int64(0), nil).Once()
+
+	err := publisher.cleanupOldMessages(context.Background())
+
+	assert.NoError(t, err)
+	repo.AssertExpectations(t)
+}
+
+func TestCleanupOldMessages_ArchiveMode(t *testing.T) {
+	repo := new(mocks.MockOutboxRepository)
+	rabbitMQ := new(mocks.MockRabbitMQClient)
+
+	publisher := newOutboxPublisher(repo, rabbitMQ)
+	publisher.archive = true
+
+	repo.On("ArchivePublishedBefore", mock.Anything, mock.AnythingOfType("time.Time"), publisher.cleanupBatchSize).
+		Return(int64(3), nil).Once()
+
+	err := publisher.cleanupOldMessages(context.Background())
+
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), publisher.PurgedTotal())
+	repo.AssertExpectations(t)
+	repo.AssertNotCalled(t, "DeletePublishedBefore")
+}
+
+func TestCleanupOldMessages_RepositoryError(t *testing.T) {
+	repo := new(mocks.MockOutboxRepository)
+	rabbitMQ := new(mocks.MockRabbitMQClient)
+
+	publisher := newOutboxPublisher(repo, rabbitMQ)
+	publisher.cleanupBatchSize = 2
+
+	repo.On("DeletePublishedBefore", mock.Anything, mock.AnythingOfType("time.Time"), 2).
+		Return(int64(2), nil).Once()
+	repo.On("DeletePublishedBefore", mock.Anything, mock.AnythingOfType("time.Time"), 2).
+		Return(int64(0), errors.New("db error")).Once()
+
+	err := publisher.cleanupOldMessages(context.Background())
+
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to cleanup published messages")
+	assert.Equal(t, int64(2), publisher.PurgedTotal())
+	repo.AssertExpectations(t)
+}
+
+func TestCleanupOldMessages_ContextCancelled(t *testing.T) {
+	repo := new(mocks.MockOutboxRepository)
+	rabbitMQ := new(mocks.MockRabbitMQClient)
+
+	publisher := newOutboxPublisher(repo, rabbitMQ)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := publisher.cleanupOldMessages(ctx)
+
+	assert.NoError(t, err)
+	repo.AssertNotCalled(t, "DeletePublishedBefore")
+}
+
+func TestPublishPendingMessages_CloudEventHeaders(t *testing.T) {
+	repo := new(mocks.MockOutboxRepository)
+	rabbitMQ := new(mocks.MockRabbitMQClient)
+
+	publisher := newOutboxPublisher(repo, rabbitMQ)
+
+	messages := sampleOutboxMessages()
+	messages[0].EventID = "bbbbbbbb-bbbb-bbbb-bbbb-bbbbbbbbbbbb"
+	messages[0].CorrelationID = "req-123"
+
+	repo.On("ClaimPendingMessages", mock.Anything, publisher.instanceID, publisher.claimLease, publisher.batchSize).Return(messages, nil)
+	rabbitMQ.On("PublishWithConfirm", mock.Anything, "refina_microservice", "wallet.created", mock.MatchedBy(func(m amqp091.Publishing) bool {
+		return m.MessageId == messages[0].EventID &&
+			m.CorrelationId == "req-123" &&
+			m.ContentType == "application/cloudevents+json" &&
+			m.Headers["cloudEvents_id"] == messages[0].EventID &&
+			m.Headers["cloudEvents_type"] == "wallet.created" &&
+			m.Headers["cloudEvents_subject"] == messages[0].AggregateID &&
+			m.Headers["cloudEvents_specversion"] == "1.0" &&
+			m.Headers["cloudEvents_correlationid"] == "req-123"
+	})).Return(nil)
+	repo.On("MarkAsPublished", mock.Anything, messages[0].ID, publisher.instanceID).Return(nil)
+
+	err := publisher.publishPendingMessages(context.Background())
+
+	assert.NoError(t, err)
+	repo.AssertExpectations(t)
+	rabbitMQ.AssertExpectations(t)
 }
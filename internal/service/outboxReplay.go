@@ -0,0 +1,140 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"refina-wallet/internal/repository"
+	"refina-wallet/internal/types/domainerr"
+	"refina-wallet/internal/types/dto"
+	"refina-wallet/internal/utils"
+	"refina-wallet/internal/utils/data"
+)
+
+// OutboxReplayService re-enqueues events through the outbox so new or recovering consumers
+// can rebuild their view of wallets.
+type OutboxReplayService interface {
+	BackfillWalletSnapshots(ctx context.Context, req dto.OutboxBackfillRequest) (int64, error)
+	ReplayPublished(ctx context.Context, req dto.OutboxReplayRequest) (int64, error)
+}
+
+var (
+	ErrInvalidIDRange   = domainerr.New(domainerr.InvalidArgument, "INVALID_ID_RANGE", "invalid id range")
+	ErrInvalidTimeRange = domainerr.New(domainerr.InvalidArgument, "INVALID_TIME_RANGE", "invalid time range")
+)
+
+type outboxReplayService struct {
+	txManager         repository.TxManager
+	walletsRepository repository.WalletsRepository
+	outboxRepository  repository.OutboxRepository
+	batchSize         int
+}
+
+func NewOutboxReplayService(
+	txManager repository.TxManager,
+	walletsRepository repository.WalletsRepository,
+	outboxRepository repository.OutboxRepository,
+) OutboxReplayService {
+	return &outboxReplayService{
+		txManager:         txManager,
+		walletsRepository: walletsRepository,
+		outboxRepository:  outboxRepository,
+		batchSize:         data.OUTBOX_BACKFILL_BATCH,
+	}
+}
+
+// BackfillWalletSnapshots writes a wallet.snapshot event for every wallet matching req.
+// Each page is committed on its own, so a failure part-way keeps the pages already enqueued.
+func (replay_serv *outboxReplayService) BackfillWalletSnapshots(ctx context.Context, req dto.OutboxBackfillRequest) (int64, error) {
+	filter, err := parseBackfillFilter(req)
+	if err != nil {
+		return 0, err
+	}
+
+	var enqueued int64
+	afterID := ""
+	for {
+		if err := ctx.Err(); err != nil {
+			return enqueued, fmt.Errorf("backfill wallet snapshots: %w", err)
+		}
+
+		wallets, err := replay_serv.walletsRepository.GetWalletsPage(ctx, nil, filter, afterID, replay_serv.batchSize)
+		if err != nil {
+			return enqueued, fmt.Errorf("backfill wallet snapshots: get wallets: %w", err)
+		}
+		if len(wallets) == 0 {
+			return enqueued, nil
+		}
+
+		tx, err := replay_serv.txManager.Begin(ctx)
+		if err != nil {
+			return enqueued, fmt.Errorf("backfill wallet snapshots: begin transaction: %w", err)
+		}
+
+		for _, wallet := range wallets {
+			walletResponse := utils.ConvertToResponseType(wallet).(dto.WalletsResponse)
+
+			if err := enqueueOutboxEvent(ctx, replay_serv.outboxRepository, tx, data.OUTBOX_EVENT_WALLET_SNAPSHOT, walletResponse.ID, walletResponse); err != nil {
+				tx.Rollback()
+				return enqueued, fmt.Errorf("backfill wallet snapshots: save outbox message: %w", err)
+			}
+		}
+
+		if err := tx.Commit(); err != nil {
+			return enqueued, fmt.Errorf("backfill wallet snapshots: commit transaction: %w", err)
+		}
+
+		enqueued += int64(len(wallets))
+		if len(wallets) < replay_serv.batchSize {
+			return enqueued, nil
+		}
+		afterID = wallets[len(wallets)-1].ID.String()
+	}
+}
+
+func (replay_serv *outboxReplayService) ReplayPublished(ctx context.Context, req dto.OutboxReplayRequest) (int64, error) {
+	if req.FromID == 0 || req.ToID < req.FromID {
+		return 0, fmt.Errorf("%w [from=%d to=%d]", ErrInvalidIDRange, req.FromID, req.ToID)
+	}
+
+	enqueued, err := replay_serv.outboxRepository.ReplayPublishedRange(ctx, req.FromID, req.ToID)
+	if err != nil {
+		return 0, fmt.Errorf("replay published [from=%d to=%d]: %w", req.FromID, req.ToID, err)
+	}
+
+	return enqueued, nil
+}
+
+func parseBackfillFilter(req dto.OutboxBackfillRequest) (repository.WalletFilter, error) {
+	filter := repository.WalletFilter{}
+
+	if req.UserID != "" {
+		if _, err := utils.ParseUUID(req.UserID); err != nil {
+			return filter, fmt.Errorf("%w: %w", ErrInvalidUserID, err)
+		}
+		filter.UserID = req.UserID
+	}
+
+	if req.From != "" {
+		from, err := time.Parse(time.RFC3339, req.From)
+		if err != nil {
+			return filter, fmt.Errorf("%w: %w", ErrInvalidTimeRange, err)
+		}
+		filter.CreatedFrom = &from
+	}
+
+	if req.To != "" {
+		to, err := time.Parse(time.RFC3339, req.To)
+		if err != nil {
+			return filter, fmt.Errorf("%w: %w", ErrInvalidTimeRange, err)
+		}
+		filter.CreatedTo = &to
+	}
+
+	if filter.CreatedFrom != nil && filter.CreatedTo != nil && !filter.CreatedFrom.Before(*filter.CreatedTo) {
+		return filter, fmt.Errorf("%w: from must be before to", ErrInvalidTimeRange)
+	}
+
+	return filter, nil
+}
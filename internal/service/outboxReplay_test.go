@@ -0,0 +1,198 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"refina-wallet/internal/repository"
+	"refina-wallet/internal/service/mocks"
+	"refina-wallet/internal/types/domainerr"
+	"refina-wallet/internal/types/dto"
+	"refina-wallet/internal/types/model"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// ---------- helpers ----------
+
+type outboxReplayTestDeps struct {
+	txManager   *mocks.MockTxManager
+	walletsRepo *mocks.MockWalletsRepository
+	outboxRepo  *mocks.MockOutboxRepository
+	tx          *mocks.MockTransaction
+}
+
+func newOutboxReplayTestDeps() *outboxReplayTestDeps {
+	return &outboxReplayTestDeps{
+		txManager:   new(mocks.MockTxManager),
+		walletsRepo: new(mocks.MockWalletsRepository),
+		outboxRepo:  new(mocks.MockOutboxRepository),
+		tx:          new(mocks.MockTransaction),
+	}
+}
+
+func (d *outboxReplayTestDeps) service(batchSize int) *outboxReplayService {
+	svc := NewOutboxReplayService(d.txManager, d.walletsRepo, d.outboxRepo).(*outboxReplayService)
+	svc.batchSize = batchSize
+	return svc
+}
+
+func (d *outboxReplayTestDeps) assertAll(t *testing.T) {
+	d.txManager.AssertExpectations(t)
+	d.walletsRepo.AssertExpectations(t)
+	d.outboxRepo.AssertExpectations(t)
+	d.tx.AssertExpectations(t)
+}
+
+func walletWithID(id string) model.Wallets {
+	w := sampleWalletModel()
+	w.ID = uuid.MustParse(id)
+	return w
+}
+
+// =====================================================================
+// BackfillWalletSnapshots
+// =====================================================================
+
+func TestBackfillWalletSnapshots_PagesThroughAllWallets(t *testing.T) {
+	d := newOutboxReplayTestDeps()
+	svc := d.service(2)
+
+	first := []model.Wallets{
+		walletWithID("00000000-0000-0000-0000-000000000001"),
+		walletWithID("00000000-0000-0000-0000-000000000002"),
+	}
+	second := []model.Wallets{walletWithID("00000000-0000-0000-0000-000000000003")}
+
+	d.walletsRepo.On("GetWalletsPage", mock.Anything, nil, repository.WalletFilter{}, "", 2).Return(first, nil)
+	d.walletsRepo.On("GetWalletsPage", mock.Anything, nil, repository.WalletFilter{}, first[1].ID.String(), 2).Return(second, nil)
+	d.txManager.On("Begin", mock.Anything).Return(d.tx, nil)
+	d.outboxRepo.On("NextSequence", mock.Anything, d.tx, mock.Anything).Return(int64(1), nil)
+	d.outboxRepo.On("Create", mock.Anything, d.tx, mock.MatchedBy(func(msg *model.OutboxMessage) bool {
+		return msg.EventType == "wallet.snapshot" && msg.EventID != ""
+	})).Return(nil)
+	d.tx.On("Commit").Return(nil)
+
+	enqueued, err := svc.BackfillWalletSnapshots(context.Background(), dto.OutboxBackfillRequest{})
+
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), enqueued)
+	d.outboxRepo.AssertNumberOfCalls(t, "Create", 3)
+	d.tx.AssertNumberOfCalls(t, "Commit", 2)
+	d.assertAll(t)
+}
+
+func TestBackfillWalletSnapshots_AppliesFilter(t *testing.T) {
+	d := newOutboxReplayTestDeps()
+	svc := d.service(10)
+
+	from := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)
+	filter := repository.WalletFilter{UserID: userID.String(), CreatedFrom: &from, CreatedTo: &to}
+
+	d.walletsRepo.On("GetWalletsPage", mock.Anything, nil, filter, "", 10).Return([]model.Wallets{}, nil)
+
+	enqueued, err := svc.BackfillWalletSnapshots(context.Background(), dto.OutboxBackfillRequest{
+		UserID: userID.String(),
+		From:   "2025-01-01T00:00:00Z",
+		To:     "2025-02-01T00:00:00Z",
+	})
+
+	assert.NoError(t, err)
+	assert.Zero(t, enqueued)
+	d.txManager.AssertNotCalled(t, "Begin", mock.Anything)
+	d.assertAll(t)
+}
+
+func TestBackfillWalletSnapshots_InvalidRange(t *testing.T) {
+	d := newOutboxReplayTestDeps()
+	svc := d.service(10)
+
+	_, err := svc.BackfillWalletSnapshots(context.Background(), dto.OutboxBackfillRequest{
+		From: "2025-02-01T00:00:00Z",
+		To:   "2025-01-01T00:00:00Z",
+	})
+
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid time range")
+	assert.ErrorIs(t, err, ErrInvalidTimeRange)
+	assert.Equal(t, domainerr.InvalidArgument, domainerr.KindOf(err))
+	d.assertAll(t)
+}
+
+func TestBackfillWalletSnapshots_InvalidUserID(t *testing.T) {
+	d := newOutboxReplayTestDeps()
+	svc := d.service(10)
+
+	_, err := svc.BackfillWalletSnapshots(context.Background(), dto.OutboxBackfillRequest{UserID: "nope"})
+
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid user id")
+	d.assertAll(t)
+}
+
+func TestBackfillWalletSnapshots_CreateErrorRollsBackPage(t *testing.T) {
+	d := newOutboxReplayTestDeps()
+	svc := d.service(10)
+
+	d.walletsRepo.On("GetWalletsPage", mock.Anything, nil, repository.WalletFilter{}, "", 10).
+		Return([]model.Wallets{sampleWalletModel()}, nil)
+	d.txManager.On("Begin", mock.Anything).Return(d.tx, nil)
+	d.outboxRepo.On("NextSequence", mock.Anything, d.tx, mock.Anything).Return(int64(1), nil)
+	d.outboxRepo.On("Create", mock.Anything, d.tx, mock.Anything).Return(errors.New("outbox error"))
+	d.tx.On("Rollback").Return(nil)
+
+	enqueued, err := svc.BackfillWalletSnapshots(context.Background(), dto.OutboxBackfillRequest{})
+
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "save outbox message")
+	assert.Zero(t, enqueued)
+	d.tx.AssertNotCalled(t, "Commit")
+	d.assertAll(t)
+}
+
+// =====================================================================
+// ReplayPublished
+// =====================================================================
+
+func TestReplayPublished_Success(t *testing.T) {
+	d := newOutboxReplayTestDeps()
+	svc := d.service(10)
+
+	d.outboxRepo.On("ReplayPublishedRange", mock.Anything, uint(10), uint(20)).Return(int64(11), nil)
+
+	enqueued, err := svc.ReplayPublished(context.Background(), dto.OutboxReplayRequest{FromID: 10, ToID: 20})
+
+	assert.NoError(t, err)
+	assert.Equal(t, int64(11), enqueued)
+	d.assertAll(t)
+}
+
+func TestReplayPublished_InvalidRange(t *testing.T) {
+	d := newOutboxReplayTestDeps()
+	svc := d.service(10)
+
+	_, err := svc.ReplayPublished(context.Background(), dto.OutboxReplayRequest{FromID: 20, ToID: 10})
+
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid id range")
+	assert.ErrorIs(t, err, ErrInvalidIDRange)
+	d.outboxRepo.AssertNotCalled(t, "ReplayPublishedRange", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestReplayPublished_RepositoryError(t *testing.T) {
+	d := newOutboxReplayTestDeps()
+	svc := d.service(10)
+
+	d.outboxRepo.On("ReplayPublishedRange", mock.Anything, uint(1), uint(5)).Return(int64(0), errors.New("db error"))
+
+	_, err := svc.ReplayPublished(context.Background(), dto.OutboxReplayRequest{FromID: 1, ToID: 5})
+
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "replay published")
+	d.assertAll(t)
+}
@@ -0,0 +1,363 @@
+package service
+
+import (
+	"context"
+	"crypto"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"refina-wallet/config/env"
+	"refina-wallet/internal/types/domainerr"
+	"refina-wallet/internal/utils/data"
+)
+
+// ErrInvalidToken is returned for bearer tokens that are malformed, badly signed or expired.
+var ErrInvalidToken = domainerr.New(domainerr.Unauthenticated, "INVALID_TOKEN", "invalid token")
+
+// TokenClaims are the JWT claims the API relies on. Subject is the user id.
+type TokenClaims struct {
+	Subject   string       `json:"sub"`
+	Email     string       `json:"email"`
+	Username  string       `json:"preferred_username"`
+	Provider  string       `json:"provider"`
+	Roles     claimStrings `json:"roles"`
+	Issuer    string       `json:"iss"`
+	Audience  claimStrings `json:"aud"`
+	ExpiresAt *float64     `json:"exp"`
+	NotBefore *float64     `json:"nbf"`
+}
+
+// claimStrings is a claim that may be a single string or an array, like aud in RFC 7519
+type claimStrings []string
+
+func (a *claimStrings) UnmarshalJSON(b []byte) error {
+	var single string
+	if err := json.Unmarshal(b, &single); err == nil {
+		*a = claimStrings{single}
+		return nil
+	}
+
+	var many []string
+	if err := json.Unmarshal(b, &many); err != nil {
+		return err
+	}
+	*a = many
+	return nil
+}
+
+func (a claimStrings) contains(value string) bool {
+	for _, v := range a {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// TokenVerifier authenticates the bearer tokens of incoming requests.
+type TokenVerifier interface {
+	Verify(ctx context.Context, token string) (TokenClaims, error)
+}
+
+// JWTVerifier verifies compact JWS tokens signed with HS256 (shared secret) or RS256 (keys of a JWKS).
+type JWTVerifier struct {
+	secret   []byte
+	issuer   string
+	audience string
+	jwks     *jwksKeySet
+	now      func() time.Time
+}
+
+// NewJWTVerifier builds the verifier described by cfg, loading the JWKS up front so a bad
+// source fails at startup rather than on the first request.
+func NewJWTVerifier(ctx context.Context, cfg env.Auth) (*JWTVerifier, error) {
+	if cfg.JWTSecret == "" && cfg.JWKSSource == "" {
+		return nil, errors.New("auth: neither a JWT secret nor a JWKS source is configured")
+	}
+
+	verifier := &JWTVerifier{
+		issuer:   cfg.Issuer,
+		audience: cfg.Audience,
+		now:      time.Now,
+	}
+	if cfg.JWTSecret != "" {
+		verifier.secret = []byte(cfg.JWTSecret)
+	}
+	if cfg.JWKSSource != "" {
+		verifier.jwks = &jwksKeySet{source: cfg.JWKSSource, now: time.Now}
+		if err := verifier.jwks.load(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	return verifier, nil
+}
+
+type jwtHeader struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+}
+
+func (v *JWTVerifier) Verify(ctx context.Context, token string) (TokenClaims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return TokenClaims{}, fmt.Errorf("%w: not a compact JWS", ErrInvalidToken)
+	}
+
+	var header jwtHeader
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return TokenClaims{}, fmt.Errorf("%w: header: %v", ErrInvalidToken, err)
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return TokenClaims{}, fmt.Errorf("%w: signature: %v", ErrInvalidToken, err)
+	}
+
+	signingInput := parts[0] + "." + parts[1]
+	if err := v.verifySignature(ctx, header, signingInput, signature); err != nil {
+		return TokenClaims{}, err
+	}
+
+	var claims TokenClaims
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return TokenClaims{}, fmt.Errorf("%w: claims: %v", ErrInvalidToken, err)
+	}
+	if err := v.validateClaims(claims); err != nil {
+		return TokenClaims{}, err
+	}
+
+	return claims, nil
+}
+
+// verifySignature only accepts the algorithm whose key is configured, so an HS256 token can
+// never be checked against a public RSA key and "none" is always rejected.
+func (v *JWTVerifier) verifySignature(ctx context.Context, header jwtHeader, signingInput string, signature []byte) error {
+	switch header.Alg {
+	case "HS256":
+		if v.secret == nil {
+			return fmt.Errorf("%w: HS256 tokens are not accepted", ErrInvalidToken)
+		}
+		mac := hmac.New(sha256.New, v.secret)
+		mac.Write([]byte(signingInput))
+		if !hmac.Equal(mac.Sum(nil), signature) {
+			return fmt.Errorf("%w: signature mismatch", ErrInvalidToken)
+		}
+		return nil
+	case "RS256":
+		if v.jwks == nil {
+			return fmt.Errorf("%w: RS256 tokens are not accepted", ErrInvalidToken)
+		}
+		key, err := v.jwks.key(ctx, header.Kid)
+		if err != nil {
+			return err
+		}
+		digest := sha256.Sum256([]byte(signingInput))
+		if err := rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], signature); err != nil {
+			return fmt.Errorf("%w: signature mismatch", ErrInvalidToken)
+		}
+		return nil
+	default:
+		return fmt.Errorf("%w: unsupported alg %q", ErrInvalidToken, header.Alg)
+	}
+}
+
+func (v *JWTVerifier) validateClaims(claims TokenClaims) error {
+	now := v.now()
+
+	if claims.ExpiresAt == nil {
+		return fmt.Errorf("%w: missing exp", ErrInvalidToken)
+	}
+	if now.After(unixTime(*claims.ExpiresAt).Add(data.JWT_CLOCK_LEEWAY)) {
+		return fmt.Errorf("%w: token expired", ErrInvalidToken)
+	}
+	if claims.NotBefore != nil && now.Add(data.JWT_CLOCK_LEEWAY).Before(unixTime(*claims.NotBefore)) {
+		return fmt.Errorf("%w: token not valid yet", ErrInvalidToken)
+	}
+	if v.issuer != "" && claims.Issuer != v.issuer {
+		return fmt.Errorf("%w: unexpected issuer %q", ErrInvalidToken, claims.Issuer)
+	}
+	if v.audience != "" && !claims.Audience.contains(v.audience) {
+		return fmt.Errorf("%w: token not issued for this audience", ErrInvalidToken)
+	}
+	if claims.Subject == "" {
+		return fmt.Errorf("%w: missing sub", ErrInvalidToken)
+	}
+
+	return nil
+}
+
+func decodeSegment(segment string, v any) error {
+	raw, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(raw, v)
+}
+
+func unixTime(seconds float64) time.Time {
+	return time.Unix(0, int64(seconds*float64(time.Second)))
+}
+
+// jwksKeySet holds the RSA keys of a JWKS. A source served over http(s) is fetched again when
+// a token names an unknown kid (key rotation), at most once per JWKS_MIN_REFRESH_INTERVAL.
+type jwksKeySet struct {
+	source string
+	now    func() time.Time
+
+	mu        sync.RWMutex
+	keys      map[string]*rsa.PublicKey
+	fetchedAt time.Time
+}
+
+type jwksDocument struct {
+	Keys []struct {
+		Kty string `json:"kty"`
+		Kid string `json:"kid"`
+		Use string `json:"use"`
+		Alg string `json:"alg"`
+		N   string `json:"n"`
+		E   string `json:"e"`
+	} `json:"keys"`
+}
+
+func (s *jwksKeySet) key(ctx context.Context, kid string) (*rsa.PublicKey, error) {
+	if key, ok := s.lookup(kid); ok {
+		return key, nil
+	}
+
+	if s.isRemote() && s.refreshDue() {
+		if err := s.load(ctx); err != nil {
+			return nil, err
+		}
+		if key, ok := s.lookup(kid); ok {
+			return key, nil
+		}
+	}
+
+	return nil, fmt.Errorf("%w: unknown signing key %q", ErrInvalidToken, kid)
+}
+
+// lookup finds the key by kid; a token without kid is accepted only when the set has a single key.
+func (s *jwksKeySet) lookup(kid string) (*rsa.PublicKey, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if kid == "" {
+		if len(s.keys) != 1 {
+			return nil, false
+		}
+		for _, key := range s.keys {
+			return key, true
+		}
+	}
+	key, ok := s.keys[kid]
+	return key, ok
+}
+
+func (s *jwksKeySet) refreshDue() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.now().Sub(s.fetchedAt) >= data.JWKS_MIN_REFRESH_INTERVAL
+}
+
+func (s *jwksKeySet) isRemote() bool {
+	return strings.HasPrefix(s.source, "http://") || strings.HasPrefix(s.source, "https://")
+}
+
+func (s *jwksKeySet) load(ctx context.Context) error {
+	raw, err := s.read(ctx)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// Catat waktu fetch walau gagal agar token dengan kid asing tidak memicu fetch di setiap request
+	s.fetchedAt = s.now()
+	if err != nil {
+		return err
+	}
+
+	keys, err := parseJWKS(raw)
+	if err != nil {
+		return fmt.Errorf("decode JWKS %s: %w", s.source, err)
+	}
+	s.keys = keys
+	return nil
+}
+
+func (s *jwksKeySet) read(ctx context.Context) ([]byte, error) {
+	if !s.isRemote() {
+		raw, err := os.ReadFile(s.source)
+		if err != nil {
+			return nil, fmt.Errorf("read JWKS file: %w", err)
+		}
+		return raw, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, data.JWKS_FETCH_TIMEOUT)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.source, nil)
+	if err != nil {
+		return nil, fmt.Errorf("fetch JWKS: %w", err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("fetch JWKS: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetch JWKS: %s returned %s", s.source, resp.Status)
+	}
+	raw, err := io.ReadAll(io.LimitReader(resp.Body, data.JWKS_MAX_SIZE))
+	if err != nil {
+		return nil, fmt.Errorf("fetch JWKS: %w", err)
+	}
+	return raw, nil
+}
+
+// parseJWKS keeps the RSA signing keys of a JWKS and skips everything else (EC keys, encryption keys).
+func parseJWKS(raw []byte) (map[string]*rsa.PublicKey, error) {
+	var doc jwksDocument
+	if err := json.Unmarshal(raw, &doc); err != nil {
+		return nil, err
+	}
+
+	keys := make(map[string]*rsa.PublicKey, len(doc.Keys))
+	for _, jwk := range doc.Keys {
+		if jwk.Kty != "RSA" || (jwk.Use != "" && jwk.Use != "sig") || (jwk.Alg != "" && jwk.Alg != "RS256") {
+			continue
+		}
+
+		n, err := base64.RawURLEncoding.DecodeString(jwk.N)
+		if err != nil {
+			return nil, fmt.Errorf("key %q: modulus: %w", jwk.Kid, err)
+		}
+		e, err := base64.RawURLEncoding.DecodeString(jwk.E)
+		if err != nil {
+			return nil, fmt.Errorf("key %q: exponent: %w", jwk.Kid, err)
+		}
+		exponent := new(big.Int).SetBytes(e)
+		if !exponent.IsInt64() || exponent.Int64() < 3 || exponent.Int64() > 1<<31-1 {
+			return nil, fmt.Errorf("key %q: unsupported exponent", jwk.Kid)
+		}
+
+		keys[jwk.Kid] = &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(exponent.Int64())}
+	}
+
+	if len(keys) == 0 {
+		return nil, errors.New("no RSA signing keys")
+	}
+	return keys, nil
+}
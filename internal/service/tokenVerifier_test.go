@@ -0,0 +1,276 @@
+package service
+
+import (
+	"context"
+	"crypto"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"refina-wallet/config/env"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testJWTSecret = "test-secret"
+
+func validClaims() map[string]any {
+	return map[string]any{
+		"sub":   "user-123",
+		"email": "user@example.com",
+		"iss":   "https://auth.example.com",
+		"aud":   []string{"refina-wallet"},
+		"exp":   time.Now().Add(time.Hour).Unix(),
+	}
+}
+
+func encodeSegment(v any) string {
+	raw, _ := json.Marshal(v)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+func signHS256(claims map[string]any, secret string) string {
+	input := encodeSegment(map[string]string{"alg": "HS256", "typ": "JWT"}) + "." + encodeSegment(claims)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(input))
+	return input + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func signRS256(claims map[string]any, key *rsa.PrivateKey, kid string) string {
+	input := encodeSegment(map[string]string{"alg": "RS256", "typ": "JWT", "kid": kid}) + "." + encodeSegment(claims)
+	digest := sha256.Sum256([]byte(input))
+	sig, _ := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	return input + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
+
+func jwksJSON(keys map[string]*rsa.PrivateKey) []byte {
+	var doc struct {
+		Keys []map[string]string `json:"keys"`
+	}
+	for kid, key := range keys {
+		doc.Keys = append(doc.Keys, map[string]string{
+			"kty": "RSA",
+			"kid": kid,
+			"use": "sig",
+			"alg": "RS256",
+			"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		})
+	}
+	raw, _ := json.Marshal(doc)
+	return raw
+}
+
+func newRSAKey(t *testing.T) *rsa.PrivateKey {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+	return key
+}
+
+func writeJWKSFile(t *testing.T, keys map[string]*rsa.PrivateKey) string {
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	assert.NoError(t, os.WriteFile(path, jwksJSON(keys), 0o600))
+	return path
+}
+
+func TestNewJWTVerifier_RequiresAKeySource(t *testing.T) {
+	_, err := NewJWTVerifier(context.Background(), env.Auth{})
+
+	assert.Error(t, err)
+}
+
+func TestNewJWTVerifier_MissingJWKSFile(t *testing.T) {
+	_, err := NewJWTVerifier(context.Background(), env.Auth{JWKSSource: filepath.Join(t.TempDir(), "missing.json")})
+
+	assert.Error(t, err)
+}
+
+func TestVerify_HS256Valid(t *testing.T) {
+	verifier, err := NewJWTVerifier(context.Background(), env.Auth{
+		JWTSecret: testJWTSecret,
+		Issuer:    "https://auth.example.com",
+		Audience:  "refina-wallet",
+	})
+	assert.NoError(t, err)
+
+	claims, err := verifier.Verify(context.Background(), signHS256(validClaims(), testJWTSecret))
+
+	assert.NoError(t, err)
+	assert.Equal(t, "user-123", claims.Subject)
+	assert.Equal(t, "user@example.com", claims.Email)
+}
+
+func TestVerify_RolesClaimAcceptsStringOrArray(t *testing.T) {
+	verifier, _ := NewJWTVerifier(context.Background(), env.Auth{JWTSecret: testJWTSecret})
+
+	claims := validClaims()
+	claims["roles"] = []string{"support", "admin"}
+	parsed, err := verifier.Verify(context.Background(), signHS256(claims, testJWTSecret))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"support", "admin"}, []string(parsed.Roles))
+
+	claims["roles"] = "admin"
+	parsed, err = verifier.Verify(context.Background(), signHS256(claims, testJWTSecret))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"admin"}, []string(parsed.Roles))
+}
+
+func TestVerify_HS256WrongSecret(t *testing.T) {
+	verifier, _ := NewJWTVerifier(context.Background(), env.Auth{JWTSecret: testJWTSecret})
+
+	_, err := verifier.Verify(context.Background(), signHS256(validClaims(), "other-secret"))
+
+	assert.ErrorIs(t, err, ErrInvalidToken)
+}
+
+func TestVerify_TamperedClaims(t *testing.T) {
+	verifier, _ := NewJWTVerifier(context.Background(), env.Auth{JWTSecret: testJWTSecret})
+	token := signHS256(validClaims(), testJWTSecret)
+
+	forged := validClaims()
+	forged["sub"] = "someone-else"
+	parts := strings.Split(token, ".")
+	tampered := parts[0] + "." + encodeSegment(forged) + "." + parts[2]
+
+	_, err := verifier.Verify(context.Background(), tampered)
+
+	assert.ErrorIs(t, err, ErrInvalidToken)
+}
+
+func TestVerify_AlgNoneRejected(t *testing.T) {
+	verifier, _ := NewJWTVerifier(context.Background(), env.Auth{JWTSecret: testJWTSecret})
+	token := encodeSegment(map[string]string{"alg": "none"}) + "." + encodeSegment(validClaims()) + "."
+
+	_, err := verifier.Verify(context.Background(), token)
+
+	assert.ErrorIs(t, err, ErrInvalidToken)
+}
+
+func TestVerify_Expired(t *testing.T) {
+	verifier, _ := NewJWTVerifier(context.Background(), env.Auth{JWTSecret: testJWTSecret})
+	claims := validClaims()
+	claims["exp"] = time.Now().Add(-time.Hour).Unix()
+
+	_, err := verifier.Verify(context.Background(), signHS256(claims, testJWTSecret))
+
+	assert.ErrorIs(t, err, ErrInvalidToken)
+}
+
+func TestVerify_MissingExpiry(t *testing.T) {
+	verifier, _ := NewJWTVerifier(context.Background(), env.Auth{JWTSecret: testJWTSecret})
+	claims := validClaims()
+	delete(claims, "exp")
+
+	_, err := verifier.Verify(context.Background(), signHS256(claims, testJWTSecret))
+
+	assert.ErrorIs(t, err, ErrInvalidToken)
+}
+
+func TestVerify_WrongIssuerOrAudience(t *testing.T) {
+	verifier, _ := NewJWTVerifier(context.Background(), env.Auth{
+		JWTSecret: testJWTSecret,
+		Issuer:    "https://auth.example.com",
+		Audience:  "refina-wallet",
+	})
+
+	wrongIssuer := validClaims()
+	wrongIssuer["iss"] = "https://evil.example.com"
+	_, err := verifier.Verify(context.Background(), signHS256(wrongIssuer, testJWTSecret))
+	assert.ErrorIs(t, err, ErrInvalidToken)
+
+	wrongAudience := validClaims()
+	wrongAudience["aud"] = "another-service"
+	_, err = verifier.Verify(context.Background(), signHS256(wrongAudience, testJWTSecret))
+	assert.ErrorIs(t, err, ErrInvalidToken)
+}
+
+func TestVerify_RS256FromJWKSFile(t *testing.T) {
+	key := newRSAKey(t)
+	verifier, err := NewJWTVerifier(context.Background(), env.Auth{
+		JWKSSource: writeJWKSFile(t, map[string]*rsa.PrivateKey{"key-1": key}),
+	})
+	assert.NoError(t, err)
+
+	claims, err := verifier.Verify(context.Background(), signRS256(validClaims(), key, "key-1"))
+
+	assert.NoError(t, err)
+	assert.Equal(t, "user-123", claims.Subject)
+}
+
+func TestVerify_RS256UnknownSigner(t *testing.T) {
+	verifier, _ := NewJWTVerifier(context.Background(), env.Auth{
+		JWKSSource: writeJWKSFile(t, map[string]*rsa.PrivateKey{"key-1": newRSAKey(t)}),
+	})
+
+	_, err := verifier.Verify(context.Background(), signRS256(validClaims(), newRSAKey(t), "key-1"))
+
+	assert.ErrorIs(t, err, ErrInvalidToken)
+}
+
+func TestVerify_HS256RejectedWithoutSecret(t *testing.T) {
+	verifier, _ := NewJWTVerifier(context.Background(), env.Auth{
+		JWKSSource: writeJWKSFile(t, map[string]*rsa.PrivateKey{"key-1": newRSAKey(t)}),
+	})
+
+	_, err := verifier.Verify(context.Background(), signHS256(validClaims(), ""))
+
+	assert.ErrorIs(t, err, ErrInvalidToken)
+}
+
+func TestVerify_RS256RefetchesRemoteJWKSOnKeyRotation(t *testing.T) {
+	oldKey, newKey := newRSAKey(t), newRSAKey(t)
+	var rotated atomic.Bool
+	var fetches atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches.Add(1)
+		if rotated.Load() {
+			w.Write(jwksJSON(map[string]*rsa.PrivateKey{"key-2": newKey}))
+			return
+		}
+		w.Write(jwksJSON(map[string]*rsa.PrivateKey{"key-1": oldKey}))
+	}))
+	defer server.Close()
+
+	verifier, err := NewJWTVerifier(context.Background(), env.Auth{JWKSSource: server.URL})
+	assert.NoError(t, err)
+	// Geser jam agar refresh tidak tertahan JWKS_MIN_REFRESH_INTERVAL
+	verifier.jwks.now = func() time.Time { return time.Now().Add(time.Hour) }
+
+	rotated.Store(true)
+	claims, err := verifier.Verify(context.Background(), signRS256(validClaims(), newKey, "key-2"))
+
+	assert.NoError(t, err)
+	assert.Equal(t, "user-123", claims.Subject)
+	assert.Equal(t, int32(2), fetches.Load())
+}
+
+func TestVerify_RS256RemoteRefreshIsRateLimited(t *testing.T) {
+	var fetches atomic.Int32
+	key := newRSAKey(t)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches.Add(1)
+		w.Write(jwksJSON(map[string]*rsa.PrivateKey{"key-1": key}))
+	}))
+	defer server.Close()
+
+	verifier, _ := NewJWTVerifier(context.Background(), env.Auth{JWKSSource: server.URL})
+
+	for i := 0; i < 3; i++ {
+		_, err := verifier.Verify(context.Background(), signRS256(validClaims(), newRSAKey(t), "unknown"))
+		assert.ErrorIs(t, err, ErrInvalidToken)
+	}
+
+	assert.Equal(t, int32(1), fetches.Load())
+}
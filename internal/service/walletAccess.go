@@ -0,0 +1,57 @@
+package service
+
+import (
+	"refina-wallet/internal/types/domainerr"
+	"refina-wallet/internal/types/model"
+)
+
+// ErrCallerUnidentified is returned when owner access is requested without a user id, e.g. a
+// gRPC call without x-user-id metadata.
+var ErrCallerUnidentified = domainerr.New(domainerr.Unauthenticated, "CALLER_UNIDENTIFIED", "invalid user id: caller identity is missing")
+
+// WalletAccess says on whose behalf a single wallet is read or modified. Owner access only
+// reaches the user's own wallets; admin access skips the ownership check and has to be asked
+// for explicitly with AdminAccess, never derived from a missing user id.
+type WalletAccess struct {
+	userID string
+	admin  bool
+}
+
+// OwnerAccess limits access to the wallets of userID
+func OwnerAccess(userID string) WalletAccess {
+	return WalletAccess{userID: userID}
+}
+
+// AdminAccess reaches every wallet; reserve it for operators and internal jobs.
+func AdminAccess() WalletAccess {
+	return WalletAccess{admin: true}
+}
+
+// WalletAccessFor derives the access of a caller from its user id and raw role values (as sent
+// in x-user-roles): admin access when the roles grant anyWallet (PermissionReadAnyWallet or
+// PermissionWriteAnyWallet), owner access otherwise.
+func WalletAccessFor(userID string, rawRoles []string, anyWallet Permission) WalletAccess {
+	if HasPermission(ParseRoles(rawRoles), anyWallet) {
+		return AdminAccess()
+	}
+	return OwnerAccess(userID)
+}
+
+func (a WalletAccess) UserID() string {
+	return a.userID
+}
+
+func (a WalletAccess) IsAdmin() bool {
+	return a.admin
+}
+
+func (a WalletAccess) validate() error {
+	if !a.admin && a.userID == "" {
+		return ErrCallerUnidentified
+	}
+	return nil
+}
+
+func (a WalletAccess) allows(wallet model.Wallets) bool {
+	return a.admin || wallet.UserID.String() == a.userID
+}
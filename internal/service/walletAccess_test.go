@@ -0,0 +1,46 @@
+package service
+
+import (
+	"testing"
+
+	"refina-wallet/internal/types/model"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOwnerAccess_AllowsOnlyOwnWallets(t *testing.T) {
+	owner := uuid.New()
+	access := OwnerAccess(owner.String())
+
+	assert.NoError(t, access.validate())
+	assert.False(t, access.IsAdmin())
+	assert.True(t, access.allows(model.Wallets{UserID: owner}))
+	assert.False(t, access.allows(model.Wallets{UserID: uuid.New()}))
+}
+
+func TestOwnerAccess_EmptyUserIDIsRejected(t *testing.T) {
+	access := OwnerAccess("")
+
+	assert.ErrorIs(t, access.validate(), ErrCallerUnidentified)
+	assert.False(t, access.allows(model.Wallets{UserID: uuid.New()}))
+}
+
+func TestAdminAccess_AllowsEveryWallet(t *testing.T) {
+	access := AdminAccess()
+
+	assert.NoError(t, access.validate())
+	assert.True(t, access.IsAdmin())
+	assert.True(t, access.allows(model.Wallets{UserID: uuid.New()}))
+}
+
+func TestWalletAccessFor_AdminBypassFollowsPermission(t *testing.T) {
+	assert.True(t, WalletAccessFor("u1", []string{"support"}, PermissionReadAnyWallet).IsAdmin())
+
+	access := WalletAccessFor("u1", []string{"support"}, PermissionWriteAnyWallet)
+	assert.False(t, access.IsAdmin())
+	assert.Equal(t, "u1", access.UserID())
+
+	assert.True(t, WalletAccessFor("u1", []string{"user,admin"}, PermissionWriteAnyWallet).IsAdmin())
+	assert.False(t, WalletAccessFor("u1", nil, PermissionReadAnyWallet).IsAdmin())
+}
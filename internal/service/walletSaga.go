@@ -0,0 +1,226 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"time"
+
+	"refina-wallet/config/log"
+	"refina-wallet/interface/grpc/client"
+	"refina-wallet/internal/repository"
+	"refina-wallet/internal/types/model"
+	"refina-wallet/internal/utils/data"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// WalletSagaWorker finishes the wallet creation sagas CreateWallet could not: it retries
+// compensations until the initial deposit is gone and resolves sagas abandoned mid-flight.
+type WalletSagaWorker struct {
+	sagaRepository    repository.WalletSagaRepository
+	walletsRepository repository.WalletsRepository
+	transactionClient client.TransactionClient
+	interval          time.Duration
+	lease             time.Duration
+	batchSize         int
+	baseBackoff       time.Duration
+	maxBackoff        time.Duration
+	alertAfter        int
+	staleAfter        time.Duration
+}
+
+func NewWalletSagaWorker(
+	sagaRepository repository.WalletSagaRepository,
+	walletsRepository repository.WalletsRepository,
+	transactionClient client.TransactionClient,
+) *WalletSagaWorker {
+	return &WalletSagaWorker{
+		sagaRepository:    sagaRepository,
+		walletsRepository: walletsRepository,
+		transactionClient: transactionClient,
+		interval:          data.SAGA_WORKER_INTERVAL,
+		lease:             data.SAGA_COMPENSATION_LEASE,
+		batchSize:         data.SAGA_COMPENSATION_BATCH,
+		baseBackoff:       data.SAGA_RETRY_BASE_BACKOFF,
+		maxBackoff:        data.SAGA_RETRY_MAX_BACKOFF,
+		alertAfter:        data.SAGA_ALERT_AFTER_ATTEMPTS,
+		staleAfter:        data.SAGA_STALE_AFTER,
+	}
+}
+
+// Start runs the worker until ctx is cancelled
+func (w *WalletSagaWorker) Start(ctx context.Context) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := w.runOnce(ctx); err != nil {
+				log.Error(data.LogSagaWorkerFailed, map[string]any{"service": data.WalletSagaService, "error": err.Error()})
+			}
+		}
+	}
+}
+
+func (w *WalletSagaWorker) runOnce(ctx context.Context) error {
+	if err := w.recoverStale(ctx); err != nil {
+		return err
+	}
+	return w.compensateDue(ctx)
+}
+
+// recoverStale resolves sagas whose CreateWallet call died before recording the outcome.
+// The wallet row is the source of truth: if it exists, even soft-deleted, the creation committed.
+// A saga still pending may have a deposit nobody knows the id of, so it is left to an operator.
+func (w *WalletSagaWorker) recoverStale(ctx context.Context) error {
+	sagas, err := w.sagaRepository.GetStale(ctx, time.Now().Add(-w.staleAfter), w.batchSize)
+	if err != nil {
+		return fmt.Errorf("recover stale sagas: %w", err)
+	}
+
+	for _, saga := range sagas {
+		id := saga.ID.String()
+		fields := map[string]any{
+			"service":   data.WalletSagaService,
+			"saga_id":   id,
+			"wallet_id": saga.WalletID.String(),
+			"status":    string(saga.Status),
+		}
+
+		// Wallet yang sudah di-soft-delete tetap berarti pembuatannya pernah commit
+		exists, getErr := w.walletsRepository.WalletEverCreated(ctx, nil, saga.WalletID.String())
+		if getErr != nil {
+			// Tanpa kepastian wallet ada atau tidak, saga dibiarkan untuk putaran berikutnya
+			fields["error"] = getErr.Error()
+			log.Warn(data.LogSagaStateUpdateFailed, fields)
+			continue
+		}
+
+		var updateErr error
+		switch {
+		case exists:
+			updateErr = w.sagaRepository.MarkCommitted(ctx, id)
+			fields["resolved_to"] = string(model.SagaCommitted)
+		case saga.Status == model.SagaDepositCreated:
+			updateErr = w.sagaRepository.ScheduleCompensation(ctx, id, time.Now(), "")
+			fields["resolved_to"] = string(model.SagaCompensating)
+		default:
+			// InitialDeposit mungkin sudah tercatat tapi responnya hilang; operator yang memutuskan
+			updateErr = w.sagaRepository.MarkNeedsReview(ctx, id, "creation abandoned while the initial deposit was in flight")
+			fields["resolved_to"] = string(model.SagaNeedsReview)
+			fields["user_id"] = saga.UserID.String()
+			fields["amount"] = saga.Amount
+			log.Error(data.LogSagaUnknownDepositOutcome, fields)
+		}
+
+		if updateErr != nil {
+			fields["error"] = updateErr.Error()
+			log.Error(data.LogSagaStateUpdateFailed, fields)
+			continue
+		}
+		log.Info(data.LogSagaRecovered, fields)
+	}
+
+	return nil
+}
+
+func (w *WalletSagaWorker) compensateDue(ctx context.Context) error {
+	sagas, err := w.sagaRepository.ClaimDueCompensations(ctx, w.lease, w.batchSize)
+	if err != nil {
+		return fmt.Errorf("claim due compensations: %w", err)
+	}
+
+	for _, saga := range sagas {
+		if ctx.Err() != nil {
+			return nil
+		}
+		w.compensate(ctx, saga)
+	}
+
+	return nil
+}
+
+// compensate deletes the initial deposit of saga. Failures are rescheduled with backoff
+// forever; once attempts reach alertAfter every further failure is logged as an alert.
+func (w *WalletSagaWorker) compensate(ctx context.Context, saga model.WalletCreationSaga) {
+	id := saga.ID.String()
+
+	depositID := ""
+	if saga.DepositTransactionID != nil {
+		depositID = *saga.DepositTransactionID
+	}
+
+	err := cancelInitialDeposit(ctx, w.transactionClient, depositID)
+	if err == nil {
+		if err := w.sagaRepository.MarkFailed(ctx, id); err != nil {
+			log.Error(data.LogSagaStateUpdateFailed, map[string]any{"service": data.WalletSagaService, "saga_id": id, "error": err.Error()})
+			return
+		}
+		log.Info(data.LogSagaCompensated, map[string]any{
+			"service":                data.WalletSagaService,
+			"saga_id":                id,
+			"wallet_id":              saga.WalletID.String(),
+			"deposit_transaction_id": depositID,
+		})
+		return
+	}
+
+	attempts := saga.Attempts + 1
+	fields := map[string]any{
+		"service":                data.WalletSagaService,
+		"saga_id":                id,
+		"wallet_id":              saga.WalletID.String(),
+		"deposit_transaction_id": depositID,
+		"attempts":               attempts,
+		"error":                  err.Error(),
+	}
+	if attempts >= w.alertAfter {
+		log.Error(data.LogSagaCompensationAlert, fields)
+	} else {
+		log.Warn(data.LogSagaCompensationFailed, fields)
+	}
+
+	if err := w.sagaRepository.ScheduleCompensation(ctx, id, time.Now().Add(w.retryDelay(attempts)), err.Error()); err != nil {
+		log.Error(data.LogSagaStateUpdateFailed, map[string]any{"service": data.WalletSagaService, "saga_id": id, "error": err.Error()})
+	}
+}
+
+func (w *WalletSagaWorker) retryDelay(attempts int) time.Duration {
+	delay := w.baseBackoff
+	for i := 1; i < attempts && delay < w.maxBackoff; i++ {
+		delay *= 2
+	}
+	delay = min(delay, w.maxBackoff)
+
+	half := delay / 2
+	return half + rand.N(delay-half+1)
+}
+
+// cancelInitialDeposit deletes a deposit in the transaction service. A deposit that is already
+// gone counts as cancelled, so retries after a lost response still converge.
+func cancelInitialDeposit(ctx context.Context, transactionClient client.TransactionClient, depositID string) error {
+	if depositID == "" {
+		return nil
+	}
+
+	if _, err := transactionClient.CancelInitialDeposit(ctx, depositID); err != nil && status.Code(err) != codes.NotFound {
+		return err
+	}
+	return nil
+}
+
+// depositOutcomeUnknown reports whether a failed InitialDeposit call may still have created the
+// deposit, i.e. the call ended without a definite answer from the transaction service.
+func depositOutcomeUnknown(err error) bool {
+	switch status.Code(err) {
+	case codes.DeadlineExceeded, codes.Canceled, codes.Unavailable, codes.Unknown:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,227 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"refina-wallet/internal/service/mocks"
+	"refina-wallet/internal/types/model"
+	"refina-wallet/internal/types/money"
+
+	tpb "github.com/MuhammadMiftaa/Refina-Protobuf/transaction"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ---------- helpers ----------
+
+type walletSagaTestDeps struct {
+	sagaRepo    *mocks.MockWalletSagaRepository
+	walletsRepo *mocks.MockWalletsRepository
+	txClient    *mocks.MockTransactionClient
+}
+
+func newWalletSagaTestDeps() *walletSagaTestDeps {
+	return &walletSagaTestDeps{
+		sagaRepo:    new(mocks.MockWalletSagaRepository),
+		walletsRepo: new(mocks.MockWalletsRepository),
+		txClient:    new(mocks.MockTransactionClient),
+	}
+}
+
+func (d *walletSagaTestDeps) worker() *WalletSagaWorker {
+	return NewWalletSagaWorker(d.sagaRepo, d.walletsRepo, d.txClient)
+}
+
+func (d *walletSagaTestDeps) assertAll(t *testing.T) {
+	t.Helper()
+	d.sagaRepo.AssertExpectations(t)
+	d.walletsRepo.AssertExpectations(t)
+	d.txClient.AssertExpectations(t)
+}
+
+var sagaID = uuid.MustParse("ffffffff-ffff-ffff-ffff-ffffffffffff")
+
+func sampleSaga(status model.SagaStatus, depositID string, attempts int) model.WalletCreationSaga {
+	saga := model.WalletCreationSaga{
+		ID:       sagaID,
+		WalletID: walletID,
+		UserID:   userID,
+		Amount:   money.MustParse("100000"),
+		Status:   status,
+		Attempts: attempts,
+	}
+	if depositID != "" {
+		saga.DepositTransactionID = &depositID
+	}
+	return saga
+}
+
+// =====================================================================
+// compensateDue
+// =====================================================================
+
+func TestWalletSagaWorker_CompensationSucceeds(t *testing.T) {
+	d := newWalletSagaTestDeps()
+	w := d.worker()
+
+	d.sagaRepo.On("ClaimDueCompensations", mock.Anything, w.lease, w.batchSize).
+		Return([]model.WalletCreationSaga{sampleSaga(model.SagaCompensating, "tx-123", 1)}, nil)
+	d.txClient.On("CancelInitialDeposit", mock.Anything, "tx-123").Return(&tpb.TransactionDetail{Id: "tx-123"}, nil)
+	d.sagaRepo.On("MarkFailed", mock.Anything, sagaID.String()).Return(nil)
+
+	err := w.compensateDue(context.Background())
+
+	assert.NoError(t, err)
+	d.assertAll(t)
+}
+
+func TestWalletSagaWorker_DepositAlreadyGoneCountsAsCompensated(t *testing.T) {
+	d := newWalletSagaTestDeps()
+	w := d.worker()
+
+	d.sagaRepo.On("ClaimDueCompensations", mock.Anything, w.lease, w.batchSize).
+		Return([]model.WalletCreationSaga{sampleSaga(model.SagaCompensating, "tx-123", 2)}, nil)
+	d.txClient.On("CancelInitialDeposit", mock.Anything, "tx-123").Return(nil, status.Error(codes.NotFound, "transaction not found"))
+	d.sagaRepo.On("MarkFailed", mock.Anything, sagaID.String()).Return(nil)
+
+	err := w.compensateDue(context.Background())
+
+	assert.NoError(t, err)
+	d.assertAll(t)
+}
+
+func TestWalletSagaWorker_CompensationFailureIsRescheduled(t *testing.T) {
+	d := newWalletSagaTestDeps()
+	w := d.worker()
+
+	before := time.Now()
+	d.sagaRepo.On("ClaimDueCompensations", mock.Anything, w.lease, w.batchSize).
+		Return([]model.WalletCreationSaga{sampleSaga(model.SagaCompensating, "tx-123", 3)}, nil)
+	d.txClient.On("CancelInitialDeposit", mock.Anything, "tx-123").Return(nil, errors.New("unavailable"))
+	d.sagaRepo.On("ScheduleCompensation", mock.Anything, sagaID.String(), mock.MatchedBy(func(next time.Time) bool {
+		return next.After(before)
+	}), "unavailable").Return(nil)
+
+	err := w.compensateDue(context.Background())
+
+	assert.NoError(t, err)
+	d.sagaRepo.AssertNotCalled(t, "MarkFailed", mock.Anything, mock.Anything)
+	d.sagaRepo.AssertNotCalled(t, "MarkNeedsReview", mock.Anything, mock.Anything, mock.Anything)
+	d.assertAll(t)
+}
+
+func TestWalletSagaWorker_ClaimError(t *testing.T) {
+	d := newWalletSagaTestDeps()
+	w := d.worker()
+
+	d.sagaRepo.On("ClaimDueCompensations", mock.Anything, w.lease, w.batchSize).
+		Return([]model.WalletCreationSaga(nil), errors.New("db down"))
+
+	err := w.compensateDue(context.Background())
+
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "claim due compensations")
+	d.assertAll(t)
+}
+
+func TestWalletSagaWorker_RetryDelayIsBounded(t *testing.T) {
+	w := newWalletSagaTestDeps().worker()
+
+	for attempts := 1; attempts <= 20; attempts++ {
+		delay := w.retryDelay(attempts)
+		assert.GreaterOrEqual(t, delay, w.baseBackoff/2)
+		assert.LessOrEqual(t, delay, w.maxBackoff)
+	}
+}
+
+// =====================================================================
+// recoverStale
+// =====================================================================
+
+func TestWalletSagaWorker_StaleSagaWithWalletIsCommitted(t *testing.T) {
+	d := newWalletSagaTestDeps()
+	w := d.worker()
+
+	d.sagaRepo.On("GetStale", mock.Anything, mock.Anything, w.batchSize).
+		Return([]model.WalletCreationSaga{sampleSaga(model.SagaDepositCreated, "tx-123", 0)}, nil)
+	d.walletsRepo.On("WalletEverCreated", mock.Anything, nil, walletID.String()).Return(true, nil)
+	d.sagaRepo.On("MarkCommitted", mock.Anything, sagaID.String()).Return(nil)
+
+	err := w.recoverStale(context.Background())
+
+	assert.NoError(t, err)
+	d.assertAll(t)
+}
+
+func TestWalletSagaWorker_StaleDepositWithoutWalletIsCompensated(t *testing.T) {
+	d := newWalletSagaTestDeps()
+	w := d.worker()
+
+	d.sagaRepo.On("GetStale", mock.Anything, mock.Anything, w.batchSize).
+		Return([]model.WalletCreationSaga{sampleSaga(model.SagaDepositCreated, "tx-123", 0)}, nil)
+	d.walletsRepo.On("WalletEverCreated", mock.Anything, nil, walletID.String()).Return(false, nil)
+	d.sagaRepo.On("ScheduleCompensation", mock.Anything, sagaID.String(), mock.Anything, "").Return(nil)
+
+	err := w.recoverStale(context.Background())
+
+	assert.NoError(t, err)
+	d.assertAll(t)
+}
+
+func TestWalletSagaWorker_StalePendingWithoutWalletNeedsReview(t *testing.T) {
+	d := newWalletSagaTestDeps()
+	w := d.worker()
+
+	d.sagaRepo.On("GetStale", mock.Anything, mock.Anything, w.batchSize).
+		Return([]model.WalletCreationSaga{sampleSaga(model.SagaPending, "", 0)}, nil)
+	d.walletsRepo.On("WalletEverCreated", mock.Anything, nil, walletID.String()).Return(false, nil)
+	d.sagaRepo.On("MarkNeedsReview", mock.Anything, sagaID.String(), mock.AnythingOfType("string")).Return(nil)
+
+	err := w.recoverStale(context.Background())
+
+	assert.NoError(t, err)
+	d.sagaRepo.AssertNotCalled(t, "MarkFailed", mock.Anything, mock.Anything)
+	d.txClient.AssertNotCalled(t, "CancelInitialDeposit", mock.Anything, mock.Anything)
+	d.assertAll(t)
+}
+
+func TestWalletSagaWorker_StaleSagaSkippedOnLookupError(t *testing.T) {
+	d := newWalletSagaTestDeps()
+	w := d.worker()
+
+	d.sagaRepo.On("GetStale", mock.Anything, mock.Anything, w.batchSize).
+		Return([]model.WalletCreationSaga{sampleSaga(model.SagaDepositCreated, "tx-123", 0)}, nil)
+	d.walletsRepo.On("WalletEverCreated", mock.Anything, nil, walletID.String()).
+		Return(false, errors.New("connection reset"))
+
+	err := w.recoverStale(context.Background())
+
+	assert.NoError(t, err)
+	d.sagaRepo.AssertNotCalled(t, "ScheduleCompensation", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	d.sagaRepo.AssertNotCalled(t, "MarkFailed", mock.Anything, mock.Anything)
+	d.sagaRepo.AssertNotCalled(t, "MarkNeedsReview", mock.Anything, mock.Anything, mock.Anything)
+	d.assertAll(t)
+}
+
+func TestDepositOutcomeUnknown(t *testing.T) {
+	cases := []struct {
+		err  error
+		want bool
+	}{
+		{status.Error(codes.DeadlineExceeded, "timeout"), true},
+		{status.Error(codes.Unavailable, "connection reset"), true},
+		{status.Error(codes.Canceled, "client went away"), true},
+		{errors.New("boom"), true},
+		{status.Error(codes.InvalidArgument, "invalid amount"), false},
+		{status.Error(codes.NotFound, "wallet not found"), false},
+	}
+	for _, c := range cases {
+		assert.Equal(t, c.want, depositOutcomeUnknown(c.err), "%v", c.err)
+	}
+}
@@ -2,16 +2,20 @@ package service
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
+	"slices"
+	"strings"
+	"time"
 
 	"refina-wallet/config/log"
 	"refina-wallet/interface/grpc/client"
 	"refina-wallet/interface/queue"
 	"refina-wallet/internal/repository"
+	"refina-wallet/internal/types/domainerr"
 	"refina-wallet/internal/types/dto"
 	"refina-wallet/internal/types/model"
-	"refina-wallet/internal/types/view"
+	"refina-wallet/internal/types/money"
 	"refina-wallet/internal/utils"
 	"refina-wallet/internal/utils/data"
 
@@ -22,21 +26,37 @@ import (
 
 type WalletsService interface {
 	GetAllWallets(ctx context.Context) ([]dto.WalletsResponse, error)
-	GetWalletByID(ctx context.Context, id string) (dto.WalletsResponse, error)
+	GetWalletByID(ctx context.Context, access WalletAccess, id string) (dto.WalletsResponse, error)
 	GetWalletsByUserID(ctx context.Context, userID string) ([]dto.WalletsResponse, error)
-	GetWalletsByUserIDGroupByType(ctx context.Context, userID string) ([]view.ViewUserWalletsGroupByType, error)
+	GetWalletsByUserIDGroupByType(ctx context.Context, userID, baseCurrency string) ([]dto.WalletsGroupByTypeResponse, error)
+	GetWalletSummary(ctx context.Context, userID, baseCurrency string) (dto.WalletSummaryResponse, error)
 	CreateWallet(ctx context.Context, userID string, wallet dto.WalletsRequest) (dto.WalletsResponse, error)
 	CreateWalletGRPC(ctx context.Context, wallet dto.WalletsRequest) (dto.WalletsResponse, error)
-	UpdateWallet(ctx context.Context, id string, wallet dto.WalletsRequest) (dto.WalletsResponse, error)
-	DeleteWallet(ctx context.Context, id string) (dto.WalletsResponse, error)
+	UpdateWallet(ctx context.Context, access WalletAccess, id string, wallet dto.WalletsRequest, expectedVersion int64) (dto.WalletsResponse, error)
+	DeleteWallet(ctx context.Context, access WalletAccess, id string, expectedVersion int64) (dto.WalletsResponse, error)
+	GetWalletBalanceEntries(ctx context.Context, access WalletAccess, walletID string, page int) ([]dto.WalletBalanceEntryResponse, error)
+	GetWalletBalanceAsOf(ctx context.Context, access WalletAccess, walletID string, asOf time.Time) (dto.WalletBalanceResponse, error)
+	TransferBetweenWallets(ctx context.Context, access WalletAccess, req dto.WalletTransferRequest) (dto.WalletTransferResponse, error)
 }
 
+var (
+	ErrInvalidUserID       = domainerr.New(domainerr.InvalidArgument, "INVALID_USER_ID", "invalid user id")
+	ErrInvalidWalletTypeID = domainerr.New(domainerr.InvalidArgument, "INVALID_WALLET_TYPE_ID", "invalid wallet type id")
+	// ErrInvalidTransfer is returned for transfer requests that can never succeed as sent
+	ErrInvalidTransfer = domainerr.New(domainerr.InvalidArgument, "INVALID_TRANSFER", "invalid transfer")
+	// ErrWalletBalanceNotZero is returned when deleting a wallet that still holds money
+	ErrWalletBalanceNotZero = domainerr.New(domainerr.FailedPrecondition, "WALLET_BALANCE_NOT_ZERO", "wallet balance must be zero before deletion")
+	ErrInsufficientBalance  = domainerr.New(domainerr.FailedPrecondition, "INSUFFICIENT_BALANCE", "insufficient balance")
+)
+
 type walletsService struct {
 	txManager             repository.TxManager
 	walletsRepository     repository.WalletsRepository
 	walletTypesRepository repository.WalletTypesRepository
 	outboxRepository      repository.OutboxRepository
+	sagaRepository        repository.WalletSagaRepository
 	transactionClient     client.TransactionClient
+	exchangeRates         ExchangeRateProvider
 	queue                 queue.RabbitMQClient
 }
 
@@ -45,7 +65,9 @@ func NewWalletService(
 	walletsRepository repository.WalletsRepository,
 	walletTypesRepository repository.WalletTypesRepository,
 	outboxRepository repository.OutboxRepository,
+	sagaRepository repository.WalletSagaRepository,
 	transactionRepository client.TransactionClient,
+	exchangeRates ExchangeRateProvider,
 	queue queue.RabbitMQClient,
 ) WalletsService {
 	return &walletsService{
@@ -53,7 +75,9 @@ func NewWalletService(
 		walletsRepository:     walletsRepository,
 		walletTypesRepository: walletTypesRepository,
 		outboxRepository:      outboxRepository,
+		sagaRepository:        sagaRepository,
 		transactionClient:     transactionRepository,
+		exchangeRates:         exchangeRates,
 		queue:                 queue,
 	}
 }
@@ -73,8 +97,8 @@ func (wallet_serv *walletsService) GetAllWallets(ctx context.Context) ([]dto.Wal
 	return walletsResponse, nil
 }
 
-func (wallet_serv *walletsService) GetWalletByID(ctx context.Context, id string) (dto.WalletsResponse, error) {
-	wallet, err := wallet_serv.walletsRepository.GetWalletByID(ctx, nil, id)
+func (wallet_serv *walletsService) GetWalletByID(ctx context.Context, access WalletAccess, id string) (dto.WalletsResponse, error) {
+	wallet, err := wallet_serv.getAccessibleWallet(ctx, access, id)
 	if err != nil {
 		return dto.WalletsResponse{}, fmt.Errorf("get wallet [id=%s]: %w", id, err)
 	}
@@ -84,6 +108,24 @@ func (wallet_serv *walletsService) GetWalletByID(ctx context.Context, id string)
 	return walletResponse, nil
 }
 
+// getAccessibleWallet loads a wallet on behalf of access. Wallets of other users are reported
+// exactly like missing ones, so wallet ids cannot be probed across users.
+func (wallet_serv *walletsService) getAccessibleWallet(ctx context.Context, access WalletAccess, id string) (model.Wallets, error) {
+	if err := access.validate(); err != nil {
+		return model.Wallets{}, err
+	}
+
+	wallet, err := wallet_serv.walletsRepository.GetWalletByID(ctx, nil, id)
+	if err != nil {
+		return model.Wallets{}, fmt.Errorf("wallet not found [id=%s]: %w", id, err)
+	}
+	if !access.allows(wallet) {
+		return model.Wallets{}, fmt.Errorf("wallet not found [id=%s]: %w", id, repository.ErrWalletNotFound)
+	}
+
+	return wallet, nil
+}
+
 func (wallet_serv *walletsService) GetWalletsByUserID(ctx context.Context, userID string) ([]dto.WalletsResponse, error) {
 	wallets, err := wallet_serv.walletsRepository.GetWalletsByUserID(ctx, nil, userID)
 	if err != nil {
@@ -99,112 +141,110 @@ func (wallet_serv *walletsService) GetWalletsByUserID(ctx context.Context, userI
 	return walletsResponse, nil
 }
 
-func (wallet_serv *walletsService) GetWalletsByUserIDGroupByType(ctx context.Context, userID string) ([]view.ViewUserWalletsGroupByType, error) {
-	wallets, err := wallet_serv.walletsRepository.GetWalletsByUserIDGroupByType(ctx, nil, userID)
+// GetWalletsByUserIDGroupByType adds per-currency totals and their sum in baseCurrency to every
+// wallet type group. An empty baseCurrency falls back to the exchange-rate provider's base.
+func (wallet_serv *walletsService) GetWalletsByUserIDGroupByType(ctx context.Context, userID, baseCurrency string) ([]dto.WalletsGroupByTypeResponse, error) {
+	base, err := wallet_serv.summaryCurrency(baseCurrency)
 	if err != nil {
 		return nil, err
 	}
 
-	return wallets, err
-}
-
-func (wallet_serv *walletsService) CreateWallet(ctx context.Context, userID string, wallet dto.WalletsRequest) (dto.WalletsResponse, error) {
-	UserID, err := utils.ParseUUID(userID)
+	groups, err := wallet_serv.walletsRepository.GetWalletsByUserIDGroupByType(ctx, nil, userID)
 	if err != nil {
-		return dto.WalletsResponse{}, fmt.Errorf("invalid user id: %w", err)
+		return nil, err
 	}
 
-	WalletTypeID, err := utils.ParseUUID(wallet.WalletTypeID)
-	if err != nil {
-		return dto.WalletsResponse{}, fmt.Errorf("invalid wallet type id: %w", err)
-	}
+	var groupsResponse []dto.WalletsGroupByTypeResponse
+	for _, group := range groups {
+		totals := currencyTotals{}
+		for _, wallet := range group.Wallets {
+			totals.add(wallet.Currency, wallet.Balance)
+		}
 
-	walletType, err := wallet_serv.walletTypesRepository.GetWalletTypeByID(ctx, nil, wallet.WalletTypeID)
-	if err != nil {
-		return dto.WalletsResponse{}, fmt.Errorf("wallet type not found [id=%s]: %w", wallet.WalletTypeID, err)
-	}
+		currencies, totalBalance, err := totals.convert(ctx, wallet_serv.exchangeRates, base)
+		if err != nil {
+			return nil, fmt.Errorf("get wallets by user group by type [id=%s]: %w", userID, err)
+		}
 
-	tx, err := wallet_serv.txManager.Begin(ctx)
-	if err != nil {
-		return dto.WalletsResponse{}, fmt.Errorf("create wallet: begin transaction: %w", err)
+		groupsResponse = append(groupsResponse, dto.WalletsGroupByTypeResponse{
+			ViewUserWalletsGroupByType: group,
+			BaseCurrency:               base,
+			TotalBalance:               totalBalance,
+			Currencies:                 currencies,
+		})
 	}
 
-	initialDeposit := new(tpb.TransactionDetail)
-
-	defer func() {
-		tx.Rollback()
-		if err != nil && initialDeposit != nil && initialDeposit.GetId() != "" {
-			// GRPC call
-			wallet_serv.transactionClient.CancelInitialDeposit(ctx, initialDeposit.GetId())
-		}
-	}()
+	return groupsResponse, nil
+}
 
-	// Create wallet
-	walletID := uuid.New()
-	newWallet, err := wallet_serv.walletsRepository.CreateWallet(ctx, tx, model.Wallets{
-		Base: model.Base{
-			ID: walletID,
-		},
-		UserID:       UserID,
-		WalletTypeID: WalletTypeID,
-		Name:         wallet.Name,
-		Number:       wallet.Number,
-		Balance:      wallet.Balance,
-		WalletType:   walletType,
-	})
+// GetWalletSummary totals the user's wallets per currency and converts the totals to
+// baseCurrency, which falls back to the exchange-rate provider's base when empty.
+func (wallet_serv *walletsService) GetWalletSummary(ctx context.Context, userID, baseCurrency string) (dto.WalletSummaryResponse, error) {
+	base, err := wallet_serv.summaryCurrency(baseCurrency)
 	if err != nil {
-		return dto.WalletsResponse{}, fmt.Errorf("create wallet: insert to db: %w", err)
+		return dto.WalletSummaryResponse{}, err
 	}
 
-	// GRPC call
-	initialDeposit, err = wallet_serv.transactionClient.InitialDeposit(ctx, walletID.String(), wallet.Balance)
+	wallets, err := wallet_serv.walletsRepository.GetWalletsByUserID(ctx, nil, userID)
 	if err != nil {
-		log.Warn(data.LogCreateWalletGRPCFailedRollback, map[string]any{
-			"service":   data.WalletService,
-			"wallet_id": walletID.String(),
-			"amount":    wallet.Balance,
-			"error":     err.Error(),
-		})
-		return dto.WalletsResponse{}, fmt.Errorf("create wallet: initial deposit via grpc: %w", err)
+		return dto.WalletSummaryResponse{}, fmt.Errorf("get wallets by user [id=%s]: %w", userID, err)
 	}
 
-	walletResponse := utils.ConvertToResponseType(newWallet).(dto.WalletsResponse)
-
-	payload, err := json.Marshal(walletResponse)
-	if err != nil {
-		return dto.WalletsResponse{}, fmt.Errorf("create wallet: marshal wallet response: %w", err)
+	totals := currencyTotals{}
+	for _, wallet := range wallets {
+		totals.add(wallet.Currency, wallet.Balance)
 	}
 
-	outboxMsg := &model.OutboxMessage{
-		AggregateID: walletResponse.ID,
-		EventType:   data.OUTBOX_EVENT_WALLET_CREATED,
-		Payload:     payload,
-		Published:   false,
-		MaxRetries:  data.OUTBOX_PUBLISH_MAX_RETRIES,
+	currencies, totalBalance, err := totals.convert(ctx, wallet_serv.exchangeRates, base)
+	if err != nil {
+		return dto.WalletSummaryResponse{}, fmt.Errorf("get wallet summary [id=%s]: %w", userID, err)
 	}
 
-	if err := wallet_serv.outboxRepository.Create(ctx, tx, outboxMsg); err != nil {
-		return dto.WalletsResponse{}, fmt.Errorf("create wallet: save outbox message: %w", err)
-	}
+	return dto.WalletSummaryResponse{
+		UserID:       userID,
+		BaseCurrency: base,
+		TotalWallets: len(wallets),
+		TotalBalance: totalBalance,
+		Currencies:   currencies,
+	}, nil
+}
 
-	// Commit transaction
-	if err := tx.Commit(); err != nil {
-		return dto.WalletsResponse{}, fmt.Errorf("create wallet: commit transaction: %w", err)
+func (wallet_serv *walletsService) summaryCurrency(baseCurrency string) (string, error) {
+	if baseCurrency == "" {
+		return wallet_serv.exchangeRates.BaseCurrency(), nil
 	}
+	return utils.NormalizeCurrency(baseCurrency)
+}
 
-	return walletResponse, nil
+func (wallet_serv *walletsService) CreateWallet(ctx context.Context, userID string, wallet dto.WalletsRequest) (dto.WalletsResponse, error) {
+	return wallet_serv.createWallet(ctx, userID, wallet, true)
 }
 
 // CreateWalletGRPC is used by the gRPC server — user_id is already validated by the BFF
 func (wallet_serv *walletsService) CreateWalletGRPC(ctx context.Context, wallet dto.WalletsRequest) (dto.WalletsResponse, error) {
-	UserID, err := utils.ParseUUID(wallet.UserID)
+	return wallet_serv.createWallet(ctx, wallet.UserID, wallet, wallet.Balance > 0)
+}
+
+// createWallet runs wallet creation as a saga: the local insert and the remote initial deposit
+// are tracked in wallet_creation_sagas outside the database transaction, so a deposit left behind
+// by a failed creation is cancelled inline or, failing that, by WalletSagaWorker.
+func (wallet_serv *walletsService) createWallet(ctx context.Context, userID string, wallet dto.WalletsRequest, withDeposit bool) (dto.WalletsResponse, error) {
+	UserID, err := utils.ParseUUID(userID)
 	if err != nil {
-		return dto.WalletsResponse{}, fmt.Errorf("invalid user id: %w", err)
+		return dto.WalletsResponse{}, fmt.Errorf("%w: %w", ErrInvalidUserID, err)
 	}
 
 	WalletTypeID, err := utils.ParseUUID(wallet.WalletTypeID)
 	if err != nil {
-		return dto.WalletsResponse{}, fmt.Errorf("invalid wallet type id: %w", err)
+		return dto.WalletsResponse{}, fmt.Errorf("%w: %w", ErrInvalidWalletTypeID, err)
+	}
+
+	currency, err := wallet_serv.newWalletCurrency(ctx, wallet.Currency)
+	if err != nil {
+		return dto.WalletsResponse{}, err
+	}
+	if !wallet.Balance.IsRounded(currency) {
+		return dto.WalletsResponse{}, fmt.Errorf("%w: balance %s is finer than the minor unit of %s", money.ErrInvalidAmount, wallet.Balance, currency)
 	}
 
 	walletType, err := wallet_serv.walletTypesRepository.GetWalletTypeByID(ctx, nil, wallet.WalletTypeID)
@@ -212,21 +252,48 @@ func (wallet_serv *walletsService) CreateWalletGRPC(ctx context.Context, wallet
 		return dto.WalletsResponse{}, fmt.Errorf("wallet type not found [id=%s]: %w", wallet.WalletTypeID, err)
 	}
 
+	walletID := uuid.New()
+
+	// Saga hanya diperlukan bila ada side effect di transaction service
+	var saga *model.WalletCreationSaga
+	if withDeposit {
+		saga = &model.WalletCreationSaga{
+			ID:       uuid.New(),
+			WalletID: walletID,
+			UserID:   UserID,
+			Amount:   wallet.Balance,
+			Status:   model.SagaPending,
+		}
+		if err = wallet_serv.sagaRepository.Create(ctx, saga); err != nil {
+			return dto.WalletsResponse{}, fmt.Errorf("create wallet: save saga: %w", err)
+		}
+	}
+
 	tx, err := wallet_serv.txManager.Begin(ctx)
 	if err != nil {
+		wallet_serv.failWalletSaga(ctx, saga)
 		return dto.WalletsResponse{}, fmt.Errorf("create wallet: begin transaction: %w", err)
 	}
 
 	initialDeposit := new(tpb.TransactionDetail)
+	depositUnknown := false
 
 	defer func() {
 		tx.Rollback()
-		if err != nil && initialDeposit != nil && initialDeposit.GetId() != "" {
-			wallet_serv.transactionClient.CancelInitialDeposit(ctx, initialDeposit.GetId())
+		if err == nil {
+			return
+		}
+		if initialDeposit.GetId() != "" {
+			wallet_serv.compensateInitialDeposit(ctx, saga, initialDeposit.GetId(), err)
+			return
 		}
+		if depositUnknown {
+			wallet_serv.reviewWalletSaga(ctx, saga, err)
+			return
+		}
+		wallet_serv.failWalletSaga(ctx, saga)
 	}()
 
-	walletID := uuid.New()
 	newWallet, err := wallet_serv.walletsRepository.CreateWallet(ctx, tx, model.Wallets{
 		Base: model.Base{
 			ID: walletID,
@@ -235,16 +302,21 @@ func (wallet_serv *walletsService) CreateWalletGRPC(ctx context.Context, wallet
 		WalletTypeID: WalletTypeID,
 		Name:         wallet.Name,
 		Number:       wallet.Number,
-		Balance:      wallet.Balance,
+		Balance:      0, // saldo hanya berubah lewat ledger
+		Currency:     currency,
+		Version:      1,
 		WalletType:   walletType,
 	})
 	if err != nil {
 		return dto.WalletsResponse{}, fmt.Errorf("create wallet: insert to db: %w", err)
 	}
 
-	if wallet.Balance > 0 {
-		initialDeposit, err = wallet_serv.transactionClient.InitialDeposit(ctx, walletID.String(), wallet.Balance)
-		if err != nil {
+	if withDeposit {
+		// GRPC call
+		deposit, depositErr := wallet_serv.transactionClient.InitialDeposit(ctx, walletID.String(), wallet.Balance)
+		if depositErr != nil {
+			err = depositErr
+			depositUnknown = depositOutcomeUnknown(depositErr)
 			log.Warn(data.LogCreateWalletGRPCFailedRollback, map[string]any{
 				"service":   data.WalletService,
 				"wallet_id": walletID.String(),
@@ -253,46 +325,164 @@ func (wallet_serv *walletsService) CreateWalletGRPC(ctx context.Context, wallet
 			})
 			return dto.WalletsResponse{}, fmt.Errorf("create wallet: initial deposit via grpc: %w", err)
 		}
+		if deposit != nil {
+			initialDeposit = deposit
+		}
+
+		if err = wallet_serv.sagaRepository.MarkDepositCreated(ctx, saga.ID.String(), initialDeposit.GetId()); err != nil {
+			return dto.WalletsResponse{}, fmt.Errorf("create wallet: update saga: %w", err)
+		}
+	}
+
+	if entry, ok := newBalanceEntry(walletID, wallet.Balance, data.BALANCE_REASON_INITIAL_DEPOSIT, initialDeposit.GetId()); ok {
+		entry, err = wallet_serv.walletsRepository.AppendBalanceEntry(ctx, tx, entry)
+		if err != nil {
+			return dto.WalletsResponse{}, fmt.Errorf("create wallet: append balance entry: %w", err)
+		}
+		newWallet.Balance = entry.BalanceAfter
+		newWallet.Version = entry.WalletVersion
 	}
 
 	walletResponse := utils.ConvertToResponseType(newWallet).(dto.WalletsResponse)
 
-	payload, err := json.Marshal(walletResponse)
+	if err = enqueueOutboxEvent(ctx, wallet_serv.outboxRepository, tx, data.OUTBOX_EVENT_WALLET_CREATED, walletResponse.ID, walletResponse); err != nil {
+		return dto.WalletsResponse{}, fmt.Errorf("create wallet: save outbox message: %w", err)
+	}
+
+	// Commit transaction
+	if err = tx.Commit(); err != nil {
+		return dto.WalletsResponse{}, fmt.Errorf("create wallet: commit transaction: %w", err)
+	}
+
+	if saga != nil {
+		// Wallet sudah tersimpan; saga yang tertinggal akan diselesaikan oleh worker
+		if sagaErr := wallet_serv.sagaRepository.MarkCommitted(ctx, saga.ID.String()); sagaErr != nil {
+			log.Warn(data.LogSagaStateUpdateFailed, map[string]any{
+				"service": data.WalletService,
+				"saga_id": saga.ID.String(),
+				"error":   sagaErr.Error(),
+			})
+		}
+	}
+
+	return walletResponse, nil
+}
+
+// newWalletCurrency defaults requested to the exchange-rate provider's base and rejects currencies
+// the provider cannot convert, since such a wallet would break every summary of its owner.
+func (wallet_serv *walletsService) newWalletCurrency(ctx context.Context, requested string) (string, error) {
+	base := wallet_serv.exchangeRates.BaseCurrency()
+	if requested == "" {
+		return base, nil
+	}
+
+	currency, err := utils.NormalizeCurrency(requested)
 	if err != nil {
-		return dto.WalletsResponse{}, fmt.Errorf("create wallet: marshal wallet response: %w", err)
+		return "", err
+	}
+
+	if _, err := wallet_serv.exchangeRates.Rate(ctx, currency, base); err != nil {
+		if errors.Is(err, ErrExchangeRateUnavailable) {
+			return "", fmt.Errorf("%w %s: not supported by the exchange-rate provider", utils.ErrInvalidCurrency, currency)
+		}
+		return "", fmt.Errorf("check wallet currency %s: %w", currency, err)
 	}
 
-	outboxMsg := &model.OutboxMessage{
-		AggregateID: walletResponse.ID,
-		EventType:   data.OUTBOX_EVENT_WALLET_CREATED,
-		Payload:     payload,
-		Published:   false,
-		MaxRetries:  data.OUTBOX_PUBLISH_MAX_RETRIES,
+	return currency, nil
+}
+
+// compensateInitialDeposit cancels the deposit of a failed creation right away. When that fails
+// too the saga is handed to WalletSagaWorker, which keeps retrying.
+func (wallet_serv *walletsService) compensateInitialDeposit(ctx context.Context, saga *model.WalletCreationSaga, depositID string, cause error) {
+	// Kompensasi tetap dijalankan walaupun request sudah dibatalkan oleh client
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), data.SAGA_COMPENSATION_TIMEOUT)
+	defer cancel()
+
+	cancelErr := cancelInitialDeposit(ctx, wallet_serv.transactionClient, depositID)
+	if cancelErr == nil {
+		wallet_serv.failWalletSaga(ctx, saga)
+		return
+	}
+
+	log.Warn(data.LogSagaCompensationFailed, map[string]any{
+		"service":                data.WalletService,
+		"saga_id":                saga.ID.String(),
+		"wallet_id":              saga.WalletID.String(),
+		"deposit_transaction_id": depositID,
+		"cause":                  cause.Error(),
+		"error":                  cancelErr.Error(),
+	})
+
+	if err := wallet_serv.sagaRepository.ScheduleCompensation(ctx, saga.ID.String(), time.Now(), cancelErr.Error()); err != nil {
+		log.Error(data.LogSagaStateUpdateFailed, map[string]any{
+			"service": data.WalletService,
+			"saga_id": saga.ID.String(),
+			"error":   err.Error(),
+		})
 	}
+}
 
-	if err := wallet_serv.outboxRepository.Create(ctx, tx, outboxMsg); err != nil {
-		return dto.WalletsResponse{}, fmt.Errorf("create wallet: save outbox message: %w", err)
+// failWalletSaga records that the creation rolled back with nothing left to compensate
+func (wallet_serv *walletsService) failWalletSaga(ctx context.Context, saga *model.WalletCreationSaga) {
+	if saga == nil {
+		return
 	}
 
-	if err := tx.Commit(); err != nil {
-		return dto.WalletsResponse{}, fmt.Errorf("create wallet: commit transaction: %w", err)
+	if err := wallet_serv.sagaRepository.MarkFailed(context.WithoutCancel(ctx), saga.ID.String()); err != nil {
+		log.Error(data.LogSagaStateUpdateFailed, map[string]any{
+			"service": data.WalletService,
+			"saga_id": saga.ID.String(),
+			"error":   err.Error(),
+		})
 	}
+}
 
-	return walletResponse, nil
+// reviewWalletSaga hands a creation whose initial deposit may exist, e.g. after a timeout, over
+// to an operator: cancelling blindly could hit nothing, and marking it failed would hide the money.
+func (wallet_serv *walletsService) reviewWalletSaga(ctx context.Context, saga *model.WalletCreationSaga, cause error) {
+	log.Error(data.LogSagaUnknownDepositOutcome, map[string]any{
+		"service":   data.WalletService,
+		"saga_id":   saga.ID.String(),
+		"wallet_id": saga.WalletID.String(),
+		"user_id":   saga.UserID.String(),
+		"amount":    saga.Amount,
+		"error":     cause.Error(),
+	})
+
+	if err := wallet_serv.sagaRepository.MarkNeedsReview(context.WithoutCancel(ctx), saga.ID.String(), cause.Error()); err != nil {
+		log.Error(data.LogSagaStateUpdateFailed, map[string]any{
+			"service": data.WalletService,
+			"saga_id": saga.ID.String(),
+			"error":   err.Error(),
+		})
+	}
 }
 
-func (wallet_serv *walletsService) UpdateWallet(ctx context.Context, id string, wallet dto.WalletsRequest) (dto.WalletsResponse, error) {
-	existingWallet, err := wallet_serv.walletsRepository.GetWalletByID(ctx, nil, id)
+// UpdateWallet applies wallet on top of the stored one. A non-zero expectedVersion makes the
+// update conditional: it fails with ErrWalletVersionConflict if anyone changed the wallet since.
+func (wallet_serv *walletsService) UpdateWallet(ctx context.Context, access WalletAccess, id string, wallet dto.WalletsRequest, expectedVersion int64) (dto.WalletsResponse, error) {
+	existingWallet, err := wallet_serv.getAccessibleWallet(ctx, access, id)
 	if err != nil {
-		return dto.WalletsResponse{}, fmt.Errorf("wallet not found [id=%s]: %w", id, err)
+		return dto.WalletsResponse{}, err
 	}
 
-	existingWallet.Name = wallet.Name
-	existingWallet.Number = wallet.Number
-	existingWallet.Balance = wallet.Balance
-	existingWallet.WalletTypeID, err = utils.ParseUUID(wallet.WalletTypeID)
+	walletTypeID, err := utils.ParseUUID(wallet.WalletTypeID)
 	if err != nil {
-		return dto.WalletsResponse{}, fmt.Errorf("invalid wallet type id: %w", err)
+		return dto.WalletsResponse{}, fmt.Errorf("%w: %w", ErrInvalidWalletTypeID, err)
+	}
+
+	// Mata uang tetap sejak wallet dibuat; saldo dan ledger-nya tidak dikonversi
+	if wallet.Currency != "" {
+		currency, err := utils.NormalizeCurrency(wallet.Currency)
+		if err != nil {
+			return dto.WalletsResponse{}, err
+		}
+		if currency != existingWallet.Currency {
+			return dto.WalletsResponse{}, fmt.Errorf("%w: the currency of wallet [id=%s] cannot be changed", utils.ErrInvalidCurrency, id)
+		}
+	}
+	if !wallet.Balance.IsRounded(existingWallet.Currency) {
+		return dto.WalletsResponse{}, fmt.Errorf("%w: balance %s is finer than the minor unit of %s", money.ErrInvalidAmount, wallet.Balance, existingWallet.Currency)
 	}
 
 	tx, err := wallet_serv.txManager.Begin(ctx)
@@ -304,27 +494,60 @@ func (wallet_serv *walletsService) UpdateWallet(ctx context.Context, id string,
 		tx.Rollback()
 	}()
 
-	walletUpdated, err := wallet_serv.walletsRepository.UpdateWallet(ctx, tx, existingWallet)
+	// Versi dan saldo dibandingkan dengan baris yang dikunci, bukan dengan pembacaan di atas:
+	// ledger bisa saja bertambah di antaranya
+	lockedWallet, err := wallet_serv.lockWallet(ctx, tx, existingWallet)
 	if err != nil {
-		return dto.WalletsResponse{}, fmt.Errorf("update wallet: update in db: %w", err)
+		return dto.WalletsResponse{}, fmt.Errorf("update wallet: lock wallet: %w", err)
 	}
 
-	walletResponse := utils.ConvertToResponseType(walletUpdated).(dto.WalletsResponse)
+	if expectedVersion != 0 && expectedVersion != lockedWallet.Version {
+		return dto.WalletsResponse{}, fmt.Errorf("update wallet [id=%s]: %w", id, repository.ErrWalletVersionConflict)
+	}
 
-	payload, err := json.Marshal(walletResponse)
+	previous := utils.ConvertToResponseType(lockedWallet).(dto.WalletsResponse)
+
+	changedFields := walletChangedFields(lockedWallet, wallet, walletTypeID)
+	if len(changedFields) == 0 {
+		// Tidak ada perubahan, jadi tidak perlu menulis ke db maupun mengirim event wallet.updated
+		return previous, nil
+	}
+
+	walletToUpdate := lockedWallet
+	if walletTypeID != lockedWallet.WalletTypeID {
+		walletType, err := wallet_serv.walletTypesRepository.GetWalletTypeByID(ctx, nil, wallet.WalletTypeID)
+		if err != nil {
+			return dto.WalletsResponse{}, fmt.Errorf("wallet type not found [id=%s]: %w", wallet.WalletTypeID, err)
+		}
+		walletToUpdate.WalletType = walletType
+	}
+
+	walletToUpdate.Name = wallet.Name
+	walletToUpdate.Number = wallet.Number
+	walletToUpdate.WalletTypeID = walletTypeID
+
+	walletUpdated, err := wallet_serv.walletsRepository.UpdateWallet(ctx, tx, walletToUpdate)
 	if err != nil {
-		return dto.WalletsResponse{}, fmt.Errorf("update wallet: marshal wallet response: %w", err)
+		return dto.WalletsResponse{}, fmt.Errorf("update wallet: update in db: %w", err)
 	}
 
-	outboxMsg := &model.OutboxMessage{
-		AggregateID: walletResponse.ID,
-		EventType:   data.OUTBOX_EVENT_WALLET_UPDATED,
-		Payload:     payload,
-		Published:   false,
-		MaxRetries:  data.OUTBOX_PUBLISH_MAX_RETRIES,
+	// Perubahan saldo manual dicatat sebagai entry penyesuaian, bukan menimpa kolom balance
+	if entry, ok := newBalanceEntry(lockedWallet.ID, wallet.Balance-lockedWallet.Balance, data.BALANCE_REASON_MANUAL_ADJUSTMENT, ""); ok {
+		entry, err = wallet_serv.walletsRepository.AppendBalanceEntry(ctx, tx, entry)
+		if err != nil {
+			return dto.WalletsResponse{}, fmt.Errorf("update wallet: append balance entry: %w", err)
+		}
+		walletUpdated.Balance = entry.BalanceAfter
+		walletUpdated.Version++
 	}
 
-	if err := wallet_serv.outboxRepository.Create(ctx, tx, outboxMsg); err != nil {
+	walletResponse := utils.ConvertToResponseType(walletUpdated).(dto.WalletsResponse)
+
+	if err := enqueueOutboxEvent(ctx, wallet_serv.outboxRepository, tx, data.OUTBOX_EVENT_WALLET_UPDATED, walletResponse.ID, dto.WalletUpdatedEvent{
+		WalletsResponse: walletResponse,
+		Previous:        previous,
+		ChangedFields:   changedFields,
+	}); err != nil {
 		return dto.WalletsResponse{}, fmt.Errorf("update wallet: save outbox message: %w", err)
 	}
 
@@ -336,14 +559,11 @@ func (wallet_serv *walletsService) UpdateWallet(ctx context.Context, id string,
 	return walletResponse, nil
 }
 
-func (wallet_serv *walletsService) DeleteWallet(ctx context.Context, id string) (dto.WalletsResponse, error) {
-	existingWallet, err := wallet_serv.walletsRepository.GetWalletByID(ctx, nil, id)
+// DeleteWallet soft-deletes an empty wallet, conditionally on expectedVersion like UpdateWallet
+func (wallet_serv *walletsService) DeleteWallet(ctx context.Context, access WalletAccess, id string, expectedVersion int64) (dto.WalletsResponse, error) {
+	existingWallet, err := wallet_serv.getAccessibleWallet(ctx, access, id)
 	if err != nil {
-		return dto.WalletsResponse{}, fmt.Errorf("wallet not found [id=%s]: %w", id, err)
-	}
-
-	if existingWallet.Balance != 0 {
-		return dto.WalletsResponse{}, fmt.Errorf("wallet balance must be zero before deletion")
+		return dto.WalletsResponse{}, err
 	}
 
 	tx, err := wallet_serv.txManager.Begin(ctx)
@@ -355,27 +575,28 @@ func (wallet_serv *walletsService) DeleteWallet(ctx context.Context, id string)
 		tx.Rollback()
 	}()
 
-	deletedWallet, err := wallet_serv.walletsRepository.DeleteWallet(ctx, tx, existingWallet)
+	// Saldo dicek pada baris yang dikunci agar tidak ada transaksi yang masuk sebelum wallet terhapus
+	lockedWallet, err := wallet_serv.lockWallet(ctx, tx, existingWallet)
 	if err != nil {
-		return dto.WalletsResponse{}, fmt.Errorf("delete wallet: delete from db: %w", err)
+		return dto.WalletsResponse{}, fmt.Errorf("delete wallet: lock wallet: %w", err)
 	}
 
-	walletResponse := utils.ConvertToResponseType(deletedWallet).(dto.WalletsResponse)
+	if expectedVersion != 0 && expectedVersion != lockedWallet.Version {
+		return dto.WalletsResponse{}, fmt.Errorf("delete wallet [id=%s]: %w", id, repository.ErrWalletVersionConflict)
+	}
 
-	payload, err := json.Marshal(walletResponse)
-	if err != nil {
-		return dto.WalletsResponse{}, fmt.Errorf("delete wallet: marshal wallet response: %w", err)
+	if lockedWallet.Balance != 0 {
+		return dto.WalletsResponse{}, ErrWalletBalanceNotZero
 	}
 
-	outboxMsg := &model.OutboxMessage{
-		AggregateID: walletResponse.ID,
-		EventType:   data.OUTBOX_EVENT_WALLET_DELETED,
-		Payload:     payload,
-		Published:   false,
-		MaxRetries:  data.OUTBOX_PUBLISH_MAX_RETRIES,
+	deletedWallet, err := wallet_serv.walletsRepository.DeleteWallet(ctx, tx, lockedWallet)
+	if err != nil {
+		return dto.WalletsResponse{}, fmt.Errorf("delete wallet: delete from db: %w", err)
 	}
 
-	if err := wallet_serv.outboxRepository.Create(ctx, tx, outboxMsg); err != nil {
+	walletResponse := utils.ConvertToResponseType(deletedWallet).(dto.WalletsResponse)
+
+	if err := enqueueOutboxEvent(ctx, wallet_serv.outboxRepository, tx, data.OUTBOX_EVENT_WALLET_DELETED, walletResponse.ID, walletResponse); err != nil {
 		return dto.WalletsResponse{}, fmt.Errorf("delete wallet: save outbox message: %w", err)
 	}
 
@@ -386,3 +607,392 @@ func (wallet_serv *walletsService) DeleteWallet(ctx context.Context, id string)
 
 	return walletResponse, nil
 }
+
+// lockWallet locks the row of wallet until tx ends and returns it as it is now. The wallet type
+// read before is kept unless the type changed in the meantime.
+func (wallet_serv *walletsService) lockWallet(ctx context.Context, tx repository.Transaction, wallet model.Wallets) (model.Wallets, error) {
+	locked, err := wallet_serv.walletsRepository.LockWallets(ctx, tx, wallet.ID.String())
+	if err != nil {
+		return model.Wallets{}, err
+	}
+	if len(locked) != 1 {
+		return model.Wallets{}, fmt.Errorf("%w [id=%s]", repository.ErrWalletNotFound, wallet.ID)
+	}
+
+	return wallet_serv.withWalletType(ctx, locked[0], wallet)
+}
+
+// withWalletType gives a locked row the wallet type preloaded on the earlier read of it,
+// or reloads the type when the row was moved to another one in between.
+func (wallet_serv *walletsService) withWalletType(ctx context.Context, lockedWallet, wallet model.Wallets) (model.Wallets, error) {
+	if lockedWallet.WalletTypeID == wallet.WalletTypeID {
+		lockedWallet.WalletType = wallet.WalletType
+		return lockedWallet, nil
+	}
+
+	walletType, err := wallet_serv.walletTypesRepository.GetWalletTypeByID(ctx, nil, lockedWallet.WalletTypeID.String())
+	if err != nil {
+		return model.Wallets{}, fmt.Errorf("wallet type not found [id=%s]: %w", lockedWallet.WalletTypeID, err)
+	}
+	lockedWallet.WalletType = walletType
+	return lockedWallet, nil
+}
+
+func (wallet_serv *walletsService) GetWalletBalanceEntries(ctx context.Context, access WalletAccess, walletID string, page int) ([]dto.WalletBalanceEntryResponse, error) {
+	if _, err := wallet_serv.getAccessibleWallet(ctx, access, walletID); err != nil {
+		return nil, err
+	}
+
+	if page < 1 {
+		page = 1
+	}
+
+	entries, err := wallet_serv.walletsRepository.GetBalanceEntries(ctx, nil, walletID, data.WALLET_BALANCE_ENTRIES_PAGE_SIZE, (page-1)*data.WALLET_BALANCE_ENTRIES_PAGE_SIZE)
+	if err != nil {
+		return nil, fmt.Errorf("get wallet balance entries [id=%s]: %w", walletID, err)
+	}
+
+	entriesResponse := make([]dto.WalletBalanceEntryResponse, 0, len(entries))
+	for _, entry := range entries {
+		entriesResponse = append(entriesResponse, utils.ConvertToResponseType(entry).(dto.WalletBalanceEntryResponse))
+	}
+
+	return entriesResponse, nil
+}
+
+func (wallet_serv *walletsService) GetWalletBalanceAsOf(ctx context.Context, access WalletAccess, walletID string, asOf time.Time) (dto.WalletBalanceResponse, error) {
+	if _, err := wallet_serv.getAccessibleWallet(ctx, access, walletID); err != nil {
+		return dto.WalletBalanceResponse{}, err
+	}
+
+	balance, err := wallet_serv.walletsRepository.GetBalanceAsOf(ctx, nil, walletID, asOf)
+	if err != nil {
+		return dto.WalletBalanceResponse{}, fmt.Errorf("get wallet balance [id=%s]: %w", walletID, err)
+	}
+
+	return dto.WalletBalanceResponse{
+		WalletID: walletID,
+		Balance:  balance,
+		AsOf:     asOf.Format(time.RFC3339),
+	}, nil
+}
+
+// TransferBetweenWallets moves money between two wallets of the same user. Owner access only reaches
+// the caller's own wallets; moving another user's money needs admin access. Both wallet rows are locked
+// for the whole transaction, the matching cash-out/cash-in transactions are created through the
+// transaction service, and they are deleted again if anything fails before commit. The completed event
+// is written once under each wallet's aggregate, so it is ordered with the other events of both wallets.
+func (wallet_serv *walletsService) TransferBetweenWallets(ctx context.Context, access WalletAccess, req dto.WalletTransferRequest) (dto.WalletTransferResponse, error) {
+	if err := validateTransferRequest(req); err != nil {
+		return dto.WalletTransferResponse{}, err
+	}
+
+	fromWallet, err := wallet_serv.getAccessibleWallet(ctx, access, req.FromWalletID)
+	if err != nil {
+		return dto.WalletTransferResponse{}, err
+	}
+
+	toWallet, err := wallet_serv.getAccessibleWallet(ctx, access, req.ToWalletID)
+	if err != nil {
+		return dto.WalletTransferResponse{}, err
+	}
+
+	if fromWallet.UserID != toWallet.UserID {
+		return dto.WalletTransferResponse{}, fmt.Errorf("%w: wallets must belong to the same user", ErrInvalidTransfer)
+	}
+
+	if fromWallet.Currency != toWallet.Currency {
+		return dto.WalletTransferResponse{}, fmt.Errorf("%w: wallets must hold the same currency, got %s and %s", ErrInvalidTransfer, fromWallet.Currency, toWallet.Currency)
+	}
+
+	if !req.Amount.IsRounded(fromWallet.Currency) || !req.AdminFee.IsRounded(fromWallet.Currency) {
+		return dto.WalletTransferResponse{}, fmt.Errorf("%w: amounts are finer than the minor unit of %s", ErrInvalidTransfer, fromWallet.Currency)
+	}
+
+	tx, err := wallet_serv.txManager.Begin(ctx)
+	if err != nil {
+		return dto.WalletTransferResponse{}, fmt.Errorf("transfer wallet: begin transaction: %w", err)
+	}
+
+	transfer := new(tpb.FundTransferResponse)
+	transferUnknown := false
+
+	defer func() {
+		tx.Rollback()
+		if err == nil {
+			return
+		}
+
+		if transferUnknown {
+			// Tanpa id transaksi tidak ada yang bisa dibatalkan; operator yang memutuskan
+			log.Error(data.LogTransferUnknownOutcome, map[string]any{
+				"service":        data.WalletService,
+				"from_wallet_id": req.FromWalletID,
+				"to_wallet_id":   req.ToWalletID,
+				"amount":         req.Amount,
+				"error":          err.Error(),
+			})
+			wallet_serv.reviewTransfer(ctx, fromWallet.UserID, req, nil, model.TransferReviewUnknownOutcome, err)
+			return
+		}
+
+		if transfer != nil && transfer.GetCashOutTransactionId() != "" {
+			// Kompensasi tetap dijalankan walaupun request sudah dibatalkan oleh client
+			cancelCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), data.SAGA_COMPENSATION_TIMEOUT)
+			defer cancel()
+
+			// GRPC call
+			if cancelErr := wallet_serv.transactionClient.CancelFundTransfer(cancelCtx, transfer); cancelErr != nil {
+				log.Error(data.LogTransferCompensationFailed, map[string]any{
+					"service":                 data.WalletService,
+					"cash_out_transaction_id": transfer.GetCashOutTransactionId(),
+					"cash_in_transaction_id":  transfer.GetCashInTransactionId(),
+					"error":                   cancelErr.Error(),
+				})
+				wallet_serv.reviewTransfer(ctx, fromWallet.UserID, req, transfer, model.TransferReviewCompensationFailed, cancelErr)
+			}
+		}
+	}()
+
+	// Lock kedua wallet agar saldo tidak berubah selama transfer berlangsung
+	locked, err := wallet_serv.walletsRepository.LockWallets(ctx, tx, req.FromWalletID, req.ToWalletID)
+	if err != nil {
+		return dto.WalletTransferResponse{}, fmt.Errorf("transfer wallet: lock wallets: %w", err)
+	}
+	if len(locked) != 2 {
+		err = fmt.Errorf("%w: transfer wallets are no longer available", repository.ErrWalletNotFound)
+		return dto.WalletTransferResponse{}, err
+	}
+	// Saldo dan versi untuk response diambil dari baris yang sudah di-lock, bukan dari pembacaan awal
+	var lockedFrom, lockedTo model.Wallets
+	for _, wallet := range locked {
+		switch wallet.ID {
+		case fromWallet.ID:
+			lockedFrom = wallet
+		case toWallet.ID:
+			lockedTo = wallet
+		}
+	}
+	if lockedFrom.Balance < req.Amount+req.AdminFee {
+		err = fmt.Errorf("%w [id=%s]: have %s, need %s", ErrInsufficientBalance, req.FromWalletID, lockedFrom.Balance, req.Amount+req.AdminFee)
+		return dto.WalletTransferResponse{}, err
+	}
+	if lockedFrom, err = wallet_serv.withWalletType(ctx, lockedFrom, fromWallet); err != nil {
+		return dto.WalletTransferResponse{}, fmt.Errorf("transfer wallet: %w", err)
+	}
+	if lockedTo, err = wallet_serv.withWalletType(ctx, lockedTo, toWallet); err != nil {
+		return dto.WalletTransferResponse{}, fmt.Errorf("transfer wallet: %w", err)
+	}
+
+	// GRPC call
+	transfer, err = wallet_serv.transactionClient.CreateFundTransfer(ctx, &tpb.CreateFundTransferRequest{
+		UserId:            fromWallet.UserID.String(),
+		FromWalletId:      req.FromWalletID,
+		ToWalletId:        req.ToWalletID,
+		Amount:            req.Amount.Float64(),
+		AdminFee:          req.AdminFee.Float64(),
+		CashOutCategoryId: req.CashOutCategoryID,
+		CashInCategoryId:  req.CashInCategoryID,
+		TransactionDate:   req.TransactionDate,
+		Description:       req.Description,
+	})
+	if err != nil {
+		transferUnknown = depositOutcomeUnknown(err)
+		log.Warn(data.LogTransferGRPCFailedRollback, map[string]any{
+			"service":        data.WalletService,
+			"from_wallet_id": req.FromWalletID,
+			"to_wallet_id":   req.ToWalletID,
+			"amount":         req.Amount,
+			"error":          err.Error(),
+		})
+		return dto.WalletTransferResponse{}, fmt.Errorf("transfer wallet: create fund transfer via grpc: %w", err)
+	}
+
+	entries := []struct {
+		wallet *model.Wallets
+		delta  money.Amount
+		reason string
+		source string
+	}{
+		{&lockedFrom, -req.Amount, data.BALANCE_REASON_TRANSFER_OUT, transfer.GetCashOutTransactionId()},
+		{&lockedFrom, -req.AdminFee, data.BALANCE_REASON_TRANSFER_FEE, transfer.GetCashOutTransactionId() + data.TRANSFER_FEE_SOURCE_SUFFIX},
+		{&lockedTo, req.Amount, data.BALANCE_REASON_TRANSFER_IN, transfer.GetCashInTransactionId()},
+	}
+	for _, e := range entries {
+		entry, ok := newBalanceEntry(e.wallet.ID, e.delta, e.reason, e.source)
+		if !ok {
+			continue
+		}
+
+		entry, err = wallet_serv.walletsRepository.AppendBalanceEntry(ctx, tx, entry)
+		if err != nil {
+			return dto.WalletTransferResponse{}, fmt.Errorf("transfer wallet: append balance entry: %w", err)
+		}
+		e.wallet.Balance = entry.BalanceAfter
+		e.wallet.Version = entry.WalletVersion
+	}
+
+	transferResponse := dto.WalletTransferResponse{
+		CashOutTransactionID: transfer.GetCashOutTransactionId(),
+		CashInTransactionID:  transfer.GetCashInTransactionId(),
+		FromWallet:           utils.ConvertToResponseType(lockedFrom).(dto.WalletsResponse),
+		ToWallet:             utils.ConvertToResponseType(lockedTo).(dto.WalletsResponse),
+		Amount:               req.Amount,
+		AdminFee:             req.AdminFee,
+		TransactionDate:      req.TransactionDate,
+		Description:          req.Description,
+	}
+
+	// Satu event per wallet, agar event ini terurut bersama event lain dari kedua wallet
+	for _, aggregateID := range []string{req.FromWalletID, req.ToWalletID} {
+		if err = enqueueOutboxEvent(ctx, wallet_serv.outboxRepository, tx, data.OUTBOX_EVENT_WALLET_TRANSFER, aggregateID, transferResponse); err != nil {
+			return dto.WalletTransferResponse{}, fmt.Errorf("transfer wallet: save outbox message: %w", err)
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return dto.WalletTransferResponse{}, fmt.Errorf("transfer wallet: commit transaction: %w", err)
+	}
+
+	return transferResponse, nil
+}
+
+// reviewTransfer records a transfer whose fund transfer may exist without the matching ledger
+// entries, so an operator can cancel it or book it by hand.
+func (wallet_serv *walletsService) reviewTransfer(ctx context.Context, userID uuid.UUID, req dto.WalletTransferRequest, transfer *tpb.FundTransferResponse, reason model.TransferReviewReason, cause error) {
+	// Id wallet sudah divalidasi oleh validateTransferRequest
+	fromWalletID, _ := uuid.Parse(req.FromWalletID)
+	toWalletID, _ := uuid.Parse(req.ToWalletID)
+
+	review := &model.WalletTransferReview{
+		UserID:       userID,
+		FromWalletID: fromWalletID,
+		ToWalletID:   toWalletID,
+		Amount:       req.Amount,
+		AdminFee:     req.AdminFee,
+		Reason:       reason,
+		LastError:    cause.Error(),
+	}
+	if transfer != nil {
+		cashOutID, cashInID := transfer.GetCashOutTransactionId(), transfer.GetCashInTransactionId()
+		review.CashOutTransactionID = &cashOutID
+		review.CashInTransactionID = &cashInID
+	}
+
+	if err := wallet_serv.sagaRepository.RecordTransferReview(context.WithoutCancel(ctx), review); err != nil {
+		log.Error(data.LogTransferReviewRecordFailed, map[string]any{
+			"service":        data.WalletService,
+			"from_wallet_id": req.FromWalletID,
+			"to_wallet_id":   req.ToWalletID,
+			"reason":         string(reason),
+			"error":          err.Error(),
+		})
+	}
+}
+
+func validateTransferRequest(req dto.WalletTransferRequest) error {
+	switch {
+	case req.FromWalletID == "" || req.ToWalletID == "":
+		return fmt.Errorf("%w: from_wallet_id and to_wallet_id are required", ErrInvalidTransfer)
+	case req.FromWalletID == req.ToWalletID:
+		return fmt.Errorf("%w: cannot transfer to the same wallet", ErrInvalidTransfer)
+	case req.Amount <= 0:
+		return fmt.Errorf("%w: amount must be positive", ErrInvalidTransfer)
+	case req.AdminFee < 0:
+		return fmt.Errorf("%w: admin fee must not be negative", ErrInvalidTransfer)
+	}
+
+	if _, err := utils.ParseUUID(req.FromWalletID); err != nil {
+		return fmt.Errorf("%w: from_wallet_id: %w", ErrInvalidTransfer, err)
+	}
+	if _, err := utils.ParseUUID(req.ToWalletID); err != nil {
+		return fmt.Errorf("%w: to_wallet_id: %w", ErrInvalidTransfer, err)
+	}
+
+	return nil
+}
+
+// newBalanceEntry builds the ledger entry for a signed balance change.
+// ok is false when the change is zero and nothing has to be recorded.
+func newBalanceEntry(walletID uuid.UUID, delta money.Amount, reason, sourceTransactionID string) (model.WalletBalanceEntries, bool) {
+	if delta == 0 {
+		return model.WalletBalanceEntries{}, false
+	}
+
+	entry := model.WalletBalanceEntries{
+		WalletID:  walletID,
+		Amount:    delta.Abs(),
+		Direction: model.Credit,
+		Reason:    reason,
+	}
+	if delta < 0 {
+		entry.Direction = model.Debit
+	}
+	if sourceTransactionID != "" {
+		entry.SourceTransactionID = &sourceTransactionID
+	}
+
+	return entry, true
+}
+
+// walletChangedFields lists the json names of the fields an update request actually changes.
+func walletChangedFields(existing model.Wallets, req dto.WalletsRequest, walletTypeID uuid.UUID) []string {
+	var changed []string
+
+	if existing.Name != req.Name {
+		changed = append(changed, "name")
+	}
+	if existing.Number != req.Number {
+		changed = append(changed, "number")
+	}
+	if existing.WalletTypeID != walletTypeID {
+		changed = append(changed, "wallet_type_id")
+	}
+	if existing.Balance != req.Balance {
+		changed = append(changed, "balance")
+	}
+
+	return changed
+}
+
+// currencyTotals accumulates wallet balances per currency
+type currencyTotals map[string]*dto.CurrencyTotal
+
+func (totals currencyTotals) add(currency string, balance money.Amount) {
+	if currency == "" {
+		currency = data.DEFAULT_CURRENCY
+	}
+
+	total, ok := totals[currency]
+	if !ok {
+		total = &dto.CurrencyTotal{Currency: currency}
+		totals[currency] = total
+	}
+	total.TotalWallets++
+	total.TotalBalance += balance
+}
+
+// convert values every currency total in base, rounded to the minor unit of base. It returns
+// the totals ordered by currency and their sum; a single missing rate fails the whole conversion.
+func (totals currencyTotals) convert(ctx context.Context, rates ExchangeRateProvider, base string) ([]dto.CurrencyTotal, money.Amount, error) {
+	result := make([]dto.CurrencyTotal, 0, len(totals))
+	var sum money.Amount
+
+	for _, total := range totals {
+		rate, err := rates.Rate(ctx, total.Currency, base)
+		if err != nil {
+			return nil, 0, fmt.Errorf("convert %s to %s: %w", total.Currency, base, err)
+		}
+
+		total.ExchangeRate = rate
+		total.ConvertedBalance = total.TotalBalance.MulRate(rate).Round(base)
+		sum += total.ConvertedBalance
+		result = append(result, *total)
+	}
+
+	slices.SortFunc(result, func(a, b dto.CurrencyTotal) int {
+		return strings.Compare(a.Currency, b.Currency)
+	})
+
+	return result, sum, nil
+}
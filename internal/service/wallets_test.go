@@ -2,19 +2,28 @@ package service
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
+	"fmt"
 	"testing"
 	"time"
 
+	"refina-wallet/internal/repository"
 	"refina-wallet/internal/service/mocks"
+	"refina-wallet/internal/types/domainerr"
 	"refina-wallet/internal/types/dto"
 	"refina-wallet/internal/types/model"
+	"refina-wallet/internal/types/money"
 	"refina-wallet/internal/types/view"
+	"refina-wallet/internal/utils"
 
 	tpb "github.com/MuhammadMiftaa/Refina-Protobuf/transaction"
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"gorm.io/gorm"
 )
 
 // ---------- helpers ----------
@@ -24,7 +33,9 @@ type walletTestDeps struct {
 	walletsRepo *mocks.MockWalletsRepository
 	typesRepo   *mocks.MockWalletTypesRepository
 	outboxRepo  *mocks.MockOutboxRepository
+	sagaRepo    *mocks.MockWalletSagaRepository
 	txClient    *mocks.MockTransactionClient
+	rates       ExchangeRateProvider
 	rabbitMQ    *mocks.MockRabbitMQClient
 	tx          *mocks.MockTransaction
 }
@@ -35,7 +46,9 @@ func newWalletTestDeps() *walletTestDeps {
 		walletsRepo: new(mocks.MockWalletsRepository),
 		typesRepo:   new(mocks.MockWalletTypesRepository),
 		outboxRepo:  new(mocks.MockOutboxRepository),
+		sagaRepo:    new(mocks.MockWalletSagaRepository),
 		txClient:    new(mocks.MockTransactionClient),
+		rates:       sampleExchangeRates(),
 		rabbitMQ:    new(mocks.MockRabbitMQClient),
 		tx:          new(mocks.MockTransaction),
 	}
@@ -47,7 +60,9 @@ func (d *walletTestDeps) service() WalletsService {
 		d.walletsRepo,
 		d.typesRepo,
 		d.outboxRepo,
+		d.sagaRepo,
 		d.txClient,
+		d.rates,
 		d.rabbitMQ,
 	)
 }
@@ -58,6 +73,7 @@ func (d *walletTestDeps) assertAll(t *testing.T) {
 	d.walletsRepo.AssertExpectations(t)
 	d.typesRepo.AssertExpectations(t)
 	d.outboxRepo.AssertExpectations(t)
+	d.sagaRepo.AssertExpectations(t)
 	d.txClient.AssertExpectations(t)
 	d.rabbitMQ.AssertExpectations(t)
 	d.tx.AssertExpectations(t)
@@ -84,18 +100,31 @@ func sampleWalletModel() model.Wallets {
 		WalletTypeID: walletTypeID,
 		Name:         "My BCA",
 		Number:       "1234567890",
-		Balance:      100000,
+		Balance:      money.MustParse("100000"),
+		Currency:     "IDR",
+		Version:      1,
 		WalletType:   sampleWalletType(),
 	}
 }
 
+// sampleExchangeRates quotes USD and EUR against the default IDR base
+func sampleExchangeRates() ExchangeRateProvider {
+	rates, _ := NewStaticExchangeRateProvider("IDR", map[string]float64{"USD": 16000, "EUR": 17500})
+	return rates
+}
+
+// ownerAccess acts as the owner of sampleWalletModel
+func ownerAccess() WalletAccess {
+	return OwnerAccess(userID.String())
+}
+
 func sampleWalletRequest() dto.WalletsRequest {
 	return dto.WalletsRequest{
 		UserID:       userID.String(),
 		WalletTypeID: walletTypeID.String(),
 		Name:         "My BCA",
 		Number:       "1234567890",
-		Balance:      100000,
+		Balance:      money.MustParse("100000"),
 	}
 }
 
@@ -159,7 +188,7 @@ func TestGetWalletByID_Success(t *testing.T) {
 	id := w.ID.String()
 	d.walletsRepo.On("GetWalletByID", mock.Anything, nil, id).Return(w, nil)
 
-	result, err := svc.GetWalletByID(context.Background(), id)
+	result, err := svc.GetWalletByID(context.Background(), ownerAccess(), id)
 
 	assert.NoError(t, err)
 	assert.Equal(t, id, result.ID)
@@ -176,7 +205,7 @@ func TestGetWalletByID_NotFound(t *testing.T) {
 	d.walletsRepo.On("GetWalletByID", mock.Anything, nil, id).
 		Return(model.Wallets{}, errors.New("record not found"))
 
-	result, err := svc.GetWalletByID(context.Background(), id)
+	result, err := svc.GetWalletByID(context.Background(), ownerAccess(), id)
 
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "get wallet")
@@ -184,6 +213,76 @@ func TestGetWalletByID_NotFound(t *testing.T) {
 	d.assertAll(t)
 }
 
+func TestGetWalletByID_ForeignWalletReportedAsNotFound(t *testing.T) {
+	d := newWalletTestDeps()
+	svc := d.service()
+
+	w := sampleWalletModel()
+	d.walletsRepo.On("GetWalletByID", mock.Anything, nil, w.ID.String()).Return(w, nil)
+
+	result, err := svc.GetWalletByID(context.Background(), OwnerAccess(uuid.New().String()), w.ID.String())
+
+	assert.ErrorIs(t, err, repository.ErrWalletNotFound)
+	assert.Equal(t, domainerr.NotFound, domainerr.KindOf(err))
+	assert.Empty(t, result.ID)
+	d.assertAll(t)
+}
+
+func TestGetWalletByID_MissingWalletIsNotFound(t *testing.T) {
+	d := newWalletTestDeps()
+	svc := d.service()
+
+	id := uuid.New().String()
+	d.walletsRepo.On("GetWalletByID", mock.Anything, nil, id).
+		Return(model.Wallets{}, fmt.Errorf("%w: %w", repository.ErrWalletNotFound, gorm.ErrRecordNotFound))
+
+	_, err := svc.GetWalletByID(context.Background(), ownerAccess(), id)
+
+	assert.ErrorIs(t, err, repository.ErrWalletNotFound)
+	assert.Equal(t, domainerr.NotFound, domainerr.KindOf(err))
+	d.assertAll(t)
+}
+
+func TestGetWalletByID_DatabaseErrorStaysInternal(t *testing.T) {
+	d := newWalletTestDeps()
+	svc := d.service()
+
+	id := uuid.New().String()
+	d.walletsRepo.On("GetWalletByID", mock.Anything, nil, id).
+		Return(model.Wallets{}, errors.New("connection refused"))
+
+	_, err := svc.GetWalletByID(context.Background(), ownerAccess(), id)
+
+	assert.Error(t, err)
+	assert.Equal(t, domainerr.Internal, domainerr.KindOf(err))
+	d.assertAll(t)
+}
+
+func TestGetWalletByID_AdminAccessReachesAnyWallet(t *testing.T) {
+	d := newWalletTestDeps()
+	svc := d.service()
+
+	w := sampleWalletModel()
+	d.walletsRepo.On("GetWalletByID", mock.Anything, nil, w.ID.String()).Return(w, nil)
+
+	result, err := svc.GetWalletByID(context.Background(), AdminAccess(), w.ID.String())
+
+	assert.NoError(t, err)
+	assert.Equal(t, w.ID.String(), result.ID)
+	d.assertAll(t)
+}
+
+func TestGetWalletByID_MissingCallerIdentity(t *testing.T) {
+	d := newWalletTestDeps()
+	svc := d.service()
+
+	result, err := svc.GetWalletByID(context.Background(), OwnerAccess(""), walletID.String())
+
+	assert.ErrorIs(t, err, ErrCallerUnidentified)
+	assert.Empty(t, result.ID)
+	d.walletsRepo.AssertNotCalled(t, "GetWalletByID", mock.Anything, mock.Anything, mock.Anything)
+}
+
 // =====================================================================
 // GetWalletsByUserID
 // =====================================================================
@@ -234,18 +333,36 @@ func TestGetWalletsByUserIDGroupByType_Success(t *testing.T) {
 			UserID: uid,
 			Type:   "bank",
 			Wallets: []view.ViewUserWalletsGroupByTypeDetailWallet{
-				{ID: walletID.String(), Name: "My BCA", Number: "1234567890", Balance: 100000},
+				{ID: walletID.String(), Name: "My BCA", Number: "1234567890", Balance: money.MustParse("100000"), Currency: "IDR"},
+				{ID: targetWalletID.String(), Name: "Jenius USD", Number: "0987654321", Balance: money.MustParse("12.5"), Currency: "USD"},
 			},
 		},
 	}
 	d.walletsRepo.On("GetWalletsByUserIDGroupByType", mock.Anything, nil, uid).Return(grouped, nil)
 
-	result, err := svc.GetWalletsByUserIDGroupByType(context.Background(), uid)
+	result, err := svc.GetWalletsByUserIDGroupByType(context.Background(), uid, "")
 
 	assert.NoError(t, err)
 	assert.Len(t, result, 1)
 	assert.Equal(t, "bank", result[0].Type)
-	assert.Len(t, result[0].Wallets, 1)
+	assert.Len(t, result[0].Wallets, 2)
+	assert.Equal(t, "IDR", result[0].BaseCurrency)
+	assert.Equal(t, money.MustParse("300000"), result[0].TotalBalance)
+	assert.Equal(t, []dto.CurrencyTotal{
+		{Currency: "IDR", TotalWallets: 1, TotalBalance: money.MustParse("100000"), ExchangeRate: 1, ConvertedBalance: money.MustParse("100000")},
+		{Currency: "USD", TotalWallets: 1, TotalBalance: money.MustParse("12.5"), ExchangeRate: 16000, ConvertedBalance: money.MustParse("200000")},
+	}, result[0].Currencies)
+	d.assertAll(t)
+}
+
+func TestGetWalletsByUserIDGroupByType_InvalidBaseCurrency(t *testing.T) {
+	d := newWalletTestDeps()
+	svc := d.service()
+
+	result, err := svc.GetWalletsByUserIDGroupByType(context.Background(), userID.String(), "rupiah")
+
+	assert.ErrorIs(t, err, utils.ErrInvalidCurrency)
+	assert.Nil(t, result)
 	d.assertAll(t)
 }
 
@@ -257,13 +374,103 @@ func TestGetWalletsByUserIDGroupByType_RepositoryError(t *testing.T) {
 	d.walletsRepo.On("GetWalletsByUserIDGroupByType", mock.Anything, nil, uid).
 		Return([]view.ViewUserWalletsGroupByType{}, errors.New("db error"))
 
-	result, err := svc.GetWalletsByUserIDGroupByType(context.Background(), uid)
+	result, err := svc.GetWalletsByUserIDGroupByType(context.Background(), uid, "")
 
 	assert.Error(t, err)
 	assert.Nil(t, result)
 	d.assertAll(t)
 }
 
+// =====================================================================
+// GetWalletSummary
+// =====================================================================
+
+func TestGetWalletSummary_ConvertsEveryCurrencyToBase(t *testing.T) {
+	d := newWalletTestDeps()
+	svc := d.service()
+
+	uid := userID.String()
+	idr, usd, eur := sampleWalletModel(), sampleWalletModel(), sampleWalletModel()
+	usd.Currency, usd.Balance = "USD", money.MustParse("100")
+	eur.Currency, eur.Balance = "EUR", money.MustParse("10.25")
+	d.walletsRepo.On("GetWalletsByUserID", mock.Anything, nil, uid).Return([]model.Wallets{idr, usd, eur, usd}, nil)
+
+	result, err := svc.GetWalletSummary(context.Background(), uid, "usd")
+
+	assert.NoError(t, err)
+	assert.Equal(t, "USD", result.BaseCurrency)
+	assert.Equal(t, 4, result.TotalWallets)
+	assert.Equal(t, []string{"EUR", "IDR", "USD"}, []string{result.Currencies[0].Currency, result.Currencies[1].Currency, result.Currencies[2].Currency})
+	assert.Equal(t, money.MustParse("200"), result.Currencies[2].TotalBalance)
+	assert.Equal(t, 2, result.Currencies[2].TotalWallets)
+	assert.Equal(t, money.MustParse("11.21"), result.Currencies[0].ConvertedBalance)
+	assert.Equal(t, money.MustParse("6.25"), result.Currencies[1].ConvertedBalance)
+	assert.Equal(t, money.MustParse("217.46"), result.TotalBalance)
+	d.assertAll(t)
+}
+
+func TestGetWalletSummary_RoundsToMinorUnitOfBase(t *testing.T) {
+	d := newWalletTestDeps()
+	d.rates, _ = NewStaticExchangeRateProvider("IDR", map[string]float64{"JPY": 107.5})
+	svc := d.service()
+
+	uid := userID.String()
+	d.walletsRepo.On("GetWalletsByUserID", mock.Anything, nil, uid).Return([]model.Wallets{sampleWalletModel()}, nil)
+
+	result, err := svc.GetWalletSummary(context.Background(), uid, "JPY")
+
+	assert.NoError(t, err)
+	// 100000 / 107.5 = 930.23 JPY, dibulatkan ke yen penuh
+	assert.Equal(t, money.MustParse("930"), result.TotalBalance)
+	d.assertAll(t)
+}
+
+func TestGetWalletSummary_DefaultsToProviderBase(t *testing.T) {
+	d := newWalletTestDeps()
+	svc := d.service()
+
+	uid := userID.String()
+	d.walletsRepo.On("GetWalletsByUserID", mock.Anything, nil, uid).Return([]model.Wallets{sampleWalletModel()}, nil)
+
+	result, err := svc.GetWalletSummary(context.Background(), uid, "")
+
+	assert.NoError(t, err)
+	assert.Equal(t, "IDR", result.BaseCurrency)
+	assert.Equal(t, money.MustParse("100000"), result.TotalBalance)
+	d.assertAll(t)
+}
+
+func TestGetWalletSummary_NoWallets(t *testing.T) {
+	d := newWalletTestDeps()
+	svc := d.service()
+
+	uid := userID.String()
+	d.walletsRepo.On("GetWalletsByUserID", mock.Anything, nil, uid).Return([]model.Wallets(nil), nil)
+
+	result, err := svc.GetWalletSummary(context.Background(), uid, "")
+
+	assert.NoError(t, err)
+	assert.Zero(t, result.TotalWallets)
+	assert.Zero(t, result.TotalBalance)
+	assert.Empty(t, result.Currencies)
+	d.assertAll(t)
+}
+
+func TestGetWalletSummary_MissingRate(t *testing.T) {
+	d := newWalletTestDeps()
+	svc := d.service()
+
+	uid := userID.String()
+	w := sampleWalletModel()
+	w.Currency = "SGD"
+	d.walletsRepo.On("GetWalletsByUserID", mock.Anything, nil, uid).Return([]model.Wallets{w}, nil)
+
+	_, err := svc.GetWalletSummary(context.Background(), uid, "")
+
+	assert.ErrorIs(t, err, ErrExchangeRateUnavailable)
+	d.assertAll(t)
+}
+
 // =====================================================================
 // CreateWallet
 // =====================================================================
@@ -277,12 +484,22 @@ func TestCreateWallet_Success(t *testing.T) {
 	w := sampleWalletModel()
 
 	d.typesRepo.On("GetWalletTypeByID", mock.Anything, nil, req.WalletTypeID).Return(wt, nil)
+	d.sagaRepo.On("Create", mock.Anything, mock.MatchedBy(func(saga *model.WalletCreationSaga) bool {
+		return saga.Status == model.SagaPending && saga.UserID == userID && saga.Amount == req.Balance
+	})).Return(nil)
 	d.txManager.On("Begin", mock.Anything).Return(d.tx, nil)
 	d.walletsRepo.On("CreateWallet", mock.Anything, d.tx, mock.Anything).Return(w, nil)
 	d.txClient.On("InitialDeposit", mock.Anything, mock.AnythingOfType("string"), req.Balance).
 		Return(&tpb.TransactionDetail{Id: "tx-123"}, nil)
+	d.sagaRepo.On("MarkDepositCreated", mock.Anything, mock.AnythingOfType("string"), "tx-123").Return(nil)
+	d.walletsRepo.On("AppendBalanceEntry", mock.Anything, d.tx, mock.MatchedBy(func(e model.WalletBalanceEntries) bool {
+		return e.Amount == req.Balance && e.Direction == model.Credit && e.Reason == "initial_deposit" &&
+			e.SourceTransactionID != nil && *e.SourceTransactionID == "tx-123"
+	})).Return(model.WalletBalanceEntries{BalanceAfter: req.Balance}, nil)
+	d.outboxRepo.On("NextSequence", mock.Anything, d.tx, mock.Anything).Return(int64(1), nil)
 	d.outboxRepo.On("Create", mock.Anything, d.tx, mock.Anything).Return(nil)
 	d.tx.On("Commit").Return(nil)
+	d.sagaRepo.On("MarkCommitted", mock.Anything, mock.AnythingOfType("string")).Return(nil)
 	d.tx.On("Rollback").Return(nil)
 
 	result, err := svc.CreateWallet(context.Background(), userID.String(), req)
@@ -293,6 +510,91 @@ func TestCreateWallet_Success(t *testing.T) {
 	d.assertAll(t)
 }
 
+func TestCreateWallet_DepositReturnsBumpedVersion(t *testing.T) {
+	d := newWalletTestDeps()
+	svc := d.service()
+
+	req := sampleWalletRequest()
+	w := sampleWalletModel()
+	w.Version = 1
+
+	d.typesRepo.On("GetWalletTypeByID", mock.Anything, nil, req.WalletTypeID).Return(sampleWalletType(), nil)
+	d.sagaRepo.On("Create", mock.Anything, mock.Anything).Return(nil)
+	d.txManager.On("Begin", mock.Anything).Return(d.tx, nil)
+	d.walletsRepo.On("CreateWallet", mock.Anything, d.tx, mock.Anything).Return(w, nil)
+	d.txClient.On("InitialDeposit", mock.Anything, mock.AnythingOfType("string"), req.Balance).
+		Return(&tpb.TransactionDetail{Id: "tx-123"}, nil)
+	d.sagaRepo.On("MarkDepositCreated", mock.Anything, mock.AnythingOfType("string"), "tx-123").Return(nil)
+	// the deposit entry bumps the version of the new row from 1 to 2
+	d.walletsRepo.On("AppendBalanceEntry", mock.Anything, d.tx, mock.Anything).
+		Return(model.WalletBalanceEntries{BalanceAfter: req.Balance, WalletVersion: 2}, nil)
+	d.outboxRepo.On("NextSequence", mock.Anything, d.tx, mock.Anything).Return(int64(1), nil)
+	d.outboxRepo.On("Create", mock.Anything, d.tx, mock.MatchedBy(func(msg *model.OutboxMessage) bool {
+		var event dto.CloudEvent
+		if err := json.Unmarshal(msg.Payload, &event); err != nil {
+			return false
+		}
+		var payload dto.WalletsResponse
+		if err := json.Unmarshal(event.Data, &payload); err != nil {
+			return false
+		}
+		return msg.EventType == "wallet.created" && payload.Version == 2 && payload.Balance == req.Balance
+	})).Return(nil)
+	d.tx.On("Commit").Return(nil)
+	d.sagaRepo.On("MarkCommitted", mock.Anything, mock.AnythingOfType("string")).Return(nil)
+	d.tx.On("Rollback").Return(nil)
+
+	result, err := svc.CreateWallet(context.Background(), userID.String(), req)
+
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), result.Version)
+	assert.Equal(t, req.Balance, result.Balance)
+	d.assertAll(t)
+}
+
+func TestCreateWallet_StoresRequestedCurrency(t *testing.T) {
+	d := newWalletTestDeps()
+	svc := d.service()
+
+	req := sampleWalletRequest()
+	req.Balance = 0
+	req.Currency = "usd"
+
+	d.typesRepo.On("GetWalletTypeByID", mock.Anything, nil, req.WalletTypeID).Return(sampleWalletType(), nil)
+	d.txManager.On("Begin", mock.Anything).Return(d.tx, nil)
+	d.walletsRepo.On("CreateWallet", mock.Anything, d.tx, mock.MatchedBy(func(w model.Wallets) bool {
+		return w.Currency == "USD"
+	})).Return(func() model.Wallets { w := sampleWalletModel(); w.Currency = "USD"; return w }(), nil)
+	d.outboxRepo.On("NextSequence", mock.Anything, d.tx, mock.Anything).Return(int64(1), nil)
+	d.outboxRepo.On("Create", mock.Anything, d.tx, mock.Anything).Return(nil)
+	d.tx.On("Commit").Return(nil)
+	d.tx.On("Rollback").Return(nil)
+
+	result, err := svc.CreateWalletGRPC(context.Background(), req)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "USD", result.Currency)
+	d.assertAll(t)
+}
+
+func TestCreateWallet_UnsupportedCurrency(t *testing.T) {
+	for name, currency := range map[string]string{"malformed": "US$", "no exchange rate": "SGD"} {
+		t.Run(name, func(t *testing.T) {
+			d := newWalletTestDeps()
+			svc := d.service()
+
+			req := sampleWalletRequest()
+			req.Currency = currency
+
+			result, err := svc.CreateWallet(context.Background(), userID.String(), req)
+
+			assert.ErrorIs(t, err, utils.ErrInvalidCurrency)
+			assert.Empty(t, result.ID)
+			d.assertAll(t)
+		})
+	}
+}
+
 func TestCreateWallet_InvalidUserID(t *testing.T) {
 	d := newWalletTestDeps()
 	svc := d.service()
@@ -303,6 +605,8 @@ func TestCreateWallet_InvalidUserID(t *testing.T) {
 
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "invalid user id")
+	assert.ErrorIs(t, err, ErrInvalidUserID)
+	assert.Equal(t, domainerr.InvalidArgument, domainerr.KindOf(err))
 	assert.Empty(t, result.ID)
 	d.assertAll(t)
 }
@@ -347,7 +651,9 @@ func TestCreateWallet_BeginTxError(t *testing.T) {
 	wt := sampleWalletType()
 
 	d.typesRepo.On("GetWalletTypeByID", mock.Anything, nil, req.WalletTypeID).Return(wt, nil)
+	d.sagaRepo.On("Create", mock.Anything, mock.Anything).Return(nil)
 	d.txManager.On("Begin", mock.Anything).Return(nil, errors.New("tx error"))
+	d.sagaRepo.On("MarkFailed", mock.Anything, mock.AnythingOfType("string")).Return(nil)
 
 	result, err := svc.CreateWallet(context.Background(), userID.String(), req)
 
@@ -365,10 +671,12 @@ func TestCreateWallet_InsertError(t *testing.T) {
 	wt := sampleWalletType()
 
 	d.typesRepo.On("GetWalletTypeByID", mock.Anything, nil, req.WalletTypeID).Return(wt, nil)
+	d.sagaRepo.On("Create", mock.Anything, mock.Anything).Return(nil)
 	d.txManager.On("Begin", mock.Anything).Return(d.tx, nil)
 	d.walletsRepo.On("CreateWallet", mock.Anything, d.tx, mock.Anything).
 		Return(model.Wallets{}, errors.New("insert failed"))
 	d.tx.On("Rollback").Return(nil)
+	d.sagaRepo.On("MarkFailed", mock.Anything, mock.AnythingOfType("string")).Return(nil)
 
 	result, err := svc.CreateWallet(context.Background(), userID.String(), req)
 
@@ -387,11 +695,13 @@ func TestCreateWallet_GRPCDepositError(t *testing.T) {
 	w := sampleWalletModel()
 
 	d.typesRepo.On("GetWalletTypeByID", mock.Anything, nil, req.WalletTypeID).Return(wt, nil)
+	d.sagaRepo.On("Create", mock.Anything, mock.Anything).Return(nil)
 	d.txManager.On("Begin", mock.Anything).Return(d.tx, nil)
 	d.walletsRepo.On("CreateWallet", mock.Anything, d.tx, mock.Anything).Return(w, nil)
 	d.txClient.On("InitialDeposit", mock.Anything, mock.AnythingOfType("string"), req.Balance).
-		Return(nil, errors.New("grpc error"))
+		Return(nil, status.Error(codes.InvalidArgument, "invalid amount"))
 	d.tx.On("Rollback").Return(nil)
+	d.sagaRepo.On("MarkFailed", mock.Anything, mock.AnythingOfType("string")).Return(nil)
 
 	result, err := svc.CreateWallet(context.Background(), userID.String(), req)
 
@@ -401,6 +711,33 @@ func TestCreateWallet_GRPCDepositError(t *testing.T) {
 	d.assertAll(t)
 }
 
+func TestCreateWallet_DepositTimeoutNeedsReview(t *testing.T) {
+	d := newWalletTestDeps()
+	svc := d.service()
+
+	req := sampleWalletRequest()
+	wt := sampleWalletType()
+	w := sampleWalletModel()
+
+	d.typesRepo.On("GetWalletTypeByID", mock.Anything, nil, req.WalletTypeID).Return(wt, nil)
+	d.sagaRepo.On("Create", mock.Anything, mock.Anything).Return(nil)
+	d.txManager.On("Begin", mock.Anything).Return(d.tx, nil)
+	d.walletsRepo.On("CreateWallet", mock.Anything, d.tx, mock.Anything).Return(w, nil)
+	d.txClient.On("InitialDeposit", mock.Anything, mock.AnythingOfType("string"), req.Balance).
+		Return(nil, status.Error(codes.DeadlineExceeded, "context deadline exceeded"))
+	d.tx.On("Rollback").Return(nil)
+	d.sagaRepo.On("MarkNeedsReview", mock.Anything, mock.AnythingOfType("string"), mock.AnythingOfType("string")).Return(nil)
+
+	result, err := svc.CreateWallet(context.Background(), userID.String(), req)
+
+	assert.Error(t, err)
+	assert.Empty(t, result.ID)
+	// Deposit mungkin sudah ada, jadi saga tidak boleh ditutup sebagai failed
+	d.sagaRepo.AssertNotCalled(t, "MarkFailed", mock.Anything, mock.Anything)
+	d.txClient.AssertNotCalled(t, "CancelInitialDeposit", mock.Anything, mock.Anything)
+	d.assertAll(t)
+}
+
 func TestCreateWallet_OutboxCreateError(t *testing.T) {
 	d := newWalletTestDeps()
 	svc := d.service()
@@ -410,21 +747,24 @@ func TestCreateWallet_OutboxCreateError(t *testing.T) {
 	w := sampleWalletModel()
 
 	d.typesRepo.On("GetWalletTypeByID", mock.Anything, nil, req.WalletTypeID).Return(wt, nil)
+	d.sagaRepo.On("Create", mock.Anything, mock.Anything).Return(nil)
 	d.txManager.On("Begin", mock.Anything).Return(d.tx, nil)
 	d.walletsRepo.On("CreateWallet", mock.Anything, d.tx, mock.Anything).Return(w, nil)
 	d.txClient.On("InitialDeposit", mock.Anything, mock.AnythingOfType("string"), req.Balance).
 		Return(&tpb.TransactionDetail{Id: "tx-123"}, nil)
+	d.sagaRepo.On("MarkDepositCreated", mock.Anything, mock.AnythingOfType("string"), "tx-123").Return(nil)
+	d.walletsRepo.On("AppendBalanceEntry", mock.Anything, d.tx, mock.Anything).Return(model.WalletBalanceEntries{BalanceAfter: req.Balance}, nil)
+	d.outboxRepo.On("NextSequence", mock.Anything, d.tx, mock.Anything).Return(int64(1), nil)
 	d.outboxRepo.On("Create", mock.Anything, d.tx, mock.Anything).Return(errors.New("outbox error"))
 	d.tx.On("Rollback").Return(nil)
-	// NOTE: CancelInitialDeposit is NOT called because the outbox create uses `:=`
-	// which shadows the outer `err`, so the defer sees err == nil.
+	d.txClient.On("CancelInitialDeposit", mock.Anything, "tx-123").Return(&tpb.TransactionDetail{Id: "tx-123"}, nil)
+	d.sagaRepo.On("MarkFailed", mock.Anything, mock.AnythingOfType("string")).Return(nil)
 
 	result, err := svc.CreateWallet(context.Background(), userID.String(), req)
 
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "save outbox message")
 	assert.Empty(t, result.ID)
-	d.txClient.AssertNotCalled(t, "CancelInitialDeposit")
 	d.assertAll(t)
 }
 
@@ -437,22 +777,74 @@ func TestCreateWallet_CommitError(t *testing.T) {
 	w := sampleWalletModel()
 
 	d.typesRepo.On("GetWalletTypeByID", mock.Anything, nil, req.WalletTypeID).Return(wt, nil)
+	d.sagaRepo.On("Create", mock.Anything, mock.Anything).Return(nil)
 	d.txManager.On("Begin", mock.Anything).Return(d.tx, nil)
 	d.walletsRepo.On("CreateWallet", mock.Anything, d.tx, mock.Anything).Return(w, nil)
 	d.txClient.On("InitialDeposit", mock.Anything, mock.AnythingOfType("string"), req.Balance).
 		Return(&tpb.TransactionDetail{Id: "tx-123"}, nil)
+	d.sagaRepo.On("MarkDepositCreated", mock.Anything, mock.AnythingOfType("string"), "tx-123").Return(nil)
+	d.walletsRepo.On("AppendBalanceEntry", mock.Anything, d.tx, mock.Anything).Return(model.WalletBalanceEntries{BalanceAfter: req.Balance}, nil)
+	d.outboxRepo.On("NextSequence", mock.Anything, d.tx, mock.Anything).Return(int64(1), nil)
 	d.outboxRepo.On("Create", mock.Anything, d.tx, mock.Anything).Return(nil)
 	d.tx.On("Commit").Return(errors.New("commit error"))
 	d.tx.On("Rollback").Return(nil)
-	// NOTE: CancelInitialDeposit is NOT called because commit uses `:=`
-	// which shadows the outer `err`, so the defer sees err == nil.
+	d.txClient.On("CancelInitialDeposit", mock.Anything, "tx-123").Return(&tpb.TransactionDetail{Id: "tx-123"}, nil)
+	d.sagaRepo.On("MarkFailed", mock.Anything, mock.AnythingOfType("string")).Return(nil)
 
 	result, err := svc.CreateWallet(context.Background(), userID.String(), req)
 
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "commit transaction")
 	assert.Empty(t, result.ID)
-	d.txClient.AssertNotCalled(t, "CancelInitialDeposit")
+	d.assertAll(t)
+}
+
+func TestCreateWallet_CompensationFailureSchedulesRetry(t *testing.T) {
+	d := newWalletTestDeps()
+	svc := d.service()
+
+	req := sampleWalletRequest()
+	wt := sampleWalletType()
+	w := sampleWalletModel()
+
+	d.typesRepo.On("GetWalletTypeByID", mock.Anything, nil, req.WalletTypeID).Return(wt, nil)
+	d.sagaRepo.On("Create", mock.Anything, mock.Anything).Return(nil)
+	d.txManager.On("Begin", mock.Anything).Return(d.tx, nil)
+	d.walletsRepo.On("CreateWallet", mock.Anything, d.tx, mock.Anything).Return(w, nil)
+	d.txClient.On("InitialDeposit", mock.Anything, mock.AnythingOfType("string"), req.Balance).
+		Return(&tpb.TransactionDetail{Id: "tx-123"}, nil)
+	d.sagaRepo.On("MarkDepositCreated", mock.Anything, mock.AnythingOfType("string"), "tx-123").Return(nil)
+	d.walletsRepo.On("AppendBalanceEntry", mock.Anything, d.tx, mock.Anything).Return(model.WalletBalanceEntries{BalanceAfter: req.Balance}, nil)
+	d.outboxRepo.On("NextSequence", mock.Anything, d.tx, mock.Anything).Return(int64(1), nil)
+	d.outboxRepo.On("Create", mock.Anything, d.tx, mock.Anything).Return(nil)
+	d.tx.On("Commit").Return(errors.New("commit error"))
+	d.tx.On("Rollback").Return(nil)
+	d.txClient.On("CancelInitialDeposit", mock.Anything, "tx-123").Return(nil, errors.New("transaction service unavailable"))
+	d.sagaRepo.On("ScheduleCompensation", mock.Anything, mock.AnythingOfType("string"), mock.Anything, "transaction service unavailable").Return(nil)
+
+	_, err := svc.CreateWallet(context.Background(), userID.String(), req)
+
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "commit transaction")
+	d.sagaRepo.AssertNotCalled(t, "MarkFailed", mock.Anything, mock.Anything)
+	d.assertAll(t)
+}
+
+func TestCreateWallet_SagaCreateError(t *testing.T) {
+	d := newWalletTestDeps()
+	svc := d.service()
+
+	req := sampleWalletRequest()
+
+	d.typesRepo.On("GetWalletTypeByID", mock.Anything, nil, req.WalletTypeID).Return(sampleWalletType(), nil)
+	d.sagaRepo.On("Create", mock.Anything, mock.Anything).Return(errors.New("db down"))
+
+	result, err := svc.CreateWallet(context.Background(), userID.String(), req)
+
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "save saga")
+	assert.Empty(t, result.ID)
+	d.txManager.AssertNotCalled(t, "Begin", mock.Anything)
 	d.assertAll(t)
 }
 
@@ -469,12 +861,19 @@ func TestCreateWalletGRPC_Success(t *testing.T) {
 	w := sampleWalletModel()
 
 	d.typesRepo.On("GetWalletTypeByID", mock.Anything, nil, req.WalletTypeID).Return(wt, nil)
+	d.sagaRepo.On("Create", mock.Anything, mock.MatchedBy(func(saga *model.WalletCreationSaga) bool {
+		return saga.Status == model.SagaPending && saga.UserID == userID && saga.Amount == req.Balance
+	})).Return(nil)
 	d.txManager.On("Begin", mock.Anything).Return(d.tx, nil)
 	d.walletsRepo.On("CreateWallet", mock.Anything, d.tx, mock.Anything).Return(w, nil)
 	d.txClient.On("InitialDeposit", mock.Anything, mock.AnythingOfType("string"), req.Balance).
 		Return(&tpb.TransactionDetail{Id: "tx-456"}, nil)
+	d.sagaRepo.On("MarkDepositCreated", mock.Anything, mock.AnythingOfType("string"), "tx-456").Return(nil)
+	d.walletsRepo.On("AppendBalanceEntry", mock.Anything, d.tx, mock.Anything).Return(model.WalletBalanceEntries{BalanceAfter: req.Balance}, nil)
+	d.outboxRepo.On("NextSequence", mock.Anything, d.tx, mock.Anything).Return(int64(1), nil)
 	d.outboxRepo.On("Create", mock.Anything, d.tx, mock.Anything).Return(nil)
 	d.tx.On("Commit").Return(nil)
+	d.sagaRepo.On("MarkCommitted", mock.Anything, mock.AnythingOfType("string")).Return(nil)
 	d.tx.On("Rollback").Return(nil)
 
 	result, err := svc.CreateWalletGRPC(context.Background(), req)
@@ -500,6 +899,7 @@ func TestCreateWalletGRPC_SuccessZeroBalance(t *testing.T) {
 	d.txManager.On("Begin", mock.Anything).Return(d.tx, nil)
 	d.walletsRepo.On("CreateWallet", mock.Anything, d.tx, mock.Anything).Return(w, nil)
 	// InitialDeposit should NOT be called when balance is 0
+	d.outboxRepo.On("NextSequence", mock.Anything, d.tx, mock.Anything).Return(int64(1), nil)
 	d.outboxRepo.On("Create", mock.Anything, d.tx, mock.Anything).Return(nil)
 	d.tx.On("Commit").Return(nil)
 	d.tx.On("Rollback").Return(nil)
@@ -507,8 +907,9 @@ func TestCreateWalletGRPC_SuccessZeroBalance(t *testing.T) {
 	result, err := svc.CreateWalletGRPC(context.Background(), req)
 
 	assert.NoError(t, err)
-	assert.Equal(t, float64(0), result.Balance)
+	assert.Equal(t, money.Amount(0), result.Balance)
 	d.txClient.AssertNotCalled(t, "InitialDeposit")
+	d.sagaRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
 	d.assertAll(t)
 }
 
@@ -567,7 +968,9 @@ func TestCreateWalletGRPC_BeginTxError(t *testing.T) {
 	wt := sampleWalletType()
 
 	d.typesRepo.On("GetWalletTypeByID", mock.Anything, nil, req.WalletTypeID).Return(wt, nil)
+	d.sagaRepo.On("Create", mock.Anything, mock.Anything).Return(nil)
 	d.txManager.On("Begin", mock.Anything).Return(nil, errors.New("tx error"))
+	d.sagaRepo.On("MarkFailed", mock.Anything, mock.AnythingOfType("string")).Return(nil)
 
 	result, err := svc.CreateWalletGRPC(context.Background(), req)
 
@@ -585,10 +988,12 @@ func TestCreateWalletGRPC_InsertError(t *testing.T) {
 	wt := sampleWalletType()
 
 	d.typesRepo.On("GetWalletTypeByID", mock.Anything, nil, req.WalletTypeID).Return(wt, nil)
+	d.sagaRepo.On("Create", mock.Anything, mock.Anything).Return(nil)
 	d.txManager.On("Begin", mock.Anything).Return(d.tx, nil)
 	d.walletsRepo.On("CreateWallet", mock.Anything, d.tx, mock.Anything).
 		Return(model.Wallets{}, errors.New("insert failed"))
 	d.tx.On("Rollback").Return(nil)
+	d.sagaRepo.On("MarkFailed", mock.Anything, mock.AnythingOfType("string")).Return(nil)
 
 	result, err := svc.CreateWalletGRPC(context.Background(), req)
 
@@ -607,11 +1012,13 @@ func TestCreateWalletGRPC_GRPCDepositError(t *testing.T) {
 	w := sampleWalletModel()
 
 	d.typesRepo.On("GetWalletTypeByID", mock.Anything, nil, req.WalletTypeID).Return(wt, nil)
+	d.sagaRepo.On("Create", mock.Anything, mock.Anything).Return(nil)
 	d.txManager.On("Begin", mock.Anything).Return(d.tx, nil)
 	d.walletsRepo.On("CreateWallet", mock.Anything, d.tx, mock.Anything).Return(w, nil)
 	d.txClient.On("InitialDeposit", mock.Anything, mock.AnythingOfType("string"), req.Balance).
-		Return(nil, errors.New("grpc error"))
+		Return(nil, status.Error(codes.InvalidArgument, "invalid amount"))
 	d.tx.On("Rollback").Return(nil)
+	d.sagaRepo.On("MarkFailed", mock.Anything, mock.AnythingOfType("string")).Return(nil)
 
 	result, err := svc.CreateWalletGRPC(context.Background(), req)
 
@@ -630,20 +1037,24 @@ func TestCreateWalletGRPC_OutboxCreateError(t *testing.T) {
 	w := sampleWalletModel()
 
 	d.typesRepo.On("GetWalletTypeByID", mock.Anything, nil, req.WalletTypeID).Return(wt, nil)
+	d.sagaRepo.On("Create", mock.Anything, mock.Anything).Return(nil)
 	d.txManager.On("Begin", mock.Anything).Return(d.tx, nil)
 	d.walletsRepo.On("CreateWallet", mock.Anything, d.tx, mock.Anything).Return(w, nil)
 	d.txClient.On("InitialDeposit", mock.Anything, mock.AnythingOfType("string"), req.Balance).
 		Return(&tpb.TransactionDetail{Id: "tx-789"}, nil)
+	d.sagaRepo.On("MarkDepositCreated", mock.Anything, mock.AnythingOfType("string"), "tx-789").Return(nil)
+	d.walletsRepo.On("AppendBalanceEntry", mock.Anything, d.tx, mock.Anything).Return(model.WalletBalanceEntries{BalanceAfter: req.Balance}, nil)
+	d.outboxRepo.On("NextSequence", mock.Anything, d.tx, mock.Anything).Return(int64(1), nil)
 	d.outboxRepo.On("Create", mock.Anything, d.tx, mock.Anything).Return(errors.New("outbox error"))
 	d.tx.On("Rollback").Return(nil)
-	// CancelInitialDeposit NOT called — `:=` shadows outer `err`
+	d.txClient.On("CancelInitialDeposit", mock.Anything, "tx-789").Return(&tpb.TransactionDetail{Id: "tx-789"}, nil)
+	d.sagaRepo.On("MarkFailed", mock.Anything, mock.AnythingOfType("string")).Return(nil)
 
 	result, err := svc.CreateWalletGRPC(context.Background(), req)
 
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "save outbox message")
 	assert.Empty(t, result.ID)
-	d.txClient.AssertNotCalled(t, "CancelInitialDeposit")
 	d.assertAll(t)
 }
 
@@ -656,21 +1067,25 @@ func TestCreateWalletGRPC_CommitError(t *testing.T) {
 	w := sampleWalletModel()
 
 	d.typesRepo.On("GetWalletTypeByID", mock.Anything, nil, req.WalletTypeID).Return(wt, nil)
+	d.sagaRepo.On("Create", mock.Anything, mock.Anything).Return(nil)
 	d.txManager.On("Begin", mock.Anything).Return(d.tx, nil)
 	d.walletsRepo.On("CreateWallet", mock.Anything, d.tx, mock.Anything).Return(w, nil)
 	d.txClient.On("InitialDeposit", mock.Anything, mock.AnythingOfType("string"), req.Balance).
 		Return(&tpb.TransactionDetail{Id: "tx-789"}, nil)
+	d.sagaRepo.On("MarkDepositCreated", mock.Anything, mock.AnythingOfType("string"), "tx-789").Return(nil)
+	d.walletsRepo.On("AppendBalanceEntry", mock.Anything, d.tx, mock.Anything).Return(model.WalletBalanceEntries{BalanceAfter: req.Balance}, nil)
+	d.outboxRepo.On("NextSequence", mock.Anything, d.tx, mock.Anything).Return(int64(1), nil)
 	d.outboxRepo.On("Create", mock.Anything, d.tx, mock.Anything).Return(nil)
 	d.tx.On("Commit").Return(errors.New("commit failed"))
 	d.tx.On("Rollback").Return(nil)
-	// CancelInitialDeposit NOT called — `:=` shadows outer `err`
+	d.txClient.On("CancelInitialDeposit", mock.Anything, "tx-789").Return(&tpb.TransactionDetail{Id: "tx-789"}, nil)
+	d.sagaRepo.On("MarkFailed", mock.Anything, mock.AnythingOfType("string")).Return(nil)
 
 	result, err := svc.CreateWalletGRPC(context.Background(), req)
 
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "commit transaction")
 	assert.Empty(t, result.ID)
-	d.txClient.AssertNotCalled(t, "CancelInitialDeposit")
 	d.assertAll(t)
 }
 
@@ -688,7 +1103,7 @@ func TestUpdateWallet_Success(t *testing.T) {
 		WalletTypeID: walletTypeID.String(),
 		Name:         "Updated BCA",
 		Number:       "9999999999",
-		Balance:      200000,
+		Balance:      money.MustParse("200000"),
 	}
 
 	updated := existing
@@ -698,12 +1113,17 @@ func TestUpdateWallet_Success(t *testing.T) {
 
 	d.walletsRepo.On("GetWalletByID", mock.Anything, nil, id).Return(existing, nil)
 	d.txManager.On("Begin", mock.Anything).Return(d.tx, nil)
+	d.walletsRepo.On("LockWallets", mock.Anything, d.tx, []string{id}).Return([]model.Wallets{existing}, nil)
+	d.walletsRepo.On("AppendBalanceEntry", mock.Anything, d.tx, mock.MatchedBy(func(e model.WalletBalanceEntries) bool {
+		return e.Amount == money.MustParse("100000") && e.Direction == model.Credit && e.Reason == "manual_adjustment" && e.SourceTransactionID == nil
+	})).Return(model.WalletBalanceEntries{BalanceAfter: req.Balance}, nil)
 	d.walletsRepo.On("UpdateWallet", mock.Anything, d.tx, mock.Anything).Return(updated, nil)
+	d.outboxRepo.On("NextSequence", mock.Anything, d.tx, mock.Anything).Return(int64(1), nil)
 	d.outboxRepo.On("Create", mock.Anything, d.tx, mock.Anything).Return(nil)
 	d.tx.On("Commit").Return(nil)
 	d.tx.On("Rollback").Return(nil)
 
-	result, err := svc.UpdateWallet(context.Background(), id, req)
+	result, err := svc.UpdateWallet(context.Background(), ownerAccess(), id, req, 0)
 
 	assert.NoError(t, err)
 	assert.Equal(t, req.Name, result.Name)
@@ -712,69 +1132,187 @@ func TestUpdateWallet_Success(t *testing.T) {
 	d.assertAll(t)
 }
 
-func TestUpdateWallet_NotFound(t *testing.T) {
+func TestUpdateWallet_EventCarriesPreviousSnapshotAndChangedFields(t *testing.T) {
 	d := newWalletTestDeps()
 	svc := d.service()
 
-	id := uuid.New().String()
+	existing := sampleWalletModel()
+	id := existing.ID.String()
 	req := sampleWalletRequest()
+	req.Name = "Updated BCA"
+	req.Balance = money.MustParse("250000")
 
-	d.walletsRepo.On("GetWalletByID", mock.Anything, nil, id).
-		Return(model.Wallets{}, errors.New("record not found"))
+	updated := existing
+	updated.Name = req.Name
+	updated.Balance = req.Balance
+
+	d.walletsRepo.On("GetWalletByID", mock.Anything, nil, id).Return(existing, nil)
+	d.txManager.On("Begin", mock.Anything).Return(d.tx, nil)
+	d.walletsRepo.On("LockWallets", mock.Anything, d.tx, []string{id}).Return([]model.Wallets{existing}, nil)
+	d.walletsRepo.On("AppendBalanceEntry", mock.Anything, d.tx, mock.Anything).Return(model.WalletBalanceEntries{BalanceAfter: req.Balance}, nil)
+	d.walletsRepo.On("UpdateWallet", mock.Anything, d.tx, mock.Anything).Return(updated, nil)
+	d.outboxRepo.On("NextSequence", mock.Anything, d.tx, mock.Anything).Return(int64(1), nil)
+	d.outboxRepo.On("Create", mock.Anything, d.tx, mock.MatchedBy(func(msg *model.OutboxMessage) bool {
+		var event dto.CloudEvent
+		if err := json.Unmarshal(msg.Payload, &event); err != nil {
+			return false
+		}
+		var payload dto.WalletUpdatedEvent
+		if err := json.Unmarshal(event.Data, &payload); err != nil {
+			return false
+		}
+		return msg.EventType == "wallet.updated" &&
+			payload.Name == "Updated BCA" &&
+			payload.Previous.Name == "My BCA" &&
+			payload.Previous.Balance == money.MustParse("100000") &&
+			assert.ObjectsAreEqual([]string{"name", "balance"}, payload.ChangedFields)
+	})).Return(nil)
+	d.tx.On("Commit").Return(nil)
+	d.tx.On("Rollback").Return(nil)
 
-	result, err := svc.UpdateWallet(context.Background(), id, req)
+	_, err := svc.UpdateWallet(context.Background(), ownerAccess(), id, req, 0)
 
-	assert.Error(t, err)
-	assert.Contains(t, err.Error(), "wallet not found")
-	assert.Empty(t, result.ID)
+	assert.NoError(t, err)
 	d.assertAll(t)
 }
 
-func TestUpdateWallet_InvalidWalletTypeID(t *testing.T) {
+func TestUpdateWallet_NoChangesSkipsWriteAndEvent(t *testing.T) {
 	d := newWalletTestDeps()
 	svc := d.service()
 
 	existing := sampleWalletModel()
 	id := existing.ID.String()
-	req := dto.WalletsRequest{
-		WalletTypeID: "not-valid",
-		Name:         "Updated",
-		Number:       "123",
-		Balance:      100,
-	}
 
 	d.walletsRepo.On("GetWalletByID", mock.Anything, nil, id).Return(existing, nil)
+	d.txManager.On("Begin", mock.Anything).Return(d.tx, nil)
+	d.walletsRepo.On("LockWallets", mock.Anything, d.tx, []string{id}).Return([]model.Wallets{existing}, nil)
+	d.tx.On("Rollback").Return(nil)
 
-	result, err := svc.UpdateWallet(context.Background(), id, req)
+	result, err := svc.UpdateWallet(context.Background(), ownerAccess(), id, sampleWalletRequest(), 0)
 
-	assert.Error(t, err)
-	assert.Contains(t, err.Error(), "invalid wallet type id")
-	assert.Empty(t, result.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, existing.Name, result.Name)
+	d.walletsRepo.AssertNotCalled(t, "UpdateWallet", mock.Anything, mock.Anything, mock.Anything)
+	d.outboxRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything, mock.Anything)
+	d.tx.AssertNotCalled(t, "Commit")
 	d.assertAll(t)
 }
 
-func TestUpdateWallet_BeginTxError(t *testing.T) {
+func TestUpdateWallet_WalletTypeChangeReloadsType(t *testing.T) {
 	d := newWalletTestDeps()
 	svc := d.service()
 
 	existing := sampleWalletModel()
 	id := existing.ID.String()
-	req := dto.WalletsRequest{
-		WalletTypeID: walletTypeID.String(),
-		Name:         "Updated",
-		Number:       "123",
-		Balance:      100,
-	}
+	newTypeID := uuid.New()
+	newType := model.WalletTypes{Base: model.Base{ID: newTypeID}, Name: "GoPay", Type: model.EWallet}
 
-	d.walletsRepo.On("GetWalletByID", mock.Anything, nil, id).Return(existing, nil)
-	d.txManager.On("Begin", mock.Anything).Return(nil, errors.New("tx error"))
+	req := sampleWalletRequest()
+	req.WalletTypeID = newTypeID.String()
 
-	result, err := svc.UpdateWallet(context.Background(), id, req)
+	updated := existing
+	updated.WalletTypeID = newTypeID
+	updated.WalletType = newType
 
-	assert.Error(t, err)
-	assert.Contains(t, err.Error(), "begin transaction")
-	assert.Empty(t, result.ID)
-	d.assertAll(t)
+	d.walletsRepo.On("GetWalletByID", mock.Anything, nil, id).Return(existing, nil)
+	d.typesRepo.On("GetWalletTypeByID", mock.Anything, nil, newTypeID.String()).Return(newType, nil)
+	d.txManager.On("Begin", mock.Anything).Return(d.tx, nil)
+	d.walletsRepo.On("LockWallets", mock.Anything, d.tx, []string{id}).Return([]model.Wallets{existing}, nil)
+	d.walletsRepo.On("UpdateWallet", mock.Anything, d.tx, mock.MatchedBy(func(w model.Wallets) bool {
+		return w.WalletTypeID == newTypeID && w.WalletType.Name == "GoPay"
+	})).Return(updated, nil)
+	d.outboxRepo.On("NextSequence", mock.Anything, d.tx, mock.Anything).Return(int64(1), nil)
+	d.outboxRepo.On("Create", mock.Anything, d.tx, mock.Anything).Return(nil)
+	d.tx.On("Commit").Return(nil)
+	d.tx.On("Rollback").Return(nil)
+
+	result, err := svc.UpdateWallet(context.Background(), ownerAccess(), id, req, 0)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "GoPay", result.WalletTypeName)
+	d.assertAll(t)
+}
+
+func TestUpdateWallet_NotFound(t *testing.T) {
+	d := newWalletTestDeps()
+	svc := d.service()
+
+	id := uuid.New().String()
+	req := sampleWalletRequest()
+
+	d.walletsRepo.On("GetWalletByID", mock.Anything, nil, id).
+		Return(model.Wallets{}, errors.New("record not found"))
+
+	result, err := svc.UpdateWallet(context.Background(), ownerAccess(), id, req, 0)
+
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "wallet not found")
+	assert.Empty(t, result.ID)
+	d.assertAll(t)
+}
+
+func TestUpdateWallet_ForeignWalletReportedAsNotFound(t *testing.T) {
+	d := newWalletTestDeps()
+	svc := d.service()
+
+	existing := sampleWalletModel()
+	req := sampleWalletRequest()
+	req.Name = "Hijacked"
+	d.walletsRepo.On("GetWalletByID", mock.Anything, nil, existing.ID.String()).Return(existing, nil)
+
+	result, err := svc.UpdateWallet(context.Background(), OwnerAccess(uuid.New().String()), existing.ID.String(), req, 0)
+
+	assert.ErrorIs(t, err, repository.ErrWalletNotFound)
+	assert.Empty(t, result.ID)
+	d.txManager.AssertNotCalled(t, "Begin", mock.Anything)
+	d.walletsRepo.AssertNotCalled(t, "UpdateWallet", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestUpdateWallet_InvalidWalletTypeID(t *testing.T) {
+	d := newWalletTestDeps()
+	svc := d.service()
+
+	existing := sampleWalletModel()
+	id := existing.ID.String()
+	req := dto.WalletsRequest{
+		WalletTypeID: "not-valid",
+		Name:         "Updated",
+		Number:       "123",
+		Balance:      money.MustParse("100"),
+	}
+
+	d.walletsRepo.On("GetWalletByID", mock.Anything, nil, id).Return(existing, nil)
+
+	result, err := svc.UpdateWallet(context.Background(), ownerAccess(), id, req, 0)
+
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid wallet type id")
+	assert.Empty(t, result.ID)
+	d.assertAll(t)
+}
+
+func TestUpdateWallet_BeginTxError(t *testing.T) {
+	d := newWalletTestDeps()
+	svc := d.service()
+
+	existing := sampleWalletModel()
+	id := existing.ID.String()
+	req := dto.WalletsRequest{
+		WalletTypeID: walletTypeID.String(),
+		Name:         "Updated",
+		Number:       "123",
+		Balance:      money.MustParse("100"),
+	}
+
+	d.walletsRepo.On("GetWalletByID", mock.Anything, nil, id).Return(existing, nil)
+	d.txManager.On("Begin", mock.Anything).Return(nil, errors.New("tx error"))
+
+	result, err := svc.UpdateWallet(context.Background(), ownerAccess(), id, req, 0)
+
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "begin transaction")
+	assert.Empty(t, result.ID)
+	d.assertAll(t)
 }
 
 func TestUpdateWallet_UpdateError(t *testing.T) {
@@ -787,20 +1325,22 @@ func TestUpdateWallet_UpdateError(t *testing.T) {
 		WalletTypeID: walletTypeID.String(),
 		Name:         "Updated",
 		Number:       "123",
-		Balance:      100,
+		Balance:      money.MustParse("100"),
 	}
 
 	d.walletsRepo.On("GetWalletByID", mock.Anything, nil, id).Return(existing, nil)
 	d.txManager.On("Begin", mock.Anything).Return(d.tx, nil)
+	d.walletsRepo.On("LockWallets", mock.Anything, d.tx, []string{id}).Return([]model.Wallets{existing}, nil)
 	d.walletsRepo.On("UpdateWallet", mock.Anything, d.tx, mock.Anything).
 		Return(model.Wallets{}, errors.New("update failed"))
 	d.tx.On("Rollback").Return(nil)
 
-	result, err := svc.UpdateWallet(context.Background(), id, req)
+	result, err := svc.UpdateWallet(context.Background(), ownerAccess(), id, req, 0)
 
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "update in db")
 	assert.Empty(t, result.ID)
+	d.walletsRepo.AssertNotCalled(t, "AppendBalanceEntry", mock.Anything, mock.Anything, mock.Anything)
 	d.assertAll(t)
 }
 
@@ -814,7 +1354,7 @@ func TestUpdateWallet_OutboxError(t *testing.T) {
 		WalletTypeID: walletTypeID.String(),
 		Name:         "Updated",
 		Number:       "123",
-		Balance:      100,
+		Balance:      money.MustParse("100"),
 	}
 
 	updated := existing
@@ -822,11 +1362,14 @@ func TestUpdateWallet_OutboxError(t *testing.T) {
 
 	d.walletsRepo.On("GetWalletByID", mock.Anything, nil, id).Return(existing, nil)
 	d.txManager.On("Begin", mock.Anything).Return(d.tx, nil)
+	d.walletsRepo.On("LockWallets", mock.Anything, d.tx, []string{id}).Return([]model.Wallets{existing}, nil)
+	d.walletsRepo.On("AppendBalanceEntry", mock.Anything, d.tx, mock.Anything).Return(model.WalletBalanceEntries{BalanceAfter: req.Balance}, nil)
 	d.walletsRepo.On("UpdateWallet", mock.Anything, d.tx, mock.Anything).Return(updated, nil)
+	d.outboxRepo.On("NextSequence", mock.Anything, d.tx, mock.Anything).Return(int64(1), nil)
 	d.outboxRepo.On("Create", mock.Anything, d.tx, mock.Anything).Return(errors.New("outbox error"))
 	d.tx.On("Rollback").Return(nil)
 
-	result, err := svc.UpdateWallet(context.Background(), id, req)
+	result, err := svc.UpdateWallet(context.Background(), ownerAccess(), id, req, 0)
 
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "save outbox message")
@@ -844,7 +1387,7 @@ func TestUpdateWallet_CommitError(t *testing.T) {
 		WalletTypeID: walletTypeID.String(),
 		Name:         "Updated",
 		Number:       "123",
-		Balance:      100,
+		Balance:      money.MustParse("100"),
 	}
 
 	updated := existing
@@ -852,12 +1395,15 @@ func TestUpdateWallet_CommitError(t *testing.T) {
 
 	d.walletsRepo.On("GetWalletByID", mock.Anything, nil, id).Return(existing, nil)
 	d.txManager.On("Begin", mock.Anything).Return(d.tx, nil)
+	d.walletsRepo.On("LockWallets", mock.Anything, d.tx, []string{id}).Return([]model.Wallets{existing}, nil)
+	d.walletsRepo.On("AppendBalanceEntry", mock.Anything, d.tx, mock.Anything).Return(model.WalletBalanceEntries{BalanceAfter: req.Balance}, nil)
 	d.walletsRepo.On("UpdateWallet", mock.Anything, d.tx, mock.Anything).Return(updated, nil)
+	d.outboxRepo.On("NextSequence", mock.Anything, d.tx, mock.Anything).Return(int64(1), nil)
 	d.outboxRepo.On("Create", mock.Anything, d.tx, mock.Anything).Return(nil)
 	d.tx.On("Commit").Return(errors.New("commit error"))
 	d.tx.On("Rollback").Return(nil)
 
-	result, err := svc.UpdateWallet(context.Background(), id, req)
+	result, err := svc.UpdateWallet(context.Background(), ownerAccess(), id, req, 0)
 
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "commit transaction")
@@ -879,12 +1425,14 @@ func TestDeleteWallet_Success(t *testing.T) {
 
 	d.walletsRepo.On("GetWalletByID", mock.Anything, nil, id).Return(existing, nil)
 	d.txManager.On("Begin", mock.Anything).Return(d.tx, nil)
+	d.walletsRepo.On("LockWallets", mock.Anything, d.tx, []string{id}).Return([]model.Wallets{existing}, nil)
 	d.walletsRepo.On("DeleteWallet", mock.Anything, d.tx, existing).Return(existing, nil)
+	d.outboxRepo.On("NextSequence", mock.Anything, d.tx, mock.Anything).Return(int64(1), nil)
 	d.outboxRepo.On("Create", mock.Anything, d.tx, mock.Anything).Return(nil)
 	d.tx.On("Commit").Return(nil)
 	d.tx.On("Rollback").Return(nil)
 
-	result, err := svc.DeleteWallet(context.Background(), id)
+	result, err := svc.DeleteWallet(context.Background(), ownerAccess(), id, 0)
 
 	assert.NoError(t, err)
 	assert.Equal(t, id, result.ID)
@@ -900,7 +1448,7 @@ func TestDeleteWallet_NotFound(t *testing.T) {
 	d.walletsRepo.On("GetWalletByID", mock.Anything, nil, id).
 		Return(model.Wallets{}, errors.New("record not found"))
 
-	result, err := svc.DeleteWallet(context.Background(), id)
+	result, err := svc.DeleteWallet(context.Background(), ownerAccess(), id, 0)
 
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "wallet not found")
@@ -908,20 +1456,41 @@ func TestDeleteWallet_NotFound(t *testing.T) {
 	d.assertAll(t)
 }
 
+func TestDeleteWallet_ForeignWalletReportedAsNotFound(t *testing.T) {
+	d := newWalletTestDeps()
+	svc := d.service()
+
+	existing := sampleWalletModel()
+	existing.Balance = 0
+	d.walletsRepo.On("GetWalletByID", mock.Anything, nil, existing.ID.String()).Return(existing, nil)
+
+	result, err := svc.DeleteWallet(context.Background(), OwnerAccess(uuid.New().String()), existing.ID.String(), 0)
+
+	assert.ErrorIs(t, err, repository.ErrWalletNotFound)
+	assert.Empty(t, result.ID)
+	d.txManager.AssertNotCalled(t, "Begin", mock.Anything)
+	d.walletsRepo.AssertNotCalled(t, "DeleteWallet", mock.Anything, mock.Anything, mock.Anything)
+}
+
 func TestDeleteWallet_BalanceNotZero(t *testing.T) {
 	d := newWalletTestDeps()
 	svc := d.service()
 
 	existing := sampleWalletModel()
-	existing.Balance = 50000 // not zero
+	existing.Balance = money.MustParse("50000") // not zero
 	id := existing.ID.String()
 
 	d.walletsRepo.On("GetWalletByID", mock.Anything, nil, id).Return(existing, nil)
+	d.txManager.On("Begin", mock.Anything).Return(d.tx, nil)
+	d.walletsRepo.On("LockWallets", mock.Anything, d.tx, []string{id}).Return([]model.Wallets{existing}, nil)
+	d.tx.On("Rollback").Return(nil)
 
-	result, err := svc.DeleteWallet(context.Background(), id)
+	result, err := svc.DeleteWallet(context.Background(), ownerAccess(), id, 0)
 
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "wallet balance must be zero")
+	assert.ErrorIs(t, err, ErrWalletBalanceNotZero)
+	assert.Equal(t, domainerr.FailedPrecondition, domainerr.KindOf(err))
 	assert.Empty(t, result.ID)
 	d.assertAll(t)
 }
@@ -937,7 +1506,7 @@ func TestDeleteWallet_BeginTxError(t *testing.T) {
 	d.walletsRepo.On("GetWalletByID", mock.Anything, nil, id).Return(existing, nil)
 	d.txManager.On("Begin", mock.Anything).Return(nil, errors.New("tx error"))
 
-	result, err := svc.DeleteWallet(context.Background(), id)
+	result, err := svc.DeleteWallet(context.Background(), ownerAccess(), id, 0)
 
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "begin transaction")
@@ -955,11 +1524,12 @@ func TestDeleteWallet_DeleteError(t *testing.T) {
 
 	d.walletsRepo.On("GetWalletByID", mock.Anything, nil, id).Return(existing, nil)
 	d.txManager.On("Begin", mock.Anything).Return(d.tx, nil)
+	d.walletsRepo.On("LockWallets", mock.Anything, d.tx, []string{id}).Return([]model.Wallets{existing}, nil)
 	d.walletsRepo.On("DeleteWallet", mock.Anything, d.tx, existing).
 		Return(model.Wallets{}, errors.New("delete failed"))
 	d.tx.On("Rollback").Return(nil)
 
-	result, err := svc.DeleteWallet(context.Background(), id)
+	result, err := svc.DeleteWallet(context.Background(), ownerAccess(), id, 0)
 
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "delete from db")
@@ -977,11 +1547,13 @@ func TestDeleteWallet_OutboxError(t *testing.T) {
 
 	d.walletsRepo.On("GetWalletByID", mock.Anything, nil, id).Return(existing, nil)
 	d.txManager.On("Begin", mock.Anything).Return(d.tx, nil)
+	d.walletsRepo.On("LockWallets", mock.Anything, d.tx, []string{id}).Return([]model.Wallets{existing}, nil)
 	d.walletsRepo.On("DeleteWallet", mock.Anything, d.tx, existing).Return(existing, nil)
+	d.outboxRepo.On("NextSequence", mock.Anything, d.tx, mock.Anything).Return(int64(1), nil)
 	d.outboxRepo.On("Create", mock.Anything, d.tx, mock.Anything).Return(errors.New("outbox error"))
 	d.tx.On("Rollback").Return(nil)
 
-	result, err := svc.DeleteWallet(context.Background(), id)
+	result, err := svc.DeleteWallet(context.Background(), ownerAccess(), id, 0)
 
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "save outbox message")
@@ -999,15 +1571,775 @@ func TestDeleteWallet_CommitError(t *testing.T) {
 
 	d.walletsRepo.On("GetWalletByID", mock.Anything, nil, id).Return(existing, nil)
 	d.txManager.On("Begin", mock.Anything).Return(d.tx, nil)
+	d.walletsRepo.On("LockWallets", mock.Anything, d.tx, []string{id}).Return([]model.Wallets{existing}, nil)
 	d.walletsRepo.On("DeleteWallet", mock.Anything, d.tx, existing).Return(existing, nil)
+	d.outboxRepo.On("NextSequence", mock.Anything, d.tx, mock.Anything).Return(int64(1), nil)
 	d.outboxRepo.On("Create", mock.Anything, d.tx, mock.Anything).Return(nil)
 	d.tx.On("Commit").Return(errors.New("commit error"))
 	d.tx.On("Rollback").Return(nil)
 
-	result, err := svc.DeleteWallet(context.Background(), id)
+	result, err := svc.DeleteWallet(context.Background(), ownerAccess(), id, 0)
 
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "commit transaction")
 	assert.Empty(t, result.ID)
 	d.assertAll(t)
 }
+
+func TestDeleteWallet_StaleExpectedVersion(t *testing.T) {
+	d := newWalletTestDeps()
+	svc := d.service()
+
+	existing := sampleWalletModel()
+	existing.Balance = 0
+	existing.Version = 2
+	id := existing.ID.String()
+
+	d.walletsRepo.On("GetWalletByID", mock.Anything, nil, id).Return(existing, nil)
+	d.txManager.On("Begin", mock.Anything).Return(d.tx, nil)
+	d.walletsRepo.On("LockWallets", mock.Anything, d.tx, []string{id}).Return([]model.Wallets{existing}, nil)
+	d.tx.On("Rollback").Return(nil)
+
+	_, err := svc.DeleteWallet(context.Background(), ownerAccess(), id, 1)
+
+	assert.ErrorIs(t, err, repository.ErrWalletVersionConflict)
+	d.walletsRepo.AssertNotCalled(t, "DeleteWallet", mock.Anything, mock.Anything, mock.Anything)
+	d.assertAll(t)
+}
+
+func TestDeleteWallet_ConcurrentEditConflicts(t *testing.T) {
+	d := newWalletTestDeps()
+	svc := d.service()
+
+	existing := sampleWalletModel()
+	existing.Balance = 0
+	id := existing.ID.String()
+
+	d.walletsRepo.On("GetWalletByID", mock.Anything, nil, id).Return(existing, nil)
+	d.txManager.On("Begin", mock.Anything).Return(d.tx, nil)
+	d.walletsRepo.On("LockWallets", mock.Anything, d.tx, []string{id}).Return([]model.Wallets{existing}, nil)
+	d.walletsRepo.On("DeleteWallet", mock.Anything, d.tx, existing).Return(model.Wallets{}, repository.ErrWalletVersionConflict)
+	d.tx.On("Rollback").Return(nil)
+
+	_, err := svc.DeleteWallet(context.Background(), ownerAccess(), id, existing.Version)
+
+	assert.ErrorIs(t, err, repository.ErrWalletVersionConflict)
+	d.tx.AssertNotCalled(t, "Commit")
+	d.assertAll(t)
+}
+
+// =====================================================================
+// Balance ledger
+// =====================================================================
+
+func TestUpdateWallet_BalanceDecreaseAppendsDebitEntry(t *testing.T) {
+	d := newWalletTestDeps()
+	svc := d.service()
+
+	existing := sampleWalletModel()
+	id := existing.ID.String()
+	req := sampleWalletRequest()
+	req.Balance = money.MustParse("40000.5")
+
+	updated := existing
+	updated.Version++
+
+	d.walletsRepo.On("GetWalletByID", mock.Anything, nil, id).Return(existing, nil)
+	d.txManager.On("Begin", mock.Anything).Return(d.tx, nil)
+	d.walletsRepo.On("LockWallets", mock.Anything, d.tx, []string{id}).Return([]model.Wallets{existing}, nil)
+	d.walletsRepo.On("UpdateWallet", mock.Anything, d.tx, mock.Anything).Return(updated, nil)
+	d.walletsRepo.On("AppendBalanceEntry", mock.Anything, d.tx, mock.MatchedBy(func(e model.WalletBalanceEntries) bool {
+		return e.WalletID == walletID && e.Amount == money.MustParse("59999.5") && e.Direction == model.Debit && e.Reason == "manual_adjustment"
+	})).Return(model.WalletBalanceEntries{BalanceAfter: req.Balance}, nil)
+	d.outboxRepo.On("NextSequence", mock.Anything, d.tx, mock.Anything).Return(int64(1), nil)
+	d.outboxRepo.On("Create", mock.Anything, d.tx, mock.Anything).Return(nil)
+	d.tx.On("Commit").Return(nil)
+	d.tx.On("Rollback").Return(nil)
+
+	result, err := svc.UpdateWallet(context.Background(), ownerAccess(), id, req, 0)
+
+	assert.NoError(t, err)
+	assert.Equal(t, req.Balance, result.Balance)
+	// the ledger append bumps the version once more after the field update
+	assert.Equal(t, existing.Version+2, result.Version)
+	d.assertAll(t)
+}
+
+func TestUpdateWallet_AppendBalanceEntryError(t *testing.T) {
+	d := newWalletTestDeps()
+	svc := d.service()
+
+	existing := sampleWalletModel()
+	id := existing.ID.String()
+	req := sampleWalletRequest()
+	req.Balance = 0
+
+	d.walletsRepo.On("GetWalletByID", mock.Anything, nil, id).Return(existing, nil)
+	d.txManager.On("Begin", mock.Anything).Return(d.tx, nil)
+	d.walletsRepo.On("LockWallets", mock.Anything, d.tx, []string{id}).Return([]model.Wallets{existing}, nil)
+	d.walletsRepo.On("UpdateWallet", mock.Anything, d.tx, mock.Anything).Return(existing, nil)
+	d.walletsRepo.On("AppendBalanceEntry", mock.Anything, d.tx, mock.Anything).
+		Return(model.WalletBalanceEntries{}, errors.New("insert failed"))
+	d.tx.On("Rollback").Return(nil)
+
+	_, err := svc.UpdateWallet(context.Background(), ownerAccess(), id, req, 0)
+
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "append balance entry")
+	d.tx.AssertNotCalled(t, "Commit")
+	d.assertAll(t)
+}
+
+func TestUpdateWallet_CurrencyChangeRejected(t *testing.T) {
+	d := newWalletTestDeps()
+	svc := d.service()
+
+	existing := sampleWalletModel()
+	id := existing.ID.String()
+	req := sampleWalletRequest()
+	req.Currency = "USD"
+
+	d.walletsRepo.On("GetWalletByID", mock.Anything, nil, id).Return(existing, nil)
+
+	result, err := svc.UpdateWallet(context.Background(), ownerAccess(), id, req, 0)
+
+	assert.ErrorIs(t, err, utils.ErrInvalidCurrency)
+	assert.Empty(t, result.ID)
+	d.txManager.AssertNotCalled(t, "Begin", mock.Anything)
+	d.assertAll(t)
+}
+
+func TestUpdateWallet_StaleExpectedVersion(t *testing.T) {
+	d := newWalletTestDeps()
+	svc := d.service()
+
+	existing := sampleWalletModel()
+	existing.Version = 4
+	id := existing.ID.String()
+
+	d.walletsRepo.On("GetWalletByID", mock.Anything, nil, id).Return(existing, nil)
+	d.txManager.On("Begin", mock.Anything).Return(d.tx, nil)
+	d.walletsRepo.On("LockWallets", mock.Anything, d.tx, []string{id}).Return([]model.Wallets{existing}, nil)
+	d.tx.On("Rollback").Return(nil)
+
+	result, err := svc.UpdateWallet(context.Background(), ownerAccess(), id, sampleWalletRequest(), 3)
+
+	assert.ErrorIs(t, err, repository.ErrWalletVersionConflict)
+	assert.Empty(t, result.ID)
+	d.walletsRepo.AssertNotCalled(t, "UpdateWallet", mock.Anything, mock.Anything, mock.Anything)
+	d.assertAll(t)
+}
+
+func TestUpdateWallet_ConcurrentEditConflicts(t *testing.T) {
+	d := newWalletTestDeps()
+	svc := d.service()
+
+	existing := sampleWalletModel()
+	id := existing.ID.String()
+	req := sampleWalletRequest()
+	req.Name = "Renamed"
+
+	d.walletsRepo.On("GetWalletByID", mock.Anything, nil, id).Return(existing, nil)
+	d.txManager.On("Begin", mock.Anything).Return(d.tx, nil)
+	d.walletsRepo.On("LockWallets", mock.Anything, d.tx, []string{id}).Return([]model.Wallets{existing}, nil)
+	// Update bersyarat memakai versi baris yang dikunci
+	d.walletsRepo.On("UpdateWallet", mock.Anything, d.tx, mock.MatchedBy(func(w model.Wallets) bool {
+		return w.Version == existing.Version && w.Name == "Renamed"
+	})).Return(model.Wallets{}, repository.ErrWalletVersionConflict)
+	d.tx.On("Rollback").Return(nil)
+
+	_, err := svc.UpdateWallet(context.Background(), ownerAccess(), id, req, existing.Version)
+
+	assert.ErrorIs(t, err, repository.ErrWalletVersionConflict)
+	d.outboxRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything, mock.Anything)
+	d.tx.AssertNotCalled(t, "Commit")
+	d.assertAll(t)
+}
+
+func TestUpdateWallet_LedgerAppendedSinceReadUsesLockedBalance(t *testing.T) {
+	d := newWalletTestDeps()
+	svc := d.service()
+
+	existing := sampleWalletModel()
+	id := existing.ID.String()
+	req := sampleWalletRequest()
+	req.Balance = money.MustParse("200000")
+
+	// Transaksi lain menambah 30000 ke ledger setelah wallet dibaca dan sebelum baris dikunci
+	locked := existing
+	locked.Balance = money.MustParse("130000")
+	locked.Version = existing.Version + 1
+
+	updated := locked
+	updated.Version++
+
+	d.walletsRepo.On("GetWalletByID", mock.Anything, nil, id).Return(existing, nil)
+	d.txManager.On("Begin", mock.Anything).Return(d.tx, nil)
+	d.walletsRepo.On("LockWallets", mock.Anything, d.tx, []string{id}).Return([]model.Wallets{locked}, nil)
+	d.walletsRepo.On("UpdateWallet", mock.Anything, d.tx, mock.MatchedBy(func(w model.Wallets) bool {
+		return w.Version == locked.Version
+	})).Return(updated, nil)
+	d.walletsRepo.On("AppendBalanceEntry", mock.Anything, d.tx, mock.MatchedBy(func(e model.WalletBalanceEntries) bool {
+		return e.Amount == money.MustParse("70000") && e.Direction == model.Credit
+	})).Return(model.WalletBalanceEntries{BalanceAfter: req.Balance}, nil)
+	d.outboxRepo.On("NextSequence", mock.Anything, d.tx, mock.Anything).Return(int64(1), nil)
+	d.outboxRepo.On("Create", mock.Anything, d.tx, mock.MatchedBy(func(msg *model.OutboxMessage) bool {
+		var event dto.CloudEvent
+		if err := json.Unmarshal(msg.Payload, &event); err != nil {
+			return false
+		}
+		var payload dto.WalletUpdatedEvent
+		if err := json.Unmarshal(event.Data, &payload); err != nil {
+			return false
+		}
+		return payload.Previous.Balance == locked.Balance && payload.Previous.Version == locked.Version
+	})).Return(nil)
+	d.tx.On("Commit").Return(nil)
+	d.tx.On("Rollback").Return(nil)
+
+	result, err := svc.UpdateWallet(context.Background(), ownerAccess(), id, req, 0)
+
+	assert.NoError(t, err)
+	assert.Equal(t, req.Balance, result.Balance)
+	d.assertAll(t)
+}
+
+func TestUpdateWallet_LedgerAppendedSinceReadConflicts(t *testing.T) {
+	d := newWalletTestDeps()
+	svc := d.service()
+
+	existing := sampleWalletModel()
+	id := existing.ID.String()
+	req := sampleWalletRequest()
+	req.Name = "Renamed"
+
+	locked := existing
+	locked.Balance = money.MustParse("130000")
+	locked.Version = existing.Version + 1
+
+	d.walletsRepo.On("GetWalletByID", mock.Anything, nil, id).Return(existing, nil)
+	d.txManager.On("Begin", mock.Anything).Return(d.tx, nil)
+	d.walletsRepo.On("LockWallets", mock.Anything, d.tx, []string{id}).Return([]model.Wallets{locked}, nil)
+	d.tx.On("Rollback").Return(nil)
+
+	_, err := svc.UpdateWallet(context.Background(), ownerAccess(), id, req, existing.Version)
+
+	assert.ErrorIs(t, err, repository.ErrWalletVersionConflict)
+	d.walletsRepo.AssertNotCalled(t, "UpdateWallet", mock.Anything, mock.Anything, mock.Anything)
+	d.walletsRepo.AssertNotCalled(t, "AppendBalanceEntry", mock.Anything, mock.Anything, mock.Anything)
+	d.assertAll(t)
+}
+
+func TestUpdateWallet_WalletDeletedSinceRead(t *testing.T) {
+	d := newWalletTestDeps()
+	svc := d.service()
+
+	existing := sampleWalletModel()
+	id := existing.ID.String()
+	req := sampleWalletRequest()
+	req.Name = "Renamed"
+
+	d.walletsRepo.On("GetWalletByID", mock.Anything, nil, id).Return(existing, nil)
+	d.txManager.On("Begin", mock.Anything).Return(d.tx, nil)
+	d.walletsRepo.On("LockWallets", mock.Anything, d.tx, []string{id}).Return([]model.Wallets{}, nil)
+	d.tx.On("Rollback").Return(nil)
+
+	_, err := svc.UpdateWallet(context.Background(), ownerAccess(), id, req, 0)
+
+	assert.ErrorIs(t, err, repository.ErrWalletNotFound)
+	d.walletsRepo.AssertNotCalled(t, "UpdateWallet", mock.Anything, mock.Anything, mock.Anything)
+	d.assertAll(t)
+}
+
+func TestDeleteWallet_LedgerAppendedSinceRead(t *testing.T) {
+	d := newWalletTestDeps()
+	svc := d.service()
+
+	existing := sampleWalletModel()
+	existing.Balance = 0
+	id := existing.ID.String()
+
+	locked := existing
+	locked.Balance = money.MustParse("5000")
+	locked.Version = existing.Version + 1
+
+	d.walletsRepo.On("GetWalletByID", mock.Anything, nil, id).Return(existing, nil)
+	d.txManager.On("Begin", mock.Anything).Return(d.tx, nil)
+	d.walletsRepo.On("LockWallets", mock.Anything, d.tx, []string{id}).Return([]model.Wallets{locked}, nil)
+	d.tx.On("Rollback").Return(nil)
+
+	_, err := svc.DeleteWallet(context.Background(), ownerAccess(), id, 0)
+
+	assert.ErrorIs(t, err, ErrWalletBalanceNotZero)
+	d.walletsRepo.AssertNotCalled(t, "DeleteWallet", mock.Anything, mock.Anything, mock.Anything)
+	d.tx.AssertNotCalled(t, "Commit")
+	d.assertAll(t)
+}
+
+func TestGetWalletBalanceEntries_Success(t *testing.T) {
+	d := newWalletTestDeps()
+	svc := d.service()
+
+	sourceTransactionID := "tx-123"
+	entries := []model.WalletBalanceEntries{
+		{ID: 2, WalletID: walletID, Amount: money.MustParse("5000"), Direction: model.Debit, Reason: "manual_adjustment", BalanceAfter: money.MustParse("95000"), CreatedAt: fixedTime},
+		{ID: 1, WalletID: walletID, Amount: money.MustParse("100000"), Direction: model.Credit, SourceTransactionID: &sourceTransactionID, Reason: "initial_deposit", BalanceAfter: money.MustParse("100000"), CreatedAt: fixedTime},
+	}
+
+	d.walletsRepo.On("GetWalletByID", mock.Anything, nil, walletID.String()).Return(sampleWalletModel(), nil)
+	d.walletsRepo.On("GetBalanceEntries", mock.Anything, nil, walletID.String(), 50, 50).Return(entries, nil)
+
+	result, err := svc.GetWalletBalanceEntries(context.Background(), ownerAccess(), walletID.String(), 2)
+
+	assert.NoError(t, err)
+	assert.Len(t, result, 2)
+	assert.Equal(t, "debit", result[0].Direction)
+	assert.Equal(t, money.MustParse("95000"), result[0].BalanceAfter)
+	assert.Equal(t, "tx-123", result[1].SourceTransactionID)
+	d.assertAll(t)
+}
+
+func TestGetWalletBalanceEntries_WalletNotFound(t *testing.T) {
+	d := newWalletTestDeps()
+	svc := d.service()
+
+	id := uuid.New().String()
+	d.walletsRepo.On("GetWalletByID", mock.Anything, nil, id).
+		Return(model.Wallets{}, errors.New("record not found"))
+
+	result, err := svc.GetWalletBalanceEntries(context.Background(), ownerAccess(), id, 1)
+
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "wallet not found")
+	assert.Nil(t, result)
+	d.assertAll(t)
+}
+
+func TestGetWalletBalanceEntries_ForeignWalletReportedAsNotFound(t *testing.T) {
+	d := newWalletTestDeps()
+	svc := d.service()
+
+	d.walletsRepo.On("GetWalletByID", mock.Anything, nil, walletID.String()).Return(sampleWalletModel(), nil)
+
+	result, err := svc.GetWalletBalanceEntries(context.Background(), OwnerAccess(uuid.New().String()), walletID.String(), 1)
+
+	assert.ErrorIs(t, err, repository.ErrWalletNotFound)
+	assert.Nil(t, result)
+	d.walletsRepo.AssertNotCalled(t, "GetBalanceEntries", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestGetWalletBalanceAsOf_Success(t *testing.T) {
+	d := newWalletTestDeps()
+	svc := d.service()
+
+	asOf := fixedTime.Add(24 * time.Hour)
+	d.walletsRepo.On("GetWalletByID", mock.Anything, nil, walletID.String()).Return(sampleWalletModel(), nil)
+	d.walletsRepo.On("GetBalanceAsOf", mock.Anything, nil, walletID.String(), asOf).Return(money.MustParse("75000"), nil)
+
+	result, err := svc.GetWalletBalanceAsOf(context.Background(), ownerAccess(), walletID.String(), asOf)
+
+	assert.NoError(t, err)
+	assert.Equal(t, money.MustParse("75000"), result.Balance)
+	assert.Equal(t, asOf.Format(time.RFC3339), result.AsOf)
+	d.assertAll(t)
+}
+
+// =====================================================================
+// TransferBetweenWallets
+// =====================================================================
+
+var targetWalletID = uuid.MustParse("eeeeeeee-eeee-eeee-eeee-eeeeeeeeeeee")
+
+func sampleTransferRequest() dto.WalletTransferRequest {
+	return dto.WalletTransferRequest{
+		FromWalletID: walletID.String(),
+		ToWalletID:   targetWalletID.String(),
+		Amount:       money.MustParse("30000"),
+		AdminFee:     money.MustParse("2500"),
+		Description:  "Tarik tunai",
+	}
+}
+
+func sampleTargetWallet() model.Wallets {
+	w := sampleWalletModel()
+	w.ID = targetWalletID
+	w.Name = "GoPay"
+	w.Balance = money.MustParse("5000")
+	return w
+}
+
+func sampleFundTransfer() *tpb.FundTransferResponse {
+	return &tpb.FundTransferResponse{CashOutTransactionId: "tx-out", CashInTransactionId: "tx-in"}
+}
+
+func TestTransferBetweenWallets_Success(t *testing.T) {
+	d := newWalletTestDeps()
+	svc := d.service()
+
+	req := sampleTransferRequest()
+	from, to := sampleWalletModel(), sampleTargetWallet()
+
+	d.walletsRepo.On("GetWalletByID", mock.Anything, nil, req.FromWalletID).Return(from, nil)
+	d.walletsRepo.On("GetWalletByID", mock.Anything, nil, req.ToWalletID).Return(to, nil)
+	d.txManager.On("Begin", mock.Anything).Return(d.tx, nil)
+	d.walletsRepo.On("LockWallets", mock.Anything, d.tx, []string{req.FromWalletID, req.ToWalletID}).
+		Return([]model.Wallets{from, to}, nil)
+	d.txClient.On("CreateFundTransfer", mock.Anything, mock.MatchedBy(func(r *tpb.CreateFundTransferRequest) bool {
+		return r.UserId == userID.String() && r.Amount == 30000 && r.AdminFee == 2500
+	})).Return(sampleFundTransfer(), nil)
+	d.walletsRepo.On("AppendBalanceEntry", mock.Anything, d.tx, mock.MatchedBy(func(e model.WalletBalanceEntries) bool {
+		return e.WalletID == walletID && e.Direction == model.Debit && e.Amount == money.MustParse("30000") &&
+			e.Reason == "transfer_out" && *e.SourceTransactionID == "tx-out"
+	})).Return(model.WalletBalanceEntries{BalanceAfter: money.MustParse("70000")}, nil).Once()
+	d.walletsRepo.On("AppendBalanceEntry", mock.Anything, d.tx, mock.MatchedBy(func(e model.WalletBalanceEntries) bool {
+		return e.WalletID == walletID && e.Direction == model.Debit && e.Amount == money.MustParse("2500") &&
+			e.Reason == "transfer_fee" && *e.SourceTransactionID == "tx-out:admin_fee"
+	})).Return(model.WalletBalanceEntries{BalanceAfter: money.MustParse("67500")}, nil).Once()
+	d.walletsRepo.On("AppendBalanceEntry", mock.Anything, d.tx, mock.MatchedBy(func(e model.WalletBalanceEntries) bool {
+		return e.WalletID == targetWalletID && e.Direction == model.Credit && e.Amount == money.MustParse("30000") &&
+			e.Reason == "transfer_in" && *e.SourceTransactionID == "tx-in"
+	})).Return(model.WalletBalanceEntries{BalanceAfter: money.MustParse("35000")}, nil).Once()
+	d.outboxRepo.On("NextSequence", mock.Anything, d.tx, req.FromWalletID).Return(int64(4), nil).Once()
+	d.outboxRepo.On("NextSequence", mock.Anything, d.tx, req.ToWalletID).Return(int64(2), nil).Once()
+	d.outboxRepo.On("Create", mock.Anything, d.tx, mock.MatchedBy(func(msg *model.OutboxMessage) bool {
+		return msg.EventType == "wallet.transfer.completed" && msg.AggregateID == req.FromWalletID && msg.Sequence == 4
+	})).Return(nil).Once()
+	d.outboxRepo.On("Create", mock.Anything, d.tx, mock.MatchedBy(func(msg *model.OutboxMessage) bool {
+		return msg.EventType == "wallet.transfer.completed" && msg.AggregateID == req.ToWalletID && msg.Sequence == 2
+	})).Return(nil).Once()
+	d.tx.On("Commit").Return(nil)
+	d.tx.On("Rollback").Return(nil)
+
+	result, err := svc.TransferBetweenWallets(context.Background(), ownerAccess(), req)
+
+	assert.NoError(t, err)
+	assert.Equal(t, money.MustParse("67500"), result.FromWallet.Balance)
+	assert.Equal(t, money.MustParse("35000"), result.ToWallet.Balance)
+	assert.Equal(t, "tx-out", result.CashOutTransactionID)
+	d.txClient.AssertNotCalled(t, "CancelFundTransfer", mock.Anything, mock.Anything)
+	d.assertAll(t)
+}
+
+func TestTransferBetweenWallets_ResponseUsesLockedRows(t *testing.T) {
+	d := newWalletTestDeps()
+	svc := d.service()
+
+	req := sampleTransferRequest()
+	from, to := sampleWalletModel(), sampleTargetWallet()
+	from.Version, to.Version = 3, 1
+	// both wallets moved between the first read and the lock
+	lockedFrom, lockedTo := from, to
+	lockedFrom.Balance, lockedFrom.Version = money.MustParse("80000"), 4
+	lockedTo.Balance, lockedTo.Version = money.MustParse("6000"), 2
+
+	d.walletsRepo.On("GetWalletByID", mock.Anything, nil, req.FromWalletID).Return(from, nil)
+	d.walletsRepo.On("GetWalletByID", mock.Anything, nil, req.ToWalletID).Return(to, nil)
+	d.txManager.On("Begin", mock.Anything).Return(d.tx, nil)
+	d.walletsRepo.On("LockWallets", mock.Anything, d.tx, mock.Anything).Return([]model.Wallets{lockedTo, lockedFrom}, nil)
+	d.txClient.On("CreateFundTransfer", mock.Anything, mock.Anything).Return(sampleFundTransfer(), nil)
+	d.walletsRepo.On("AppendBalanceEntry", mock.Anything, d.tx, mock.MatchedBy(func(e model.WalletBalanceEntries) bool {
+		return e.Reason == "transfer_out"
+	})).Return(model.WalletBalanceEntries{BalanceAfter: money.MustParse("50000"), WalletVersion: 5}, nil).Once()
+	d.walletsRepo.On("AppendBalanceEntry", mock.Anything, d.tx, mock.MatchedBy(func(e model.WalletBalanceEntries) bool {
+		return e.Reason == "transfer_fee"
+	})).Return(model.WalletBalanceEntries{BalanceAfter: money.MustParse("47500"), WalletVersion: 6}, nil).Once()
+	d.walletsRepo.On("AppendBalanceEntry", mock.Anything, d.tx, mock.MatchedBy(func(e model.WalletBalanceEntries) bool {
+		return e.Reason == "transfer_in"
+	})).Return(model.WalletBalanceEntries{BalanceAfter: money.MustParse("36000"), WalletVersion: 3}, nil).Once()
+	d.outboxRepo.On("NextSequence", mock.Anything, d.tx, mock.Anything).Return(int64(1), nil)
+	d.outboxRepo.On("Create", mock.Anything, d.tx, mock.Anything).Return(nil)
+	d.tx.On("Commit").Return(nil)
+	d.tx.On("Rollback").Return(nil)
+
+	result, err := svc.TransferBetweenWallets(context.Background(), ownerAccess(), req)
+
+	assert.NoError(t, err)
+	assert.Equal(t, money.MustParse("47500"), result.FromWallet.Balance)
+	assert.Equal(t, int64(6), result.FromWallet.Version)
+	assert.Equal(t, money.MustParse("36000"), result.ToWallet.Balance)
+	assert.Equal(t, int64(3), result.ToWallet.Version)
+	assert.Equal(t, from.WalletType.Name, result.FromWallet.WalletTypeName)
+	d.assertAll(t)
+}
+
+func TestTransferBetweenWallets_InvalidRequest(t *testing.T) {
+	cases := map[string]func(r *dto.WalletTransferRequest){
+		"same wallet":     func(r *dto.WalletTransferRequest) { r.ToWalletID = r.FromWalletID },
+		"zero amount":     func(r *dto.WalletTransferRequest) { r.Amount = 0 },
+		"negative fee":    func(r *dto.WalletTransferRequest) { r.AdminFee = money.MustParse("-1") },
+		"invalid uuid":    func(r *dto.WalletTransferRequest) { r.ToWalletID = "nope" },
+		"missing from id": func(r *dto.WalletTransferRequest) { r.FromWalletID = "" },
+	}
+
+	for name, mutate := range cases {
+		t.Run(name, func(t *testing.T) {
+			d := newWalletTestDeps()
+			svc := d.service()
+
+			req := sampleTransferRequest()
+			mutate(&req)
+
+			_, err := svc.TransferBetweenWallets(context.Background(), ownerAccess(), req)
+
+			assert.Error(t, err)
+			assert.Contains(t, err.Error(), "invalid transfer")
+			assert.ErrorIs(t, err, ErrInvalidTransfer)
+			assert.Equal(t, domainerr.InvalidArgument, domainerr.KindOf(err))
+			d.assertAll(t)
+		})
+	}
+}
+
+func TestTransferBetweenWallets_DifferentOwners(t *testing.T) {
+	d := newWalletTestDeps()
+	svc := d.service()
+
+	req := sampleTransferRequest()
+	to := sampleTargetWallet()
+	to.UserID = uuid.New()
+
+	d.walletsRepo.On("GetWalletByID", mock.Anything, nil, req.FromWalletID).Return(sampleWalletModel(), nil)
+	d.walletsRepo.On("GetWalletByID", mock.Anything, nil, req.ToWalletID).Return(to, nil)
+
+	// even admin access cannot move money between two users
+	_, err := svc.TransferBetweenWallets(context.Background(), AdminAccess(), req)
+
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "same user")
+	d.txManager.AssertNotCalled(t, "Begin", mock.Anything)
+	d.assertAll(t)
+}
+
+func TestTransferBetweenWallets_ForeignWalletReportedAsNotFound(t *testing.T) {
+	d := newWalletTestDeps()
+	svc := d.service()
+
+	req := sampleTransferRequest()
+
+	d.walletsRepo.On("GetWalletByID", mock.Anything, nil, req.FromWalletID).Return(sampleWalletModel(), nil)
+
+	_, err := svc.TransferBetweenWallets(context.Background(), OwnerAccess(uuid.New().String()), req)
+
+	assert.ErrorIs(t, err, repository.ErrWalletNotFound)
+	assert.Equal(t, domainerr.NotFound, domainerr.KindOf(err))
+	d.txManager.AssertNotCalled(t, "Begin", mock.Anything)
+	d.assertAll(t)
+}
+
+func TestTransferBetweenWallets_AdminMovesAnotherUsersMoney(t *testing.T) {
+	d := newWalletTestDeps()
+	svc := d.service()
+
+	req := sampleTransferRequest()
+	req.AdminFee = 0
+	from, to := sampleWalletModel(), sampleTargetWallet()
+
+	d.walletsRepo.On("GetWalletByID", mock.Anything, nil, req.FromWalletID).Return(from, nil)
+	d.walletsRepo.On("GetWalletByID", mock.Anything, nil, req.ToWalletID).Return(to, nil)
+	d.txManager.On("Begin", mock.Anything).Return(d.tx, nil)
+	d.walletsRepo.On("LockWallets", mock.Anything, d.tx, mock.Anything).Return([]model.Wallets{from, to}, nil)
+	d.txClient.On("CreateFundTransfer", mock.Anything, mock.MatchedBy(func(r *tpb.CreateFundTransferRequest) bool {
+		// the transactions are booked for the wallet owner, not for the admin
+		return r.UserId == userID.String()
+	})).Return(sampleFundTransfer(), nil)
+	d.walletsRepo.On("AppendBalanceEntry", mock.Anything, d.tx, mock.Anything).Return(model.WalletBalanceEntries{}, nil).Twice()
+	d.outboxRepo.On("NextSequence", mock.Anything, d.tx, mock.Anything).Return(int64(1), nil)
+	d.outboxRepo.On("Create", mock.Anything, d.tx, mock.Anything).Return(nil)
+	d.tx.On("Commit").Return(nil)
+	d.tx.On("Rollback").Return(nil)
+
+	_, err := svc.TransferBetweenWallets(context.Background(), AdminAccess(), req)
+
+	assert.NoError(t, err)
+	d.assertAll(t)
+}
+
+func TestTransferBetweenWallets_CallerUnidentified(t *testing.T) {
+	d := newWalletTestDeps()
+	svc := d.service()
+
+	_, err := svc.TransferBetweenWallets(context.Background(), OwnerAccess(""), sampleTransferRequest())
+
+	assert.ErrorIs(t, err, ErrCallerUnidentified)
+	d.walletsRepo.AssertNotCalled(t, "GetWalletByID", mock.Anything, mock.Anything, mock.Anything)
+	d.assertAll(t)
+}
+
+func TestTransferBetweenWallets_DifferentCurrencies(t *testing.T) {
+	d := newWalletTestDeps()
+	svc := d.service()
+
+	req := sampleTransferRequest()
+	to := sampleTargetWallet()
+	to.Currency = "USD"
+
+	d.walletsRepo.On("GetWalletByID", mock.Anything, nil, req.FromWalletID).Return(sampleWalletModel(), nil)
+	d.walletsRepo.On("GetWalletByID", mock.Anything, nil, req.ToWalletID).Return(to, nil)
+
+	_, err := svc.TransferBetweenWallets(context.Background(), ownerAccess(), req)
+
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid transfer")
+	assert.Contains(t, err.Error(), "same currency")
+	d.txManager.AssertNotCalled(t, "Begin", mock.Anything)
+	d.assertAll(t)
+}
+
+func TestTransferBetweenWallets_AmountFinerThanCurrency(t *testing.T) {
+	d := newWalletTestDeps()
+	svc := d.service()
+
+	req := sampleTransferRequest()
+	req.Amount = money.MustParse("1500.50")
+	from, to := sampleWalletModel(), sampleTargetWallet()
+	from.Currency, to.Currency = "JPY", "JPY"
+
+	d.walletsRepo.On("GetWalletByID", mock.Anything, nil, req.FromWalletID).Return(from, nil)
+	d.walletsRepo.On("GetWalletByID", mock.Anything, nil, req.ToWalletID).Return(to, nil)
+
+	_, err := svc.TransferBetweenWallets(context.Background(), ownerAccess(), req)
+
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid transfer")
+	d.txManager.AssertNotCalled(t, "Begin", mock.Anything)
+	d.assertAll(t)
+}
+
+func TestTransferBetweenWallets_InsufficientBalance(t *testing.T) {
+	d := newWalletTestDeps()
+	svc := d.service()
+
+	req := sampleTransferRequest()
+	from, to := sampleWalletModel(), sampleTargetWallet()
+	locked := from
+	locked.Balance = money.MustParse("32000") // kurang dari amount + fee
+
+	d.walletsRepo.On("GetWalletByID", mock.Anything, nil, req.FromWalletID).Return(from, nil)
+	d.walletsRepo.On("GetWalletByID", mock.Anything, nil, req.ToWalletID).Return(to, nil)
+	d.txManager.On("Begin", mock.Anything).Return(d.tx, nil)
+	d.walletsRepo.On("LockWallets", mock.Anything, d.tx, mock.Anything).Return([]model.Wallets{locked, to}, nil)
+	d.tx.On("Rollback").Return(nil)
+
+	_, err := svc.TransferBetweenWallets(context.Background(), ownerAccess(), req)
+
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "insufficient balance")
+	assert.ErrorIs(t, err, ErrInsufficientBalance)
+	assert.Equal(t, domainerr.FailedPrecondition, domainerr.KindOf(err))
+	d.txClient.AssertNotCalled(t, "CreateFundTransfer", mock.Anything, mock.Anything)
+	d.assertAll(t)
+}
+
+func TestTransferBetweenWallets_GRPCError(t *testing.T) {
+	d := newWalletTestDeps()
+	svc := d.service()
+
+	req := sampleTransferRequest()
+	from, to := sampleWalletModel(), sampleTargetWallet()
+
+	d.walletsRepo.On("GetWalletByID", mock.Anything, nil, req.FromWalletID).Return(from, nil)
+	d.walletsRepo.On("GetWalletByID", mock.Anything, nil, req.ToWalletID).Return(to, nil)
+	d.txManager.On("Begin", mock.Anything).Return(d.tx, nil)
+	d.walletsRepo.On("LockWallets", mock.Anything, d.tx, mock.Anything).Return([]model.Wallets{from, to}, nil)
+	d.txClient.On("CreateFundTransfer", mock.Anything, mock.Anything).Return(nil, status.Error(codes.InvalidArgument, "invalid category"))
+	d.tx.On("Rollback").Return(nil)
+
+	_, err := svc.TransferBetweenWallets(context.Background(), ownerAccess(), req)
+
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "create fund transfer via grpc")
+	d.txClient.AssertNotCalled(t, "CancelFundTransfer", mock.Anything, mock.Anything)
+	d.sagaRepo.AssertNotCalled(t, "RecordTransferReview", mock.Anything, mock.Anything)
+	d.assertAll(t)
+}
+
+func TestTransferBetweenWallets_GRPCTimeoutNeedsReview(t *testing.T) {
+	d := newWalletTestDeps()
+	svc := d.service()
+
+	req := sampleTransferRequest()
+	from, to := sampleWalletModel(), sampleTargetWallet()
+
+	d.walletsRepo.On("GetWalletByID", mock.Anything, nil, req.FromWalletID).Return(from, nil)
+	d.walletsRepo.On("GetWalletByID", mock.Anything, nil, req.ToWalletID).Return(to, nil)
+	d.txManager.On("Begin", mock.Anything).Return(d.tx, nil)
+	d.walletsRepo.On("LockWallets", mock.Anything, d.tx, mock.Anything).Return([]model.Wallets{from, to}, nil)
+	d.txClient.On("CreateFundTransfer", mock.Anything, mock.Anything).Return(nil, status.Error(codes.DeadlineExceeded, "deadline exceeded"))
+	d.tx.On("Rollback").Return(nil)
+	// the fund transfer may exist, but without its ids there is nothing to cancel
+	d.sagaRepo.On("RecordTransferReview", mock.Anything, mock.MatchedBy(func(r *model.WalletTransferReview) bool {
+		return r.Reason == model.TransferReviewUnknownOutcome && r.UserID == userID &&
+			r.FromWalletID == walletID && r.ToWalletID == targetWalletID &&
+			r.Amount == req.Amount && r.AdminFee == req.AdminFee && r.CashOutTransactionID == nil
+	})).Return(nil)
+
+	_, err := svc.TransferBetweenWallets(context.Background(), ownerAccess(), req)
+
+	assert.Error(t, err)
+	d.txClient.AssertNotCalled(t, "CancelFundTransfer", mock.Anything, mock.Anything)
+	d.assertAll(t)
+}
+
+func TestTransferBetweenWallets_FailedCompensationNeedsReview(t *testing.T) {
+	d := newWalletTestDeps()
+	svc := d.service()
+
+	req := sampleTransferRequest()
+	from, to := sampleWalletModel(), sampleTargetWallet()
+	transfer := sampleFundTransfer()
+
+	d.walletsRepo.On("GetWalletByID", mock.Anything, nil, req.FromWalletID).Return(from, nil)
+	d.walletsRepo.On("GetWalletByID", mock.Anything, nil, req.ToWalletID).Return(to, nil)
+	d.txManager.On("Begin", mock.Anything).Return(d.tx, nil)
+	d.walletsRepo.On("LockWallets", mock.Anything, d.tx, mock.Anything).Return([]model.Wallets{from, to}, nil)
+	d.txClient.On("CreateFundTransfer", mock.Anything, mock.Anything).Return(transfer, nil)
+	d.walletsRepo.On("AppendBalanceEntry", mock.Anything, d.tx, mock.Anything).Return(model.WalletBalanceEntries{}, errors.New("db error")).Once()
+	d.tx.On("Rollback").Return(nil)
+	d.txClient.On("CancelFundTransfer", mock.Anything, transfer).Return(status.Error(codes.Unavailable, "unavailable"))
+	d.sagaRepo.On("RecordTransferReview", mock.Anything, mock.MatchedBy(func(r *model.WalletTransferReview) bool {
+		return r.Reason == model.TransferReviewCompensationFailed &&
+			r.CashOutTransactionID != nil && *r.CashOutTransactionID == "tx-out" &&
+			r.CashInTransactionID != nil && *r.CashInTransactionID == "tx-in"
+	})).Return(nil)
+
+	_, err := svc.TransferBetweenWallets(context.Background(), ownerAccess(), req)
+
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "append balance entry")
+	d.assertAll(t)
+}
+
+func TestTransferBetweenWallets_CommitErrorCancelsFundTransfer(t *testing.T) {
+	d := newWalletTestDeps()
+	svc := d.service()
+
+	req := sampleTransferRequest()
+	req.AdminFee = 0
+	from, to := sampleWalletModel(), sampleTargetWallet()
+	transfer := sampleFundTransfer()
+
+	d.walletsRepo.On("GetWalletByID", mock.Anything, nil, req.FromWalletID).Return(from, nil)
+	d.walletsRepo.On("GetWalletByID", mock.Anything, nil, req.ToWalletID).Return(to, nil)
+	d.txManager.On("Begin", mock.Anything).Return(d.tx, nil)
+	d.walletsRepo.On("LockWallets", mock.Anything, d.tx, mock.Anything).Return([]model.Wallets{from, to}, nil)
+	d.txClient.On("CreateFundTransfer", mock.Anything, mock.Anything).Return(transfer, nil)
+	d.walletsRepo.On("AppendBalanceEntry", mock.Anything, d.tx, mock.Anything).Return(model.WalletBalanceEntries{}, nil).Twice()
+	d.outboxRepo.On("NextSequence", mock.Anything, d.tx, mock.Anything).Return(int64(1), nil)
+	d.outboxRepo.On("Create", mock.Anything, d.tx, mock.Anything).Return(nil)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// the client gives up while the commit fails; compensation must still get its own deadline
+	d.tx.On("Commit").Return(errors.New("commit error")).Run(func(mock.Arguments) { cancel() })
+	d.tx.On("Rollback").Return(nil)
+	d.txClient.On("CancelFundTransfer", mock.MatchedBy(func(ctx context.Context) bool {
+		_, hasDeadline := ctx.Deadline()
+		return ctx.Err() == nil && hasDeadline
+	}), transfer).Return(nil)
+
+	_, err := svc.TransferBetweenWallets(ctx, ownerAccess(), req)
+
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "commit transaction")
+	d.assertAll(t)
+}
@@ -0,0 +1,139 @@
+package domainerr
+
+import (
+	"errors"
+	"net/http"
+
+	"google.golang.org/grpc/codes"
+)
+
+// Kind classifies an error by what the client can do about it; the transports map it to an
+// HTTP status and a gRPC code.
+type Kind int
+
+const (
+	// Internal is the kind of every error that was not classified
+	Internal Kind = iota
+	NotFound
+	InvalidArgument
+	// Conflict means the request lost a race; reading again and retrying may succeed
+	Conflict
+	// FailedPrecondition means the system is not in a state the request can be applied to
+	FailedPrecondition
+	PermissionDenied
+	Unauthenticated
+	Unavailable
+)
+
+func (k Kind) String() string {
+	switch k {
+	case NotFound:
+		return "not_found"
+	case InvalidArgument:
+		return "invalid_argument"
+	case Conflict:
+		return "conflict"
+	case FailedPrecondition:
+		return "failed_precondition"
+	case PermissionDenied:
+		return "permission_denied"
+	case Unauthenticated:
+		return "unauthenticated"
+	case Unavailable:
+		return "unavailable"
+	default:
+		return "internal"
+	}
+}
+
+func (k Kind) HTTPStatus() int {
+	switch k {
+	case NotFound:
+		return http.StatusNotFound
+	case InvalidArgument:
+		return http.StatusBadRequest
+	case Conflict:
+		return http.StatusConflict
+	case FailedPrecondition:
+		return http.StatusUnprocessableEntity
+	case PermissionDenied:
+		return http.StatusForbidden
+	case Unauthenticated:
+		return http.StatusUnauthorized
+	case Unavailable:
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+func (k Kind) GRPCCode() codes.Code {
+	switch k {
+	case NotFound:
+		return codes.NotFound
+	case InvalidArgument:
+		return codes.InvalidArgument
+	case Conflict:
+		// Aborted: client sebaiknya membaca ulang / menunggu lalu mencoba lagi
+		return codes.Aborted
+	case FailedPrecondition:
+		return codes.FailedPrecondition
+	case PermissionDenied:
+		return codes.PermissionDenied
+	case Unauthenticated:
+		return codes.Unauthenticated
+	case Unavailable:
+		return codes.Unavailable
+	default:
+		return codes.Internal
+	}
+}
+
+// Error is a classified error. Message is safe to show to clients and Reason is a stable
+// UPPER_SNAKE_CASE identifier for programmatic handling; Err is the optional cause, kept
+// for logs.
+type Error struct {
+	Kind    Kind
+	Reason  string
+	Message string
+	Err     error
+}
+
+// New returns a classified error without a cause, typically a package-level sentinel that
+// callers wrap with fmt.Errorf("%w: ...") to add detail for the logs.
+func New(kind Kind, reason, message string) *Error {
+	return &Error{Kind: kind, Reason: reason, Message: message}
+}
+
+// Wrap classifies err, e.g. a driver error or a parse error of a request field.
+func Wrap(err error, kind Kind, reason, message string) *Error {
+	return &Error{Kind: kind, Reason: reason, Message: message, Err: err}
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return e.Message + ": " + e.Err.Error()
+	}
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// As returns the outermost classified error in err's chain.
+func As(err error) (*Error, bool) {
+	var domainErr *Error
+	if errors.As(err, &domainErr) {
+		return domainErr, true
+	}
+	return nil, false
+}
+
+// KindOf returns the kind of the outermost classified error in err's chain, or Internal.
+func KindOf(err error) Kind {
+	if domainErr, ok := As(err); ok {
+		return domainErr.Kind
+	}
+	return Internal
+}
@@ -0,0 +1,89 @@
+package domainerr
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+)
+
+func TestKind_Mapping(t *testing.T) {
+	cases := []struct {
+		kind     Kind
+		name     string
+		httpCode int
+		grpcCode codes.Code
+	}{
+		{Internal, "internal", http.StatusInternalServerError, codes.Internal},
+		{NotFound, "not_found", http.StatusNotFound, codes.NotFound},
+		{InvalidArgument, "invalid_argument", http.StatusBadRequest, codes.InvalidArgument},
+		{Conflict, "conflict", http.StatusConflict, codes.Aborted},
+		{FailedPrecondition, "failed_precondition", http.StatusUnprocessableEntity, codes.FailedPrecondition},
+		{PermissionDenied, "permission_denied", http.StatusForbidden, codes.PermissionDenied},
+		{Unauthenticated, "unauthenticated", http.StatusUnauthorized, codes.Unauthenticated},
+		{Unavailable, "unavailable", http.StatusServiceUnavailable, codes.Unavailable},
+		// kind di luar daftar diperlakukan sebagai Internal
+		{Kind(99), "internal", http.StatusInternalServerError, codes.Internal},
+	}
+	for _, c := range cases {
+		assert.Equal(t, c.name, c.kind.String())
+		assert.Equal(t, c.httpCode, c.kind.HTTPStatus(), c.name)
+		assert.Equal(t, c.grpcCode, c.kind.GRPCCode(), c.name)
+	}
+}
+
+func TestError_Message(t *testing.T) {
+	sentinel := New(NotFound, "WALLET_NOT_FOUND", "wallet not found")
+	wrapped := Wrap(errors.New("invalid UUID length: 3"), InvalidArgument, "INVALID_USER_ID", "invalid user id")
+
+	assert.Equal(t, "wallet not found", sentinel.Error())
+	assert.Nil(t, sentinel.Unwrap())
+	assert.Equal(t, "invalid user id: invalid UUID length: 3", wrapped.Error())
+	assert.EqualError(t, wrapped.Unwrap(), "invalid UUID length: 3")
+}
+
+func TestAs_ThroughWrapping(t *testing.T) {
+	sentinel := New(NotFound, "WALLET_NOT_FOUND", "wallet not found")
+	cause := errors.New("record not found")
+
+	cases := []struct {
+		name string
+		err  error
+		want *Error
+	}{
+		{"bare", sentinel, sentinel},
+		{"wrapped once", fmt.Errorf("get wallet [id=1]: %w", sentinel), sentinel},
+		{"wrapped twice", fmt.Errorf("update wallet: %w", fmt.Errorf("%w: %w", sentinel, cause)), sentinel},
+		{"joined", errors.Join(cause, sentinel), sentinel},
+	}
+	for _, c := range cases {
+		got, ok := As(c.err)
+		assert.True(t, ok, c.name)
+		assert.Same(t, c.want, got, c.name)
+		assert.Equal(t, NotFound, KindOf(c.err), c.name)
+		assert.ErrorIs(t, c.err, sentinel, c.name)
+	}
+}
+
+func TestAs_ReturnsOutermost(t *testing.T) {
+	inner := New(NotFound, "WALLET_NOT_FOUND", "wallet not found")
+	outer := Wrap(fmt.Errorf("lock wallet: %w", inner), Conflict, "WALLET_BUSY", "wallet busy")
+
+	got, ok := As(fmt.Errorf("transfer: %w", outer))
+
+	assert.True(t, ok)
+	assert.Same(t, outer, got)
+	assert.Equal(t, Conflict, KindOf(outer))
+}
+
+func TestAs_Unclassified(t *testing.T) {
+	for _, err := range []error{nil, errors.New("boom"), fmt.Errorf("wrapped: %w", errors.New("boom"))} {
+		got, ok := As(err)
+		assert.False(t, ok)
+		assert.Nil(t, got)
+		assert.Equal(t, Internal, KindOf(err))
+	}
+}
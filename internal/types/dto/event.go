@@ -0,0 +1,20 @@
+package dto
+
+import "encoding/json"
+
+// CloudEvent is the versioned envelope every wallet domain event is published in.
+// Field names follow the CloudEvents 1.0 JSON format; sequence, schemaversion and correlationid are
+// extensions. Sequence increases by one per event of the same subject so consumers can spot gaps.
+type CloudEvent struct {
+	ID              string          `json:"id"`
+	Source          string          `json:"source"`
+	Type            string          `json:"type"`
+	SpecVersion     string          `json:"specversion"`
+	Time            string          `json:"time"`
+	Subject         string          `json:"subject"`
+	Sequence        string          `json:"sequence"`
+	DataContentType string          `json:"datacontenttype"`
+	SchemaVersion   string          `json:"schemaversion"`
+	CorrelationID   string          `json:"correlationid,omitempty"`
+	Data            json.RawMessage `json:"data"`
+}
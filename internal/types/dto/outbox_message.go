@@ -0,0 +1,35 @@
+package dto
+
+import "encoding/json"
+
+type OutboxMessageResponse struct {
+	ID            uint            `json:"id"`
+	AggregateID   string          `json:"aggregate_id"`
+	EventID       string          `json:"event_id"`
+	EventType     string          `json:"event_type"`
+	Payload       json.RawMessage `json:"payload"`
+	Retries       int             `json:"retries"`
+	MaxRetries    int             `json:"max_retries"`
+	LastError     string          `json:"last_error"`
+	CorrelationID string          `json:"correlation_id"`
+	FailedAt      string          `json:"failed_at"`
+	CreatedAt     string          `json:"created_at"`
+}
+
+// OutboxBackfillRequest selects which wallets get a fresh wallet.snapshot event.
+// From/To are RFC3339 bounds on the wallet created_at (From inclusive, To exclusive).
+type OutboxBackfillRequest struct {
+	UserID string `json:"user_id"`
+	From   string `json:"from"`
+	To     string `json:"to"`
+}
+
+// OutboxReplayRequest selects the already-published outbox rows to publish again (inclusive range).
+type OutboxReplayRequest struct {
+	FromID uint `json:"from_id"`
+	ToID   uint `json:"to_id"`
+}
+
+type OutboxEnqueueResponse struct {
+	Enqueued int64 `json:"enqueued"`
+}
@@ -0,0 +1,14 @@
+package dto
+
+import "refina-wallet/internal/types/money"
+
+// TransactionEvent is the transaction carried in transaction.* events of the transaction service.
+type TransactionEvent struct {
+	ID              string       `json:"id"`
+	UserID          string       `json:"user_id"`
+	WalletID        string       `json:"wallet_id"`
+	Amount          money.Amount `json:"amount"`
+	CategoryID      string       `json:"category_id"`
+	CategoryType    string       `json:"category_type"`
+	TransactionDate string       `json:"transaction_date"`
+}
@@ -1,23 +1,105 @@
 package dto
 
+import (
+	"refina-wallet/internal/types/money"
+	"refina-wallet/internal/types/view"
+)
+
 type WalletsResponse struct {
-	ID                    string  `json:"id"`
-	UserID                string  `json:"user_id"`
-	WalletTypeID          string  `json:"wallet_type_id"`
-	WalletType            string  `json:"wallet_type"`
-	WalletTypeName        string  `json:"wallet_type_name"`
-	WalletTypeDescription string  `json:"wallet_type_description"`
-	Name                  string  `json:"name"`
-	Number                string  `json:"number"`
-	Balance               float64 `json:"balance"`
-	CreatedAt             string  `json:"created_at"`
-	UpdatedAt             string  `json:"updated_at"`
+	ID                    string       `json:"id"`
+	UserID                string       `json:"user_id"`
+	WalletTypeID          string       `json:"wallet_type_id"`
+	WalletType            string       `json:"wallet_type"`
+	WalletTypeName        string       `json:"wallet_type_name"`
+	WalletTypeDescription string       `json:"wallet_type_description"`
+	Name                  string       `json:"name"`
+	Number                string       `json:"number"`
+	Balance               money.Amount `json:"balance"`
+	Currency              string       `json:"currency"`
+	Version               int64        `json:"version"`
+	CreatedAt             string       `json:"created_at"`
+	UpdatedAt             string       `json:"updated_at"`
 }
 
 type WalletsRequest struct {
-	UserID       string  `json:"user_id"`
-	WalletTypeID string  `json:"wallet_type_id"`
-	Name         string  `json:"name"`
-	Number       string  `json:"number"`
-	Balance      float64 `json:"balance"`
+	UserID       string       `json:"user_id"`
+	WalletTypeID string       `json:"wallet_type_id"`
+	Name         string       `json:"name"`
+	Number       string       `json:"number"`
+	Balance      money.Amount `json:"balance"`
+	Currency     string       `json:"currency"`
+}
+
+// WalletUpdatedEvent is the data of a wallet.updated event: the wallet after the update
+// plus the snapshot before it and which fields changed.
+type WalletUpdatedEvent struct {
+	WalletsResponse
+	Previous      WalletsResponse `json:"previous"`
+	ChangedFields []string        `json:"changed_fields"`
+}
+
+type WalletBalanceEntryResponse struct {
+	ID                  uint         `json:"id"`
+	WalletID            string       `json:"wallet_id"`
+	Amount              money.Amount `json:"amount"`
+	Direction           string       `json:"direction"`
+	SourceTransactionID string       `json:"source_transaction_id,omitempty"`
+	Reason              string       `json:"reason"`
+	BalanceAfter        money.Amount `json:"balance_after"`
+	CreatedAt           string       `json:"created_at"`
+}
+
+type WalletBalanceResponse struct {
+	WalletID string       `json:"wallet_id"`
+	Balance  money.Amount `json:"balance"`
+	AsOf     string       `json:"as_of"`
+}
+
+type WalletTransferRequest struct {
+	FromWalletID      string       `json:"from_wallet_id"`
+	ToWalletID        string       `json:"to_wallet_id"`
+	Amount            money.Amount `json:"amount"`
+	AdminFee          money.Amount `json:"admin_fee"`
+	CashOutCategoryID string       `json:"cash_out_category_id"`
+	CashInCategoryID  string       `json:"cash_in_category_id"`
+	TransactionDate   string       `json:"transaction_date"`
+	Description       string       `json:"description"`
+}
+
+// WalletTransferResponse is also the data of a wallet.transfer.completed event.
+type WalletTransferResponse struct {
+	CashOutTransactionID string          `json:"cash_out_transaction_id"`
+	CashInTransactionID  string          `json:"cash_in_transaction_id"`
+	FromWallet           WalletsResponse `json:"from_wallet"`
+	ToWallet             WalletsResponse `json:"to_wallet"`
+	Amount               money.Amount    `json:"amount"`
+	AdminFee             money.Amount    `json:"admin_fee"`
+	TransactionDate      string          `json:"transaction_date"`
+	Description          string          `json:"description"`
+}
+
+// CurrencyTotal is what a set of wallets holds in one currency and its value in the base currency.
+type CurrencyTotal struct {
+	Currency         string       `json:"currency"`
+	TotalWallets     int          `json:"total_wallets"`
+	TotalBalance     money.Amount `json:"total_balance"`
+	ExchangeRate     float64      `json:"exchange_rate"`
+	ConvertedBalance money.Amount `json:"converted_balance"`
+}
+
+// WalletSummaryResponse totals a user's wallets per currency; TotalBalance is in BaseCurrency.
+type WalletSummaryResponse struct {
+	UserID       string          `json:"user_id"`
+	BaseCurrency string          `json:"base_currency"`
+	TotalWallets int             `json:"total_wallets"`
+	TotalBalance money.Amount    `json:"total_balance"`
+	Currencies   []CurrencyTotal `json:"currencies"`
+}
+
+// WalletsGroupByTypeResponse is one wallet type group with the same totals as WalletSummaryResponse.
+type WalletsGroupByTypeResponse struct {
+	view.ViewUserWalletsGroupByType
+	BaseCurrency string          `json:"base_currency"`
+	TotalBalance money.Amount    `json:"total_balance"`
+	Currencies   []CurrencyTotal `json:"currencies"`
 }
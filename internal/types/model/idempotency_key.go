@@ -0,0 +1,25 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// IdempotencyKey remembers a client request by the key it was sent with, so a retry of the
+// same request is answered with the stored Response instead of being executed again.
+type IdempotencyKey struct {
+	ID               uint      `gorm:"primaryKey;autoIncrement" json:"id"`
+	Key              string    `gorm:"column:idempotency_key;type:varchar(255);not null" json:"idempotency_key"`
+	Operation        string    `gorm:"type:varchar(100);not null" json:"operation"`
+	UserID           string    `gorm:"type:varchar(100);not null;default:''" json:"user_id"`
+	RequestHash      string    `gorm:"type:varchar(64);not null" json:"request_hash"`
+	Response         []byte    `gorm:"type:jsonb" json:"response"` // nil while the request is in progress
+	CreatedAt        time.Time `json:"created_at"`
+	ExpiresAt        time.Time `gorm:"not null" json:"expires_at"`
+	ReservationToken uuid.UUID `gorm:"type:uuid;not null;default:uuid_generate_v4()" json:"reservation_token"` // new on every reservation
+}
+
+func (IdempotencyKey) TableName() string {
+	return "idempotency_keys"
+}
@@ -0,0 +1,17 @@
+package model
+
+import "time"
+
+// InboxMessage records a consumed message so redeliveries of it are ignored;
+// it is the consuming counterpart of OutboxMessage.
+type InboxMessage struct {
+	ID        uint      `gorm:"primaryKey;autoIncrement" json:"id"`
+	MessageID string    `gorm:"type:varchar(255);not null" json:"message_id"`
+	Source    string    `gorm:"type:varchar(100);not null" json:"source"`
+	EventType string    `gorm:"type:varchar(100);not null" json:"event_type"`
+	HandledAt time.Time `gorm:"autoCreateTime" json:"handled_at"`
+}
+
+func (InboxMessage) TableName() string {
+	return "inbox_messages"
+}
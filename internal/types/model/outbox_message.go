@@ -3,16 +3,24 @@ package model
 import "time"
 
 type OutboxMessage struct {
-	ID          uint       `gorm:"primaryKey;autoIncrement" json:"id"`
-	AggregateID string     `gorm:"type:uuid;index;not null" json:"aggregate_id"`
-	EventType   string     `gorm:"index;not null" json:"event_type"`
-	Payload     []byte     `gorm:"type:jsonb;not null" json:"payload"`
-	Published   bool       `gorm:"index;default:false" json:"published"`
-	PublishedAt *time.Time `json:"published_at"`
-	Retries     int        `gorm:"default:0" json:"retries"`
-	MaxRetries  int        `gorm:"default:3" json:"max_retries"`
-	CreatedAt   time.Time  `json:"created_at"`
-	UpdatedAt   time.Time  `json:"updated_at"`
+	ID            uint       `gorm:"primaryKey;autoIncrement" json:"id"`
+	AggregateID   string     `gorm:"type:uuid;index;not null" json:"aggregate_id"`
+	EventID       string     `gorm:"type:uuid" json:"event_id"`
+	EventType     string     `gorm:"index;not null" json:"event_type"`
+	Sequence      int64      `gorm:"not null;default:0" json:"sequence"`
+	Payload       []byte     `gorm:"type:jsonb;not null" json:"payload"`
+	Published     bool       `gorm:"index;default:false" json:"published"`
+	PublishedAt   *time.Time `json:"published_at"`
+	Retries       int        `gorm:"default:0" json:"retries"`
+	MaxRetries    int        `gorm:"default:3" json:"max_retries"`
+	FailedAt      *time.Time `gorm:"index" json:"failed_at"`
+	LastError     string     `gorm:"type:text" json:"last_error"`
+	CorrelationID string     `gorm:"type:varchar(100)" json:"correlation_id"`
+	LockedBy      *string    `gorm:"type:varchar(100)" json:"locked_by"`
+	LockedUntil   *time.Time `json:"locked_until"`
+	NextAttemptAt *time.Time `json:"next_attempt_at"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
 }
 
 func (OutboxMessage) TableName() string {
@@ -0,0 +1,34 @@
+package model
+
+import (
+	"time"
+
+	"refina-wallet/internal/types/money"
+
+	"github.com/google/uuid"
+)
+
+type BalanceDirection string
+
+const (
+	Credit BalanceDirection = "credit"
+	Debit  BalanceDirection = "debit"
+)
+
+// WalletBalanceEntries is one append-only ledger line; BalanceAfter is the wallet balance
+// right after the entry was applied.
+type WalletBalanceEntries struct {
+	ID                  uint             `gorm:"primaryKey;autoIncrement"`
+	WalletID            uuid.UUID        `gorm:"type:uuid;not null;index"`
+	Amount              money.Amount     `gorm:"type:decimal(18,2);not null"`
+	Direction           BalanceDirection `gorm:"type:varchar(6);not null"`
+	SourceTransactionID *string          `gorm:"type:varchar(100)"`
+	Reason              string           `gorm:"type:varchar(50);not null"`
+	BalanceAfter        money.Amount     `gorm:"type:decimal(18,2);not null"`
+	CreatedAt           time.Time
+	WalletVersion       int64 `gorm:"-"` // wallet version right after the entry, not stored
+}
+
+func (WalletBalanceEntries) TableName() string {
+	return "wallet_balance_entries"
+}
@@ -0,0 +1,41 @@
+package model
+
+import (
+	"time"
+
+	"refina-wallet/internal/types/money"
+
+	"github.com/google/uuid"
+)
+
+type SagaStatus string
+
+const (
+	SagaPending        SagaStatus = "pending"
+	SagaDepositCreated SagaStatus = "deposit_created"
+	SagaCommitted      SagaStatus = "committed"
+	SagaCompensating   SagaStatus = "compensating"
+	SagaFailed         SagaStatus = "failed"
+	// SagaNeedsReview means the initial deposit may or may not exist; an operator has to check
+	SagaNeedsReview SagaStatus = "needs_review"
+)
+
+// WalletCreationSaga tracks CreateWallet across the local transaction and the remote initial
+// deposit, so a deposit left behind by a failed creation is always cancelled eventually.
+type WalletCreationSaga struct {
+	ID                   uuid.UUID    `gorm:"type:uuid;primaryKey;default:uuid_generate_v4()"`
+	WalletID             uuid.UUID    `gorm:"type:uuid;not null"`
+	UserID               uuid.UUID    `gorm:"type:uuid;not null"`
+	Amount               money.Amount `gorm:"type:decimal(18,2);not null;default:0"`
+	DepositTransactionID *string      `gorm:"type:varchar(100)"`
+	Status               SagaStatus   `gorm:"type:varchar(20);not null;default:pending"`
+	Attempts             int          `gorm:"not null;default:0"`
+	LastError            string       `gorm:"type:text"`
+	NextAttemptAt        *time.Time
+	CreatedAt            time.Time
+	UpdatedAt            time.Time
+}
+
+func (WalletCreationSaga) TableName() string {
+	return "wallet_creation_sagas"
+}
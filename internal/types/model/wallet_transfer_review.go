@@ -0,0 +1,41 @@
+package model
+
+import (
+	"time"
+
+	"refina-wallet/internal/types/money"
+
+	"github.com/google/uuid"
+)
+
+type TransferReviewReason string
+
+const (
+	// TransferReviewUnknownOutcome means CreateFundTransfer ended without an answer, e.g. after a
+	// timeout, so the fund transfer may exist while the wallet balances were rolled back
+	TransferReviewUnknownOutcome TransferReviewReason = "unknown_outcome"
+	// TransferReviewCompensationFailed means the fund transfer exists but could not be cancelled
+	// after the local transaction failed
+	TransferReviewCompensationFailed TransferReviewReason = "compensation_failed"
+)
+
+// WalletTransferReview hands a transfer that left the transaction service and the wallet ledger
+// out of step over to an operator.
+type WalletTransferReview struct {
+	ID                   uuid.UUID            `gorm:"type:uuid;primaryKey;default:uuid_generate_v4()"`
+	UserID               uuid.UUID            `gorm:"type:uuid;not null"`
+	FromWalletID         uuid.UUID            `gorm:"type:uuid;not null"`
+	ToWalletID           uuid.UUID            `gorm:"type:uuid;not null"`
+	Amount               money.Amount         `gorm:"type:decimal(18,2);not null"`
+	AdminFee             money.Amount         `gorm:"type:decimal(18,2);not null;default:0"`
+	CashOutTransactionID *string              `gorm:"type:varchar(100)"`
+	CashInTransactionID  *string              `gorm:"type:varchar(100)"`
+	Reason               TransferReviewReason `gorm:"type:varchar(30);not null"`
+	LastError            string               `gorm:"type:text"`
+	ResolvedAt           *time.Time
+	CreatedAt            time.Time
+}
+
+func (WalletTransferReview) TableName() string {
+	return "wallet_transfer_reviews"
+}
@@ -1,14 +1,20 @@
 package model
 
-import "github.com/google/uuid"
+import (
+	"refina-wallet/internal/types/money"
+
+	"github.com/google/uuid"
+)
 
 type Wallets struct {
 	Base
-	UserID       uuid.UUID `gorm:"type:uuid;not null"`
-	WalletTypeID uuid.UUID `gorm:"type:uuid;not null"`
-	Name         string    `gorm:"type:varchar(50);not null"`
-	Number       string    `gorm:"type:varchar(50);not null"`
-	Balance      float64   `gorm:"type:decimal(18,2);not null"`
+	UserID       uuid.UUID    `gorm:"type:uuid;not null"`
+	WalletTypeID uuid.UUID    `gorm:"type:uuid;not null"`
+	Name         string       `gorm:"type:varchar(50);not null"`
+	Number       string       `gorm:"type:varchar(50);not null"`
+	Balance      money.Amount `gorm:"type:decimal(18,2);not null"`
+	Currency     string       `gorm:"type:char(3);not null;default:IDR"`
+	Version      int64        `gorm:"not null;default:1"`
 
 	WalletType WalletTypes `gorm:"foreignKey:WalletTypeID;constraint:OnUpdate:CASCADE,OnDelete:SET NULL;"`
 }
\ No newline at end of file
@@ -0,0 +1,248 @@
+package money
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"refina-wallet/internal/types/domainerr"
+)
+
+// Scale is the number of decimal places every Amount carries, matching the decimal(18,2) columns.
+const Scale = 2
+
+const unit = 100 // 10^Scale
+
+// Amount is an exact amount of money in hundredths of the currency unit. Amounts are added
+// and compared with the usual operators; JSON carries them as decimal strings ("1234.50")
+// and the database as decimals.
+type Amount int64
+
+var ErrInvalidAmount = domainerr.New(domainerr.InvalidArgument, "INVALID_AMOUNT", "invalid amount")
+
+// zeroDecimalCurrencies are the ISO 4217 currencies without a minor unit
+var zeroDecimalCurrencies = map[string]bool{
+	"BIF": true, "CLP": true, "DJF": true, "GNF": true, "ISK": true, "JPY": true,
+	"KMF": true, "KRW": true, "PYG": true, "RWF": true, "UGX": true, "UYI": true,
+	"VND": true, "VUV": true, "XAF": true, "XOF": true, "XPF": true,
+}
+
+// FromMinor returns the amount of minor units (hundredths)
+func FromMinor(minor int64) Amount {
+	return Amount(minor)
+}
+
+// FromFloat rounds f half away from zero to Scale decimals. It is meant for the double
+// fields of the protobuf messages; everything else should use Parse.
+func FromFloat(f float64) Amount {
+	return Amount(math.Round(f * unit))
+}
+
+// Parse reads a decimal such as "1234.5" or "-10". More than Scale decimals is an error
+// rather than a silent rounding.
+func Parse(s string) (Amount, error) {
+	raw := s
+	s = strings.TrimSpace(s)
+
+	// Paling banyak satu tanda di depan, jadi "-+5" ditolak
+	s, negative := strings.CutPrefix(s, "-")
+	if !negative {
+		s = strings.TrimPrefix(s, "+")
+	}
+
+	whole, frac, _ := strings.Cut(s, ".")
+	if whole == "" && frac == "" {
+		return 0, fmt.Errorf("%w %q", ErrInvalidAmount, raw)
+	}
+	if len(frac) > Scale {
+		return 0, fmt.Errorf("%w %q: more than %d decimal places", ErrInvalidAmount, raw, Scale)
+	}
+	frac += strings.Repeat("0", Scale-len(frac))
+	if whole == "" {
+		whole = "0"
+	}
+
+	for _, part := range []string{whole, frac} {
+		for _, c := range part {
+			if c < '0' || c > '9' {
+				return 0, fmt.Errorf("%w %q", ErrInvalidAmount, raw)
+			}
+		}
+	}
+
+	minor, err := strconv.ParseInt(whole+frac, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%w %q: %v", ErrInvalidAmount, raw, err)
+	}
+	if negative {
+		minor = -minor
+	}
+	return Amount(minor), nil
+}
+
+// MustParse is Parse for constants; it panics on malformed input.
+func MustParse(s string) Amount {
+	a, err := Parse(s)
+	if err != nil {
+		panic(err)
+	}
+	return a
+}
+
+// Minor returns the amount in hundredths
+func (a Amount) Minor() int64 {
+	return int64(a)
+}
+
+// Float64 converts the amount for the double fields of the protobuf messages
+func (a Amount) Float64() float64 {
+	return float64(a) / unit
+}
+
+func (a Amount) String() string {
+	minor := int64(a)
+	sign := ""
+	if minor < 0 {
+		sign = "-"
+	}
+
+	// Pakai uint64 agar math.MinInt64 tidak overflow saat dinegasikan
+	abs := uint64(minor)
+	if minor < 0 {
+		abs = -abs
+	}
+	return fmt.Sprintf("%s%d.%0*d", sign, abs/unit, Scale, abs%unit)
+}
+
+func (a Amount) Abs() Amount {
+	if a < 0 {
+		return -a
+	}
+	return a
+}
+
+// MulRate converts the amount with an exchange rate, rounding half away from zero to Scale.
+// The product is computed exactly so large balances do not pick up float error.
+func (a Amount) MulRate(rate float64) Amount {
+	r := new(big.Rat).SetFloat64(rate)
+	if r == nil {
+		return 0
+	}
+	product := r.Mul(r, new(big.Rat).SetInt64(int64(a)))
+	return Amount(roundRat(product, 1))
+}
+
+// Round rounds the amount half away from zero to the minor unit of currency (ISO 4217), e.g.
+// whole yen for JPY. Currencies with more than Scale decimals keep Scale.
+func (a Amount) Round(currency string) Amount {
+	if !zeroDecimalCurrencies[currency] {
+		return a
+	}
+	return Amount(roundRat(new(big.Rat).SetInt64(int64(a)), unit) * unit)
+}
+
+// IsRounded reports whether the amount has no more precision than currency allows
+func (a Amount) IsRounded(currency string) bool {
+	return a.Round(currency) == a
+}
+
+// roundRat returns x/step rounded half away from zero
+func roundRat(x *big.Rat, step int64) int64 {
+	x = new(big.Rat).Quo(x, new(big.Rat).SetInt64(step))
+	num, den := new(big.Int).Set(x.Num()), x.Denom()
+
+	negative := num.Sign() < 0
+	num.Abs(num)
+
+	q, r := new(big.Int).QuoRem(num, den, new(big.Int))
+	if new(big.Int).Lsh(r, 1).Cmp(den) >= 0 {
+		q.Add(q, big.NewInt(1))
+	}
+	if negative {
+		q.Neg(q)
+	}
+	return q.Int64()
+}
+
+// MarshalJSON writes the amount as a decimal string so clients never round-trip it through a float.
+func (a Amount) MarshalJSON() ([]byte, error) {
+	return json.Marshal(a.String())
+}
+
+// UnmarshalJSON accepts a decimal string or, for older clients and events, a JSON number. A string
+// must have at most Scale decimals; a number is a float to its sender, so it is rounded half away
+// from zero like FromFloat (0.30000000000000004 reads as 0.30).
+func (a *Amount) UnmarshalJSON(b []byte) error {
+	if string(b) == "null" {
+		return nil
+	}
+
+	raw := string(b)
+	if !strings.HasPrefix(raw, `"`) {
+		return a.unmarshalNumber(raw)
+	}
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return err
+	}
+
+	parsed, err := Parse(raw)
+	if err != nil {
+		return err
+	}
+	*a = parsed
+	return nil
+}
+
+func (a *Amount) unmarshalNumber(raw string) error {
+	// Angka yang sudah presisi dibaca tanpa lewat float
+	if parsed, err := Parse(raw); err == nil {
+		*a = parsed
+		return nil
+	}
+
+	f, err := strconv.ParseFloat(raw, 64)
+	if err != nil || math.Abs(f*unit) >= math.MaxInt64 {
+		return fmt.Errorf("%w %s", ErrInvalidAmount, raw)
+	}
+	*a = FromFloat(f)
+	return nil
+}
+
+// Value stores the amount as a decimal literal
+func (a Amount) Value() (driver.Value, error) {
+	return a.String(), nil
+}
+
+// Scan reads a decimal column; the driver hands numeric values over as text.
+func (a *Amount) Scan(src any) error {
+	switch v := src.(type) {
+	case nil:
+		*a = 0
+		return nil
+	case []byte:
+		return a.scanText(string(v))
+	case string:
+		return a.scanText(v)
+	case int64:
+		*a = Amount(v * unit)
+		return nil
+	case float64:
+		*a = FromFloat(v)
+		return nil
+	default:
+		return fmt.Errorf("scan amount: unsupported type %T", src)
+	}
+}
+
+func (a *Amount) scanText(s string) error {
+	parsed, err := Parse(s)
+	if err != nil {
+		return fmt.Errorf("scan amount: %w", err)
+	}
+	*a = parsed
+	return nil
+}
@@ -0,0 +1,214 @@
+package money
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse_Valid(t *testing.T) {
+	cases := []struct {
+		in   string
+		want Amount
+	}{
+		{"0", 0},
+		{"10", FromMinor(1000)},
+		{"1234.5", FromMinor(123450)},
+		{"1234.50", FromMinor(123450)},
+		{"0.01", FromMinor(1)},
+		{".5", FromMinor(50)},
+		{"5.", FromMinor(500)},
+		{"+5", FromMinor(500)},
+		{"-10", FromMinor(-1000)},
+		{"-0.01", FromMinor(-1)},
+		{"  42.10 ", FromMinor(4210)},
+		{"92233720368547758.07", Amount(math.MaxInt64)},
+	}
+	for _, c := range cases {
+		got, err := Parse(c.in)
+		assert.NoError(t, err, "%q", c.in)
+		assert.Equal(t, c.want, got, "%q", c.in)
+	}
+}
+
+func TestParse_Invalid(t *testing.T) {
+	cases := []string{
+		"",
+		"   ",
+		"-",
+		"+",
+		".",
+		"-+5",
+		"+-5",
+		"--5",
+		"5-",
+		"1.234",
+		"0.001",
+		"1,50",
+		"1e3",
+		"abc",
+		"1.2.3",
+		"92233720368547758.08",
+		"100000000000000000000",
+	}
+	for _, in := range cases {
+		_, err := Parse(in)
+		assert.ErrorIs(t, err, ErrInvalidAmount, "%q", in)
+	}
+}
+
+func TestString(t *testing.T) {
+	cases := []struct {
+		in   Amount
+		want string
+	}{
+		{0, "0.00"},
+		{FromMinor(5), "0.05"},
+		{FromMinor(-5), "-0.05"},
+		{FromMinor(123450), "1234.50"},
+		{FromMinor(-123450), "-1234.50"},
+		{Amount(math.MaxInt64), "92233720368547758.07"},
+		{Amount(math.MinInt64), "-92233720368547758.08"},
+	}
+	for _, c := range cases {
+		assert.Equal(t, c.want, c.in.String(), "%d", int64(c.in))
+	}
+}
+
+func TestMulRate_RoundsHalfAwayFromZero(t *testing.T) {
+	cases := []struct {
+		amount Amount
+		rate   float64
+		want   Amount
+	}{
+		{MustParse("100"), 1, MustParse("100")},
+		{MustParse("100"), 0.5, MustParse("50")},
+		{FromMinor(1), 0.5, FromMinor(1)},
+		{FromMinor(-1), 0.5, FromMinor(-1)},
+		{FromMinor(3), 0.5, FromMinor(2)},
+		{FromMinor(1), 0.25, 0},
+		{FromMinor(-1), 0.25, 0},
+		{FromMinor(7), 0.75, FromMinor(5)},
+		// tanpa error float walaupun saldonya besar
+		{MustParse("1000000000.01"), 16250, MustParse("16250000000162.50")},
+		{MustParse("100"), 0, 0},
+	}
+	for _, c := range cases {
+		assert.Equal(t, c.want, c.amount.MulRate(c.rate), "%s x %v", c.amount, c.rate)
+	}
+}
+
+func TestRound_ZeroDecimalCurrency(t *testing.T) {
+	cases := []struct {
+		amount   Amount
+		currency string
+		want     Amount
+	}{
+		{MustParse("100.49"), "JPY", MustParse("100")},
+		{MustParse("100.50"), "JPY", MustParse("101")},
+		{MustParse("-100.50"), "JPY", MustParse("-101")},
+		{MustParse("0.49"), "JPY", 0},
+		{MustParse("100"), "JPY", MustParse("100")},
+		{MustParse("100.49"), "IDR", MustParse("100.49")},
+		{MustParse("100.49"), "USD", MustParse("100.49")},
+	}
+	for _, c := range cases {
+		assert.Equal(t, c.want, c.amount.Round(c.currency), "%s %s", c.amount, c.currency)
+	}
+
+	assert.True(t, MustParse("100").IsRounded("JPY"))
+	assert.False(t, MustParse("100.01").IsRounded("JPY"))
+	assert.True(t, MustParse("100.01").IsRounded("IDR"))
+}
+
+func TestJSON_RoundTrip(t *testing.T) {
+	for _, a := range []Amount{0, FromMinor(1), FromMinor(-123450), Amount(math.MaxInt64), Amount(math.MinInt64 + 1)} {
+		b, err := json.Marshal(a)
+		assert.NoError(t, err)
+		assert.Equal(t, `"`+a.String()+`"`, string(b))
+
+		var got Amount
+		assert.NoError(t, json.Unmarshal(b, &got))
+		assert.Equal(t, a, got)
+	}
+}
+
+func TestUnmarshalJSON_AcceptsNumbersAndNull(t *testing.T) {
+	var got struct {
+		Number Amount  `json:"number"`
+		String Amount  `json:"string"`
+		Null   *Amount `json:"null"`
+		Kept   Amount  `json:"kept"`
+	}
+	got.Kept = FromMinor(7)
+
+	err := json.Unmarshal([]byte(`{"number": 1234.5, "string": "-0.01", "null": null, "kept": null}`), &got)
+
+	assert.NoError(t, err)
+	assert.Equal(t, FromMinor(123450), got.Number)
+	assert.Equal(t, FromMinor(-1), got.String)
+	assert.Nil(t, got.Null)
+	assert.Equal(t, FromMinor(7), got.Kept)
+}
+
+func TestUnmarshalJSON_NumbersRoundHalfAwayFromZero(t *testing.T) {
+	cases := []struct {
+		in   string
+		want Amount
+	}{
+		{`0.30000000000000004`, MustParse("0.3")},
+		{`12.345`, MustParse("12.35")},
+		{`-12.345`, MustParse("-12.35")},
+		{`1e3`, MustParse("1000")},
+		{`92233720368547758.07`, Amount(math.MaxInt64)},
+	}
+
+	for _, c := range cases {
+		var got Amount
+		assert.NoError(t, json.Unmarshal([]byte(c.in), &got), c.in)
+		assert.Equal(t, c.want, got, c.in)
+	}
+}
+
+func TestUnmarshalJSON_Invalid(t *testing.T) {
+	for _, in := range []string{`"1.234"`, `"0.30000000000000004"`, `"-+5"`, `""`, `true`, `"abc"`, `1e300`} {
+		var got Amount
+		assert.Error(t, json.Unmarshal([]byte(in), &got), in)
+	}
+}
+
+func TestScan_RoundTrip(t *testing.T) {
+	for _, a := range []Amount{0, FromMinor(1), FromMinor(-123450), Amount(math.MaxInt64), Amount(math.MinInt64 + 1)} {
+		value, err := a.Value()
+		assert.NoError(t, err)
+
+		var fromString, fromBytes Amount
+		assert.NoError(t, fromString.Scan(value))
+		assert.NoError(t, fromBytes.Scan([]byte(value.(string))))
+		assert.Equal(t, a, fromString)
+		assert.Equal(t, a, fromBytes)
+	}
+}
+
+func TestScan_OtherSources(t *testing.T) {
+	cases := []struct {
+		src  any
+		want Amount
+	}{
+		{nil, 0},
+		{int64(12), MustParse("12")},
+		{float64(12.345), MustParse("12.35")},
+		{"12.3", MustParse("12.3")},
+	}
+	for _, c := range cases {
+		got := FromMinor(99)
+		assert.NoError(t, got.Scan(c.src), "%v", c.src)
+		assert.Equal(t, c.want, got, "%v", c.src)
+	}
+
+	var got Amount
+	assert.Error(t, got.Scan(true))
+	assert.ErrorIs(t, got.Scan("1.234"), ErrInvalidAmount)
+}
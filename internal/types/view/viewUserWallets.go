@@ -1,10 +1,13 @@
 package view
 
+import "refina-wallet/internal/types/money"
+
 type ViewUserWalletsGroupByTypeDetailWallet struct {
-	ID      string  `json:"id"`
-	Name    string  `json:"name"`
-	Number  string  `json:"number"`
-	Balance float64 `json:"balance"`
+	ID       string       `json:"id"`
+	Name     string       `json:"name"`
+	Number   string       `json:"number"`
+	Balance  money.Amount `json:"balance"`
+	Currency string       `json:"currency"`
 }
 
 type ViewUserWalletsGroupByType struct {
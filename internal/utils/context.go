@@ -0,0 +1,20 @@
+package utils
+
+import "context"
+
+type requestIDKey struct{}
+
+// WithRequestID stores the X-Request-ID of the originating call so it can follow the request
+// into the service layer (e.g. as the correlation id of emitted events).
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	if requestID == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, requestIDKey{}, requestID)
+}
+
+// RequestIDFromContext returns the request ID stored by WithRequestID, or "" if none.
+func RequestIDFromContext(ctx context.Context) string {
+	v, _ := ctx.Value(requestIDKey{}).(string)
+	return v
+}
@@ -7,13 +7,113 @@ var (
 	STAGING_MODE     = "staging"
 	PRODUCTION_MODE  = "production"
 
-	OUTBOX_PUBLISH_EXCHANGE     = "refina_microservice"
-	OUTBOX_PUBLISH_INTERVAL     = 5 * time.Second
-	OUTBOX_PUBLISH_BATCH        = 100
-	OUTBOX_PUBLISH_MAX_RETRIES  = 5
-	OUTBOX_EVENT_WALLET_CREATED = "wallet.created"
-	OUTBOX_EVENT_WALLET_UPDATED = "wallet.updated"
-	OUTBOX_EVENT_WALLET_DELETED = "wallet.deleted"
+	OUTBOX_PUBLISH_EXCHANGE = "refina_microservice"
+	OUTBOX_PUBLISH_INTERVAL = 5 * time.Second
+	// OUTBOX_PUBLISH_FALLBACK_INTERVAL is the safety-net poll used while LISTEN/NOTIFY wakes the publisher.
+	OUTBOX_PUBLISH_FALLBACK_INTERVAL = 30 * time.Second
+	OUTBOX_NOTIFY_CHANNEL            = "outbox_messages"
+	OUTBOX_LISTEN_MIN_BACKOFF        = 1 * time.Second
+	OUTBOX_LISTEN_MAX_BACKOFF        = 30 * time.Second
+	OUTBOX_PUBLISH_BATCH             = 100
+	OUTBOX_PUBLISH_MAX_RETRIES       = 5
+	OUTBOX_RETRY_BASE_BACKOFF        = 30 * time.Second
+	OUTBOX_RETRY_MAX_BACKOFF         = 30 * time.Minute
+	// OUTBOX_CLAIM_LEASE is kept short so a crashed replica holds its rows (and their aggregates) only
+	// briefly; the publisher renews the rest of its batch whenever less than OUTBOX_CLAIM_RENEW_MARGIN
+	// is left, which covers one message waiting out the confirm timeout of its publish and of its
+	// dead-letter notification, plus a margin for the database
+	OUTBOX_CLAIM_LEASE           = 1 * time.Minute
+	OUTBOX_CLAIM_RENEW_MARGIN    = 2*OUTBOX_CONFIRM_TIMEOUT + 10*time.Second
+	OUTBOX_CONFIRM_TIMEOUT       = 10 * time.Second
+	OUTBOX_CLEANUP_INTERVAL      = 1 * time.Hour
+	OUTBOX_RETENTION_PERIOD      = 7 * 24 * time.Hour
+	OUTBOX_CLEANUP_BATCH         = 1000
+	OUTBOX_EVENT_WALLET_CREATED  = "wallet.created"
+	OUTBOX_EVENT_WALLET_UPDATED  = "wallet.updated"
+	OUTBOX_EVENT_WALLET_DELETED  = "wallet.deleted"
+	OUTBOX_EVENT_WALLET_TRANSFER = "wallet.transfer.completed"
+	// OUTBOX_EVENT_WALLET_SNAPSHOT carries the same data as wallet.created but is emitted by a backfill,
+	// so existing consumers of wallet.created are not handed duplicates.
+	OUTBOX_EVENT_WALLET_SNAPSHOT = "wallet.snapshot"
+	OUTBOX_BACKFILL_BATCH        = 500
+
+	RABBITMQ_CHANNEL_POOL_SIZE     = 10
+	RABBITMQ_RECONNECT_MIN_BACKOFF = 1 * time.Second
+	RABBITMQ_RECONNECT_MAX_BACKOFF = 30 * time.Second
+
+	CLOUDEVENTS_SPEC_VERSION       = "1.0"
+	CLOUDEVENTS_CONTENT_TYPE       = "application/cloudevents+json"
+	CLOUDEVENTS_HEADER_PREFIX      = "cloudEvents_"
+	OUTBOX_EVENT_SOURCE            = "/refina/wallet"
+	OUTBOX_EVENT_SCHEMA_VERSION    = "2" // 2: amounts are decimal strings ("1234.50"), no longer JSON numbers
+	OUTBOX_EVENT_DATA_CONTENT_TYPE = "application/json"
+
+	OUTBOX_DEAD_LETTER_ROUTING_KEY = "outbox.dead_letter"
+	OUTBOX_DEAD_LETTER_PAGE_SIZE   = 50
+
+	BALANCE_REASON_OPENING           = "opening_balance"
+	BALANCE_REASON_INITIAL_DEPOSIT   = "initial_deposit"
+	BALANCE_REASON_MANUAL_ADJUSTMENT = "manual_adjustment"
+	BALANCE_REASON_TRANSACTION       = "transaction"
+	BALANCE_REASON_TRANSFER_OUT      = "transfer_out"
+	BALANCE_REASON_TRANSFER_IN       = "transfer_in"
+	BALANCE_REASON_TRANSFER_FEE      = "transfer_fee"
+	WALLET_BALANCE_ENTRIES_PAGE_SIZE = 50
+	// TRANSFER_FEE_SOURCE_SUFFIX is appended to the cash-out transaction id to give the admin fee
+	// its own ledger source, so created/updated events of the cash-out leave the fee alone while a
+	// transaction.deleted for it reverses the fee too.
+	TRANSFER_FEE_SOURCE_SUFFIX = ":admin_fee"
+
+	CONSUMER_PREFETCH    = 10
+	CONSUMER_RETRY_DELAY = 1 * time.Second
+
+	// INBOX_RETENTION_PERIOD must outlive any redelivery the broker can still make of a handled message.
+	INBOX_RETENTION_PERIOD = 30 * 24 * time.Hour
+	INBOX_CLEANUP_INTERVAL = 1 * time.Hour
+	INBOX_CLEANUP_BATCH    = 1000
+
+	TRANSACTION_EVENTS_QUEUE             = "wallet.transaction_events"
+	TRANSACTION_EVENTS_DEAD_LETTER_QUEUE = "wallet.transaction_events.dead_letter"
+	TRANSACTION_EVENT_SOURCE             = "/refina/transaction"
+	TRANSACTION_EVENT_CREATED            = "transaction.created"
+	TRANSACTION_EVENT_UPDATED            = "transaction.updated"
+	TRANSACTION_EVENT_DELETED            = "transaction.deleted"
+	TRANSACTION_CATEGORY_INCOME          = "income"
+	TRANSACTION_CATEGORY_EXPENSE         = "expense"
+
+	SAGA_WORKER_INTERVAL      = 30 * time.Second
+	SAGA_COMPENSATION_BATCH   = 50
+	SAGA_COMPENSATION_LEASE   = 1 * time.Minute
+	SAGA_RETRY_BASE_BACKOFF   = 30 * time.Second
+	SAGA_RETRY_MAX_BACKOFF    = 30 * time.Minute
+	SAGA_ALERT_AFTER_ATTEMPTS = 5
+	SAGA_STALE_AFTER          = 10 * time.Minute
+	SAGA_COMPENSATION_TIMEOUT = 15 * time.Second
+
+	// IDEMPOTENCY_KEY_TTL is how long a completed request is replayed to retries of its key.
+	IDEMPOTENCY_KEY_TTL = 24 * time.Hour
+	// IDEMPOTENCY_IN_PROGRESS_TTL bounds how long a crashed request keeps its key reserved.
+	IDEMPOTENCY_IN_PROGRESS_TTL  = 1 * time.Minute
+	IDEMPOTENCY_KEY_MAX_LENGTH   = 255
+	IDEMPOTENCY_CLEANUP_INTERVAL = 1 * time.Hour
+	IDEMPOTENCY_CLEANUP_BATCH    = 1000
+	IDEMPOTENCY_OP_CREATE_WALLET = "wallet.create"
+	IDEMPOTENCY_OP_UPDATE_WALLET = "wallet.update"
+	IDEMPOTENCY_OP_DELETE_WALLET = "wallet.delete"
+	IDEMPOTENCY_OP_TRANSFER      = "wallet.transfer"
+
+	// GRPC_WALLET_TRANSFER_SERVICE serves TransferBetweenWallets until Refina-Protobuf has a typed RPC
+	// for it; its messages are google.protobuf.Struct in the JSON shape of POST /wallets/transfers.
+	GRPC_WALLET_TRANSFER_SERVICE              = "refina.wallet.v1.WalletTransferService"
+	GRPC_TRANSFER_BETWEEN_WALLETS_FULL_METHOD = "/" + GRPC_WALLET_TRANSFER_SERVICE + "/TransferBetweenWallets"
+	// GRPC_OUTBOX_ADMIN_SERVICE mirrors POST /admin/outbox/backfill and /admin/outbox/replay the same way
+	GRPC_OUTBOX_ADMIN_SERVICE                  = "refina.wallet.v1.OutboxAdminService"
+	GRPC_BACKFILL_WALLET_SNAPSHOTS_FULL_METHOD = "/" + GRPC_OUTBOX_ADMIN_SERVICE + "/BackfillWalletSnapshots"
+	GRPC_REPLAY_PUBLISHED_FULL_METHOD          = "/" + GRPC_OUTBOX_ADMIN_SERVICE + "/ReplayPublished"
+
+	// DEFAULT_CURRENCY is the ISO 4217 code of wallets created without one and the base
+	// currency of summaries when neither the request nor the config names one.
+	DEFAULT_CURRENCY = "IDR"
 
 	INITIAL_DEPOSIT_CATEGORY_ID = "00000000-0000-0000-0000-000000000000"
 	INITIAL_DEPOSIT_DESC        = "Deposit awal"
@@ -22,4 +122,23 @@ var (
 	REQUEST_ID_HEADER = "X-Request-ID"
 	// REQUEST_ID_LOCAL_KEY is the key used to store the request ID in Gin's context locals.
 	REQUEST_ID_LOCAL_KEY = "request_id"
+	// IDEMPOTENCY_KEY_HEADER carries the client key that makes a mutating request safe to retry.
+	IDEMPOTENCY_KEY_HEADER = "Idempotency-Key"
+	// IDEMPOTENCY_REPLAYED_HEADER is set on responses replayed from an earlier request.
+	IDEMPOTENCY_REPLAYED_HEADER = "Idempotent-Replayed"
+	// BASE_CURRENCY_QUERY names the currency summaries are converted to.
+	BASE_CURRENCY_QUERY = "base_currency"
+	// AUTHORIZATION_HEADER carries the bearer token of authenticated HTTP requests.
+	AUTHORIZATION_HEADER = "Authorization"
+	// USER_DATA_LOCAL_KEY is the key the auth middleware stores the caller's dto.UserData under in Gin's context locals.
+	USER_DATA_LOCAL_KEY = "user_data"
+
+	// JWT_CLOCK_LEEWAY tolerates clock skew between the token issuer and this service on exp/nbf.
+	JWT_CLOCK_LEEWAY                = 30 * time.Second
+	JWKS_FETCH_TIMEOUT              = 10 * time.Second
+	JWKS_MIN_REFRESH_INTERVAL       = 1 * time.Minute
+	JWKS_MAX_SIZE             int64 = 1 << 20
+
+	// ERROR_INFO_DOMAIN is the domain of the google.rpc.ErrorInfo details attached to gRPC errors.
+	ERROR_INFO_DOMAIN = "wallet.refina"
 )
@@ -2,16 +2,24 @@ package data
 
 // Service field logging constants
 const (
-	MainService       = "main"
-	EnvService        = "env"
-	DatabaseService   = "database"
-	RabbitmqService   = "rabbitmq"
-	GRPCClientService = "grpc_client"
-	GRPCServerService = "grpc_server"
-	HTTPServerService = "http_server"
-	OutboxService     = "outbox"
-	WalletService     = "wallet"
-	WalletTypeService = "wallet_type"
+	MainService        = "main"
+	EnvService         = "env"
+	DatabaseService    = "database"
+	RabbitmqService    = "rabbitmq"
+	GRPCClientService  = "grpc_client"
+	GRPCServerService  = "grpc_server"
+	HTTPServerService  = "http_server"
+	HealthService      = "health"
+	ConsumerService    = "consumer"
+	InboxService       = "inbox"
+	IdempotencyService = "idempotency"
+	WalletSagaService  = "wallet_saga"
+	BalanceSyncService = "balance_sync"
+	OutboxService      = "outbox"
+	OutboxAdminService = "outbox_admin"
+	WalletService      = "wallet"
+	WalletTypeService  = "wallet_type"
+	AuthService        = "auth"
 )
 
 // Message field logging constants
@@ -20,12 +28,21 @@ const (
 	LogEnvVarMissing = "env_var_missing"
 
 	// --- infrastructure setup ---
-	LogDBSetupSuccess       = "db_setup_success"
-	LogRabbitmqSetupSuccess = "rabbitmq_setup_success"
-	LogRabbitmqInitFailed   = "rabbitmq_init_failed"
+	LogDBSetupSuccess          = "db_setup_success"
+	LogRabbitmqSetupSuccess    = "rabbitmq_setup_success"
+	LogRabbitmqInitFailed      = "rabbitmq_init_failed"
+	LogExchangeRateSetupFailed = "exchange_rate_setup_failed"
+	LogAuthSetupFailed         = "auth_setup_failed"
+
+	// --- rabbitmq connection supervisor ---
+	LogRabbitmqConnectionLost  = "rabbitmq_connection_lost"
+	LogRabbitmqReconnectFailed = "rabbitmq_reconnect_failed"
+	LogRabbitmqReconnected     = "rabbitmq_reconnected"
 
 	// --- outbox publisher ---
 	LogOutboxPublisherStarted       = "outbox_publisher_started"
+	LogOutboxListenerStarted        = "outbox_listener_started"
+	LogOutboxListenerFailed         = "outbox_listener_failed"
 	LogOutboxPublishPendingFailed   = "outbox_publish_pending_failed"
 	LogOutboxMessagePublishFailed   = "outbox_message_publish_failed"
 	LogOutboxMessageMaxRetries      = "outbox_message_max_retries_exceeded"
@@ -33,6 +50,52 @@ const (
 	LogOutboxMarkPublishedFailed    = "outbox_mark_published_failed"
 	LogOutboxMessagePublished       = "outbox_message_published"
 	LogOutboxCleanupFailed          = "outbox_cleanup_failed"
+	LogOutboxCleanupCompleted       = "outbox_cleanup_completed"
+	LogOutboxMessageDeadLettered    = "outbox_message_dead_lettered"
+	LogOutboxDeadLetterNotifyFailed = "outbox_dead_letter_notify_failed"
+	LogOutboxClaimLost              = "outbox_claim_lost"
+
+	// --- queue consumer ---
+	LogConsumerStarted              = "consumer_started"
+	LogConsumerStopped              = "consumer_stopped"
+	LogConsumerHandleFailed         = "consumer_handle_failed"
+	LogConsumerAckFailed            = "consumer_ack_failed"
+	LogInboxMessageDuplicate        = "inbox_message_duplicate"
+	LogInboxCleanupFailed           = "inbox_cleanup_failed"
+	LogInboxCleanupCompleted        = "inbox_cleanup_completed"
+	LogTransactionEventApplied      = "transaction_event_applied"
+	LogTransactionEventSkipped      = "transaction_event_skipped"
+	LogTransactionEventWalletAbsent = "transaction_event_wallet_not_found"
+	LogTransactionEventStale        = "transaction_event_stale"
+
+	// --- idempotency ---
+	LogIdempotentReplay            = "idempotent_request_replayed"
+	LogIdempotencyCompleteFailed   = "idempotency_key_complete_failed"
+	LogIdempotencyReleaseFailed    = "idempotency_key_release_failed"
+	LogIdempotencyCleanupCompleted = "idempotency_key_cleanup_completed"
+	LogIdempotencyCleanupFailed    = "idempotency_key_cleanup_failed"
+
+	// --- wallet creation saga ---
+	LogSagaStateUpdateFailed     = "wallet_saga_state_update_failed"
+	LogSagaCompensated           = "wallet_saga_compensated"
+	LogSagaCompensationFailed    = "wallet_saga_compensation_failed"
+	LogSagaCompensationAlert     = "wallet_saga_compensation_alert"
+	LogSagaRecovered             = "wallet_saga_recovered"
+	LogSagaUnknownDepositOutcome = "wallet_saga_unknown_deposit_outcome"
+	LogSagaWorkerFailed          = "wallet_saga_worker_failed"
+
+	// --- outbox admin (http handler) ---
+	LogGetDeadLettersFailed    = "get_dead_letters_failed"
+	LogGetDeadLetterFailed     = "get_dead_letter_failed"
+	LogRequeueDeadLetterFailed = "requeue_dead_letter_failed"
+	LogDeadLetterRequeued      = "dead_letter_requeued"
+	LogDiscardDeadLetterFailed = "discard_dead_letter_failed"
+	LogDeadLetterDiscarded     = "dead_letter_discarded"
+	LogOutboxAdminBadRequest   = "outbox_admin_bad_request"
+	LogOutboxBackfillFailed    = "outbox_backfill_failed"
+	LogOutboxBackfillCompleted = "outbox_backfill_completed"
+	LogOutboxReplayFailed      = "outbox_replay_failed"
+	LogOutboxReplayCompleted   = "outbox_replay_completed"
 
 	// --- grpc client ---
 	LogGRPCClientSetupSuccess   = "grpc_client_setup_success"
@@ -43,11 +106,20 @@ const (
 	LogGRPCServerStarted     = "grpc_server_started"
 	LogGRPCServerSetupFailed = "grpc_server_setup_failed"
 	LogGRPCServerServeFailed = "grpc_server_serve_failed"
+	LogGRPCCallerTokenUnset  = "grpc_trusted_caller_token_unset" // x-user-roles is ignored on every call
+
+	// --- auth ---
+	LogAuthTokenMissing       = "auth_token_missing"
+	LogAuthTokenRejected      = "auth_token_rejected"
+	LogAccessDenied           = "access_denied"
+	LogUntrustedRolesIgnored  = "untrusted_roles_ignored"
+	LogAccessPolicyIncomplete = "access_policy_incomplete"
 
 	// --- http server ---
 	LogHTTPServerStarted        = "http_server_started"
 	LogHTTPServerStartFailed    = "http_server_start_failed"
 	LogHTTPServerShutdownFailed = "http_server_shutdown_failed"
+	LogHealthCheckUnhealthy     = "health_check_unhealthy"
 
 	// --- shutdown ---
 	LogShutdownSignalReceived      = "shutdown_signal_received"
@@ -67,7 +139,18 @@ const (
 	LogWalletCreated                     = "wallet_created"
 	LogUpdateWalletBadRequest            = "update_wallet_bad_request"
 	LogUpdateWalletFailed                = "update_wallet_failed"
+	LogDeleteWalletBadRequest            = "delete_wallet_bad_request"
 	LogDeleteWalletFailed                = "delete_wallet_failed"
+	LogGetWalletBalanceEntriesFailed     = "get_wallet_balance_entries_failed"
+	LogGetWalletBalanceBadRequest        = "get_wallet_balance_bad_request"
+	LogGetWalletBalanceFailed            = "get_wallet_balance_failed"
+	LogTransferWalletBadRequest          = "transfer_wallet_bad_request"
+	LogTransferWalletFailed              = "transfer_wallet_failed"
+	LogWalletTransferred                 = "wallet_transferred"
+	LogTransferGRPCFailedRollback        = "transfer_wallet_grpc_failed_will_rollback"
+	LogTransferCompensationFailed        = "transfer_wallet_compensation_failed"
+	LogTransferUnknownOutcome            = "transfer_wallet_unknown_outcome"
+	LogTransferReviewRecordFailed        = "transfer_wallet_review_record_failed"
 
 	// --- wallet (grpc server) ---
 	LogGetAllWalletsStreamFailed  = "get_all_wallets_stream_send_failed"
@@ -1,8 +1,13 @@
 package utils
 
 import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
 	"time"
 
+	"refina-wallet/internal/types/domainerr"
 	"refina-wallet/internal/types/dto"
 	"refina-wallet/internal/types/model"
 
@@ -22,6 +27,8 @@ func ConvertToResponseType(data any) any {
 			Name:                  v.Name,
 			Number:                v.Number,
 			Balance:               v.Balance,
+			Currency:              v.Currency,
+			Version:               v.Version,
 			CreatedAt:             v.CreatedAt.Format(time.RFC3339),
 			UpdatedAt:             v.UpdatedAt.Format(time.RFC3339),
 		}
@@ -32,6 +39,39 @@ func ConvertToResponseType(data any) any {
 			Type:        dto.WalletType(v.Type),
 			Description: v.Description,
 		}
+	case model.WalletBalanceEntries:
+		sourceTransactionID := ""
+		if v.SourceTransactionID != nil {
+			sourceTransactionID = *v.SourceTransactionID
+		}
+		return dto.WalletBalanceEntryResponse{
+			ID:                  v.ID,
+			WalletID:            v.WalletID.String(),
+			Amount:              v.Amount,
+			Direction:           string(v.Direction),
+			SourceTransactionID: sourceTransactionID,
+			Reason:              v.Reason,
+			BalanceAfter:        v.BalanceAfter,
+			CreatedAt:           v.CreatedAt.Format(time.RFC3339),
+		}
+	case model.OutboxMessage:
+		failedAt := ""
+		if v.FailedAt != nil {
+			failedAt = v.FailedAt.Format(time.RFC3339)
+		}
+		return dto.OutboxMessageResponse{
+			ID:            v.ID,
+			AggregateID:   v.AggregateID,
+			EventID:       v.EventID,
+			EventType:     v.EventType,
+			Payload:       v.Payload,
+			Retries:       v.Retries,
+			MaxRetries:    v.MaxRetries,
+			LastError:     v.LastError,
+			CorrelationID: v.CorrelationID,
+			FailedAt:      failedAt,
+			CreatedAt:     v.CreatedAt.Format(time.RFC3339),
+		}
 	default:
 		return nil
 	}
@@ -48,3 +88,47 @@ func ParseUUID(id string) (uuid.UUID, error) {
 func Ms(d time.Duration) float64 {
 	return float64(d.Nanoseconds()) / 1e6
 }
+
+// FormatETag renders a wallet version as a strong entity tag
+func FormatETag(version int64) string {
+	return strconv.Quote(strconv.FormatInt(version, 10))
+}
+
+// ParseETag reads the version out of an If-Match value. An empty value or "*" returns 0,
+// meaning the caller did not ask for a precondition.
+func ParseETag(value string) (int64, error) {
+	value = strings.TrimSpace(value)
+	if value == "" || value == "*" {
+		return 0, nil
+	}
+
+	value = strings.TrimPrefix(value, "W/")
+	unquoted, err := strconv.Unquote(value)
+	if err != nil {
+		unquoted = value
+	}
+
+	version, err := strconv.ParseInt(unquoted, 10, 64)
+	if err != nil || version <= 0 {
+		return 0, errors.New("invalid etag")
+	}
+	return version, nil
+}
+
+// ErrInvalidCurrency is returned for codes that are not 3-letter ISO 4217 codes
+var ErrInvalidCurrency = domainerr.New(domainerr.InvalidArgument, "INVALID_CURRENCY", "invalid currency")
+
+// NormalizeCurrency upper-cases an ISO 4217 alphabetic code and checks its shape. Whether the
+// currency is actually supported is up to the exchange-rate provider.
+func NormalizeCurrency(code string) (string, error) {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	if len(code) != 3 {
+		return "", fmt.Errorf("%w %q: expected a 3-letter ISO 4217 code", ErrInvalidCurrency, code)
+	}
+	for _, c := range code {
+		if c < 'A' || c > 'Z' {
+			return "", fmt.Errorf("%w %q: expected a 3-letter ISO 4217 code", ErrInvalidCurrency, code)
+		}
+	}
+	return code, nil
+}